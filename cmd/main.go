@@ -1,9 +1,11 @@
 package main
 
 import (
-	"github.com/brownlow2/pdb/internal/demo"
+	"os"
+
+	"github.com/brownlow2/pdb/internal/cli"
 )
 
 func main() {
-	demo.Demo()
+	os.Exit(cli.Main(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/brownlow2/pdb/pkg/audit"
+)
+
+// The file in the data directory every change is recorded in
+const auditLogFile = "audit.jsonl"
+
+// Loads the audit log from the data directory and records every change made from now on in it
+func (e *env) openAuditLog() error {
+	path := filepath.Join(e.dataDir, auditLogFile)
+	e.auditFile = &appendFile{path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		e.dbm.SetAuditLog(audit.New(e.auditFile))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	log, err := audit.Load(f, e.auditFile)
+	if err != nil {
+		return err
+	}
+	e.dbm.SetAuditLog(log)
+
+	return nil
+}
+
+func (e *env) audit(args []string) error {
+	fs := e.flagSet("audit")
+	var q audit.Query
+	fs.StringVar(&q.Actor, "actor", "", "only print changes made by this actor")
+	fs.StringVar(&q.DB, "db", "", "only print changes to this database")
+	fs.StringVar(&q.Operation, "operation", "", "only print this kind of change, e.g. add_row or update_value")
+	fs.StringVar(&q.Key, "key", "", "only print changes to the row with this key")
+	fs.StringVar(&q.Header, "header", "", "only print changes to this header")
+	since := fs.String("since", "", "only print changes made at or after this RFC 3339 time")
+	until := fs.String("until", "", "only print changes made before this RFC 3339 time")
+	fs.IntVar(&q.Limit, "limit", 0, "print at most this many changes, 0 for no limit")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range []struct {
+		flag  string
+		value string
+		time  *time.Time
+	}{{"since", *since, &q.Since}, {"until", *until, &q.Until}} {
+		if t.value == "" {
+			continue
+		}
+
+		*t.time, err = time.Parse(time.RFC3339Nano, t.value)
+		if err != nil {
+			return usageErr(fmt.Sprintf(invalidTimeError, t.flag, t.value))
+		}
+	}
+
+	return e.dbm.AuditLog().Export(e.stdout, q)
+}
+
+// Prints the error writing the audit log the first time there is one, after which changes are
+// only recorded in memory
+func (e *env) reportAuditErr() {
+	if e.dbm == nil || e.dbm.AuditLog() == nil || e.auditReported {
+		return
+	}
+
+	err := e.dbm.AuditLog().Err()
+	if err != nil {
+		e.auditReported = true
+		fmt.Fprintf(e.stderr, "error: could not write the audit log: %s\n", err)
+	}
+}
+
+// Writes p to the file, opening it on the first write
+// The audit log serializes its writes, so the file isn't locked
+func (a *appendFile) Write(p []byte) (int, error) {
+	if a.f == nil {
+		err := os.MkdirAll(filepath.Dir(a.path), 0o755)
+		if err != nil {
+			return 0, err
+		}
+
+		a.f, err = os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return a.f.Write(p)
+}
+
+// Closes the file if it was opened
+func (a *appendFile) Close() error {
+	if a.f == nil {
+		return nil
+	}
+
+	err := a.f.Close()
+	a.f = nil
+	return err
+}
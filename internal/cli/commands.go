@@ -0,0 +1,640 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/internal/demo"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/rpc"
+	"github.com/brownlow2/pdb/pkg/server"
+)
+
+// Exit codes returned by Main, mapped from the errors returned by the db package
+const (
+	ExitOK = iota
+	ExitError
+	ExitUsage
+	ExitHeaderNotExist
+	ExitDuplicateKey
+	ExitNotANumber
+	ExitDBNotExist
+)
+
+const mainUsage = `Usage: pdb [--data <dir>] <command> [flags]
+
+Commands:
+  shell     start the interactive shell (default)
+  create    create a database
+  insert    add a row to a database
+  get       print the row with a given key
+  query     print the rows matching filters
+  import    add rows from a CSV, JSON, NDJSON or XLSX file
+  export    write every row as CSV, JSON, NDJSON or XLSX
+  schema    print the headers of a database
+  serve     serve the databases over HTTP and gRPC
+  audit     print the audit log of changes as JSON lines
+  demo      run the Platinum Tracker demo
+
+Run 'pdb <command> --help' for the flags of a command.`
+
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Runs the pdb command line with the given arguments, excluding the program name, and
+// returns the exit code
+func Main(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	global := flag.NewFlagSet("pdb", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprintln(stderr, mainUsage) }
+	dataDir := global.String("data", defaultDataDir(), "directory the databases are loaded from and saved to")
+	actor := global.String("actor", os.Getenv("USER"), "who changes are attributed to in the audit log")
+	err := global.Parse(args)
+	if err == flag.ErrHelp {
+		return ExitOK
+	}
+	if err != nil {
+		return ExitUsage
+	}
+
+	name, args := "shell", global.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	e := &env{dataDir: *dataDir, actor: *actor, stdin: stdin, stdout: stdout, stderr: stderr}
+	if name != "demo" {
+		registerBackends(e.dataDir)
+		e.dbm, err = dbmanager.Load(e.dataDir)
+		if err == nil {
+			err = e.openAuditLog()
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %s\n", err)
+			return ExitError
+		}
+	}
+
+	err = e.run(name, args)
+	// Write the rows the file backends hold
+	if e.dbm != nil {
+		if closeErr := e.dbm.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	e.saveMu.Lock()
+	e.reportAuditErr()
+	e.saveMu.Unlock()
+	if e.auditFile != nil {
+		e.auditFile.Close()
+	}
+	if err == flag.ErrHelp {
+		return ExitOK
+	}
+	if err != nil {
+		if err.Error() != "" {
+			fmt.Fprintf(stderr, "error: %s\n", err)
+		}
+		return exitCode(err)
+	}
+
+	return ExitOK
+}
+
+func (e *env) run(name string, args []string) error {
+	switch name {
+	case "shell":
+		return e.shell(args)
+	case "create":
+		return e.create(args)
+	case "insert":
+		return e.insert(args)
+	case "get":
+		return e.get(args)
+	case "query":
+		return e.query(args)
+	case "import":
+		return e.importRows(args)
+	case "export":
+		return e.export(args)
+	case "schema":
+		return e.schema(args)
+	case "serve":
+		return e.serve(args)
+	case "audit":
+		return e.audit(args)
+	case "demo":
+		demo.Demo()
+		return nil
+	}
+
+	fmt.Fprintln(e.stderr, mainUsage)
+	return usageErr(fmt.Sprintf(unknownSubcommandError, name))
+}
+
+// Returns the exit code for an error, based on the kind of error the db package returned
+func exitCode(err error) int {
+	var usage usageErr
+	if errors.As(err, &usage) {
+		return ExitUsage
+	}
+
+	switch {
+	case errors.Is(err, db.ErrHeaderNotExist):
+		return ExitHeaderNotExist
+	case errors.Is(err, db.ErrDuplicateKey):
+		return ExitDuplicateKey
+	case errors.Is(err, db.ErrNotANumber) || errors.Is(err, db.ErrHeaderNotNumber):
+		return ExitNotANumber
+	case errors.Is(err, dbmanager.ErrDBNotExist):
+		return ExitDBNotExist
+	}
+
+	return ExitError
+}
+
+func (e *env) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("pdb "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// Parses the flags, turning parse failures into usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return err
+	}
+	if err != nil {
+		// The flag package has already printed the problem
+		return usageErr("")
+	}
+
+	if fs.NArg() > 0 {
+		return usageErr(fmt.Sprintf(unexpectedArgumentError, fs.Arg(0)))
+	}
+
+	return nil
+}
+
+// Returns the DB named by the --db flag
+func (e *env) retrieveDB(name string) (db.DB, error) {
+	if name == "" {
+		return nil, usageErr(missingDBFlagError)
+	}
+
+	return e.manager().RetrieveDB(name)
+}
+
+// Returns the DB manager attributing changes to the --actor flag
+func (e *env) manager() dbmanager.DBManager {
+	if e.actor == "" {
+		return e.dbm
+	}
+
+	return e.dbm.As(e.actor)
+}
+
+func (e *env) save() error {
+	return e.dbm.Save(e.dataDir)
+}
+
+func (e *env) shell(args []string) error {
+	fs := e.flagSet("shell")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	repl := NewREPL(e.dbm, e.dataDir, e.stdout)
+	repl.Actor = e.actor
+	repl.History, err = LoadHistory(filepath.Join(e.dataDir, ".history"), 1000)
+	if err != nil {
+		return err
+	}
+
+	if e.stdin == os.Stdin {
+		return repl.RunTerminal()
+	}
+
+	return repl.Run(e.stdin)
+}
+
+func (e *env) create(args []string) error {
+	fs := e.flagSet("create")
+	name := fs.String("db", "", "name of the database")
+	keyHeader := fs.String("key", "", "name of the key header")
+	var headers stringList
+	fs.Var(&headers, "header", "header to add as <name>[:string|number], may be repeated")
+	backend := fs.String("backend", db.BACKEND_MEMORY, "backend keeping the rows: "+strings.Join(db.Backends(), ", "))
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" || *keyHeader == "" {
+		return usageErr(fmt.Sprintf(usageError, "pdb create --db <db> --key <key header> [--header <name>[:<type>] ...] [--backend <backend>]"))
+	}
+
+	hs := []db.HeaderI{&db.Header{Name: *keyHeader, KeyHeader: true, Type: db.VALUE_STRING}}
+	for _, header := range headers {
+		name, typeName, found := strings.Cut(header, ":")
+		t := db.VALUE_STRING
+		if found {
+			t, err = db.ParseType(typeName)
+			if err != nil {
+				return usageErr(err.Error())
+			}
+		}
+		hs = append(hs, &db.Header{Name: name, KeyHeader: false, Type: t})
+	}
+
+	ctx := db.WithActor(context.Background(), e.actor)
+	err = e.dbm.CreateDBWithOptionsContext(ctx, *name, hs, *keyHeader, dbmanager.CreateDBOptions{Backend: *backend})
+	if err != nil {
+		return err
+	}
+
+	return e.save()
+}
+
+func (e *env) insert(args []string) error {
+	fs := e.flagSet("insert")
+	name := fs.String("db", "", "name of the database")
+	var sets stringList
+	fs.Var(&sets, "set", "value to insert as <header>=<value>, may be repeated")
+	upsert := fs.Bool("upsert", false, "merge into the existing row if the key already exists")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	d, err := e.retrieveDB(*name)
+	if err != nil {
+		return err
+	}
+
+	values, err := parseAssignments(sets)
+	if err != nil {
+		return usageErr(err.Error())
+	}
+
+	row, err := newRow(d, values)
+	if err != nil {
+		return err
+	}
+
+	if *upsert {
+		err = d.Upsert(row)
+	} else {
+		err = d.AddRow(row)
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.save()
+}
+
+func (e *env) get(args []string) error {
+	fs := e.flagSet("get")
+	name := fs.String("db", "", "name of the database")
+	key := fs.String("key", "", "key header value of the row")
+	format := fs.String("format", formatTable, "output format: table, unicode, markdown, html, json or csv")
+	maxWidth := fs.Int("max-width", 0, "truncate table cells wider than this, 0 for no limit")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	d, err := e.retrieveDB(*name)
+	if err != nil {
+		return err
+	}
+
+	row := d.GetRowFromKeyHeader(*key)
+	if row == nil {
+		return &db.Error{Err: db.ErrRowNotExist, Key: *key, Message: fmt.Sprintf(rowNotExistError, *key)}
+	}
+
+	return writeRows(e.stdout, *format, db.SortedHeaders(d), []db.RowI{row}, *maxWidth)
+}
+
+func (e *env) query(args []string) error {
+	fs := e.flagSet("query")
+	name := fs.String("db", "", "name of the database")
+	columns := fs.String("columns", "", "comma separated headers to print, defaults to all")
+	format := fs.String("format", formatTable, "output format: table, unicode, markdown, html, json or csv")
+	maxWidth := fs.Int("max-width", 0, "truncate table cells wider than this, 0 for no limit")
+	var wheres stringList
+	fs.Var(&wheres, "where", "filter as <header><op><value> with op one of =, !=, <, >, may be repeated")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	d, err := e.retrieveDB(*name)
+	if err != nil {
+		return err
+	}
+
+	headers := db.SortedHeaders(d)
+	if *columns != "" {
+		headers = []db.HeaderI{}
+		for _, column := range strings.Split(*columns, ",") {
+			h := d.GetHeader(strings.TrimSpace(column))
+			if h.GetName() == "" {
+				return &db.Error{Err: db.ErrHeaderNotExist, Header: column, Message: fmt.Sprintf(headerNotExistError, column)}
+			}
+			headers = append(headers, h)
+		}
+	}
+
+	predicates := []db.Predicate{}
+	for _, where := range wheres {
+		tokens, err := tokenize(where)
+		if err != nil {
+			return usageErr(err.Error())
+		}
+		predicate, err := parseCondition(d, tokens)
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	rows := []db.RowI{}
+	for _, row := range d.GetRows() {
+		matches := true
+		for _, predicate := range predicates {
+			matches = matches && predicate(row)
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+
+	return writeRows(e.stdout, *format, headers, rows, *maxWidth)
+}
+
+func (e *env) importRows(args []string) error {
+	fs := e.flagSet("import")
+	name := fs.String("db", "", "name of the database")
+	file := fs.String("file", "", "file to read, defaults to stdin")
+	format := fs.String("format", formatCSV, "input format: csv, json, ndjson or xlsx")
+	bestEffort := fs.Bool("best-effort", false, "add the valid rows even if some fail")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	d, err := e.retrieveDB(*name)
+	if err != nil {
+		return err
+	}
+
+	in := e.stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// NDJSON is streamed into the DB in batches rather than read whole, and a workbook is a zip
+	// file that must be read whole, so neither can be best effort
+	switch *format {
+	case formatNDJSON:
+		_, err = db.ImportJSON(d, in, db.JSON_LINES)
+		if err != nil {
+			return err
+		}
+		return e.save()
+	case formatXLSX:
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		_, err = db.ImportXLSX(d, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		return e.save()
+	}
+
+	rows, err := readRows(in, *format, d)
+	if err != nil {
+		return err
+	}
+
+	errs, afterErr := d.AddRows(rows, db.AddRowsOptions{AllOrNothing: !*bestEffort})
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(e.stderr, "row %d: %s\n", i+1, err)
+		}
+	}
+	_, first := db.FirstRowError(errs)
+
+	if first != nil && !*bestEffort {
+		return first
+	}
+
+	err = e.save()
+	if err != nil {
+		return err
+	}
+
+	if first == nil {
+		return afterErr
+	}
+
+	return first
+}
+
+func (e *env) export(args []string) error {
+	fs := e.flagSet("export")
+	name := fs.String("db", "", "name of the database")
+	file := fs.String("file", "", "file to write, defaults to stdout")
+	format := fs.String("format", formatCSV, "output format: csv, json, ndjson or xlsx")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	d, err := e.retrieveDB(*name)
+	if err != nil {
+		return err
+	}
+
+	if *format != formatCSV && *format != formatJSON && *format != formatNDJSON && *format != formatXLSX {
+		return usageErr(fmt.Sprintf(unknownFormatError, *format))
+	}
+
+	out := e.stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch *format {
+	case formatNDJSON:
+		return db.ExportJSON(d, out, db.JSON_LINES)
+	case formatXLSX:
+		return db.ExportXLSX(out, d)
+	}
+
+	return writeRows(out, *format, db.SortedHeaders(d), d.GetRows(), 0)
+}
+
+func (e *env) schema(args []string) error {
+	fs := e.flagSet("schema")
+	name := fs.String("db", "", "name of the database")
+	format := fs.String("format", formatTable, "output format: table, json or csv")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	d, err := e.retrieveDB(*name)
+	if err != nil {
+		return err
+	}
+
+	return writeSchema(e.stdout, *format, d)
+}
+
+func (e *env) serve(args []string) error {
+	fs := e.flagSet("serve")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	grpcAddr := fs.String("grpc-addr", "", "address to serve the gRPC API on as well, if set")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	handler := e.saveAfterHTTP(server.New(e.dbm))
+
+	errs := make(chan error, 2)
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+
+		gs := rpc.NewGRPCServer(e.dbm, grpc.UnaryInterceptor(e.saveAfterGRPC))
+		fmt.Fprintf(e.stderr, "gRPC listening on %s\n", *grpcAddr)
+		go func() {
+			errs <- gs.Serve(lis)
+		}()
+	}
+
+	fmt.Fprintf(e.stderr, "listening on %s\n", *addr)
+	go func() {
+		errs <- http.ListenAndServe(*addr, handler)
+	}()
+
+	return <-errs
+}
+
+// Saves the databases after each HTTP request that changes them, which are those other than GETs
+// that succeed
+func (e *env) saveAfterHTTP(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// GETs are passed the writer itself, as streaming events needs it to flush
+		if r.Method == http.MethodGet {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		if rec.status < http.StatusBadRequest {
+			e.saveAndReport()
+		}
+	})
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Saves the databases after each gRPC call that can change them
+func (e *env) saveAfterGRPC(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	switch path.Base(info.FullMethod) {
+	case "ListDBs", "GetDB", "GetRow":
+	default:
+		if err == nil {
+			e.saveAndReport()
+		}
+	}
+
+	return resp, err
+}
+
+func (e *env) saveAndReport() {
+	e.saveMu.Lock()
+	defer e.saveMu.Unlock()
+
+	err := e.save()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "error: %s\n", err)
+	}
+	e.reportAuditErr()
+}
+
+// Registers the backends keeping rows in files, which keep them in the rows directory of dataDir
+func registerBackends(dataDir string) {
+	dir := filepath.Join(dataDir, "rows")
+	for name, backend := range map[string]db.Backend{
+		db.BACKEND_SNAPSHOT: db.SnapshotBackend(dir),
+		db.BACKEND_WAL:      db.WALBackend(dir),
+		db.BACKEND_PAGED:    db.PagedBackend(dir, 0),
+	} {
+		db.RegisterBackend(name, func(name string) (db.RowsI, error) {
+			err := os.MkdirAll(dir, 0o755)
+			if err != nil {
+				return nil, err
+			}
+			return backend(name)
+		})
+	}
+}
+
+func defaultDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".pdb"
+	}
+
+	return filepath.Join(home, ".pdb")
+}
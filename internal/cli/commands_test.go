@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/pkg/audit"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/server"
+)
+
+// Runs pdb against dir, returning the exit code, stdout and stderr
+func runMain(dir string, stdin string, args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := Main(append([]string{"--data", dir}, args...), strings.NewReader(stdin), stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func newTestDataDir(t *testing.T) string {
+	dir := t.TempDir()
+	code, _, stderr := runMain(dir, "", "create", "--db", "Plat", "--key", "Title", "--header", "Platform", "--header", "Hours:number")
+	assert.Equal(t, ExitOK, code, stderr)
+	code, _, stderr = runMain(dir, "", "insert", "--db", "Plat", "--set", "Title=Jak 2", "--set", "Platform=PS4", "--set", "Hours=23")
+	assert.Equal(t, ExitOK, code, stderr)
+
+	return dir
+}
+
+func TestMainExitCodes(t *testing.T) {
+	dir := newTestDataDir(t)
+
+	code, _, _ := runMain(dir, "", "insert", "--db", "Plat", "--set", "Title=Jak 2")
+	assert.Equal(t, ExitDuplicateKey, code)
+
+	code, _, _ = runMain(dir, "", "insert", "--db", "Plat", "--set", "Title=New", "--set", "Missing=1")
+	assert.Equal(t, ExitHeaderNotExist, code)
+
+	code, _, _ = runMain(dir, "", "insert", "--db", "Plat", "--set", "Title=New", "--set", "Hours=lots")
+	assert.Equal(t, ExitNotANumber, code)
+
+	code, _, _ = runMain(dir, "", "get", "--db", "Missing", "--key", "Jak 2")
+	assert.Equal(t, ExitDBNotExist, code)
+
+	code, _, _ = runMain(dir, "", "get", "--db", "Plat", "--key", "Missing")
+	assert.Equal(t, ExitError, code)
+
+	code, _, _ = runMain(dir, "", "bogus")
+	assert.Equal(t, ExitUsage, code)
+
+	code, _, _ = runMain(dir, "", "query")
+	assert.Equal(t, ExitUsage, code)
+
+	code, _, _ = runMain(dir, "", "query", "--db", "Plat", "--format", "xml")
+	assert.Equal(t, ExitUsage, code)
+
+	code, _, _ = runMain(dir, "", "create", "--db", "Other")
+	assert.Equal(t, ExitUsage, code)
+}
+
+func TestMainBackend(t *testing.T) {
+	dir := t.TempDir()
+	code, _, stderr := runMain(dir, "", "create", "--db", "Plat", "--key", "Title", "--backend", "paged")
+	assert.Equal(t, ExitOK, code, stderr)
+	code, _, stderr = runMain(dir, "", "insert", "--db", "Plat", "--set", "Title=Jak 2")
+	assert.Equal(t, ExitOK, code, stderr)
+
+	// The DB is reopened on its backend, which keeps its rows under the data directory
+	data, err := os.ReadFile(filepath.Join(dir, "Plat.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"backend": "paged"`)
+	_, err = os.Stat(filepath.Join(dir, "rows", "Plat.pdbp"))
+	assert.Nil(t, err)
+	code, stdout, stderr := runMain(dir, "", "get", "--db", "Plat", "--key", "Jak 2")
+	assert.Equal(t, ExitOK, code, stderr)
+	assert.Contains(t, stdout, "Jak 2")
+
+	code, _, stderr = runMain(dir, "", "create", "--db", "Other", "--key", "Title", "--backend", "tape")
+	assert.Equal(t, ExitError, code)
+	assert.Contains(t, stderr, "unknown backend 'tape'")
+}
+
+func TestMainQuery(t *testing.T) {
+	dir := newTestDataDir(t)
+	code, _, _ := runMain(dir, "", "insert", "--db", "Plat", "--set", "Title=Hogwarts Legacy", "--set", "Platform=PS5", "--set", "Hours=55")
+	assert.Equal(t, ExitOK, code)
+
+	code, stdout, _ := runMain(dir, "", "query", "--db", "Plat", "--where", "Hours > 30", "--columns", "Title", "--format", "csv")
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, "Title\nHogwarts Legacy\n", stdout)
+
+	code, stdout, _ = runMain(dir, "", "query", "--db", "Plat", "--where", "Hours > 10", "--where", "Platform=PS4", "--format", "json")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, `"Hours": 23`)
+	assert.NotContains(t, stdout, "Hogwarts")
+
+	code, stdout, _ = runMain(dir, "", "get", "--db", "Plat", "--key", "Jak 2")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "PS4")
+
+	code, stdout, _ = runMain(dir, "", "schema", "--db", "Plat")
+	assert.Equal(t, ExitOK, code)
+	assert.True(t, strings.HasPrefix(stdout, "Title (K)"))
+}
+
+func TestMainImportExport(t *testing.T) {
+	dir := newTestDataDir(t)
+
+	code, _, stderr := runMain(dir, "Title,Platform\nJak 2,PS5\nNew,PS5\n", "import", "--db", "Plat")
+	assert.Equal(t, ExitDuplicateKey, code)
+	assert.Contains(t, stderr, "row 1:")
+	_, stdout, _ := runMain(dir, "", "export", "--db", "Plat")
+	assert.NotContains(t, stdout, "New")
+
+	code, _, _ = runMain(dir, "Title,Platform\nJak 2,PS5\nNew,PS5\n", "import", "--db", "Plat", "--best-effort")
+	assert.Equal(t, ExitDuplicateKey, code)
+	_, stdout, _ = runMain(dir, "", "export", "--db", "Plat")
+	assert.Equal(t, "Title,Hours,Platform\nJak 2,23,PS4\nNew,,PS5\n", stdout)
+
+	code, _, stderr = runMain(dir, `[{"Title": "Json", "Hours": 4.5}]`, "import", "--db", "Plat", "--format", "json")
+	assert.Equal(t, ExitOK, code, stderr)
+	_, stdout, _ = runMain(dir, "", "export", "--db", "Plat", "--format", "json")
+	assert.Contains(t, stdout, `"Hours": 4.5`)
+
+	code, _, stderr = runMain(dir, `{"Title": "Lines", "Hours": 7}`+"\n", "import", "--db", "Plat", "--format", "ndjson")
+	assert.Equal(t, ExitOK, code, stderr)
+	_, stdout, _ = runMain(dir, "", "export", "--db", "Plat", "--format", "ndjson")
+	assert.Contains(t, stdout, `{"Title":"Lines","Hours":7,"Platform":""}`+"\n")
+
+	_, workbook, _ := runMain(dir, "", "export", "--db", "Plat", "--format", "xlsx")
+	code, _, _ = runMain(dir, workbook, "import", "--db", "Plat", "--format", "xlsx")
+	assert.Equal(t, ExitDuplicateKey, code)
+}
+
+func TestMainAudit(t *testing.T) {
+	dir := newTestDataDir(t)
+
+	code, _, stderr := runMain(dir, "", "--actor", "alice", "insert", "--db", "Plat", "--set", "Title=Jak 3")
+	assert.Equal(t, ExitOK, code, stderr)
+	code, _, stderr = runMain(dir, "open Plat\nset \"Jak 3\" Hours=30\n", "--actor", "bob", "shell")
+	assert.Equal(t, ExitOK, code, stderr)
+
+	code, stdout, stderr := runMain(dir, "", "audit", "--db", "Plat", "--key", "Jak 3")
+	assert.Equal(t, ExitOK, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], `"actor":"alice","db":"Plat","operation":"add_row","key":"Jak 3"`)
+	assert.Contains(t, lines[1], `"actor":"bob","db":"Plat","operation":"update_value","key":"Jak 3","header":"Hours","newValue":"30"`)
+
+	_, stdout, _ = runMain(dir, "", "audit", "--actor", "bob")
+	assert.Equal(t, lines[1]+"\n", stdout)
+
+	_, stdout, _ = runMain(dir, "", "audit", "--operation", "create_db", "--limit", "1")
+	assert.Contains(t, stdout, `"operation":"create_db"`)
+
+	code, _, _ = runMain(dir, "", "audit", "--since", "yesterday")
+	assert.Equal(t, ExitUsage, code)
+}
+
+func TestMainShell(t *testing.T) {
+	dir := newTestDataDir(t)
+
+	code, stdout, _ := runMain(dir, "open Plat\nselect Title\n", "shell")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "Jak 2")
+}
+
+func TestServeSaves(t *testing.T) {
+	dir := newTestDataDir(t)
+	dbm, err := dbmanager.Load(dir)
+	assert.Nil(t, err)
+	e := &env{dataDir: dir, dbm: dbm, stderr: &bytes.Buffer{}}
+	handler := e.saveAfterHTTP(server.New(dbm))
+	serve := func(method string, path string, body string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec.Code
+	}
+	saved := func() string {
+		data, err := os.ReadFile(filepath.Join(dir, "Plat.json"))
+		assert.Nil(t, err)
+		return string(data)
+	}
+
+	// A change made without a request is only saved by the next request that changes something
+	d, _ := dbm.RetrieveDB("Plat")
+	assert.Nil(t, d.AddValueToHeader("PS5", "Platform", "Jak 2"))
+	assert.Equal(t, http.StatusBadRequest, serve("PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": "lots"}`))
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/dbs/Plat/rows/Missing", ""))
+	assert.Equal(t, http.StatusOK, serve("GET", "/dbs/Plat/rows/Jak%202", ""))
+	assert.NotContains(t, saved(), "PS5")
+
+	assert.Equal(t, http.StatusOK, serve("PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 24}`))
+	assert.Contains(t, saved(), "PS5")
+	assert.Equal(t, "", e.stderr.(*bytes.Buffer).String())
+}
+
+func TestServeReportsAuditErr(t *testing.T) {
+	dir := newTestDataDir(t)
+	dbm, err := dbmanager.Load(dir)
+	assert.Nil(t, err)
+	// The audit log can't be created under a file
+	blocked := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, os.WriteFile(blocked, nil, 0o644))
+	dbm.SetAuditLog(audit.New(&appendFile{path: filepath.Join(blocked, auditLogFile)}))
+	stderr := &bytes.Buffer{}
+	e := &env{dataDir: dir, dbm: dbm, stderr: stderr}
+	handler := e.saveAfterHTTP(server.New(dbm))
+
+	for _, hours := range []string{"24", "25"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("PATCH", "/dbs/Plat/rows/Jak%202", strings.NewReader(`{"Hours": `+hours+`}`)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// The error is reported while serving, once
+	assert.Equal(t, 1, strings.Count(stderr.String(), "could not write the audit log"))
+}
+
+func TestAppendFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", auditLogFile)
+	f := &appendFile{path: path}
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	for _, line := range []string{"a\n", "b\n"} {
+		_, err = f.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, f.Close())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb\n", string(data))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitUsage, exitCode(usageErr("bad flag")))
+	assert.Equal(t, ExitError, exitCode(errors.New("something else")))
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Loads the history stored at path, keeping at most max entries
+// A missing file is treated as an empty history
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{Entries: []string{}, Path: path, Max: max}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.add(scanner.Text())
+	}
+
+	return h, scanner.Err()
+}
+
+// Add records a new entry, appending it to the history file if there is one
+// Empty lines and repeats of the previous entry are ignored
+func (h *History) Add(entry string) {
+	if !h.add(entry) || h.Path == "" {
+		return
+	}
+
+	f, err := os.OpenFile(h.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		// History is a convenience, failing to persist it shouldn't stop the shell
+		return
+	}
+	defer f.Close()
+	f.WriteString(entry + "\n")
+}
+
+// Len returns the number of entries in the history
+func (h *History) Len() int {
+	return len(h.Entries)
+}
+
+// At returns an entry with 0 being the most recent
+func (h *History) At(idx int) string {
+	return h.Entries[len(h.Entries)-1-idx]
+}
+
+func (h *History) add(entry string) bool {
+	entry = strings.TrimSpace(entry)
+	if entry == "" || (len(h.Entries) > 0 && h.Entries[len(h.Entries)-1] == entry) {
+		return false
+	}
+
+	h.Entries = append(h.Entries, entry)
+	if h.Max > 0 && len(h.Entries) > h.Max {
+		h.Entries = h.Entries[len(h.Entries)-h.Max:]
+	}
+
+	return true
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := LoadHistory(path, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, h.Len())
+
+	h.Add("show dbs")
+	h.Add("show dbs")
+	h.Add("  ")
+	h.Add("open test")
+	assert.Equal(t, 2, h.Len())
+	assert.Equal(t, "open test", h.At(0))
+	assert.Equal(t, "show dbs", h.At(1))
+
+	h.Add("select")
+	assert.Equal(t, 2, h.Len())
+	assert.Equal(t, []string{"open test", "select"}, h.Entries)
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "show dbs\nopen test\nselect\n", string(data))
+
+	loaded, err := LoadHistory(path, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"show dbs", "open test", "select"}, loaded.Entries)
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/internal/render"
+)
+
+const (
+	formatTable    = "table"
+	formatUnicode  = "unicode"
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatNDJSON   = "ndjson"
+	formatXLSX     = "xlsx"
+)
+
+// The table styles used for each of the table formats
+var tableStyles = map[string]render.Style{
+	formatTable:    render.ASCII,
+	formatUnicode:  render.UNICODE,
+	formatMarkdown: render.MARKDOWN,
+	formatHTML:     render.HTML,
+}
+
+// Writes the rows in the given format with one column per header, ordered by KeyHeader value
+// maxWidth truncates the cells of table formats, 0 for no limit
+func writeRows(out io.Writer, format string, headers []db.HeaderI, rows []db.RowI, maxWidth int) error {
+	rows = sortRows(rows)
+
+	if style, ok := tableStyles[format]; ok {
+		return render.Render(out, rows, render.Options{
+			Style:    style,
+			Columns:  headerNames(headers),
+			MaxWidth: maxWidth,
+		})
+	}
+
+	switch format {
+	case formatJSON:
+		objects := []map[string]interface{}{}
+		for _, row := range rows {
+			object := map[string]interface{}{}
+			for _, h := range headers {
+				v, err := row.GetValueFromHeader(h.GetName())
+				if err != nil {
+					return err
+				}
+				object[h.GetName()] = jsonValue(h, v.GetValue())
+			}
+			objects = append(objects, object)
+		}
+		return writeJSON(out, objects)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		w.Write(headerNames(headers))
+		for _, row := range rows {
+			values, err := rowValues(row, headers)
+			if err != nil {
+				return err
+			}
+			w.Write(values)
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	return usageErr(fmt.Sprintf(unknownFormatError, format))
+}
+
+// Writes the DB's headers, their types and which one is the KeyHeader in the given format
+func writeSchema(out io.Writer, format string, d db.DB) error {
+	headers := db.SortedHeaders(d)
+
+	switch format {
+	case formatTable:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, h := range headers {
+			fmt.Fprintf(w, "%s\t%s\n", headerLabel(h), h.GetType())
+		}
+		return w.Flush()
+	case formatJSON:
+		schema := []map[string]interface{}{}
+		for _, h := range headers {
+			schema = append(schema, map[string]interface{}{
+				"name": h.GetName(),
+				"type": h.GetType().String(),
+				"key":  h.IsKeyHeader(),
+			})
+		}
+		return writeJSON(out, schema)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		w.Write([]string{"name", "type", "key"})
+		for _, h := range headers {
+			w.Write([]string{h.GetName(), h.GetType().String(), fmt.Sprint(h.IsKeyHeader())})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	return usageErr(fmt.Sprintf(unknownFormatError, format))
+}
+
+// Reads rows for the DB from either a CSV file with a header line, or a JSON array of objects
+func readRows(in io.Reader, format string, d db.DB) ([]db.RowI, error) {
+	records := []map[string]string{}
+
+	switch format {
+	case formatCSV:
+		r := csv.NewReader(in)
+		lines, err := r.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(lines) == 0 {
+			return []db.RowI{}, nil
+		}
+		for _, line := range lines[1:] {
+			record := map[string]string{}
+			for i, header := range lines[0] {
+				record[header] = line[i]
+			}
+			records = append(records, record)
+		}
+	case formatJSON:
+		decoder := json.NewDecoder(in)
+		decoder.UseNumber()
+		objects := []map[string]interface{}{}
+		err := decoder.Decode(&objects)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			record := map[string]string{}
+			for header, value := range object {
+				if value != nil {
+					record[header] = fmt.Sprint(value)
+				}
+			}
+			records = append(records, record)
+		}
+	default:
+		return nil, usageErr(fmt.Sprintf(unknownFormatError, format))
+	}
+
+	rows := make([]db.RowI, 0, len(records))
+	for _, record := range records {
+		row, err := newRow(d, record)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Returns the value as a JSON number for VALUE_NUMBER headers, or a string otherwise
+// Empty numbers become null, while NaN and Inf stay strings as JSON numbers can't hold them
+func jsonValue(h db.HeaderI, value string) interface{} {
+	if !h.IsNumber() {
+		return value
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	if number, ok := db.FormatNumber(value); ok {
+		return json.Number(number)
+	}
+
+	return value
+}
+
+// Creates a row for the DB, checking each value matches its header's type
+func newRow(d db.DB, values map[string]string) (db.RowI, error) {
+	for header, value := range values {
+		h := d.GetHeader(header)
+		if h.GetName() == "" {
+			return nil, &db.Error{Err: db.ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		err := db.ValidateValue(h, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db.NewRowFromMap(d, values)
+}
+
+func headerNames(headers []db.HeaderI) []string {
+	names := []string{}
+	for _, h := range headers {
+		names = append(names, h.GetName())
+	}
+
+	return names
+}
+
+func rowValues(row db.RowI, headers []db.HeaderI) ([]string, error) {
+	values := []string{}
+	for _, h := range headers {
+		v, err := row.GetValueFromHeader(h.GetName())
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v.GetValue())
+	}
+
+	return values, nil
+}
+
+// Returns a copy of the rows ordered by their KeyHeader value
+func sortRows(rows []db.RowI) []db.RowI {
+	sorted := append([]db.RowI{}, rows...)
+	sort.Slice(sorted, func(i, j int) bool {
+		_, vi := sorted[i].GetKeyHeaderAndValue()
+		_, vj := sorted[j].GetKeyHeaderAndValue()
+		return vi.GetValue() < vj.GetValue()
+	})
+
+	return sorted
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func newOutputDB(t *testing.T) db.DB {
+	d, err := db.New("test", []db.HeaderI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "Hours", KeyHeader: false, Type: db.VALUE_NUMBER},
+	}, "Title")
+	assert.Nil(t, err)
+	for _, values := range []map[string]string{{"Title": "b", "Hours": "2"}, {"Title": "a", "Hours": ""}} {
+		row, err := newRow(d, values)
+		assert.Nil(t, err)
+		assert.Nil(t, d.AddRow(row))
+	}
+
+	return d
+}
+
+func TestWriteRows(t *testing.T) {
+	d := newOutputDB(t)
+	headers := db.SortedHeaders(d)
+
+	out := &bytes.Buffer{}
+	err := writeRows(out, formatCSV, headers, d.GetRows(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Title,Hours\na,\nb,2\n", out.String())
+
+	out.Reset()
+	err = writeRows(out, formatJSON, headers, d.GetRows(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "[\n  {\n    \"Hours\": null,\n    \"Title\": \"a\"\n  },\n  {\n    \"Hours\": 2,\n    \"Title\": \"b\"\n  }\n]\n", out.String())
+
+	out.Reset()
+	err = writeRows(out, formatTable, headers, d.GetRows(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, `+-------+-------+
+| Title | Hours |
++-------+-------+
+| a     |       |
+| b     |     2 |
++-------+-------+
+`, out.String())
+
+	out.Reset()
+	err = writeRows(out, formatMarkdown, headers, d.GetRows(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "| Title | Hours |\n| ----- | ----: |\n| a     |       |\n| b     |     2 |\n", out.String())
+
+	err = writeRows(out, "xml", headers, d.GetRows(), 0)
+	assert.Error(t, err)
+}
+
+func TestWriteRowsJSONNumbers(t *testing.T) {
+	d := newOutputDB(t)
+	for _, values := range []map[string]string{{"Title": "c", "Hours": "NaN"}, {"Title": "d", "Hours": "+5"}, {"Title": "e", "Hours": "-Inf"}} {
+		row, err := newRow(d, values)
+		assert.Nil(t, err)
+		assert.Nil(t, d.AddRow(row))
+	}
+
+	out := &bytes.Buffer{}
+	err := writeRows(out, formatJSON, db.SortedHeaders(d), d.GetRows(), 0)
+	assert.Nil(t, err)
+	assert.True(t, json.Valid(out.Bytes()))
+
+	objects := []map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &objects))
+	hours := map[interface{}]interface{}{}
+	for _, object := range objects {
+		hours[object["Title"]] = object["Hours"]
+	}
+	assert.Equal(t, "NaN", hours["c"])
+	assert.Equal(t, float64(5), hours["d"])
+	assert.Equal(t, "-Inf", hours["e"])
+}
+
+func TestWriteSchema(t *testing.T) {
+	d := newOutputDB(t)
+
+	out := &bytes.Buffer{}
+	err := writeSchema(out, formatCSV, d)
+	assert.Nil(t, err)
+	assert.Equal(t, "name,type,key\nTitle,string,true\nHours,number,false\n", out.String())
+}
+
+func TestReadRows(t *testing.T) {
+	d := newOutputDB(t)
+
+	rows, err := readRows(strings.NewReader("Title,Hours\nc,3\n"), formatCSV, d)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rows))
+
+	rows, err = readRows(strings.NewReader(`[{"Title": "c", "Hours": 3}, {"Title": "d", "Hours": null}]`), formatJSON, d)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows))
+	v, _ := rows[0].GetValueFromHeader("Hours")
+	assert.Equal(t, "3", v.GetValue())
+
+	_, err = readRows(strings.NewReader("Title,Hours\nc,three\n"), formatCSV, d)
+	assert.Error(t, err)
+
+	_, err = readRows(strings.NewReader("Title,Missing\nc,3\n"), formatCSV, d)
+	assert.Error(t, err)
+
+	_, err = readRows(strings.NewReader(""), "xml", d)
+	assert.Error(t, err)
+}
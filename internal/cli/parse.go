@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// Splits a line into whitespace separated tokens
+// Double quotes group text containing spaces and may appear anywhere in a token, so
+// Title="Jak 2" is the single token Title=Jak 2
+func tokenize(line string) ([]string, error) {
+	tokens := []string{}
+	var current strings.Builder
+	inToken := false
+	inQuote := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+		case c == '"':
+			inQuote = !inQuote
+			inToken = true
+		case !inQuote && (c == ' ' || c == '\t'):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteByte(c)
+			inToken = true
+		}
+	}
+
+	if inQuote {
+		return nil, errors.New(fmt.Sprintf(unterminatedQuoteErr, line))
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+// Parses <header>=<value> tokens into a map of header names to values
+func parseAssignments(tokens []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, token := range tokens {
+		header, value, found := strings.Cut(token, "=")
+		if !found || header == "" {
+			return nil, errors.New(fmt.Sprintf(invalidAssignmentErr, token))
+		}
+		values[header] = value
+	}
+
+	return values, nil
+}
+
+// Parses the tokens following 'where' into a predicate on the given DB
+// The condition is either three tokens, <header> <op> <value>, or a single token with
+// the operator inside it such as Platform=PS5
+// The < and > operators compare numerically and never match values that aren't numbers
+func parseCondition(d db.DB, tokens []string) (db.Predicate, error) {
+	invalid := errors.New(fmt.Sprintf(invalidConditionError, strings.Join(tokens, " ")))
+
+	var header, op, value string
+	switch len(tokens) {
+	case 1:
+		i := strings.IndexAny(tokens[0], "=!<>")
+		if i <= 0 {
+			return nil, invalid
+		}
+		header, op, value = tokens[0][:i], tokens[0][i:i+1], tokens[0][i+1:]
+		if op == "!" {
+			if !strings.HasPrefix(value, "=") {
+				return nil, invalid
+			}
+			op, value = "!=", value[1:]
+		}
+	case 3:
+		header, op, value = tokens[0], tokens[1], tokens[2]
+	default:
+		return nil, invalid
+	}
+
+	h := d.GetHeader(header)
+	if h.GetName() == "" {
+		return nil, &db.Error{Err: db.ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	switch op {
+	case "=", "!=":
+		equal := op == "="
+		return func(row db.RowI) bool {
+			v, err := row.GetValueFromHeader(header)
+			return err == nil && (v.GetValue() == value) == equal
+		}, nil
+	case "<", ">":
+		target, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return func(row db.RowI) bool {
+			v, err := row.GetValueFromHeader(header)
+			if err != nil {
+				return false
+			}
+			f, err := strconv.ParseFloat(v.GetValue(), 64)
+			if err != nil {
+				return false
+			}
+			if op == "<" {
+				return f < target
+			}
+			return f > target
+		}, nil
+	}
+
+	return nil, invalid
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`insert Title="Jak 2"  "Hours to Platinum"=23 Name="say \"hi\""`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"insert", "Title=Jak 2", "Hours to Platinum=23", `Name=say "hi"`}, tokens)
+
+	tokens, err = tokenize(`  `)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(tokens))
+
+	tokens, err = tokenize(`open ""`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"open", ""}, tokens)
+
+	_, err = tokenize(`open "Platinum Tracker`)
+	assert.Error(t, err)
+}
+
+func TestParseAssignments(t *testing.T) {
+	values, err := parseAssignments([]string{"Title=Jak 2", "Platform=", "Equation=a=b"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Title": "Jak 2", "Platform": "", "Equation": "a=b"}, values)
+
+	_, err = parseAssignments([]string{"Title"})
+	assert.Error(t, err)
+
+	_, err = parseAssignments([]string{"=value"})
+	assert.Error(t, err)
+}
+
+func TestParseCondition(t *testing.T) {
+	d, err := db.New("test", []db.HeaderI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "Hours", KeyHeader: false, Type: db.VALUE_NUMBER},
+	}, "Title")
+	assert.Nil(t, err)
+	row, _ := db.NewRowFromMap(d, map[string]string{"Title": "Jak 2", "Hours": "23"})
+	empty, _ := db.NewRowFromMap(d, map[string]string{"Title": "Empty", "Hours": ""})
+
+	expected := map[string][]bool{
+		`Title = "Jak 2"`:  {true, false},
+		`Title="Jak 2"`:    {true, false},
+		`Title != "Jak 2"`: {false, true},
+		`Title!="Jak 2"`:   {false, true},
+		`Hours < 30`:       {true, false},
+		`Hours>30`:         {false, false},
+		`Hours > 20`:       {true, false},
+	}
+	for condition, matches := range expected {
+		tokens, err := tokenize(condition)
+		assert.Nil(t, err)
+		predicate, err := parseCondition(d, tokens)
+		assert.Nil(t, err, condition)
+		assert.Equal(t, matches[0], predicate(row), condition)
+		assert.Equal(t, matches[1], predicate(empty), condition)
+	}
+
+	for _, condition := range [][]string{
+		{"Title"},
+		{"=Jak 2"},
+		{"Title", "~", "Jak 2"},
+		{"Hours", "<", "many"},
+		{"Missing", "=", "x"},
+		{"Title!Jak 2"},
+		{"Title", "="},
+	} {
+		_, err := parseCondition(d, condition)
+		assert.Error(t, err, condition)
+	}
+}
@@ -0,0 +1,475 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+const helpText = `Commands:
+  show dbs                                  list the databases
+  show headers                              list the headers of the open database
+  open <db>                                 select the database to work on
+  create db <db> <key header> [<header>:<type> ...]
+                                            create and open a database, types are string or number
+  add header <header> [string|number]       add a header to the open database
+  remove header <header>                    remove a header and its values
+  insert <header>=<value> ...               add a row
+  set <key> <header>=<value> ...            change values of the row with the given key
+  delete <key>                              remove the row with the given key
+  delete where <header> <op> <value>        remove every matching row
+  select [<header>,...] [where <header> <op> <value>]
+                                            print rows, op is one of =, !=, <, >
+  undo                                      reverse the last change to the open database
+  redo                                      make the last undone change again
+  begin                                     start grouping changes into one step for undo
+  end                                       finish the group started with begin
+  history                                   print the command history
+  help                                      print this message
+  exit                                      leave the shell
+Quote names and values containing spaces, e.g. insert Title="Jak 2"`
+
+// Creates a shell over the DB manager, saving it to dataDir after every change
+func NewREPL(dbm *dbmanager.DBManagerImpl, dataDir string, out io.Writer) *REPL {
+	return &REPL{
+		DBM:     dbm,
+		DataDir: dataDir,
+		Out:     out,
+	}
+}
+
+// Runs the shell reading commands from in until it is exhausted or 'exit' is entered
+func (r *REPL) Run(in io.Reader) error {
+	return r.loop(&scannerReader{bufio.NewScanner(in)})
+}
+
+func (r *REPL) loop(reader lineReader) error {
+	for {
+		line, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		quit, err := r.Execute(line)
+		if err != nil {
+			fmt.Fprintf(r.Out, "error: %s\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Executes a single command line, returning true if the shell should exit
+func (r *REPL) Execute(line string) (bool, error) {
+	tokens, err := tokenize(line)
+	if err != nil || len(tokens) == 0 {
+		return false, err
+	}
+
+	cmd, args := strings.ToLower(tokens[0]), tokens[1:]
+	switch cmd {
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		fmt.Fprintln(r.Out, helpText)
+		return false, nil
+	case "history":
+		return false, r.history()
+	case "show":
+		return false, r.show(args)
+	case "open":
+		return false, r.open(args)
+	case "create":
+		return false, r.mutate(r.create, args)
+	case "add":
+		return false, r.mutate(r.addHeader, args)
+	case "remove":
+		return false, r.mutate(r.removeHeader, args)
+	case "insert":
+		return false, r.mutate(r.insert, args)
+	case "set":
+		return false, r.mutate(r.set, args)
+	case "delete":
+		return false, r.mutate(r.delete, args)
+	case "select":
+		return false, r.selectRows(args)
+	case "undo":
+		return false, r.save(r.undo, args)
+	case "redo":
+		return false, r.save(r.redo, args)
+	case "begin":
+		return false, r.group(args, true)
+	case "end":
+		return false, r.group(args, false)
+	}
+
+	return false, errors.New(fmt.Sprintf(unknownCommandError, tokens[0]))
+}
+
+// Runs a command that changes data as a single step for undo, saving the DB manager if it succeeds
+func (r *REPL) mutate(command func(args []string) error, args []string) error {
+	if u, ok := r.Current.(db.Undoable); ok {
+		u.BeginUndoGroup()
+		defer u.EndUndoGroup()
+	}
+
+	return r.save(command, args)
+}
+
+// Runs a command, saving the DB manager if it succeeds
+// Undo and redo are run this way as they can't be run inside a group
+func (r *REPL) save(command func(args []string) error, args []string) error {
+	err := command(args)
+	if err != nil {
+		return err
+	}
+
+	if r.DataDir == "" {
+		return nil
+	}
+
+	return r.DBM.Save(r.DataDir)
+}
+
+func (r *REPL) history() error {
+	if r.History == nil {
+		return nil
+	}
+
+	for i, entry := range r.History.Entries {
+		fmt.Fprintf(r.Out, "%5d  %s\n", i+1, entry)
+	}
+
+	return nil
+}
+
+func (r *REPL) show(args []string) error {
+	if len(args) != 1 {
+		return errors.New(fmt.Sprintf(usageError, "show dbs|headers"))
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "dbs":
+		names := []string{}
+		for name := range r.DBM.GetDBs() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(r.Out, name)
+		}
+		return nil
+	case "headers":
+		d, err := r.currentDB()
+		if err != nil {
+			return err
+		}
+		return writeSchema(r.Out, formatTable, d)
+	}
+
+	return errors.New(fmt.Sprintf(usageError, "show dbs|headers"))
+}
+
+func (r *REPL) open(args []string) error {
+	if len(args) != 1 {
+		return errors.New(fmt.Sprintf(usageError, "open <db>"))
+	}
+
+	d, err := r.manager().RetrieveDB(args[0])
+	if err != nil {
+		return err
+	}
+	r.Current = d
+
+	return nil
+}
+
+func (r *REPL) create(args []string) error {
+	if len(args) < 3 || strings.ToLower(args[0]) != "db" {
+		return errors.New(fmt.Sprintf(usageError, "create db <db> <key header> [<header>:<type> ...]"))
+	}
+
+	name, keyHeader := args[1], args[2]
+	headers := []db.HeaderI{&db.Header{Name: keyHeader, KeyHeader: true, Type: db.VALUE_STRING}}
+	for _, arg := range args[3:] {
+		header, typeName, found := strings.Cut(arg, ":")
+		t := db.VALUE_STRING
+		if found {
+			var err error
+			t, err = db.ParseType(typeName)
+			if err != nil {
+				return err
+			}
+		}
+		headers = append(headers, &db.Header{Name: header, KeyHeader: false, Type: t})
+	}
+
+	err := r.manager().CreateDB(name, headers, keyHeader)
+	if err != nil {
+		return err
+	}
+
+	return r.open([]string{name})
+}
+
+func (r *REPL) addHeader(args []string) error {
+	if len(args) < 2 || len(args) > 3 || strings.ToLower(args[0]) != "header" {
+		return errors.New(fmt.Sprintf(usageError, "add header <header> [string|number]"))
+	}
+
+	d, err := r.currentDB()
+	if err != nil {
+		return err
+	}
+
+	t := db.VALUE_STRING
+	if len(args) == 3 {
+		t, err = db.ParseType(args[2])
+		if err != nil {
+			return err
+		}
+	}
+	d.AddHeader(&db.Header{Name: args[1], KeyHeader: false, Type: t})
+
+	return nil
+}
+
+func (r *REPL) removeHeader(args []string) error {
+	if len(args) != 2 || strings.ToLower(args[0]) != "header" {
+		return errors.New(fmt.Sprintf(usageError, "remove header <header>"))
+	}
+
+	d, err := r.currentDB()
+	if err != nil {
+		return err
+	}
+
+	if d.GetHeader(args[1]).GetName() == "" {
+		return &db.Error{Err: db.ErrHeaderNotExist, Header: args[1], Message: fmt.Sprintf(headerNotExistError, args[1])}
+	}
+
+	return d.RemoveHeader(args[1])
+}
+
+func (r *REPL) insert(args []string) error {
+	if len(args) == 0 {
+		return errors.New(fmt.Sprintf(usageError, "insert <header>=<value> ..."))
+	}
+
+	d, err := r.currentDB()
+	if err != nil {
+		return err
+	}
+
+	values, err := parseAssignments(args)
+	if err != nil {
+		return err
+	}
+
+	row, err := newRow(d, values)
+	if err != nil {
+		return err
+	}
+
+	return d.AddRow(row)
+}
+
+func (r *REPL) set(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf(usageError, "set <key> <header>=<value> ..."))
+	}
+
+	d, err := r.currentDB()
+	if err != nil {
+		return err
+	}
+
+	values, err := parseAssignments(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if d.GetRowFromKeyHeader(args[0]) == nil {
+		return &db.Error{Err: db.ErrRowNotExist, Key: args[0], Message: fmt.Sprintf(rowNotExistError, args[0])}
+	}
+
+	_, err = d.UpdateWhere(func(row db.RowI) bool {
+		return row.KeyHeaderValueEqual(args[0])
+	}, values)
+
+	return err
+}
+
+func (r *REPL) delete(args []string) error {
+	d, err := r.currentDB()
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 && strings.ToLower(args[0]) == "where" {
+		predicate, err := parseCondition(d, args[1:])
+		if err != nil {
+			return err
+		}
+
+		count, err := d.DeleteWhere(predicate)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(r.Out, "%d row(s) deleted\n", count)
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.New(fmt.Sprintf(usageError, "delete <key> | delete where <header> <op> <value>"))
+	}
+
+	if d.GetRowFromKeyHeader(args[0]) == nil {
+		return &db.Error{Err: db.ErrRowNotExist, Key: args[0], Message: fmt.Sprintf(rowNotExistError, args[0])}
+	}
+
+	return d.RemoveRow(args[0])
+}
+
+func (r *REPL) selectRows(args []string) error {
+	d, err := r.currentDB()
+	if err != nil {
+		return err
+	}
+
+	headers := db.SortedHeaders(d)
+	if len(args) > 0 && strings.ToLower(args[0]) != "where" {
+		if args[0] != "*" {
+			headers = []db.HeaderI{}
+			for _, name := range strings.Split(args[0], ",") {
+				h := d.GetHeader(strings.TrimSpace(name))
+				if h.GetName() == "" {
+					return &db.Error{Err: db.ErrHeaderNotExist, Header: name, Message: fmt.Sprintf(headerNotExistError, name)}
+				}
+				headers = append(headers, h)
+			}
+		}
+		args = args[1:]
+	}
+
+	var predicate db.Predicate
+	if len(args) > 0 {
+		if strings.ToLower(args[0]) != "where" || len(args) == 1 {
+			return errors.New(fmt.Sprintf(usageError, "select [<header>,...] [where <header> <op> <value>]"))
+		}
+		predicate, err = parseCondition(d, args[1:])
+		if err != nil {
+			return err
+		}
+	}
+
+	rows := []db.RowI{}
+	for _, row := range d.GetRows() {
+		if predicate == nil || predicate(row) {
+			rows = append(rows, row)
+		}
+	}
+
+	err = writeRows(r.Out, formatTable, headers, rows, 0)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "(%d row(s))\n", len(rows))
+
+	return nil
+}
+
+func (r *REPL) undo(args []string) error {
+	if len(args) != 0 {
+		return errors.New(fmt.Sprintf(usageError, "undo"))
+	}
+
+	u, err := r.undoableDB()
+	if err != nil {
+		return err
+	}
+
+	return u.Undo()
+}
+
+func (r *REPL) redo(args []string) error {
+	if len(args) != 0 {
+		return errors.New(fmt.Sprintf(usageError, "redo"))
+	}
+
+	u, err := r.undoableDB()
+	if err != nil {
+		return err
+	}
+
+	return u.Redo()
+}
+
+// Starts or ends a group of changes undone as one step
+func (r *REPL) group(args []string, begin bool) error {
+	if len(args) != 0 {
+		return errors.New(fmt.Sprintf(usageError, "begin|end"))
+	}
+
+	u, err := r.undoableDB()
+	if err != nil {
+		return err
+	}
+
+	if begin {
+		u.BeginUndoGroup()
+	} else {
+		u.EndUndoGroup()
+	}
+
+	return nil
+}
+
+func (r *REPL) undoableDB() (db.Undoable, error) {
+	d, err := r.currentDB()
+	if err != nil {
+		return nil, err
+	}
+
+	u, ok := d.(db.Undoable)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(undoUnsupportedError, d.GetName()))
+	}
+
+	return u, nil
+}
+
+// Returns the DB manager attributing changes to the shell's Actor
+func (r *REPL) manager() dbmanager.DBManager {
+	if r.Actor == "" {
+		return r.DBM
+	}
+
+	return r.DBM.As(r.Actor)
+}
+
+func (r *REPL) currentDB() (db.DB, error) {
+	if r.Current == nil {
+		return nil, errors.New(noDBOpenError)
+	}
+
+	return r.Current, nil
+}
+
+func headerLabel(h db.HeaderI) string {
+	if h.IsKeyHeader() {
+		return fmt.Sprintf("%s (K)", h.GetName())
+	}
+
+	return h.GetName()
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+func newTestREPL(t *testing.T) (*REPL, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	r := NewREPL(dbmanager.New(), t.TempDir(), out)
+	_, err := r.Execute(`create db "Platinum Tracker" Title Platform "Hours to Platinum:number"`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`insert Title="Jak 2" Platform=PS4 "Hours to Platinum"=23`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`insert Title="Hogwarts Legacy" Platform=PS5 "Hours to Platinum"=55`)
+	assert.Nil(t, err)
+	out.Reset()
+
+	return r, out
+}
+
+func TestExecuteSelect(t *testing.T) {
+	r, out := newTestREPL(t)
+
+	_, err := r.Execute(`select Title,Platform where "Hours to Platinum" > 30`)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "Hogwarts Legacy")
+	assert.NotContains(t, out.String(), "Jak 2")
+	assert.Contains(t, out.String(), "(1 row(s))")
+
+	out.Reset()
+	_, err = r.Execute(`select`)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "Hours to Platinum")
+	assert.Contains(t, out.String(), "(2 row(s))")
+
+	_, err = r.Execute(`select Missing`)
+	assert.Error(t, err)
+
+	_, err = r.Execute(`select * where`)
+	assert.Error(t, err)
+}
+
+func TestExecuteMutations(t *testing.T) {
+	r, out := newTestREPL(t)
+
+	_, err := r.Execute(`set "Jak 2" Platform=PS5 "Hours to Platinum"=20`)
+	assert.Nil(t, err)
+	v, _ := r.Current.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Platform")
+	assert.Equal(t, "PS5", v.GetValue())
+
+	_, err = r.Execute(`set "Jak 2" "Hours to Platinum"=lots`)
+	assert.Error(t, err)
+
+	_, err = r.Execute(`set Missing Platform=PS5`)
+	assert.Error(t, err)
+
+	_, err = r.Execute(`add header Platinumed`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Platinumed", r.Current.GetHeader("Platinumed").GetName())
+
+	_, err = r.Execute(`remove header Platinumed`)
+	assert.Nil(t, err)
+	assert.Equal(t, "", r.Current.GetHeader("Platinumed").GetName())
+
+	_, err = r.Execute(`remove header Platinumed`)
+	assert.Error(t, err)
+
+	_, err = r.Execute(`delete "Jak 2"`)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Current.GetRowFromKeyHeader("Jak 2"))
+
+	_, err = r.Execute(`delete "Jak 2"`)
+	assert.Error(t, err)
+
+	_, err = r.Execute(`delete where Platform = PS5`)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "1 row(s) deleted")
+	assert.Equal(t, 0, len(r.Current.GetRows()))
+}
+
+func TestExecuteUndoRedo(t *testing.T) {
+	r, _ := newTestREPL(t)
+
+	_, err := r.Execute(`set "Jak 2" Platform=PS5 "Hours to Platinum"=20`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`undo`)
+	assert.Nil(t, err)
+	v, _ := r.Current.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Platform")
+	assert.Equal(t, "PS4", v.GetValue())
+	v, _ = r.Current.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Hours to Platinum")
+	assert.Equal(t, "23", v.GetValue())
+
+	_, err = r.Execute(`redo`)
+	assert.Nil(t, err)
+	v, _ = r.Current.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Platform")
+	assert.Equal(t, "PS5", v.GetValue())
+
+	_, err = r.Execute(`begin`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`delete "Jak 2"`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`remove header Platform`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`end`)
+	assert.Nil(t, err)
+	_, err = r.Execute(`undo`)
+	assert.Nil(t, err)
+	assert.NotNil(t, r.Current.GetRowFromKeyHeader("Jak 2"))
+	v, _ = r.Current.GetRowFromKeyHeader("Hogwarts Legacy").GetValueFromHeader("Platform")
+	assert.Equal(t, "PS5", v.GetValue())
+
+	_, err = r.Execute(`undo now`)
+	assert.Error(t, err)
+
+	r.Current = nil
+	_, err = r.Execute(`undo`)
+	assert.Error(t, err)
+}
+
+func TestExecuteShowAndOpen(t *testing.T) {
+	r, out := newTestREPL(t)
+
+	_, err := r.Execute(`show dbs`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Platinum Tracker\n", out.String())
+
+	out.Reset()
+	_, err = r.Execute(`show headers`)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "Title (K)"))
+	assert.Contains(t, out.String(), "number")
+
+	_, err = r.Execute(`open Missing`)
+	assert.Error(t, err)
+
+	r.Current = nil
+	_, err = r.Execute(`select`)
+	assert.Error(t, err)
+
+	_, err = r.Execute(`open "Platinum Tracker"`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Platinum Tracker", r.Current.GetName())
+}
+
+func TestExecuteSaves(t *testing.T) {
+	r, _ := newTestREPL(t)
+
+	loaded, err := dbmanager.Load(r.DataDir)
+	assert.Nil(t, err)
+	d, err := loaded.RetrieveDB("Platinum Tracker")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(d.GetRows()))
+}
+
+func TestRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewREPL(dbmanager.New(), "", out)
+
+	input := "create db test Title\nbogus\nshow dbs\nexit\nshow dbs\n"
+	err := r.Run(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Equal(t, "error: unknown command 'bogus', type 'help' for a list of commands\ntest\n", out.String())
+
+	quit, err := r.Execute("quit")
+	assert.Nil(t, err)
+	assert.True(t, quit)
+}
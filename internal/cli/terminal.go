@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+const prompt = "pdb> "
+
+// Reads lines from a non-interactive source such as a pipe or script
+type scannerReader struct {
+	scanner *bufio.Scanner
+}
+
+func (s *scannerReader) ReadLine() (string, error) {
+	if !s.scanner.Scan() {
+		if s.scanner.Err() != nil {
+			return "", s.scanner.Err()
+		}
+		return "", io.EOF
+	}
+
+	return s.scanner.Text(), nil
+}
+
+// Runs the shell on the process's terminal, with line editing and history
+// Falls back to reading plain lines when stdin isn't a terminal
+func (r *REPL) RunTerminal() error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return r.Run(os.Stdin)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, prompt)
+	if r.History != nil {
+		t.History = r.History
+	}
+
+	// The terminal is in raw mode so output has to go through it to get line endings right
+	out := r.Out
+	r.Out = t
+	defer func() { r.Out = out }()
+
+	return r.loop(t)
+}
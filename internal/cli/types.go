@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+var (
+	unknownCommandError     = "unknown command '%s', type 'help' for a list of commands"
+	usageError              = "usage: %s"
+	noDBOpenError           = "no database open, use 'open <name>' first"
+	unterminatedQuoteErr    = "unterminated quote in '%s'"
+	invalidAssignmentErr    = "expected <header>=<value>, got '%s'"
+	invalidConditionError   = "expected <header> <op> <value> with op one of =, !=, <, >, got '%s'"
+	headerNotExistError     = "header '%s' does not exist"
+	dbNotExistError         = "database '%s' does not exist"
+	rowNotExistError        = "row with key value '%s' does not exist"
+	unknownFormatError      = "unknown format '%s'"
+	unknownSubcommandError  = "unknown command '%s'"
+	unexpectedArgumentError = "unexpected argument '%s'"
+	missingDBFlagError      = "the --db flag is required"
+	undoUnsupportedError    = "database '%s' does not support undo"
+	invalidTimeError        = "invalid --%s time '%s', expected RFC 3339"
+)
+
+// The implementation of the interactive shell holding the following fields:
+// DBM: The DB manager the shell operates on
+// DataDir: The directory the DB manager is saved to after each change
+// Current: The DB selected with 'open', or nil
+// Out: Where command output is written
+// History: The history of lines entered, or nil if history isn't kept
+// Actor: Who the changes made are attributed to, if anyone
+type REPL struct {
+	DBM     *dbmanager.DBManagerImpl
+	DataDir string
+	Current db.DB
+	Out     io.Writer
+	History *History
+	Actor   string
+}
+
+// An io.Writer appending to a file, which is only created when first written to and is then kept
+// open, holding the following fields:
+// path: The file written to
+// f: The open file, nil until the first write
+type appendFile struct {
+	path string
+	f    *os.File
+}
+
+// lineReader is the interface for reading lines of input into the shell
+type lineReader interface {
+	// Returns the next line without its line ending
+	// Returns io.EOF when there is no more input
+	ReadLine() (string, error)
+}
+
+// The implementation of a bounded, file backed command history holding the following fields:
+// Entries: The lines entered, oldest first
+// Path: The file the history is appended to, or empty to keep it in memory only
+// Max: The maximum number of entries kept
+type History struct {
+	Entries []string
+	Path    string
+	Max     int
+}
+
+// The environment a pdb subcommand runs in holding the following fields:
+// dataDir: The directory the DB manager is loaded from and saved to
+// actor: Who the changes made are attributed to in the audit log
+// dbm: The loaded DB manager
+// stdin, stdout, stderr: The streams the subcommand reads from and writes to
+// saveMu: Serialises saves made by concurrent requests while serving
+type env struct {
+	dataDir string
+	actor   string
+	dbm     *dbmanager.DBManagerImpl
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	// The file the audit log is written to, nil if it isn't open
+	auditFile *appendFile
+
+	saveMu sync.Mutex
+	// Whether an error writing the audit log has been reported, guarded by saveMu
+	auditReported bool
+}
+
+// A http.ResponseWriter recording the status written holding the following fields:
+// ResponseWriter: The writer the response is written to
+// status: The status written, http.StatusOK until one is
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// usageErr is returned when a command is invoked incorrectly, resulting in ExitUsage
+type usageErr string
+
+func (e usageErr) Error() string {
+	return string(e)
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Locks the DB for writing with the changes made attributed to actor, returning the function
+// that unlocks it
+func (db *DBImpl) lockAs(actor string) func() {
+	db.mu.Lock()
+	db.actor = actor
+
+	return func() {
+		db.journal.commit()
+		db.actor = ""
+		db.mu.Unlock()
+	}
+}
+
+// Locks the DB for writing with the changes made attributed to the actor set on ctx, returning
+// the function that unlocks it
+// Returns ctx's error if it is done, or an error if the DB is read-only, in which case it isn't locked
+func (db *DBImpl) lockContext(ctx context.Context) (func(), error) {
+	unlock := db.lockAs(ActorFromContext(ctx))
+	if err := ctx.Err(); err != nil {
+		unlock()
+		return nil, err
+	}
+
+	if db.readOnly {
+		unlock()
+		return nil, &Error{Err: ErrReadOnly, DB: db.Name, Message: fmt.Sprintf(readOnlyError, db.Name)}
+	}
+
+	return unlock, nil
+}
+
+// Locks the DB for reading, returning the function that unlocks it
+// Returns ctx's error if it is done, in which case it isn't locked
+func (db *DBImpl) rlockContext(ctx context.Context) (func(), error) {
+	db.mu.RLock()
+	if err := ctx.Err(); err != nil {
+		db.mu.RUnlock()
+		return nil, err
+	}
+
+	return db.mu.RUnlock, nil
+}
+
+// Returns an error for each of the rows
+func failAll(rows []RowI, err error) []error {
+	errs := make([]error, len(rows))
+	for i := range errs {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// Returns a view of the DB attributing the changes made through it to actor in events and history
+func (db *DBImpl) As(actor string) DB {
+	return &actorDB{DBImpl: db, actor: actor}
+}
+
+// Returns a copy of ctx carrying the actor changes made with it are attributed to
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Returns the actor set on ctx with WithActor, or an empty string if there is none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Sets the Auditor every change made to the DB is recorded with, nil to stop recording them
+func (db *DBImpl) SetAuditor(auditor Auditor) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.auditor = auditor
+}
+
+func (a *actorDB) AddHeader(header HeaderI) {
+	a.AddHeaderContext(context.Background(), header)
+}
+
+func (a *actorDB) AddHeaderContext(ctx context.Context, header HeaderI) error {
+	return a.DBImpl.AddHeaderContext(WithActor(ctx, a.actor), header)
+}
+
+func (a *actorDB) RemoveHeader(header string) error {
+	return a.RemoveHeaderContext(context.Background(), header)
+}
+
+func (a *actorDB) RemoveHeaderContext(ctx context.Context, header string) error {
+	return a.DBImpl.RemoveHeaderContext(WithActor(ctx, a.actor), header)
+}
+
+func (a *actorDB) AddRow(row RowI) error {
+	return a.AddRowContext(context.Background(), row)
+}
+
+func (a *actorDB) AddRowContext(ctx context.Context, row RowI) error {
+	return a.DBImpl.AddRowContext(WithActor(ctx, a.actor), row)
+}
+
+func (a *actorDB) AddRows(rows []RowI, opts AddRowsOptions) ([]error, error) {
+	return a.AddRowsContext(context.Background(), rows, opts)
+}
+
+func (a *actorDB) AddRowsContext(ctx context.Context, rows []RowI, opts AddRowsOptions) ([]error, error) {
+	return a.DBImpl.AddRowsContext(WithActor(ctx, a.actor), rows, opts)
+}
+
+func (a *actorDB) Upsert(row RowI) error {
+	return a.UpsertContext(context.Background(), row)
+}
+
+func (a *actorDB) UpsertContext(ctx context.Context, row RowI) error {
+	return a.DBImpl.UpsertContext(WithActor(ctx, a.actor), row)
+}
+
+func (a *actorDB) RemoveRow(keyValue string) error {
+	return a.RemoveRowContext(context.Background(), keyValue)
+}
+
+func (a *actorDB) RemoveRowContext(ctx context.Context, keyValue string) error {
+	return a.DBImpl.RemoveRowContext(WithActor(ctx, a.actor), keyValue)
+}
+
+func (a *actorDB) UpdateWhere(predicate Predicate, values map[string]string) (int, error) {
+	return a.UpdateWhereContext(context.Background(), predicate, values)
+}
+
+func (a *actorDB) UpdateWhereContext(ctx context.Context, predicate Predicate, values map[string]string) (int, error) {
+	return a.DBImpl.UpdateWhereContext(WithActor(ctx, a.actor), predicate, values)
+}
+
+func (a *actorDB) DeleteWhere(predicate Predicate) (int, error) {
+	return a.DeleteWhereContext(context.Background(), predicate)
+}
+
+func (a *actorDB) DeleteWhereContext(ctx context.Context, predicate Predicate) (int, error) {
+	return a.DBImpl.DeleteWhereContext(WithActor(ctx, a.actor), predicate)
+}
+
+func (a *actorDB) AddValueToHeader(value string, header string, key string) error {
+	return a.AddValueToHeaderContext(context.Background(), value, header, key)
+}
+
+func (a *actorDB) AddValueToHeaderContext(ctx context.Context, value string, header string, key string) error {
+	return a.DBImpl.AddValueToHeaderContext(WithActor(ctx, a.actor), value, header, key)
+}
+
+func (a *actorDB) Increment(key string, header string, delta float64) (float64, error) {
+	return a.IncrementContext(context.Background(), key, header, delta)
+}
+
+func (a *actorDB) IncrementContext(ctx context.Context, key string, header string, delta float64) (float64, error) {
+	return a.DBImpl.IncrementContext(WithActor(ctx, a.actor), key, header, delta)
+}
+
+func (a *actorDB) Decrement(key string, header string, delta float64) (float64, error) {
+	return a.IncrementContext(context.Background(), key, header, -delta)
+}
+
+func (a *actorDB) DecrementContext(ctx context.Context, key string, header string, delta float64) (float64, error) {
+	return a.IncrementContext(ctx, key, header, -delta)
+}
+
+func (a *actorDB) UpdateExpression(predicate Predicate, expr string) (int, error) {
+	return a.UpdateExpressionContext(context.Background(), predicate, expr)
+}
+
+func (a *actorDB) UpdateExpressionContext(ctx context.Context, predicate Predicate, expr string) (int, error) {
+	return a.DBImpl.UpdateExpressionContext(WithActor(ctx, a.actor), predicate, expr)
+}
+
+func (a *actorDB) Undo() error {
+	defer a.lockAs(a.actor)()
+
+	return a.undo()
+}
+
+func (a *actorDB) Redo() error {
+	defer a.lockAs(a.actor)()
+
+	return a.redo()
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Keeps the events it is given
+type recordingAuditor struct {
+	events []Event
+}
+
+func (r *recordingAuditor) Record(e Event) {
+	r.events = append(r.events, e)
+}
+
+func TestActorFromContext(t *testing.T) {
+	assert.Equal(t, "", ActorFromContext(context.Background()))
+
+	ctx := WithActor(context.Background(), "alice")
+	assert.Equal(t, "alice", ActorFromContext(ctx))
+	assert.Equal(t, "bob", ActorFromContext(WithActor(ctx, "bob")))
+}
+
+func TestSetAuditor(t *testing.T) {
+	db := newNumberDB(t)
+	auditor := &recordingAuditor{}
+	db.SetAuditor(auditor)
+
+	assert.Nil(t, db.AddValueToHeader("12", "Trophies", "a"))
+	assert.Nil(t, db.As("alice").RemoveRow("b"))
+
+	assert.Equal(t, 2, len(auditor.events))
+	assert.Equal(t, EVENT_UPDATE_VALUE, auditor.events[0].Type)
+	assert.Equal(t, "", auditor.events[0].Actor)
+	assert.Equal(t, "Test", auditor.events[0].DB)
+	assert.Equal(t, EVENT_REMOVE_ROW, auditor.events[1].Type)
+	assert.Equal(t, "alice", auditor.events[1].Actor)
+
+	db.SetAuditor(nil)
+	assert.Nil(t, db.RemoveRow("a"))
+	assert.Equal(t, 2, len(auditor.events))
+}
+
+func TestAsReadOnly(t *testing.T) {
+	fakeClock(t)
+	db := newNumberDB(t)
+	assert.Nil(t, db.AddValueToHeader("12", "Trophies", "a"))
+
+	past, err := db.AsOf(now())
+	assert.Nil(t, err)
+	view := past.(Attributable).As("alice")
+	assert.Equal(t, past, view)
+	assert.Error(t, view.RemoveRow("a"))
+}
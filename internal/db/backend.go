@@ -0,0 +1,274 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The names of the backends DBs can keep their rows in
+// Only BACKEND_MEMORY and BACKEND_COLUMNAR are registered to begin with, as the others need a
+// directory to keep their files in, given when they're registered
+const (
+	BACKEND_MEMORY   = "memory"
+	BACKEND_COLUMNAR = "columnar"
+	BACKEND_SNAPSHOT = "snapshot"
+	BACKEND_WAL      = "wal"
+	BACKEND_PAGED    = "paged"
+)
+
+// The extensions of the files each file backend keeps a DB's rows in
+const (
+	snapshotRowsExt = ".rows"
+	walRowsExt      = ".wal"
+	pagedRowsExt    = ".pdbp"
+)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{BACKEND_MEMORY: MemoryBackend, BACKEND_COLUMNAR: ColumnarBackend}
+)
+
+// Registers the backend under the name so NewWithBackend can create DBs with it, replacing any
+// backend registered under the name before
+func RegisterBackend(name string, backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[name] = backend
+}
+
+// Returns the names of the registered backends in order
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Returns the backend registered under the name
+// Returns an error if no backend is registered under it
+func GetBackend(name string) (Backend, error) {
+	backendsMu.RLock()
+	backend, ok := backends[name]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, &Error{Err: ErrUnknownBackend, Value: name, Message: fmt.Sprintf(unknownBackendError, name, strings.Join(Backends(), "', '"))}
+	}
+
+	return backend, nil
+}
+
+// Creates a DB like New, keeping its rows in the backend registered under the given name
+// Rows the backend already holds for the DB, such as those in a file written before, are kept,
+// with the headers they lack added to them
+// Returns an error if the backend isn't registered or can't open the rows
+// Returns an error if the rows the backend holds have a different KeyHeader
+func NewWithBackend(name string, headers []HeaderI, keyHeader string, backend string) (*DBImpl, error) {
+	open, err := GetBackend(backend)
+	if err != nil {
+		return &DBImpl{}, err
+	}
+
+	// Check the headers before opening anything
+	db, err := New(name, headers, keyHeader)
+	if err != nil {
+		return db, err
+	}
+
+	rows, err := open(name)
+	if err != nil {
+		return &DBImpl{}, err
+	}
+
+	err = db.useRows(rows)
+	if err != nil {
+		if stored, ok := rows.(StoredRowsI); ok {
+			stored.Close()
+		}
+		return &DBImpl{}, err
+	}
+	if backend != BACKEND_MEMORY {
+		db.backend = backend
+	}
+
+	return db, nil
+}
+
+// Returns the name of the backend keeping the rows, BACKEND_MEMORY for DBs made by New
+func (db *DBImpl) BackendName() string {
+	if db.backend == "" {
+		return BACKEND_MEMORY
+	}
+
+	return db.backend
+}
+
+// Moves the rows to the backend registered under the name, replacing any rows it already holds
+// for the DB, such as those in a file written before
+// Does nothing if the rows are already kept in the backend
+// Returns an error if the backend isn't registered or the rows can't be moved, in which case the
+// DB keeps them where they were
+func (db *DBImpl) UseBackend(backend string) error {
+	open, err := GetBackend(backend)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if backend == db.BackendName() {
+		return nil
+	}
+
+	rows, err := open(db.Name)
+	if err != nil {
+		return err
+	}
+
+	err = moveRows(db.Rows, rows)
+	if err != nil {
+		if stored, ok := rows.(StoredRowsI); ok {
+			stored.Close()
+		}
+		return db.annotate(err)
+	}
+
+	if stored, ok := db.Rows.(StoredRowsI); ok {
+		stored.Close()
+	}
+	db.Rows = rows
+	db.backend = ""
+	if backend != BACKEND_MEMORY {
+		db.backend = backend
+	}
+
+	return nil
+}
+
+// Replaces the rows in to with copies of those in from
+func moveRows(from RowsI, to RowsI) error {
+	// Collect the keys first as deleting may change what GetRows returned
+	keys := []string{}
+	for _, row := range to.GetRows() {
+		keys = append(keys, rowKey(row))
+	}
+	for _, key := range keys {
+		to.DeleteRowWithValue(key)
+	}
+
+	copies := []RowI{}
+	for _, row := range from.GetRows() {
+		copies = append(copies, copyRow(row))
+	}
+	err := to.AddRows(copies)
+	if err != nil {
+		return err
+	}
+
+	if stored, ok := to.(StoredRowsI); ok {
+		return stored.Err()
+	}
+
+	return nil
+}
+
+// Keeps the DB's rows in rows, adding the DB's headers to the rows lacking them
+func (db *DBImpl) useRows(rows RowsI) error {
+	for _, row := range rows.GetRows() {
+		h, _ := row.GetKeyHeaderAndValue()
+		if h == nil || h.GetName() != db.KeyHeader {
+			name := ""
+			if h != nil {
+				name = h.GetName()
+			}
+			return &Error{Err: ErrKeyHeaderIncorrect, DB: db.Name, Header: name, Message: fmt.Sprintf(keyHeaderIncorrect, name, db.KeyHeader)}
+		}
+
+		for header := range db.Headers {
+			if !row.HeaderExists(header.GetName()) {
+				row.AddHeaderWithValue(header.GetName(), false, header.GetType(), "")
+			}
+		}
+	}
+	db.Rows = rows
+
+	if stored, ok := rows.(StoredRowsI); ok {
+		return stored.Err()
+	}
+
+	return nil
+}
+
+// Writes every change to the DB's rows to the files they're kept in, doing nothing if the
+// backend keeps them in memory
+func (db *DBImpl) Flush() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if stored, ok := db.Rows.(StoredRowsI); ok {
+		return stored.Flush()
+	}
+
+	return nil
+}
+
+// Writes every change to the DB's rows to the files they're kept in and closes them, after
+// which the DB must not be used
+// Does nothing if the backend keeps them in memory
+func (db *DBImpl) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if stored, ok := db.Rows.(StoredRowsI); ok {
+		return stored.Close()
+	}
+
+	return nil
+}
+
+// The backend keeping rows in memory, the one New uses
+func MemoryBackend(name string) (RowsI, error) {
+	return &Rows{}, nil
+}
+
+// Returns a backend keeping each DB's rows in memory and writing them all to a file in dir
+// when flushed
+func SnapshotBackend(dir string) Backend {
+	return func(name string) (RowsI, error) {
+		return OpenSnapshotRows(backendPath(dir, name, snapshotRowsExt))
+	}
+}
+
+// Returns a backend keeping each DB's rows in memory and logging every change to a file in dir,
+// replayed when the rows are opened again and compacted when they're flushed
+func WALBackend(dir string) Backend {
+	return func(name string) (RowsI, error) {
+		return OpenWALRows(backendPath(dir, name, walRowsExt))
+	}
+}
+
+// Returns a backend keeping each DB's rows in a page file in dir, holding at most poolPages
+// pages of it in memory
+func PagedBackend(dir string, poolPages int) Backend {
+	return func(name string) (RowsI, error) {
+		return OpenPagedRows(backendPath(dir, name, pagedRowsExt), poolPages)
+	}
+}
+
+// Returns the path of the file a backend keeps the DB's rows in, escaping the name so any name
+// makes a valid file name
+func backendPath(dir string, name string, ext string) string {
+	return filepath.Join(dir, url.PathEscape(name)+ext)
+}
@@ -0,0 +1,385 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The RowsI implementations the conformance suite runs against, each opening empty rows in dir
+var rowsImplementations = map[string]func(t *testing.T, dir string) RowsI{
+	"memory": func(t *testing.T, dir string) RowsI {
+		return &Rows{}
+	},
+	"columnar": func(t *testing.T, dir string) RowsI {
+		return NewColumnarRows()
+	},
+	"snapshot": func(t *testing.T, dir string) RowsI {
+		return openStored(t, dir, "snapshot")
+	},
+	"wal": func(t *testing.T, dir string) RowsI {
+		return openStored(t, dir, "wal")
+	},
+	"paged": func(t *testing.T, dir string) RowsI {
+		return openStored(t, dir, "paged")
+	},
+}
+
+// Opens the rows of a file backend in dir, closing them when the test ends
+func openStored(t *testing.T, dir string, backend string) StoredRowsI {
+	var rows StoredRowsI
+	var err error
+	switch backend {
+	case "snapshot":
+		rows, err = OpenSnapshotRows(filepath.Join(dir, "rows"+snapshotRowsExt))
+	case "wal":
+		rows, err = OpenWALRows(filepath.Join(dir, "rows"+walRowsExt))
+	case "paged":
+		rows, err = OpenPagedRows(filepath.Join(dir, "rows"+pagedRowsExt), 0)
+	}
+	assert.Nil(t, err)
+
+	return rows
+}
+
+func sortedKeys(rows []RowI) []string {
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, rowKey(row))
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func nameOf(t *testing.T, rows RowsI, key string) string {
+	v, err := rows.GetRowFromKeyHeader(key).GetValueFromHeader("Name")
+	assert.Nil(t, err)
+
+	return v.GetValue()
+}
+
+// Checks the behaviour every RowsI must have, leaving out the order of GetRows, which is up to
+// the implementation
+func testRowsConformance(t *testing.T, rows RowsI) {
+	assert.Equal(t, 0, len(rows.GetRows()))
+	assert.Nil(t, rows.GetRowFromKeyHeader("a"))
+	found, err := rows.GetRowsFromHeaderAndValue("Name", "Ay")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(found))
+
+	assert.Nil(t, rows.AddRow(newPagedRow("a", "Ay")))
+	assert.Nil(t, rows.AddRows([]RowI{newPagedRow("c", "Sea"), newPagedRow("b", "Bee")}))
+	assert.Equal(t, []string{"a", "b", "c"}, sortedKeys(rows.GetRows()))
+
+	// Adding a duplicate key adds nothing
+	err = rows.AddRow(newPagedRow("a", "Again"))
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	err = rows.AddRows([]RowI{newPagedRow("d", "Dee"), newPagedRow("b", "Again")})
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	err = rows.AddRows([]RowI{newPagedRow("d", "Dee"), newPagedRow("d", "Again")})
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	assert.Nil(t, rows.GetRowFromKeyHeader("d"))
+	assert.Equal(t, "Ay", nameOf(t, rows, "a"))
+
+	// Reading a row
+	row := rows.GetRowFromKeyHeader("b")
+	h, v := row.GetKeyHeaderAndValue()
+	assert.Equal(t, "ID", h.GetName())
+	assert.True(t, h.IsKeyHeader())
+	assert.Equal(t, "b", v.GetValue())
+	assert.True(t, row.KeyHeaderValueEqual("b"))
+	assert.False(t, row.KeyHeaderValueEqual("a"))
+	assert.True(t, row.HeaderExists("Name"))
+	assert.False(t, row.HeaderExists("Score"))
+	_, err = row.GetValueFromHeader("Score")
+	assert.True(t, errors.Is(err, ErrHeaderNotExist))
+	values := map[string]string{}
+	for h, v := range row.GetRowMap() {
+		values[h.GetName()] = v.GetValue()
+	}
+	assert.Equal(t, map[string]string{"ID": "b", "Name": "Bee"}, values)
+
+	// Changes made through a row or the rows are seen by every later read
+	row.UpdateHeaderValue("Name", "Bea")
+	assert.Equal(t, "Bea", nameOf(t, rows, "b"))
+	rows.AddValueToRowWithKeyHeader("Si", "Name", "c")
+	assert.Equal(t, "Si", nameOf(t, rows, "c"))
+	rows.AddValueToRowWithKeyHeader("Nobody", "Name", "z")
+	assert.Nil(t, rows.GetRowFromKeyHeader("z"))
+
+	found, err = rows.GetRowsFromHeaderAndValue("Name", "Si")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c"}, sortedKeys(found))
+	found, err = rows.GetRowsFromHeaderAndValue("ID", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, sortedKeys(found))
+
+	// Headers added to and removed from a row
+	row = rows.GetRowFromKeyHeader("a")
+	assert.Nil(t, row.AddHeaderWithValue("Score", false, VALUE_NUMBER, "3"))
+	err = row.AddHeaderWithValue("Other", true, VALUE_STRING, "")
+	assert.True(t, errors.Is(err, ErrKeyHeaderExists))
+	assert.True(t, rows.GetRowFromKeyHeader("a").HeaderExists("Score"))
+	v, err = rows.GetRowFromKeyHeader("a").GetValueFromHeader("Score")
+	assert.Nil(t, err)
+	assert.Equal(t, "3", v.GetValue())
+
+	// A row without the header stops the search
+	_, err = rows.GetRowsFromHeaderAndValue("Score", "3")
+	assert.True(t, errors.Is(err, ErrHeaderNotExist))
+
+	err = rows.GetRowFromKeyHeader("a").RemoveHeader("ID")
+	assert.True(t, errors.Is(err, ErrDeleteKeyHeader))
+	assert.Nil(t, rows.GetRowFromKeyHeader("a").RemoveHeader("Score"))
+	assert.False(t, rows.GetRowFromKeyHeader("a").HeaderExists("Score"))
+
+	// Headers removed from every row
+	for _, row := range rows.GetRows() {
+		assert.Nil(t, row.AddHeaderWithValue("Score", false, VALUE_NUMBER, ""))
+	}
+	rows.RemoveHeader("Score")
+	for _, row := range rows.GetRows() {
+		assert.False(t, row.HeaderExists("Score"))
+	}
+
+	// Deleting rows, including ones that don't exist, while a view of another row is held
+	held := rows.GetRowFromKeyHeader("b")
+	rows.DeleteRowWithValue("a")
+	rows.DeleteRowWithValue("z")
+	rows.DeleteRow(newPagedRow("c", ""))
+	assert.Equal(t, []string{"b"}, sortedKeys(rows.GetRows()))
+	assert.Nil(t, rows.GetRowFromKeyHeader("a"))
+	v, err = held.GetValueFromHeader("Name")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bea", v.GetValue())
+	held.UpdateHeaderValue("Name", "Bee")
+	assert.Equal(t, "Bee", nameOf(t, rows, "b"))
+	held.UpdateHeaderValue("Name", "Bea")
+	assert.Nil(t, rows.AddRow(newPagedRow("a", "Ay again")))
+	assert.Equal(t, "Ay again", nameOf(t, rows, "a"))
+
+	if stored, ok := rows.(StoredRowsI); ok {
+		assert.Nil(t, stored.Err())
+	}
+}
+
+func TestRowsConformance(t *testing.T) {
+	for name, open := range rowsImplementations {
+		t.Run(name, func(t *testing.T) {
+			rows := open(t, t.TempDir())
+			testRowsConformance(t, rows)
+			if stored, ok := rows.(StoredRowsI); ok {
+				assert.Nil(t, stored.Close())
+			}
+		})
+	}
+}
+
+// Checks that removing a row through a DB keeping its rows in each implementation publishes the
+// row's values, so undoing it brings the row back
+func TestRowsConformanceDB(t *testing.T) {
+	for name, open := range rowsImplementations {
+		t.Run(name, func(t *testing.T) {
+			db, err := New("Test", []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"Name", false, VALUE_STRING}}, "ID")
+			assert.Nil(t, err)
+			assert.Nil(t, db.useRows(open(t, t.TempDir())))
+			defer db.Close()
+			errs, err := db.AddRows([]RowI{newPagedRow("a", "Ay"), newPagedRow("b", "Bee"), newPagedRow("c", "Sea")}, AddRowsOptions{})
+			assert.Nil(t, errs)
+			assert.Nil(t, err)
+
+			held := db.Rows.GetRowFromKeyHeader("c")
+			events, unsubscribe := db.Subscribe(EventFilter{})
+			defer unsubscribe()
+			assert.Nil(t, db.RemoveRow("a"))
+			e := <-events
+			assert.Equal(t, map[string]string{"ID": "a", "Name": "Ay"}, e.Row)
+			v, err := held.GetValueFromHeader("Name")
+			assert.Nil(t, err)
+			assert.Equal(t, "Sea", v.GetValue())
+
+			assert.Nil(t, db.Undo())
+			assert.Equal(t, []string{"a", "b", "c"}, sortedKeys(db.GetRows()))
+			assert.Equal(t, "Ay", getValue(t, db, "a", "Name"))
+			assert.Nil(t, db.Redo())
+			assert.Nil(t, db.GetRowFromKeyHeader("a"))
+			if stored, ok := db.Rows.(StoredRowsI); ok {
+				assert.Nil(t, stored.Err())
+			}
+		})
+	}
+}
+
+// Checks that the rows of every file backend read back as they were left once closed
+func TestStoredRowsConformance(t *testing.T) {
+	for _, backend := range []string{"snapshot", "wal", "paged"} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			rows := openStored(t, dir, backend)
+			testRowsConformance(t, rows)
+			row := rows.GetRowFromKeyHeader("b")
+			assert.Nil(t, row.AddHeaderWithValue("Score", false, VALUE_NUMBER, "9"))
+			assert.Nil(t, rows.Flush())
+			assert.Nil(t, rows.Close())
+
+			rows = openStored(t, dir, backend)
+			defer rows.Close()
+			assert.Equal(t, []string{"a", "b"}, sortedKeys(rows.GetRows()))
+			assert.Equal(t, "Ay again", nameOf(t, rows, "a"))
+			assert.Equal(t, "Bea", nameOf(t, rows, "b"))
+			v, err := rows.GetRowFromKeyHeader("b").GetValueFromHeader("Score")
+			assert.Nil(t, err)
+			assert.Equal(t, "9", v.GetValue())
+			assert.False(t, rows.GetRowFromKeyHeader("a").HeaderExists("Score"))
+		})
+	}
+}
+
+func TestNewWithBackend(t *testing.T) {
+	dir := t.TempDir()
+	RegisterBackend(BACKEND_PAGED, PagedBackend(dir, 0))
+	RegisterBackend(BACKEND_WAL, WALBackend(dir))
+	RegisterBackend(BACKEND_SNAPSHOT, SnapshotBackend(dir))
+	assert.Equal(t, []string{BACKEND_COLUMNAR, BACKEND_MEMORY, BACKEND_PAGED, BACKEND_SNAPSHOT, BACKEND_WAL}, Backends())
+
+	_, err := NewWithBackend("Test", []HeaderI{&Header{"ID", true, VALUE_STRING}}, "ID", "tape")
+	assert.True(t, errors.Is(err, ErrUnknownBackend))
+	assert.Contains(t, err.Error(), "'columnar', 'memory', 'paged', 'snapshot', 'wal'")
+
+	headers := []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"Name", false, VALUE_STRING}}
+	for _, backend := range Backends() {
+		db, err := NewWithBackend("Test/"+backend, headers, "ID", backend)
+		assert.Nil(t, err)
+		assert.Nil(t, db.AddRow(newPagedRow("a", "Ay")))
+		assert.Nil(t, db.Upsert(newPagedRow("a", "Aye")))
+		assert.Nil(t, db.Close())
+
+		// Opening the DB again with a new header keeps its rows and adds the header to them
+		db, err = NewWithBackend("Test/"+backend, append(headers, &Header{"Score", false, VALUE_NUMBER}), "ID", backend)
+		assert.Nil(t, err)
+		if _, ok := db.Rows.(StoredRowsI); !ok {
+			assert.Equal(t, 0, len(db.GetRows()))
+			continue
+		}
+		assert.Equal(t, 1, len(db.GetRows()))
+		assert.Equal(t, "Aye", getValue(t, db, "a", "Name"))
+		assert.Equal(t, "", getValue(t, db, "a", "Score"))
+		assert.Nil(t, db.Close())
+
+		// The rows can't be opened with a different KeyHeader
+		_, err = NewWithBackend("Test/"+backend, []HeaderI{&Header{"Name", true, VALUE_STRING}}, "Name", backend)
+		assert.True(t, errors.Is(err, ErrKeyHeaderIncorrect), backend)
+	}
+
+	// Names are escaped so every DB has its own file
+	_, err = os.Stat(filepath.Join(dir, "Test%2Fpaged"+pagedRowsExt))
+	assert.Nil(t, err)
+}
+
+func TestUseBackend(t *testing.T) {
+	dir := t.TempDir()
+	RegisterBackend(BACKEND_WAL, WALBackend(dir))
+	headers := []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"Name", false, VALUE_STRING}}
+
+	// Leave a row in the DB's file that the moved rows replace
+	old, err := NewWithBackend("Moved", headers, "ID", BACKEND_WAL)
+	assert.Nil(t, err)
+	assert.Nil(t, old.AddRow(newPagedRow("old", "")))
+	assert.Nil(t, old.Close())
+
+	db, err := New("Moved", headers, "ID")
+	assert.Nil(t, err)
+	assert.Equal(t, BACKEND_MEMORY, db.BackendName())
+	assert.Nil(t, db.AddRow(newPagedRow("a", "Ay")))
+	assert.True(t, errors.Is(db.UseBackend("tape"), ErrUnknownBackend))
+
+	assert.Nil(t, db.UseBackend(BACKEND_WAL))
+	assert.Equal(t, BACKEND_WAL, db.BackendName())
+	assert.Nil(t, db.UseBackend(BACKEND_WAL))
+	assert.Nil(t, db.AddRow(newPagedRow("b", "Bee")))
+	assert.Nil(t, db.Close())
+
+	db, err = NewWithBackend("Moved", headers, "ID", BACKEND_WAL)
+	assert.Nil(t, err)
+	defer db.Close()
+	assert.Equal(t, []string{"a", "b"}, sortedKeys(db.GetRows()))
+	assert.Equal(t, "Ay", getValue(t, db, "a", "Name"))
+}
+
+func TestWALRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows"+walRowsExt)
+	rows, err := OpenWALRows(path)
+	assert.Nil(t, err)
+	assert.Nil(t, rows.AddRows([]RowI{newPagedRow("a", "Ay"), newPagedRow("b", "Bee")}))
+	for _, name := range []string{"1", "2", "3"} {
+		rows.AddValueToRowWithKeyHeader(name, "Name", "a")
+	}
+
+	// Each change is in the log as soon as it's made
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+
+	// A change cut short at the end of the log is dropped
+	assert.Nil(t, os.WriteFile(path, data[:len(data)-3], 0o644))
+	rows, err = OpenWALRows(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", nameOf(t, rows, "a"))
+	rows.DeleteRowWithValue("b")
+	for _, name := range []string{"4", "5", "6"} {
+		rows.AddValueToRowWithKeyHeader(name, "Name", "a")
+	}
+
+	// Flushing replaces the changes with the rows they made
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Flush())
+	flushed, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Less(t, flushed.Size(), info.Size())
+	rows.AddValueToRowWithKeyHeader("7", "Name", "a")
+	assert.Nil(t, rows.Close())
+	rows, err = OpenWALRows(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, sortedKeys(rows.GetRows()))
+	assert.Equal(t, "7", nameOf(t, rows, "a"))
+	assert.Nil(t, rows.Close())
+
+	// A bad change before the end is corruption
+	corrupt := append([]byte{}, data...)
+	corrupt[len(walRowsMagic)+4] ^= 0xff
+	assert.Nil(t, os.WriteFile(path, corrupt, 0o644))
+	_, err = OpenWALRows(path)
+	assert.True(t, errors.Is(err, ErrCorrupt))
+
+	assert.Nil(t, os.WriteFile(path, []byte("PDBR\x00\x01"), 0o644))
+	_, err = OpenWALRows(path)
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+func TestSnapshotRowsCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows"+snapshotRowsExt)
+	rows, err := OpenSnapshotRows(path)
+	assert.Nil(t, err)
+	assert.Nil(t, rows.AddRows([]RowI{newPagedRow("a", "Ay"), newPagedRow("b", "Bee")}))
+	assert.Nil(t, rows.Close())
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	for i := 0; i < len(data); i++ {
+		assert.Nil(t, os.WriteFile(path, data[:i], 0o644))
+		_, err = OpenSnapshotRows(path)
+		assert.True(t, errors.Is(err, ErrCorrupt), i)
+	}
+
+	version := append([]byte{}, data...)
+	version[len(snapshotRowsMagic)+1] = 2
+	assert.Nil(t, os.WriteFile(path, version, 0o644))
+	_, err = OpenSnapshotRows(path)
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+}
@@ -0,0 +1,495 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The binary encoding of a DB is made of:
+//   - The magic number and a big endian uint16 version
+//   - A schema block holding the name of the DB, the column id of the KeyHeader, the name and
+//     type of each header, whose position is its column id, and from version 2 the name of the
+//     backend the DB kept its rows in
+//   - A row block for each row holding the number of values it has, then the column id and value of
+//     each, leaving out empty values
+//   - An end block holding the number of rows so a file cut short between rows is noticed
+//
+// Each block is its kind, the uvarint length of its payload, the payload and the big endian CRC32
+// of the kind and payload
+// All counts, lengths and column ids are uvarints, and strings are their length then their bytes
+// VALUE_NUMBER values are a byte saying how they're stored followed by a zigzag varint for
+// integers, the IEEE 754 bits for other numbers that print the same way, or the string otherwise
+const (
+	binaryMagic           = "PDB\x1a"
+	BINARY_VERSION uint16 = 2
+
+	// The largest block the reader accepts, so a corrupt length can't exhaust memory
+	binaryMaxBlock = 64 << 20
+	// How many rows the reader adds at a time
+	binaryBatchSize = 1000
+)
+
+// The kinds of block
+const (
+	binarySchemaBlock byte = 'S'
+	binaryRowBlock    byte = 'R'
+	binaryEndBlock    byte = 'E'
+)
+
+// How a VALUE_NUMBER value is stored
+const (
+	binaryNumberInt byte = iota
+	binaryNumberFloat
+	binaryNumberString
+)
+
+// Writes the DB to w in the binary encoding ReadBinary reads
+// Rows are written as they are read
+func WriteBinary(d DB, w io.Writer) error {
+	headers := SortedHeaders(d)
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(binaryMagic)
+	binary.Write(bw, binary.BigEndian, BINARY_VERSION)
+
+	// The KeyHeader is always first so is column 0
+	schema := appendBinaryString(nil, d.GetName())
+	schema = binary.AppendUvarint(schema, 0)
+	schema = binary.AppendUvarint(schema, uint64(len(headers)))
+	for _, h := range headers {
+		schema = appendBinaryString(schema, h.GetName())
+		schema = append(schema, byte(h.GetType()))
+	}
+	backend := BACKEND_MEMORY
+	if b, ok := d.(Backed); ok {
+		backend = b.BackendName()
+	}
+	schema = appendBinaryString(schema, backend)
+	writeBinaryBlock(bw, binarySchemaBlock, schema)
+
+	it := d.Iter(Query{})
+	defer it.Close()
+
+	count := uint64(0)
+	payload := []byte{}
+	values := []byte{}
+	for it.Next() {
+		n := uint64(0)
+		values = values[:0]
+		for i, h := range headers {
+			v, err := it.Row().GetValueFromHeader(h.GetName())
+			if err != nil || v.GetValue() == "" {
+				continue
+			}
+			n++
+			values = binary.AppendUvarint(values, uint64(i))
+			values = appendBinaryValue(values, h, v.GetValue())
+		}
+
+		payload = binary.AppendUvarint(payload[:0], n)
+		payload = append(payload, values...)
+		writeBinaryBlock(bw, binaryRowBlock, payload)
+		count++
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	writeBinaryBlock(bw, binaryEndBlock, binary.AppendUvarint(nil, count))
+
+	return bw.Flush()
+}
+
+func writeBinaryBlock(w *bufio.Writer, kind byte, payload []byte) {
+	crc := crc32.NewIEEE()
+	crc.Write([]byte{kind})
+	crc.Write(payload)
+
+	w.WriteByte(kind)
+	w.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+	w.Write(payload)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+func appendBinaryString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Appends the value, storing VALUE_NUMBER values as numbers when they read back the same
+func appendBinaryValue(b []byte, h HeaderI, value string) []byte {
+	if !h.IsNumber() {
+		return appendBinaryString(b, value)
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+		b = append(b, binaryNumberInt)
+		return binary.AppendVarint(b, n)
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		b = append(b, binaryNumberFloat)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f))
+	}
+
+	b = append(b, binaryNumberString)
+	return appendBinaryString(b, value)
+}
+
+// Creates a DB from the binary encoding WriteBinary writes, read from r
+// Rows are read and added in batches so the input never needs to fit in memory
+// Returns an error wrapping ErrCorrupt saying at what offset the input is wrong if it isn't in the
+// encoding, a checksum doesn't match or it ends early
+// Returns ErrUnsupportedVersion if it was written by a newer version of the encoding
+// The DB keeps its rows in memory whichever backend it was written from
+func ReadBinary(r io.Reader) (*DBImpl, error) {
+	db, _, err := readBinary(r)
+	return db, err
+}
+
+// Creates a DB like ReadBinary, keeping its rows in the backend it was written from, or memory if
+// it was written by version 1 of the encoding
+// Returns an error if the backend isn't registered or any of the errors ReadBinary returns
+func ReadBinaryWithBackend(r io.Reader) (*DBImpl, error) {
+	db, backend, err := readBinary(r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.UseBackend(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Reads the DB like ReadBinary, also returning the name of the backend it was written from
+func readBinary(r io.Reader) (*DBImpl, string, error) {
+	br := &binaryReader{r: bufio.NewReader(r)}
+
+	header := make([]byte, len(binaryMagic)+2)
+	if err := br.read(header); err != nil {
+		return nil, "", err
+	}
+	if string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, "", br.corrupt(0, binaryMagicError)
+	}
+	version := binary.BigEndian.Uint16(header[len(binaryMagic):])
+	if version < 1 || version > BINARY_VERSION {
+		return nil, "", &Error{Err: ErrUnsupportedVersion, Message: fmt.Sprintf(binaryVersionError, version, BINARY_VERSION)}
+	}
+
+	kind, p, err := br.block()
+	if err != nil {
+		return nil, "", err
+	}
+	if kind != binarySchemaBlock {
+		return nil, "", p.corrupt(fmt.Sprintf(binaryBlockKindError, binarySchemaBlock, kind))
+	}
+	db, headers, backend, err := p.schema(version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	count := uint64(0)
+	batch := make([]RowI, 0, binaryBatchSize)
+	offsets := make([]int64, 0, binaryBatchSize)
+	flush := func() error {
+		errs, err := db.AddRows(batch, AddRowsOptions{AllOrNothing: true})
+		if i, err := FirstRowError(errs); err != nil {
+			return &Error{Err: ErrCorrupt, DB: db.Name, Message: fmt.Sprintf(binaryCorruptError, offsets[i], err), Cause: err}
+		}
+		batch = batch[:0]
+		offsets = offsets[:0]
+
+		return err
+	}
+
+	for {
+		kind, p, err := br.block()
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch kind {
+		case binaryRowBlock:
+			row, err := p.row(db, headers)
+			if err != nil {
+				return nil, "", err
+			}
+			batch = append(batch, row)
+			offsets = append(offsets, p.offset)
+			count++
+
+			if len(batch) == binaryBatchSize {
+				if err := flush(); err != nil {
+					return nil, "", err
+				}
+			}
+		case binaryEndBlock:
+			n, err := p.uvarint()
+			if err != nil {
+				return nil, "", err
+			}
+			if n != count {
+				return nil, "", p.corrupt(fmt.Sprintf(binaryRowCountError, n, count))
+			}
+			if err := p.end(); err != nil {
+				return nil, "", err
+			}
+			if err := flush(); err != nil {
+				return nil, "", err
+			}
+			db.journal.clear()
+			return db, backend, nil
+		default:
+			return nil, "", p.corrupt(fmt.Sprintf(binaryBlockKindError, binaryRowBlock, kind))
+		}
+	}
+}
+
+// Reads exactly len(b) bytes, returning an error if the input ends first
+func (r *binaryReader) read(b []byte) error {
+	n, err := io.ReadFull(r.r, b)
+	r.offset += int64(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return r.corrupt(r.offset, binaryTruncatedError)
+	}
+
+	return err
+}
+
+// Reads a block, checking its checksum, and returns its kind and payload
+func (r *binaryReader) block() (byte, *binaryPayload, error) {
+	start := r.offset
+
+	kind, err := r.r.ReadByte()
+	if err == io.EOF {
+		return 0, nil, r.corrupt(r.offset, binaryTruncatedError)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	r.offset++
+
+	length, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return 0, nil, r.corrupt(r.offset, binaryTruncatedError)
+	}
+	if length > binaryMaxBlock {
+		return 0, nil, r.corrupt(start, fmt.Sprintf(binaryBlockSizeError, length))
+	}
+	r.offset += int64(len(binary.AppendUvarint(nil, length)))
+
+	payload := &binaryPayload{data: make([]byte, length), offset: r.offset}
+	if err := r.read(payload.data); err != nil {
+		return 0, nil, err
+	}
+
+	sum := make([]byte, 4)
+	if err := r.read(sum); err != nil {
+		return 0, nil, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write([]byte{kind})
+	crc.Write(payload.data)
+	if crc.Sum32() != binary.BigEndian.Uint32(sum) {
+		return 0, nil, r.corrupt(start, binaryChecksumError)
+	}
+
+	return kind, payload, nil
+}
+
+func (r *binaryReader) corrupt(offset int64, reason string) error {
+	return &Error{Err: ErrCorrupt, Message: fmt.Sprintf(binaryCorruptError, offset, reason)}
+}
+
+// Reads a schema block written by the given version of the encoding, creating the DB it describes
+// and returning it with its headers by column id and the backend it was written from
+func (p *binaryPayload) schema(version uint16) (*DBImpl, []HeaderI, string, error) {
+	name, err := p.string()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	key, err := p.uvarint()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	n, err := p.uvarint()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if key >= n {
+		return nil, nil, "", p.corrupt(fmt.Sprintf(binaryColumnError, key))
+	}
+
+	headers := []HeaderI{}
+	for i := uint64(0); i < n; i++ {
+		header, err := p.string()
+		if err != nil {
+			return nil, nil, "", err
+		}
+		t, err := p.byte()
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if Type(t) != VALUE_STRING && Type(t) != VALUE_NUMBER {
+			return nil, nil, "", p.corrupt(fmt.Sprintf(binaryTypeError, t))
+		}
+		headers = append(headers, &Header{Name: header, KeyHeader: i == key, Type: Type(t)})
+	}
+	backend := BACKEND_MEMORY
+	if version >= 2 {
+		backend, err = p.string()
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
+	if err := p.end(); err != nil {
+		return nil, nil, "", err
+	}
+
+	db, err := New(name, headers, headers[key].GetName())
+	if err != nil {
+		return nil, nil, "", &Error{Err: ErrCorrupt, DB: name, Message: fmt.Sprintf(binaryCorruptError, p.offset, err), Cause: err}
+	}
+
+	return db, headers, backend, nil
+}
+
+// Reads a row block, returning the row it describes
+func (p *binaryPayload) row(d DB, headers []HeaderI) (RowI, error) {
+	n, err := p.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for i := uint64(0); i < n; i++ {
+		column, err := p.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if column >= uint64(len(headers)) {
+			return nil, p.corrupt(fmt.Sprintf(binaryColumnError, column))
+		}
+		h := headers[column]
+
+		value, err := p.value(h)
+		if err != nil {
+			return nil, err
+		}
+		values[h.GetName()] = value
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+
+	row, err := NewRowFromMap(d, values)
+	if err != nil {
+		return nil, &Error{Err: ErrCorrupt, DB: d.GetName(), Message: fmt.Sprintf(binaryCorruptError, p.offset, err), Cause: err}
+	}
+
+	return row, nil
+}
+
+// Reads a value of the header's type
+func (p *binaryPayload) value(h HeaderI) (string, error) {
+	if !h.IsNumber() {
+		return p.string()
+	}
+
+	storage, err := p.byte()
+	if err != nil {
+		return "", err
+	}
+
+	switch storage {
+	case binaryNumberInt:
+		n, read := binary.Varint(p.data[p.pos:])
+		if read <= 0 {
+			return "", p.corrupt(binaryTruncatedError)
+		}
+		p.pos += read
+		return strconv.FormatInt(n, 10), nil
+	case binaryNumberFloat:
+		b, err := p.bytes(8)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(b)), 'f', -1, 64), nil
+	case binaryNumberString:
+		value, err := p.string()
+		if err != nil {
+			return "", err
+		}
+		if err := ValidateValue(h, value); err != nil {
+			return "", p.corrupt(err.Error())
+		}
+		return value, nil
+	default:
+		return "", p.corrupt(fmt.Sprintf(binaryNumberError, storage))
+	}
+}
+
+func (p *binaryPayload) uvarint() (uint64, error) {
+	n, read := binary.Uvarint(p.data[p.pos:])
+	if read <= 0 {
+		return 0, p.corrupt(binaryTruncatedError)
+	}
+	p.pos += read
+
+	return n, nil
+}
+
+func (p *binaryPayload) byte() (byte, error) {
+	b, err := p.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+func (p *binaryPayload) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(p.data)-p.pos) {
+		return nil, p.corrupt(binaryTruncatedError)
+	}
+	b := p.data[p.pos : p.pos+int(n)]
+	p.pos += int(n)
+
+	return b, nil
+}
+
+func (p *binaryPayload) string() (string, error) {
+	n, err := p.uvarint()
+	if err != nil {
+		return "", err
+	}
+	b, err := p.bytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Returns an error if there's more of the payload left to read
+func (p *binaryPayload) end() error {
+	if p.pos != len(p.data) {
+		return p.corrupt(fmt.Sprintf(binaryTrailingError, len(p.data)-p.pos))
+	}
+
+	return nil
+}
+
+// Returns an error for the payload at the position being read
+func (p *binaryPayload) corrupt(reason string) error {
+	return &Error{Err: ErrCorrupt, Message: fmt.Sprintf(binaryCorruptError, p.offset+int64(p.pos), reason)}
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinary(t *testing.T) {
+	db := newNumberDB(t)
+	for key, points := range map[string]string{"c": "-7", "d": "2.5", "e": "1e3", "f": "007"} {
+		row, err := NewRowFromMap(db, map[string]string{"Title": key, "Points": points})
+		assert.Nil(t, err)
+		assert.Nil(t, db.AddRow(row))
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteBinary(db, buf))
+	assert.Equal(t, binaryMagic, buf.String()[:len(binaryMagic)])
+
+	read, err := ReadBinary(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Test", read.GetName())
+	assert.Equal(t, "Title", read.GetKeyHeader())
+	assert.True(t, read.GetHeader("Points").IsNumber())
+	assert.False(t, read.GetHeader("Title").IsNumber())
+	assert.Equal(t, 6, len(read.GetRows()))
+	// Every number reads back as it was written however it's stored
+	for key, points := range map[string]string{"a": "100", "b": "50", "c": "-7", "d": "2.5", "e": "1e3", "f": "007"} {
+		assert.Equal(t, points, getValue(t, read, key, "Points"))
+	}
+	assert.Equal(t, "", getValue(t, read, "b", "Trophies"))
+}
+
+func TestBinaryEmpty(t *testing.T) {
+	db, err := New("Empty", []HeaderI{&Header{"ID", true, VALUE_NUMBER}}, "ID")
+	assert.Nil(t, err)
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteBinary(db, buf))
+	read, err := ReadBinary(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(read.GetRows()))
+}
+
+func TestBinaryLarge(t *testing.T) {
+	db, err := New("Large", []HeaderI{
+		&Header{"ID", true, VALUE_NUMBER},
+		&Header{"Name", false, VALUE_STRING},
+	}, "ID")
+	assert.Nil(t, err)
+	rows := []RowI{}
+	for i := 0; i < binaryBatchSize*2+1; i++ {
+		row, err := NewRowFromMap(db, map[string]string{"ID": fmt.Sprint(i), "Name": fmt.Sprint("row ", i)})
+		assert.Nil(t, err)
+		rows = append(rows, row)
+	}
+	db.AddRows(rows, AddRowsOptions{})
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteBinary(db, buf))
+	read, err := ReadBinary(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, binaryBatchSize*2+1, len(read.GetRows()))
+	assert.Equal(t, "row 1500", getValue(t, read, "1500", "Name"))
+}
+
+func TestBinaryBackend(t *testing.T) {
+	db, err := NewWithBackend("Columns", []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"Name", false, VALUE_STRING}}, "ID", BACKEND_COLUMNAR)
+	assert.Nil(t, err)
+	assert.Nil(t, db.AddRow(newPagedRow("a", "")))
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteBinary(db, buf))
+	data := buf.Bytes()
+
+	read, err := ReadBinary(bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, BACKEND_MEMORY, read.BackendName())
+
+	read, err = ReadBinaryWithBackend(bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, BACKEND_COLUMNAR, read.BackendName())
+	assert.IsType(t, &ColumnarRows{}, read.Rows)
+	assert.NotNil(t, read.GetRowFromKeyHeader("a"))
+
+	// Version 1 has no backend in its schema block, so the rows are kept in memory
+	schemaLen, n := binary.Uvarint(data[len(binaryMagic)+3:])
+	schemaStart := len(binaryMagic) + 3 + n
+	schema := data[schemaStart : schemaStart+int(schemaLen)]
+	v1 := &bytes.Buffer{}
+	w := bufio.NewWriter(v1)
+	w.WriteString(binaryMagic)
+	binary.Write(w, binary.BigEndian, uint16(1))
+	writeBinaryBlock(w, binarySchemaBlock, schema[:len(schema)-len(appendBinaryString(nil, BACKEND_COLUMNAR))])
+	w.Write(data[schemaStart+int(schemaLen)+4:])
+	assert.Nil(t, w.Flush())
+
+	read, err = ReadBinaryWithBackend(v1)
+	assert.Nil(t, err)
+	assert.Equal(t, BACKEND_MEMORY, read.BackendName())
+	assert.NotNil(t, read.GetRowFromKeyHeader("a"))
+}
+
+func TestBinaryCorrupt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteBinary(newNumberDB(t), buf))
+	data := buf.Bytes()
+
+	// The first row block starts after the header and schema block
+	schemaLen, n := binary.Uvarint(data[len(binaryMagic)+3:])
+	rowStart := len(binaryMagic) + 3 + n + int(schemaLen) + 4
+
+	// Flipping any byte after the header is caught by a checksum, reported at the block's offset
+	flipped := append([]byte{}, data...)
+	flipped[rowStart+3] ^= 0xff
+	_, err := ReadBinary(bytes.NewReader(flipped))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+	assert.Contains(t, err.Error(), fmt.Sprintf("offset %d:", rowStart))
+
+	// Cutting the data short anywhere is caught
+	for i := 0; i < len(data); i++ {
+		_, err = ReadBinary(bytes.NewReader(data[:i]))
+		assert.True(t, errors.Is(err, ErrCorrupt), i)
+	}
+
+	// Dropping a whole row block is caught by the end block's count
+	rowLen, n := binary.Uvarint(data[rowStart+1:])
+	dropped := append(append([]byte{}, data[:rowStart]...), data[rowStart+1+n+int(rowLen)+4:]...)
+	_, err = ReadBinary(bytes.NewReader(dropped))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+	assert.Contains(t, err.Error(), "end block counts 2 rows but 1 were read")
+
+	_, err = ReadBinary(bytes.NewReader([]byte("PDB!\x00\x01")))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+	assert.Contains(t, err.Error(), "offset 0:")
+
+	version := append([]byte{}, data...)
+	binary.BigEndian.PutUint16(version[len(binaryMagic):], BINARY_VERSION+1)
+	_, err = ReadBinary(bytes.NewReader(version))
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	// A block with a valid checksum but a column that doesn't exist
+	bad := &bytes.Buffer{}
+	bad.Write(data[:rowStart])
+	w := bufio.NewWriter(bad)
+	writeBinaryBlock(w, binaryRowBlock, []byte{1, 9, 1, 'a'})
+	w.Flush()
+	_, err = ReadBinary(bad)
+	assert.True(t, errors.Is(err, ErrCorrupt))
+	assert.Contains(t, err.Error(), "unknown column 9")
+}
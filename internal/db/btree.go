@@ -0,0 +1,336 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// The B+tree of a page file maps each key to the location of its row's record
+// Leaves hold the keys in order with their locations and link to the next leaf, and internal
+// nodes hold separator keys where child i holds the keys from separator i-1 up to separator i
+// Deleting a key only removes it from its leaf, so leaves may be left empty rather than merged
+
+// The longest key a B+tree holds, short enough that splitting a full node always gives two
+// nodes that fit in a page
+const PAGED_MAX_KEY = 512
+
+// The size of the type, count and next leaf fields at the start of a node
+const btreeHeaderSize = 7
+
+// Returns the location stored for the key, and false if the key isn't in the tree
+func (p *pager) btreeGet(key string) (rid, bool, error) {
+	if p.root == noPage {
+		return rid{}, false, nil
+	}
+
+	n, err := p.readNode(p.root)
+	if err != nil {
+		return rid{}, false, err
+	}
+	for !n.leaf {
+		n, err = p.readNode(n.children[n.child(key)])
+		if err != nil {
+			return rid{}, false, err
+		}
+	}
+
+	i, found := n.find(key)
+	if !found {
+		return rid{}, false, nil
+	}
+
+	return n.rids[i], true, nil
+}
+
+// Stores the location for the key, replacing the location stored for it before
+func (p *pager) btreeInsert(key string, r rid) error {
+	if p.root == noPage {
+		f, err := p.allocate(pageLeaf)
+		if err != nil {
+			return err
+		}
+		p.root = f.id
+		p.pool.release(f, true)
+	}
+
+	split, err := p.insertNode(p.root, key, r)
+	if err != nil || split == nil {
+		return err
+	}
+
+	// The root was split so a new root is needed above the two halves
+	f, err := p.allocate(pageInternal)
+	if err != nil {
+		return err
+	}
+	id := f.id
+	p.pool.release(f, true)
+
+	err = p.writeNode(id, &btreeNode{keys: []string{split.key}, children: []uint32{p.root, split.page}})
+	if err != nil {
+		return err
+	}
+	p.root = id
+
+	return nil
+}
+
+// Inserts the key into the subtree under the node with the given id, returning the separator and
+// new node if the node had to be split
+func (p *pager) insertNode(id uint32, key string, r rid) (*btreeSplit, error) {
+	n, err := p.readNode(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.leaf {
+		i, found := n.find(key)
+		if found {
+			n.rids[i] = r
+		} else {
+			n.keys = append(n.keys[:i], append([]string{key}, n.keys[i:]...)...)
+			n.rids = append(n.rids[:i], append([]rid{r}, n.rids[i:]...)...)
+		}
+	} else {
+		i := n.child(key)
+		split, err := p.insertNode(n.children[i], key, r)
+		if err != nil || split == nil {
+			return nil, err
+		}
+		n.keys = append(n.keys[:i], append([]string{split.key}, n.keys[i:]...)...)
+		n.children = append(n.children[:i+1], append([]uint32{split.page}, n.children[i+1:]...)...)
+	}
+
+	if n.size() <= PAGE_SIZE {
+		return nil, p.writeNode(id, n)
+	}
+
+	return p.splitNode(id, n)
+}
+
+// Moves the second half of the node, by size, to a new node
+func (p *pager) splitNode(id uint32, n *btreeNode) (*btreeSplit, error) {
+	kind := pageInternal
+	if n.leaf {
+		kind = pageLeaf
+	}
+	f, err := p.allocate(kind)
+	if err != nil {
+		return nil, err
+	}
+	rightID := f.id
+	p.pool.release(f, true)
+
+	// Split where the first half reaches half the size
+	m := 1
+	for size := btreeHeaderSize; m < len(n.keys)-1; m++ {
+		size += n.entrySize(m - 1)
+		if size >= n.size()/2 {
+			break
+		}
+	}
+	// An internal node's middle key moves up, so the right half needs at least one key after it
+	if !n.leaf && m > len(n.keys)-2 {
+		m = len(n.keys) - 2
+	}
+
+	left := &btreeNode{leaf: n.leaf}
+	right := &btreeNode{leaf: n.leaf}
+	split := &btreeSplit{page: rightID}
+	if n.leaf {
+		left.keys, right.keys = n.keys[:m], n.keys[m:]
+		left.rids, right.rids = n.rids[:m], n.rids[m:]
+		right.next = n.next
+		left.next = rightID
+		split.key = right.keys[0]
+	} else {
+		// The middle key moves up as the separator rather than staying in either half
+		left.keys, right.keys = n.keys[:m], n.keys[m+1:]
+		left.children, right.children = n.children[:m+1], n.children[m+1:]
+		split.key = n.keys[m]
+	}
+
+	err = p.writeNode(id, left)
+	if err != nil {
+		return nil, err
+	}
+
+	return split, p.writeNode(rightID, right)
+}
+
+// Removes the key from the tree, returning false if it wasn't in it
+func (p *pager) btreeDelete(key string) (bool, error) {
+	if p.root == noPage {
+		return false, nil
+	}
+
+	id := p.root
+	n, err := p.readNode(id)
+	if err != nil {
+		return false, err
+	}
+	for !n.leaf {
+		id = n.children[n.child(key)]
+		n, err = p.readNode(id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	i, found := n.find(key)
+	if !found {
+		return false, nil
+	}
+	n.keys = append(n.keys[:i], n.keys[i+1:]...)
+	n.rids = append(n.rids[:i], n.rids[i+1:]...)
+
+	return true, p.writeNode(id, n)
+}
+
+// Calls fn with each key and its location in key order until fn returns false
+func (p *pager) btreeScan(fn func(key string, r rid) bool) error {
+	if p.root == noPage {
+		return nil
+	}
+
+	n, err := p.readNode(p.root)
+	if err != nil {
+		return err
+	}
+	for !n.leaf {
+		n, err = p.readNode(n.children[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		for i, key := range n.keys {
+			if !fn(key, n.rids[i]) {
+				return nil
+			}
+		}
+		if n.next == noPage {
+			return nil
+		}
+
+		n, err = p.readNode(n.next)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *pager) readNode(id uint32) (*btreeNode, error) {
+	f, err := p.pool.get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer p.pool.release(f, false)
+
+	data := f.data
+	if data[0] != pageLeaf && data[0] != pageInternal {
+		return nil, p.invalid(fmt.Sprintf(btreePageError, id, data[0]))
+	}
+
+	n := &btreeNode{leaf: data[0] == pageLeaf}
+	count := int(binary.BigEndian.Uint16(data[1:]))
+	n.next = binary.BigEndian.Uint32(data[3:])
+	pos := btreeHeaderSize
+	entry := 6
+	if !n.leaf {
+		entry = 4
+		n.children = append(n.children, binary.BigEndian.Uint32(data[pos:]))
+		pos += 4
+	}
+
+	for i := 0; i < count; i++ {
+		length, read := binary.Uvarint(data[pos:])
+		if read <= 0 || pos+read+int(length)+entry > PAGE_SIZE {
+			return nil, p.invalid(fmt.Sprintf(btreeNodeError, id))
+		}
+		pos += read
+		n.keys = append(n.keys, string(data[pos:pos+int(length)]))
+		pos += int(length)
+
+		if n.leaf {
+			n.rids = append(n.rids, rid{page: binary.BigEndian.Uint32(data[pos:]), slot: binary.BigEndian.Uint16(data[pos+4:])})
+			pos += 6
+		} else {
+			n.children = append(n.children, binary.BigEndian.Uint32(data[pos:]))
+			pos += 4
+		}
+	}
+
+	return n, nil
+}
+
+func (p *pager) writeNode(id uint32, n *btreeNode) error {
+	f, err := p.pool.get(id)
+	if err != nil {
+		return err
+	}
+	defer p.pool.release(f, true)
+
+	data := f.data
+	clear(data)
+	data[0] = pageInternal
+	if n.leaf {
+		data[0] = pageLeaf
+	}
+	binary.BigEndian.PutUint16(data[1:], uint16(len(n.keys)))
+	binary.BigEndian.PutUint32(data[3:], n.next)
+
+	b := data[:btreeHeaderSize]
+	if !n.leaf {
+		b = binary.BigEndian.AppendUint32(b, n.children[0])
+	}
+	for i, key := range n.keys {
+		b = appendBinaryString(b, key)
+		if n.leaf {
+			b = binary.BigEndian.AppendUint32(b, n.rids[i].page)
+			b = binary.BigEndian.AppendUint16(b, n.rids[i].slot)
+		} else {
+			b = binary.BigEndian.AppendUint32(b, n.children[i+1])
+		}
+	}
+
+	return nil
+}
+
+// Returns the index of the key in a leaf, or where it would go, and whether it's there
+func (n *btreeNode) find(key string) (int, bool) {
+	i := sort.SearchStrings(n.keys, key)
+	return i, i < len(n.keys) && n.keys[i] == key
+}
+
+// Returns the index of the child of an internal node whose subtree holds the key
+func (n *btreeNode) child(key string) int {
+	return sort.Search(len(n.keys), func(i int) bool {
+		return n.keys[i] > key
+	})
+}
+
+// Returns the number of bytes the node takes in a page
+func (n *btreeNode) size() int {
+	size := btreeHeaderSize
+	if !n.leaf {
+		size += 4
+	}
+	for i := range n.keys {
+		size += n.entrySize(i)
+	}
+
+	return size
+}
+
+// Returns the number of bytes the key at i and its location or child take in a page
+func (n *btreeNode) entrySize(i int) int {
+	size := len(binary.AppendUvarint(nil, uint64(len(n.keys[i])))) + len(n.keys[i])
+	if n.leaf {
+		return size + 6
+	}
+
+	return size + 4
+}
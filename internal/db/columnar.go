@@ -0,0 +1,423 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ColumnarRows keeps each header's values in one slice rather than a map per row, so reading one
+// header across every row only touches that header's values
+// VALUE_NUMBER values are kept as float64s, along with the text of those that don't print back the
+// same way, such as "1e3", empty values and values that aren't numbers, so every value reads
+// back exactly as it was written
+
+// The backend keeping rows in memory in columns
+func ColumnarBackend(name string) (RowsI, error) {
+	return NewColumnarRows(), nil
+}
+
+func NewColumnarRows() *ColumnarRows {
+	return &ColumnarRows{index: map[string]int{}, columns: map[string]*column{}}
+}
+
+// Returns the rows in the order they were added
+// Each row is a view of its values in the columns, and changes made through it are made to them
+func (r *ColumnarRows) GetRows() []RowI {
+	rows := make([]RowI, len(r.keys))
+	for i, key := range r.keys {
+		rows[i] = &columnarRow{rows: r, key: key, pos: i}
+	}
+
+	return rows
+}
+
+func (r *ColumnarRows) AddRow(row RowI) error {
+	return r.AddRows([]RowI{row})
+}
+
+func (r *ColumnarRows) AddRows(rows []RowI) error {
+	// Check every row first so none are added if any can't be
+	keys := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		h, v := row.GetKeyHeaderAndValue()
+		if h == nil {
+			return &Error{Err: ErrKeyHeaderEmpty, Message: keyHeaderMissingError}
+		}
+
+		key := v.GetValue()
+		_, exists := r.index[key]
+		if _, dup := keys[key]; dup || exists {
+			return &Error{Err: ErrDuplicateKey, Header: h.GetName(), Key: key, Message: fmt.Sprintf(keyHeaderValueExistsError, h.GetName(), key)}
+		}
+		keys[key] = struct{}{}
+	}
+
+	return r.addCheckedRows(rows)
+}
+
+func (r *ColumnarRows) addCheckedRows(rows []RowI) error {
+	for _, row := range rows {
+		h, v := row.GetKeyHeaderAndValue()
+		if r.keyHeader == nil {
+			r.keyHeader = &Header{h.GetName(), true, h.GetType()}
+		}
+
+		i := len(r.keys)
+		r.keys = append(r.keys, v.GetValue())
+		r.index[v.GetValue()] = i
+		for _, c := range r.columns {
+			c.append()
+		}
+
+		for h, v := range row.GetRowMap() {
+			if !h.IsKeyHeader() {
+				r.column(h.GetName(), h.GetType()).set(i, v.GetValue())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *ColumnarRows) DeleteRow(row RowI) {
+	_, v := row.GetKeyHeaderAndValue()
+	r.DeleteRowWithValue(v.GetValue())
+}
+
+// Removes the row with the key, moving the rows after it back one so the order is kept
+func (r *ColumnarRows) DeleteRowWithValue(keyValue string) {
+	i, ok := r.index[keyValue]
+	if !ok {
+		return
+	}
+
+	r.keys = append(r.keys[:i], r.keys[i+1:]...)
+	delete(r.index, keyValue)
+	for j := i; j < len(r.keys); j++ {
+		r.index[r.keys[j]] = j
+	}
+	for _, c := range r.columns {
+		c.delete(i)
+	}
+}
+
+func (r *ColumnarRows) RemoveHeader(header string) {
+	delete(r.columns, header)
+}
+
+func (r *ColumnarRows) AddValueToRowWithKeyHeader(value string, header string, key string) {
+	row := r.GetRowFromKeyHeader(key)
+	if row != nil {
+		row.UpdateHeaderValue(header, value)
+	}
+}
+
+func (r *ColumnarRows) GetRowFromKeyHeader(keyHeaderValue string) RowI {
+	i, ok := r.index[keyHeaderValue]
+	if !ok {
+		return nil
+	}
+
+	return &columnarRow{rows: r, key: keyHeaderValue, pos: i}
+}
+
+func (r *ColumnarRows) GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error) {
+	rows := make([]RowI, 0)
+	if len(r.keys) == 0 {
+		return rows, nil
+	}
+
+	if header == r.keyHeader.GetName() {
+		if i, ok := r.index[value]; ok {
+			rows = append(rows, &columnarRow{rows: r, key: value, pos: i})
+		}
+		return rows, nil
+	}
+
+	c, ok := r.columns[header]
+	if !ok {
+		return nil, &Error{Err: ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+	match := c.matcher(value)
+	for i, key := range r.keys {
+		if !c.present[i] {
+			return nil, &Error{Err: ErrHeaderNotExist, Header: header, Key: key, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+		if match(i) {
+			rows = append(rows, &columnarRow{rows: r, key: key, pos: i})
+		}
+	}
+
+	return rows, nil
+}
+
+// Returns the sum of the header's values, leaving out empty values
+// Returns an error if the header doesn't exist or isn't a VALUE_NUMBER header
+// Returns an error if a value isn't a number
+func (r *ColumnarRows) Sum(header string) (float64, error) {
+	sum, _, err := r.sum(header)
+	return sum, err
+}
+
+// Returns the mean of the header's values, leaving out empty values, or 0 if there are none
+// Returns an error if the header doesn't exist or isn't a VALUE_NUMBER header
+// Returns an error if a value isn't a number
+func (r *ColumnarRows) Average(header string) (float64, error) {
+	sum, n, err := r.sum(header)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	return sum / float64(n), nil
+}
+
+// Returns the sum of the header's values and how many were summed
+func (r *ColumnarRows) sum(header string) (float64, int, error) {
+	c, ok := r.columns[header]
+	if !ok {
+		return 0, 0, &Error{Err: ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+	if c.t != VALUE_NUMBER {
+		return 0, 0, &Error{Err: ErrHeaderNotNumber, Header: header, Message: fmt.Sprintf(headerNotNumberError, header)}
+	}
+
+	sum := 0.0
+	n := 0
+	for i, f := range c.numbers {
+		if !c.present[i] {
+			continue
+		}
+		// Only values that aren't numbers are NaN with their text kept
+		if math.IsNaN(f) {
+			if text, ok := c.text[i]; ok {
+				if text == "" {
+					continue
+				}
+				return 0, 0, &Error{Err: ErrNotANumber, Header: header, Key: r.keys[i], Value: text, Message: fmt.Sprintf(notANumberError, text)}
+			}
+		}
+		sum += f
+		n++
+	}
+
+	return sum, n, nil
+}
+
+// Returns the column of the header, adding it with the type given if it doesn't exist
+func (r *ColumnarRows) column(header string, t Type) *column {
+	c, ok := r.columns[header]
+	if !ok {
+		c = &column{t: t, text: map[int]string{}}
+		for range r.keys {
+			c.append()
+		}
+		r.columns[header] = c
+	}
+
+	return c
+}
+
+// Adds a value to the end of the column, missing until it's set
+func (c *column) append() {
+	c.present = append(c.present, false)
+	if c.t == VALUE_NUMBER {
+		c.numbers = append(c.numbers, 0)
+	} else {
+		c.strings = append(c.strings, "")
+	}
+}
+
+func (c *column) set(i int, value string) {
+	c.present[i] = true
+	if c.t != VALUE_NUMBER {
+		c.strings[i] = value
+		return
+	}
+
+	delete(c.text, i)
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		f = math.NaN()
+	}
+	c.numbers[i] = f
+	if err != nil || strconv.FormatFloat(f, 'f', -1, 64) != value {
+		c.text[i] = value
+	}
+}
+
+func (c *column) value(i int) string {
+	if c.t != VALUE_NUMBER {
+		return c.strings[i]
+	}
+	if text, ok := c.text[i]; ok {
+		return text
+	}
+
+	return strconv.FormatFloat(c.numbers[i], 'f', -1, 64)
+}
+
+// Returns a function reporting whether the value at i is the given value, comparing VALUE_NUMBER
+// values as numbers so they aren't formatted
+func (c *column) matcher(value string) func(i int) bool {
+	if c.t != VALUE_NUMBER {
+		return func(i int) bool {
+			return c.strings[i] == value
+		}
+	}
+
+	// A value that doesn't print back the same way can only be one of those kept as text
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || strconv.FormatFloat(f, 'f', -1, 64) != value {
+		return func(i int) bool {
+			text, ok := c.text[i]
+			return ok && text == value
+		}
+	}
+
+	return func(i int) bool {
+		if len(c.text) > 0 {
+			if _, ok := c.text[i]; ok {
+				return false
+			}
+		}
+		// Numbers that print the same way, so 0 isn't -0 but every NaN is NaN
+		g := c.numbers[i]
+		return g == f && math.Signbit(g) == math.Signbit(f) || math.IsNaN(g) && math.IsNaN(f)
+	}
+}
+
+// Removes the value at i, moving the values after it back one
+func (c *column) delete(i int) {
+	c.present = append(c.present[:i], c.present[i+1:]...)
+	if c.t != VALUE_NUMBER {
+		c.strings = append(c.strings[:i], c.strings[i+1:]...)
+		return
+	}
+
+	c.numbers = append(c.numbers[:i], c.numbers[i+1:]...)
+	if len(c.text) == 0 {
+		return
+	}
+	text := make(map[int]string, len(c.text))
+	for j, value := range c.text {
+		switch {
+		case j < i:
+			text[j] = value
+		case j > i:
+			text[j-1] = value
+		}
+	}
+	c.text = text
+}
+
+// Returns the position of the row, false if it's been deleted
+// The position the view was made with is checked first, as it only changes when rows are deleted
+func (row *columnarRow) position() (int, bool) {
+	if row.pos < len(row.rows.keys) && row.rows.keys[row.pos] == row.key {
+		return row.pos, true
+	}
+
+	i, ok := row.rows.index[row.key]
+	if ok {
+		row.pos = i
+	}
+
+	return i, ok
+}
+
+func (row *columnarRow) GetKeyHeaderAndValue() (HeaderI, ValueI) {
+	return row.rows.keyHeader, &Value{row.key}
+}
+
+func (row *columnarRow) GetValueFromHeader(header string) (ValueI, error) {
+	if header == row.rows.keyHeader.GetName() {
+		return &Value{row.key}, nil
+	}
+
+	c, ok := row.rows.columns[header]
+	i, exists := row.position()
+	if !ok || !exists || !c.present[i] {
+		return nil, &Error{Err: ErrHeaderNotExist, Header: header, Key: row.key, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	return &Value{c.value(i)}, nil
+}
+
+// Returns the values of the row read from the columns
+// Changing the map doesn't change the row, which must be changed through the other methods
+func (row *columnarRow) GetRowMap() map[HeaderI]ValueI {
+	values := map[HeaderI]ValueI{}
+	i, ok := row.position()
+	if !ok {
+		return values
+	}
+
+	values[&Header{row.rows.keyHeader.Name, true, row.rows.keyHeader.Type}] = &Value{row.key}
+	for name, c := range row.rows.columns {
+		if c.present[i] {
+			values[&Header{name, false, c.t}] = &Value{c.value(i)}
+		}
+	}
+
+	return values
+}
+
+func (row *columnarRow) HeaderExists(header string) bool {
+	_, err := row.GetValueFromHeader(header)
+	return err == nil
+}
+
+func (row *columnarRow) KeyHeaderValueEqual(value string) bool {
+	return row.key == value
+}
+
+func (row *columnarRow) AddHeaderWithValue(header string, keyHeader bool, t Type, value string) error {
+	if keyHeader {
+		return &Error{Err: ErrKeyHeaderExists, Message: keyHeaderAlreadyExistsError}
+	}
+
+	i, ok := row.position()
+	if !ok {
+		return &Error{Err: ErrRowNotExist, Key: row.key, Message: fmt.Sprintf(rowNotExistError, row.key)}
+	}
+	row.rows.column(header, t).set(i, value)
+
+	return nil
+}
+
+func (row *columnarRow) RemoveHeader(header string) error {
+	if header == row.rows.keyHeader.GetName() {
+		return &Error{Err: ErrDeleteKeyHeader, Header: header, Message: fmt.Sprintf(deleteKeyHeaderError, header)}
+	}
+
+	c, ok := row.rows.columns[header]
+	i, exists := row.position()
+	if ok && exists {
+		c.present[i] = false
+	}
+
+	return nil
+}
+
+func (row *columnarRow) UpdateHeaderValue(header string, value string) {
+	i, ok := row.position()
+	if !ok {
+		return
+	}
+
+	if header == row.rows.keyHeader.GetName() {
+		// The key can only change to one no other row has
+		if _, taken := row.rows.index[value]; !taken {
+			delete(row.rows.index, row.key)
+			row.rows.keys[i] = value
+			row.rows.index[value] = i
+			row.key = value
+		}
+		return
+	}
+
+	if c, ok := row.rows.columns[header]; ok && c.present[i] {
+		c.set(i, value)
+	}
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPointsRow(key string, points string) *Row {
+	return &Row{RowMap: map[HeaderI]ValueI{
+		&Header{"Title", true, VALUE_STRING}:          &Value{key},
+		&Header{"Points Gained", false, VALUE_NUMBER}: &Value{points},
+		&Header{"Platform", false, VALUE_STRING}:      &Value{"PS2"},
+	}}
+}
+
+func TestColumnarRows(t *testing.T) {
+	rows := NewColumnarRows()
+	points := map[string]string{"a": "100", "b": "", "c": "-7", "d": "2.5", "e": "1e3", "f": "007"}
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		assert.Nil(t, rows.AddRow(newPointsRow(key, points[key])))
+	}
+
+	// Every number reads back as it was written however it's kept
+	for key, value := range points {
+		v, err := rows.GetRowFromKeyHeader(key).GetValueFromHeader("Points Gained")
+		assert.Nil(t, err)
+		assert.Equal(t, value, v.GetValue())
+	}
+
+	// Values are matched as written, so 1e3 isn't 1000
+	for value, want := range map[string][]string{"1e3": {"e"}, "1000": {}, "2.5": {"d"}, "": {"b"}, "7": {}} {
+		found, err := rows.GetRowsFromHeaderAndValue("Points Gained", value)
+		assert.Nil(t, err)
+		assert.Equal(t, want, sortedKeys(found), value)
+	}
+
+	// Empty values are left out
+	sum, err := rows.Sum("Points Gained")
+	assert.Nil(t, err)
+	assert.Equal(t, 1102.5, sum)
+	avg, err := rows.Average("Points Gained")
+	assert.Nil(t, err)
+	assert.Equal(t, 220.5, avg)
+
+	_, err = rows.Sum("Platform")
+	assert.True(t, errors.Is(err, ErrHeaderNotNumber))
+	_, err = rows.Sum("Trophies")
+	assert.True(t, errors.Is(err, ErrHeaderNotExist))
+	rows.AddValueToRowWithKeyHeader("lots", "Points Gained", "c")
+	_, err = rows.Average("Points Gained")
+	assert.True(t, errors.Is(err, ErrNotANumber))
+
+	// Deleting keeps the order and the values of the rows after
+	rows.DeleteRowWithValue("c")
+	keys := []string{}
+	for _, row := range rows.GetRows() {
+		keys = append(keys, rowKey(row))
+	}
+	assert.Equal(t, []string{"a", "b", "d", "e", "f"}, keys)
+	for key, value := range points {
+		if key == "c" {
+			continue
+		}
+		v, err := rows.GetRowFromKeyHeader(key).GetValueFromHeader("Points Gained")
+		assert.Nil(t, err)
+		assert.Equal(t, value, v.GetValue())
+	}
+	sum, err = rows.Sum("Points Gained")
+	assert.Nil(t, err)
+	assert.Equal(t, 1109.5, sum)
+
+	// A view follows its row when the key changes
+	row := rows.GetRowFromKeyHeader("a")
+	row.UpdateHeaderValue("Title", "b")
+	assert.True(t, row.KeyHeaderValueEqual("a"))
+	row.UpdateHeaderValue("Title", "z")
+	assert.True(t, row.KeyHeaderValueEqual("z"))
+	assert.Nil(t, rows.GetRowFromKeyHeader("a"))
+	v, err := rows.GetRowFromKeyHeader("z").GetValueFromHeader("Points Gained")
+	assert.Nil(t, err)
+	assert.Equal(t, "100", v.GetValue())
+}
+
+func TestColumnarRowsDB(t *testing.T) {
+	db, err := NewWithBackend("Columnar", []HeaderI{
+		&Header{"Title", true, VALUE_STRING},
+		&Header{"Points Gained", false, VALUE_NUMBER},
+	}, "Title", BACKEND_COLUMNAR)
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.AddRow(&Row{RowMap: map[HeaderI]ValueI{
+		&Header{"Title", true, VALUE_STRING}:          &Value{"a"},
+		&Header{"Points Gained", false, VALUE_NUMBER}: &Value{"10"},
+	}}))
+	db.AddHeader(&Header{"Trophies", false, VALUE_NUMBER})
+	n, err := db.UpdateWhere(func(row RowI) bool { return true }, map[string]string{"Trophies": "3"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "3", getValue(t, db, "a", "Trophies"))
+
+	sum, err := db.Rows.(*ColumnarRows).Sum("Trophies")
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, sum)
+
+	// A view taken before an earlier row is removed finds where its row moved to
+	for _, key := range []string{"b", "c"} {
+		assert.Nil(t, db.AddRow(&Row{RowMap: map[HeaderI]ValueI{
+			&Header{"Title", true, VALUE_STRING}:          &Value{key},
+			&Header{"Points Gained", false, VALUE_NUMBER}: &Value{"5"},
+			&Header{"Trophies", false, VALUE_NUMBER}:      &Value{""},
+		}}))
+	}
+	row := db.Rows.GetRowFromKeyHeader("c")
+	assert.Nil(t, db.RemoveRow("a"))
+	v, err := row.GetValueFromHeader("Points Gained")
+	assert.Nil(t, err)
+	assert.Equal(t, "5", v.GetValue())
+	row.UpdateHeaderValue("Points Gained", "6")
+	assert.Equal(t, "6", getValue(t, db, "c", "Points Gained"))
+	assert.Equal(t, "5", getValue(t, db, "b", "Points Gained"))
+}
+
+// The number of rows the benchmarks read
+const benchmarkRows = 10000
+
+// Returns the rows of each layout the benchmarks compare, holding the same values
+func benchmarkLayouts(b *testing.B) map[string]RowsI {
+	layouts := map[string]RowsI{"map": &Rows{}, "columnar": NewColumnarRows()}
+	for _, rows := range layouts {
+		batch := make([]RowI, benchmarkRows)
+		for i := range batch {
+			batch[i] = newPointsRow(fmt.Sprint("game ", i), fmt.Sprint(i%1000))
+		}
+		if err := rows.AddRows(batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return layouts
+}
+
+// Reads one header of every row through RowI
+func BenchmarkScan(b *testing.B) {
+	for name, rows := range benchmarkLayouts(b) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, row := range rows.GetRows() {
+					if _, err := row.GetValueFromHeader("Points Gained"); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Finds the rows with a value through GetRowsFromHeaderAndValue
+func BenchmarkFilter(b *testing.B) {
+	for name, rows := range benchmarkLayouts(b) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				found, err := rows.GetRowsFromHeaderAndValue("Points Gained", "500")
+				if err != nil || len(found) != benchmarkRows/1000 {
+					b.Fatal(err, len(found))
+				}
+			}
+		})
+	}
+}
+
+// Sums one header across every row, through RowI for the map layout and through the column for
+// the columnar layout
+func BenchmarkSum(b *testing.B) {
+	layouts := benchmarkLayouts(b)
+	want := float64(benchmarkRows/1000) * 999 * 1000 / 2
+
+	b.Run("map", func(b *testing.B) {
+		rows := layouts["map"]
+		for i := 0; i < b.N; i++ {
+			sum := 0.0
+			for _, row := range rows.GetRows() {
+				v, err := row.GetValueFromHeader("Points Gained")
+				if err != nil {
+					b.Fatal(err)
+				}
+				f, err := strconv.ParseFloat(v.GetValue(), 64)
+				if err != nil {
+					b.Fatal(err)
+				}
+				sum += f
+			}
+			if sum != want {
+				b.Fatal(sum)
+			}
+		}
+	})
+
+	b.Run("columnar", func(b *testing.B) {
+		rows := layouts["columnar"].(*ColumnarRows)
+		for i := 0; i < b.N; i++ {
+			sum, err := rows.Sum("Points Gained")
+			if err != nil || sum != want {
+				b.Fatal(err, sum)
+			}
+		}
+	})
+}
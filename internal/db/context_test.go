@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ DBContext = &DBImpl{}
+	_ DBContext = &actorDB{}
+	_ DBContext = &readOnlyDB{}
+)
+
+func cancelledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return ctx
+}
+
+func TestReadContext(t *testing.T) {
+	db := newNumberDB(t)
+	ctx := context.Background()
+
+	rows, err := db.GetRowsFromHeaderAndValueContext(ctx, "Points", "50")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rows))
+
+	rows, err = db.GetRowsFromHeaderAndValueNumberOperationContext(ctx, "Points", "60", ">")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rows))
+
+	_, err = db.GetRowsFromHeaderAndValueContext(ctx, "Missing", "50")
+	assert.Error(t, err)
+
+	ctx = cancelledContext()
+	_, err = db.GetRowsFromHeaderAndValueContext(ctx, "Points", "50")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.GetRowsFromHeaderAndValueNumberOperationContext(ctx, "Points", "60", ">")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.GetRowsContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.GetRowFromKeyHeaderContext(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.GetHeadersStringContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.HistoryContext(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.AsOfContext(ctx, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestScanCancelledMidway(t *testing.T) {
+	db := newNumberDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancels the scan once the predicate has seen the first row
+	n, err := db.UpdateWhereContext(ctx, func(row RowI) bool {
+		cancel()
+		return true
+	}, map[string]string{"Points": "0"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, n)
+
+	rows, _ := db.GetRowsFromHeaderAndValue("Points", "0")
+	assert.Equal(t, 0, len(rows))
+}
+
+func TestWriteContext(t *testing.T) {
+	db := newNumberDB(t)
+	events, unsubscribe := db.Subscribe(EventFilter{})
+	defer unsubscribe()
+
+	ctx := cancelledContext()
+	assert.ErrorIs(t, db.AddHeaderContext(ctx, &Header{"New", false, VALUE_STRING}), context.Canceled)
+	assert.ErrorIs(t, db.RemoveHeaderContext(ctx, "Points"), context.Canceled)
+	assert.ErrorIs(t, db.RemoveRowContext(ctx, "a"), context.Canceled)
+	assert.ErrorIs(t, db.AddValueToHeaderContext(ctx, "1", "Points", "a"), context.Canceled)
+	_, err := db.IncrementContext(ctx, "a", "Points", 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.DeleteWhereContext(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.UpdateExpressionContext(ctx, nil, "Points + 1")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	row, err := NewRowFromMap(db, map[string]string{"Title": "c", "Trophies": "1", "Points": "1"})
+	assert.Nil(t, err)
+	errs, _ := db.AddRowsContext(ctx, []RowI{row}, AddRowsOptions{})
+	assert.Equal(t, 1, len(errs))
+	assert.ErrorIs(t, errs[0], context.Canceled)
+
+	assert.Equal(t, 2, len(db.GetRows()))
+	assert.Equal(t, 3, len(db.GetHeaders()))
+	assert.Equal(t, 0, len(events))
+}
+
+func TestContextActor(t *testing.T) {
+	db := newNumberDB(t)
+	events, unsubscribe := db.Subscribe(EventFilter{})
+	defer unsubscribe()
+
+	ctx := WithActor(context.Background(), "alice")
+	assert.Nil(t, db.AddValueToHeaderContext(ctx, "1", "Points", "a"))
+	assert.Equal(t, "alice", (<-events).Actor)
+
+	// The actor given to As takes the place of the one set on ctx
+	view := db.As("bob").(DBContext)
+	assert.Nil(t, view.AddValueToHeaderContext(ctx, "2", "Points", "a"))
+	assert.Equal(t, "bob", (<-events).Actor)
+}
+
+func TestSubscribeContext(t *testing.T) {
+	db := newNumberDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := db.SubscribeContext(ctx, EventFilter{})
+
+	assert.Nil(t, db.RemoveRow("a"))
+	assert.Equal(t, EVENT_REMOVE_ROW, (<-events).Type)
+
+	cancel()
+	select {
+	case _, open := <-events:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("events not closed after the context was cancelled")
+	}
+}
+
+func TestReadOnlyContext(t *testing.T) {
+	fakeClock(t)
+	db := newNumberDB(t)
+	past, err := db.AsOf(now())
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	view := past.(DBContext)
+	assert.Error(t, view.RemoveRowContext(ctx, "a"))
+	assert.Error(t, view.AddHeaderContext(ctx, &Header{"New", false, VALUE_STRING}))
+	assert.Error(t, view.SetRetentionContext(ctx, Retention{}))
+	rows, err := view.GetRowsContext(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows))
+}
@@ -1,25 +1,30 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 )
 
 func New(name string, headers []HeaderI, keyHeader string) (*DBImpl, error) {
 	if keyHeader == "" {
-		return &DBImpl{}, errors.New("key header must exist and not be empty")
+		return &DBImpl{}, &Error{Err: ErrKeyHeaderEmpty, DB: name, Message: keyHeaderMissingError}
 	}
 
-	db := &DBImpl{name, keyHeader, map[HeaderI]struct{}{}, &Rows{}}
+	db := &DBImpl{Name: name, KeyHeader: keyHeader, Headers: map[HeaderI]struct{}{}, Rows: &Rows{}}
 	for _, header := range headers {
 		db.AddHeader(header)
 	}
 
 	if len(headers) == 0 || !db.headerExists(keyHeader) {
-		return &DBImpl{}, errors.New(fmt.Sprintf(keyHeaderEmptyError, keyHeader))
+		return &DBImpl{}, &Error{Err: ErrKeyHeaderEmpty, DB: name, Header: keyHeader, Message: fmt.Sprintf(keyHeaderEmptyError, keyHeader)}
 	}
 
+	// Adding the headers is part of creating the DB, not a change to undo
+	db.journal.clear()
+
 	return db, nil
 }
 
@@ -28,6 +33,21 @@ func (db *DBImpl) GetName() string {
 }
 
 func (db *DBImpl) GetHeader(header string) HeaderI {
+	h, _ := db.GetHeaderContext(context.Background(), header)
+	return h
+}
+
+func (db *DBImpl) GetHeaderContext(ctx context.Context, header string) (HeaderI, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return db.getHeader(header), nil
+}
+
+func (db *DBImpl) getHeader(header string) HeaderI {
 	for h := range db.Headers {
 		if h.GetName() == header {
 			return h
@@ -38,15 +58,37 @@ func (db *DBImpl) GetHeader(header string) HeaderI {
 }
 
 func (db *DBImpl) GetHeaders() []HeaderI {
+	headers, _ := db.GetHeadersContext(context.Background())
+	return headers
+}
+
+func (db *DBImpl) GetHeadersContext(ctx context.Context) ([]HeaderI, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	headers := []HeaderI{}
 	for h := range db.Headers {
 		headers = append(headers, h)
 	}
 
-	return headers
+	return headers, nil
 }
 
 func (db *DBImpl) GetHeadersString() []string {
+	headers, _ := db.GetHeadersStringContext(context.Background())
+	return headers
+}
+
+func (db *DBImpl) GetHeadersStringContext(ctx context.Context) ([]string, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	headersString := []string{}
 	for h := range db.Headers {
 		if h.IsKeyHeader() {
@@ -56,7 +98,7 @@ func (db *DBImpl) GetHeadersString() []string {
 		}
 	}
 
-	return headersString
+	return headersString, nil
 }
 
 func (db *DBImpl) GetKeyHeader() string {
@@ -64,6 +106,21 @@ func (db *DBImpl) GetKeyHeader() string {
 }
 
 func (db *DBImpl) AddHeader(header HeaderI) {
+	db.AddHeaderContext(context.Background(), header)
+}
+
+func (db *DBImpl) AddHeaderContext(ctx context.Context, header HeaderI) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db.addHeader(header)
+	return nil
+}
+
+func (db *DBImpl) addHeader(header HeaderI) {
 	// Don't need to add if it already exists
 	if !db.headerExists(header.GetName()) {
 		db.Headers[header] = struct{}{}
@@ -72,13 +129,43 @@ func (db *DBImpl) AddHeader(header HeaderI) {
 		for _, row := range db.Rows.GetRows() {
 			row.AddHeaderWithValue(header.GetName(), header.IsKeyHeader(), header.GetType(), "")
 		}
+
+		db.publish(Event{Type: EVENT_ADD_HEADER, Header: header.GetName(), HeaderType: header.GetType()})
 	}
 }
 
 func (db *DBImpl) RemoveHeader(header string) error {
+	return db.RemoveHeaderContext(context.Background(), header)
+}
+
+func (db *DBImpl) RemoveHeaderContext(ctx context.Context, header string) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return db.removeHeader(header)
+}
+
+func (db *DBImpl) removeHeader(header string) error {
 	if header == db.KeyHeader {
-		return errors.New(fmt.Sprintf(headerNotExistError, header))
+		return &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	if !db.headerExists(header) {
+		return nil
+	}
+
+	// Keep the values being removed so the change can be reversed
+	values := map[string]string{}
+	for _, row := range db.Rows.GetRows() {
+		v, err := row.GetValueFromHeader(header)
+		if err == nil {
+			values[rowKey(row)] = v.GetValue()
+		}
 	}
+	t := db.getHeader(header).GetType()
 
 	newHeaders := make(map[HeaderI]struct{}, 0)
 	for h := range db.Headers {
@@ -88,54 +175,540 @@ func (db *DBImpl) RemoveHeader(header string) error {
 	}
 	db.Headers = newHeaders
 	db.Rows.RemoveHeader(header)
+	db.publish(Event{Type: EVENT_REMOVE_HEADER, Header: header, HeaderType: t, Values: values})
 
 	return nil
 }
 
 func (db *DBImpl) AddRow(row RowI) error {
-	// Make sure the row's key header is correct
-	h, v := row.GetKeyHeaderAndValue()
-	if h.GetName() != db.GetKeyHeader() {
-		return errors.New(fmt.Sprintf(keyHeaderIncorrect, h.GetName(), db.GetKeyHeader()))
+	return db.AddRowContext(context.Background(), row)
+}
+
+func (db *DBImpl) AddRowContext(ctx context.Context, row RowI) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	// Make sure the key header's value is not empty
-	if v.GetValue() == "" {
-		return errors.New(fmt.Sprintf(keyHeaderEmptyError, h.GetName()))
+	return db.addRow(row)
+}
+
+func (db *DBImpl) addRow(row RowI) error {
+	err := db.verifyKeyHeader(row)
+	if err != nil {
+		return err
 	}
 
 	// Verify that there are no extra headers in the row
-	err := db.verifyHeaders(row)
+	err = db.verifyHeaders(row)
+	if err != nil {
+		return err
+	}
+
+	row, err = db.beforeInsert(row)
 	if err != nil {
 		return err
 	}
 
-	err = db.Rows.AddRow(row)
+	err = db.insertRow(row)
 	if err != nil {
 		return err
 	}
 
+	return db.afterChange(HOOK_AFTER_INSERT, &Change{}, row)
+}
+
+// Adds the row to the DB, publishing it
+func (db *DBImpl) insertRow(row RowI) error {
+	err := db.Rows.AddRow(row)
+	if err != nil {
+		return db.annotate(err)
+	}
+	db.publish(Event{Type: EVENT_ADD_ROW, Key: rowKey(row), Row: rowValues(row)})
+
 	return nil
 }
 
+func (db *DBImpl) AddRows(rows []RowI, opts AddRowsOptions) ([]error, error) {
+	return db.AddRowsContext(context.Background(), rows, opts)
+}
+
+func (db *DBImpl) AddRowsContext(ctx context.Context, rows []RowI, opts AddRowsOptions) ([]error, error) {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return failAll(rows, err), nil
+	}
+	defer unlock()
+
+	return db.addRows(ctx, rows, opts)
+}
+
+// Returns the errors of the rows that weren't added, and the first error from the AfterInsert
+// hooks of those that were
+// Duplicate KeyHeader values are found by the one pass here, before any hook runs, so the rows
+// are then added without the backend checking them again
+func (db *DBImpl) addRows(ctx context.Context, rows []RowI, opts AddRowsOptions) ([]error, error) {
+	errs := make([]error, len(rows))
+	failed := false
+
+	// Collect the existing key values once so each row only needs a map lookup
+	keys := map[string]struct{}{}
+	for _, row := range db.Rows.GetRows() {
+		_, v := row.GetKeyHeaderAndValue()
+		keys[v.GetValue()] = struct{}{}
+	}
+
+	valid := make([]int, 0, len(rows))
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return failAll(rows, err), nil
+		}
+
+		err := db.verifyKeyHeader(row)
+		if err == nil {
+			err = db.verifyHeaders(row)
+		}
+		if err == nil {
+			err = db.verifyKeyUnique(row, keys)
+		}
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if failed && opts.AllOrNothing {
+		return rejectBatch(errs), nil
+	}
+
+	// The hooks only run for rows that can be added, but may change their key
+	added := make([]RowI, 0, len(valid))
+	for _, i := range valid {
+		row, err := db.beforeInsert(rows[i])
+		if err == nil && rowKey(row) != rowKey(rows[i]) {
+			delete(keys, rowKey(rows[i]))
+			err = db.verifyKeyUnique(row, keys)
+		}
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+		added = append(added, row)
+	}
+
+	if failed && opts.AllOrNothing {
+		return rejectBatch(errs), nil
+	}
+
+	var err error
+	if checked, ok := db.Rows.(checkedRowsI); ok {
+		err = checked.addCheckedRows(added)
+	} else {
+		err = db.Rows.AddRows(added)
+	}
+	if err != nil {
+		err = db.annotate(err)
+		// Every remaining row has now failed
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+		return errs, nil
+	}
+
+	for _, row := range added {
+		db.publish(Event{Type: EVENT_ADD_ROW, Key: rowKey(row), Row: rowValues(row)})
+	}
+
+	// The rows are in the DB now, so a failed hook isn't an error for its row
+	var afterErr error
+	for _, row := range added {
+		err := db.afterChange(HOOK_AFTER_INSERT, &Change{}, row)
+		if err != nil && afterErr == nil {
+			afterErr = err
+		}
+	}
+
+	if failed {
+		return errs, afterErr
+	}
+
+	return nil, afterErr
+}
+
+// Sets an ErrBatchRejected error on every row without an error of its own, caused by the first
+// row that has one
+func rejectBatch(errs []error) []error {
+	_, first := FirstRowError(errs)
+	for i := range errs {
+		if errs[i] == nil {
+			errs[i] = &Error{Err: ErrBatchRejected, Message: fmt.Sprintf(batchRejectedError, first), Cause: first}
+		}
+	}
+
+	return errs
+}
+
+// Returns the index and error of the first row AddRows failed to add for a reason of its own,
+// rather than because another row in the batch failed
+// Returns -1 and nil if every row was added
+func FirstRowError(errs []error) (int, error) {
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, ErrBatchRejected) {
+			return i, err
+		}
+	}
+
+	return -1, nil
+}
+
+func (db *DBImpl) Upsert(row RowI) error {
+	return db.UpsertContext(context.Background(), row)
+}
+
+func (db *DBImpl) UpsertContext(ctx context.Context, row RowI) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return db.upsert(row)
+}
+
+func (db *DBImpl) upsert(row RowI) error {
+	err := db.verifyKeyHeader(row)
+	if err != nil {
+		return err
+	}
+
+	_, v := row.GetKeyHeaderAndValue()
+	existing := db.Rows.GetRowFromKeyHeader(v.GetValue())
+	if existing == nil {
+		return db.addRow(row)
+	}
+
+	err = db.verifyHeadersExist(row)
+	if err != nil {
+		return err
+	}
+
+	// Only the headers given in the row are merged, the rest keep their current values
+	values := map[string]string{}
+	for h, val := range row.GetRowMap() {
+		if h.IsKeyHeader() {
+			continue
+		}
+		values[h.GetName()] = val.GetValue()
+	}
+
+	return db.updateRow(existing, values)
+}
+
 func (db *DBImpl) RemoveRow(keyValue string) error {
+	return db.RemoveRowContext(context.Background(), keyValue)
+}
+
+func (db *DBImpl) RemoveRowContext(ctx context.Context, keyValue string) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return db.removeRowWithKey(keyValue)
+}
+
+func (db *DBImpl) removeRowWithKey(keyValue string) error {
 	if keyValue == "" {
-		return errors.New(keyValueEmptyError)
+		return &Error{Err: ErrKeyValueEmpty, DB: db.Name, Message: keyValueEmptyError}
+	}
+
+	row := db.Rows.GetRowFromKeyHeader(keyValue)
+	if row == nil {
+		return nil
+	}
+
+	change, err := db.beforeDelete(row)
+	if err != nil {
+		return err
+	}
+	db.removeRow(row)
+
+	return db.afterChange(HOOK_AFTER_DELETE, change, nil)
+}
+
+func (db *DBImpl) UpdateWhere(predicate Predicate, values map[string]string) (int, error) {
+	return db.UpdateWhereContext(context.Background(), predicate, values)
+}
+
+func (db *DBImpl) UpdateWhereContext(ctx context.Context, predicate Predicate, values map[string]string) (int, error) {
+	rows, unlock, err := db.lockWhere(ctx, predicate)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return db.updateWhere(ctx, rows, values)
+}
+
+// Sets the values of the rows, which must have been selected while the DB was locked
+func (db *DBImpl) updateWhere(ctx context.Context, rows []RowI, values map[string]string) (int, error) {
+	// Validate every value before any row is touched
+	for header, value := range values {
+		if !db.headerExists(header) {
+			return 0, &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		if header == db.KeyHeader {
+			return 0, &Error{Err: ErrUpdateKeyHeader, DB: db.Name, Header: header, Message: fmt.Sprintf(updateKeyHeaderError, header)}
+		}
+
+		err := ValidateValue(db.getHeader(header), value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Run the BeforeUpdate hooks for every row first so a rejected change updates none of the rows
+	// Changes the hooks made through their HookDB before one rejected it are kept
+	changes := make([]*Change, len(rows))
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		change, err := db.beforeUpdate(row, values)
+		if err != nil {
+			return 0, err
+		}
+		changes[i] = change
+	}
+
+	return len(rows), db.updateRows(rows, changes)
+}
+
+// Returns the rows the predicate selects with the DB locked for writing, along with the function
+// that unlocks it
+// The predicate is run on copies of the rows before the DB is locked, so it can call the DB's
+// methods, and is run again if the DB changed before it could be locked
+// Returns ctx's error if it is done, or an error if the DB is read-only, in which case it isn't locked
+func (db *DBImpl) lockWhere(ctx context.Context, predicate Predicate) ([]RowI, func(), error) {
+	for {
+		runlock, err := db.rlockContext(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		seq := db.feed.last()
+		copies := []RowI{}
+		if predicate != nil {
+			for _, row := range db.Rows.GetRows() {
+				copies = append(copies, detachRow(row))
+			}
+		}
+		runlock()
+
+		keys := map[string]struct{}{}
+		for _, row := range copies {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+
+			if predicate(row) {
+				keys[rowKey(row)] = struct{}{}
+			}
+		}
+
+		unlock, err := db.lockContext(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if db.feed.last() != seq {
+			unlock()
+			continue
+		}
+
+		rows := []RowI{}
+		for _, row := range db.Rows.GetRows() {
+			if _, ok := keys[rowKey(row)]; ok || predicate == nil {
+				rows = append(rows, row)
+			}
+		}
+
+		return rows, unlock, nil
+	}
+}
+
+// Returns the rows the predicate selects, which is run on the rows themselves, as the DB is
+// already locked
+func (db *DBImpl) where(ctx context.Context, predicate Predicate) ([]RowI, error) {
+	rows := []RowI{}
+	for _, row := range db.Rows.GetRows() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if predicate == nil || predicate(row) {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// Sets the values of the row as one change, running the update hooks around it
+func (db *DBImpl) updateRow(row RowI, values map[string]string) error {
+	change, err := db.beforeUpdate(row, values)
+	if err != nil {
+		return err
+	}
+
+	return db.updateRows([]RowI{row}, []*Change{change})
+}
+
+// Makes the changes returned by beforeUpdate to the rows, then runs the AfterUpdate hooks,
+// stopping at the first error
+func (db *DBImpl) updateRows(rows []RowI, changes []*Change) error {
+	for i, row := range rows {
+		db.applyUpdate(row, changes[i])
+	}
+
+	for i, row := range rows {
+		err := db.afterChange(HOOK_AFTER_UPDATE, changes[i], row)
+		if err != nil {
+			return err
+		}
 	}
 
-	db.Rows.DeleteRowWithValue(keyValue)
 	return nil
 }
 
-func (db *DBImpl) verifyHeaders(row RowI) error {
-	// Verify that no extra headers exist in row, and if any are missing add them
-	// as new empty values
+func (db *DBImpl) DeleteWhere(predicate Predicate) (int, error) {
+	return db.DeleteWhereContext(context.Background(), predicate)
+}
+
+func (db *DBImpl) DeleteWhereContext(ctx context.Context, predicate Predicate) (int, error) {
+	rows, unlock, err := db.lockWhere(ctx, predicate)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return db.deleteWhere(ctx, rows)
+}
+
+// Removes the rows, which must have been selected while the DB was locked
+func (db *DBImpl) deleteWhere(ctx context.Context, rows []RowI) (int, error) {
+	// Run the BeforeDelete hooks for every row first so a rejected removal removes none of the rows
+	// Changes the hooks made through their HookDB before one rejected it are kept
+	changes := make([]*Change, len(rows))
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		change, err := db.beforeDelete(row)
+		if err != nil {
+			return 0, err
+		}
+		changes[i] = change
+	}
+
+	for _, row := range rows {
+		db.removeRow(row)
+	}
+
+	for _, change := range changes {
+		err := db.afterChange(HOOK_AFTER_DELETE, change, nil)
+		if err != nil {
+			return len(rows), err
+		}
+	}
+
+	return len(rows), nil
+}
+
+// Returns an error if the value doesn't match the given header's type
+// Empty values are always allowed
+func ValidateValue(header HeaderI, value string) error {
+	if value == "" || !header.IsNumber() {
+		return nil
+	}
+
+	_, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return &Error{Err: ErrNotANumber, Value: value, Message: fmt.Sprintf(notANumberError, value)}
+	}
+
+	return nil
+}
+
+// Returns the number in its shortest form, such as 1 for +1, 007 or 1e0, which every export
+// writes numbers in
+// Returns false if the value isn't a finite number, as NaN and Inf have no number form in JSON,
+// SQL or XLSX
+func FormatNumber(value string) (string, bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", false
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64), true
+}
+
+// Checks the row's KeyHeader value isn't one of keys, adding it if not
+func (db *DBImpl) verifyKeyUnique(row RowI, keys map[string]struct{}) error {
+	h, v := row.GetKeyHeaderAndValue()
+	if _, exists := keys[v.GetValue()]; exists {
+		return &Error{Err: ErrDuplicateKey, DB: db.Name, Header: h.GetName(), Key: v.GetValue(), Message: fmt.Sprintf(keyHeaderValueExistsError, h.GetName(), v.GetValue())}
+	}
+	keys[v.GetValue()] = struct{}{}
+
+	return nil
+}
+
+func (db *DBImpl) verifyKeyHeader(row RowI) error {
+	// Make sure the row's key header is correct
+	h, v := row.GetKeyHeaderAndValue()
+	if h == nil {
+		return &Error{Err: ErrKeyHeaderEmpty, DB: db.Name, Header: db.KeyHeader, Message: fmt.Sprintf(keyHeaderEmptyError, db.KeyHeader)}
+	}
+
+	if h.GetName() != db.KeyHeader {
+		return &Error{Err: ErrKeyHeaderIncorrect, DB: db.Name, Header: h.GetName(), Message: fmt.Sprintf(keyHeaderIncorrect, h.GetName(), db.KeyHeader)}
+	}
+
+	// Make sure the key header's value is not empty
+	if v.GetValue() == "" {
+		return &Error{Err: ErrKeyHeaderEmpty, DB: db.Name, Header: h.GetName(), Message: fmt.Sprintf(keyHeaderEmptyError, h.GetName())}
+	}
+
+	return nil
+}
+
+func (db *DBImpl) verifyHeadersExist(row RowI) error {
 	for h := range row.GetRowMap() {
 		if !db.headerExists(h.GetName()) {
-			return errors.New(fmt.Sprintf(headerNotExistError, h.GetName()))
+			return &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: h.GetName(), Message: fmt.Sprintf(headerNotExistError, h.GetName())}
 		}
 	}
 
+	return nil
+}
+
+func (db *DBImpl) verifyHeaders(row RowI) error {
+	// Verify that no extra headers exist in row, and if any are missing add them
+	// as new empty values
+	err := db.verifyHeadersExist(row)
+	if err != nil {
+		return err
+	}
+
 	// Add any headers to row that should exist
 	for h := range db.Headers {
 		if !row.HeaderExists(h.GetName()) {
@@ -148,18 +721,46 @@ func (db *DBImpl) verifyHeaders(row RowI) error {
 }
 
 func (db *DBImpl) GetRows() []RowI {
-	return db.Rows.GetRows()
+	rows, _ := db.GetRowsContext(context.Background())
+	return rows
+}
+
+func (db *DBImpl) GetRowsContext(ctx context.Context) ([]RowI, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return detachRows(db.Rows.GetRows()), nil
 }
 
 // Adds a value to a given header for a row with KeyHeader == key
 func (db *DBImpl) AddValueToHeader(value string, header string, key string) error {
+	return db.AddValueToHeaderContext(context.Background(), value, header, key)
+}
+
+func (db *DBImpl) AddValueToHeaderContext(ctx context.Context, value string, header string, key string) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return db.addValueToHeader(value, header, key)
+}
+
+func (db *DBImpl) addValueToHeader(value string, header string, key string) error {
 	if !db.headerExists(header) {
-		return errors.New(fmt.Sprintf(headerNotExistError, header))
+		return &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
 	}
 
-	db.Rows.AddValueToRowWithKeyHeader(value, header, key)
+	row := db.Rows.GetRowFromKeyHeader(key)
+	if row == nil {
+		return nil
+	}
 
-	return nil
+	return db.updateRow(row, map[string]string{header: value})
 }
 
 func (db *DBImpl) headerExists(header string) bool {
@@ -172,35 +773,86 @@ func (db *DBImpl) headerExists(header string) bool {
 }
 
 func (db *DBImpl) GetRowFromKeyHeader(value string) RowI {
-	return db.Rows.GetRowFromKeyHeader(value)
+	row, _ := db.GetRowFromKeyHeaderContext(context.Background(), value)
+	return row
 }
 
-func (db *DBImpl) GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error) {
-	if !db.headerExists(header) {
-		return nil, errors.New(fmt.Sprintf(headerNotExistError, header))
+func (db *DBImpl) GetRowFromKeyHeaderContext(ctx context.Context, value string) (RowI, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	row := db.Rows.GetRowFromKeyHeader(value)
+	if row == nil {
+		return nil, nil
 	}
 
-	rows, err := db.Rows.GetRowsFromHeaderAndValue(header, value)
+	return detachRow(row), nil
+}
+
+func (db *DBImpl) GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error) {
+	return db.GetRowsFromHeaderAndValueContext(context.Background(), header, value)
+}
+
+func (db *DBImpl) GetRowsFromHeaderAndValueContext(ctx context.Context, header string, value string) ([]RowI, error) {
+	unlock, err := db.rlockContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
+
+	if !db.headerExists(header) {
+		return nil, &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	rows := make([]RowI, 0)
+	for _, row := range db.Rows.GetRows() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		v, err := row.GetValueFromHeader(header)
+		if err != nil {
+			return nil, err
+		}
+
+		if v.GetValue() == value {
+			rows = append(rows, detachRow(row))
+		}
+	}
 
 	return rows, nil
 }
 
 func (db *DBImpl) GetRowsFromHeaderAndValueNumberOperation(header string, value string, op string) ([]RowI, error) {
+	return db.GetRowsFromHeaderAndValueNumberOperationContext(context.Background(), header, value, op)
+}
+
+func (db *DBImpl) GetRowsFromHeaderAndValueNumberOperationContext(ctx context.Context, header string, value string, op string) ([]RowI, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	valueF, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf(notANumberError, value))
+		return nil, &Error{Err: ErrNotANumber, DB: db.Name, Value: value, Message: fmt.Sprintf(notANumberError, value)}
 	}
 
 	if !db.headerExists(header) {
-		return nil, errors.New(fmt.Sprintf(headerNotExistError, header))
+		return nil, &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
 	}
 
-	h := db.GetHeader(header)
+	h := db.getHeader(header)
 	rows := make([]RowI, 0)
-	for _, row := range db.GetRows() {
+	for _, row := range db.Rows.GetRows() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Don't need to check error, header definitely exists
 		v, _ := row.GetValueFromHeader(header)
 		vF, err := h.Number(v)
@@ -208,14 +860,195 @@ func (db *DBImpl) GetRowsFromHeaderAndValueNumberOperation(header string, value
 			return nil, err
 		}
 
-		if op == "<" && vF < valueF {
-			rows = append(rows, row)
+		if op == "<" && vF < valueF || op == ">" && vF > valueF {
+			rows = append(rows, detachRow(row))
 		}
+	}
 
-		if op == ">" && vF > valueF {
-			rows = append(rows, row)
+	return rows, nil
+}
+
+func (db *DBImpl) SetEmptyAsZero(emptyAsZero bool) {
+	db.SetEmptyAsZeroContext(context.Background(), emptyAsZero)
+}
+
+func (db *DBImpl) SetEmptyAsZeroContext(ctx context.Context, emptyAsZero bool) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db.emptyAsZero = emptyAsZero
+	return nil
+}
+
+func (db *DBImpl) Increment(key string, header string, delta float64) (float64, error) {
+	return db.IncrementContext(context.Background(), key, header, delta)
+}
+
+func (db *DBImpl) IncrementContext(ctx context.Context, key string, header string, delta float64) (float64, error) {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return db.increment(key, header, delta)
+}
+
+func (db *DBImpl) increment(key string, header string, delta float64) (float64, error) {
+	err := db.verifyNumberHeader(header)
+	if err != nil {
+		return 0, err
+	}
+
+	row := db.Rows.GetRowFromKeyHeader(key)
+	if row == nil {
+		return 0, &Error{Err: ErrRowNotExist, DB: db.Name, Key: key, Message: fmt.Sprintf(rowNotExistError, key)}
+	}
+
+	current, err := db.rowNumber(row, header)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.updateRow(row, map[string]string{header: formatNumber(current + delta)})
+	if err != nil {
+		return 0, err
+	}
+
+	// Read the result back as hooks may have changed it
+	return db.rowNumber(row, header)
+}
+
+func (db *DBImpl) Decrement(key string, header string, delta float64) (float64, error) {
+	return db.Increment(key, header, -delta)
+}
+
+func (db *DBImpl) DecrementContext(ctx context.Context, key string, header string, delta float64) (float64, error) {
+	return db.IncrementContext(ctx, key, header, -delta)
+}
+
+func (db *DBImpl) UpdateExpression(predicate Predicate, expr string) (int, error) {
+	return db.UpdateExpressionContext(context.Background(), predicate, expr)
+}
+
+func (db *DBImpl) UpdateExpressionContext(ctx context.Context, predicate Predicate, expr string) (int, error) {
+	rows, unlock, err := db.lockWhere(ctx, predicate)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return db.updateExpression(ctx, rows, expr)
+}
+
+// Sets the expression's target in the rows, which must have been selected while the DB was locked
+func (db *DBImpl) updateExpression(ctx context.Context, rows []RowI, expr string) (int, error) {
+	e, err := parseExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, header := range append([]string{e.target}, e.headers()...) {
+		err = db.verifyNumberHeader(header)
+		if err != nil {
+			return 0, err
 		}
 	}
 
-	return rows, nil
+	// Evaluate every row before writing so a failure leaves the DB untouched
+	results := make([]float64, len(rows))
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		result, err := e.evaluate(func(header string) (float64, error) {
+			return db.rowNumber(row, header)
+		})
+		if err == errDivideByZero {
+			_, v := row.GetKeyHeaderAndValue()
+			return 0, &Error{Err: ErrDivideByZero, DB: db.Name, Key: v.GetValue(), Message: fmt.Sprintf(divideByZeroError, v.GetValue())}
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		results[i] = result
+	}
+
+	changes := make([]*Change, len(rows))
+	for i, row := range rows {
+		changes[i], err = db.beforeUpdate(row, map[string]string{e.target: formatNumber(results[i])})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rows), db.updateRows(rows, changes)
+}
+
+// Returns an error if the header doesn't exist or isn't a VALUE_NUMBER header
+func (db *DBImpl) verifyNumberHeader(header string) error {
+	if !db.headerExists(header) {
+		return &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	if !db.getHeader(header).IsNumber() {
+		return &Error{Err: ErrHeaderNotNumber, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotNumberError, header)}
+	}
+
+	return nil
+}
+
+// Returns the number stored in the row for the given header, treating empty values as zero
+// if the DB has been configured to
+func (db *DBImpl) rowNumber(row RowI, header string) (float64, error) {
+	v, err := row.GetValueFromHeader(header)
+	if err != nil {
+		return 0, err
+	}
+
+	if v.GetValue() == "" && db.emptyAsZero {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseFloat(v.GetValue(), 64)
+	if err != nil {
+		return 0, &Error{Err: ErrNotANumber, DB: db.Name, Value: v.GetValue(), Message: fmt.Sprintf(notANumberError, v.GetValue())}
+	}
+
+	return n, nil
+}
+
+// Sets the value of the header in the row, publishing the change if the value is different
+func (db *DBImpl) setValue(row RowI, header string, value string) {
+	v, err := row.GetValueFromHeader(header)
+	if err == nil && v.GetValue() == value {
+		return
+	}
+
+	old := ""
+	if err == nil {
+		old = v.GetValue()
+	}
+
+	row.UpdateHeaderValue(header, value)
+	db.publish(Event{Type: EVENT_UPDATE_VALUE, Key: rowKey(row), Header: header, OldValue: old, NewValue: value})
+}
+
+// Removes the row from the DB, publishing its values
+// The values are read first, as a row may be a view that can't be read once its row is deleted
+func (db *DBImpl) removeRow(row RowI) {
+	key := rowKey(row)
+	values := rowValues(row)
+	db.Rows.DeleteRowWithValue(key)
+	db.notifyRemoved(key)
+	db.publish(Event{Type: EVENT_REMOVE_ROW, Key: key, Row: values})
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
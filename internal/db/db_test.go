@@ -2,6 +2,7 @@ package db
 
 import (
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -119,7 +120,7 @@ func TestGetHeadersString(t *testing.T) {
 
 func TestAddHeader(t *testing.T) {
 	rows := []RowI{&Row{map[HeaderI]ValueI{}}}
-	db := &DBImpl{"test", "Test", map[HeaderI]struct{}{}, &Rows{rows}}
+	db := &DBImpl{Name: "test", KeyHeader: "Test", Headers: map[HeaderI]struct{}{}, Rows: &Rows{rows}}
 	h := &Header{"Test", true, VALUE_STRING}
 	hMap := map[HeaderI]struct{}{h: struct{}{}}
 	db.AddHeader(h)
@@ -423,3 +424,377 @@ func TestGetRowsFromHeaderAndValueNumberOperation(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 3, len(rows))
 }
+
+func TestAddRows(t *testing.T) {
+	newRow := func(key string, value string) RowI {
+		return &Row{
+			RowMap: map[HeaderI]ValueI{
+				&Header{"Title", true, VALUE_STRING}:  &Value{key},
+				&Header{"Value", false, VALUE_STRING}: &Value{value},
+			},
+		}
+	}
+
+	db, _ := newDBWithValues()
+	rows := []RowI{
+		newRow("first", "1"),
+		newRow("test", "duplicate of existing"),
+		newRow("first", "duplicate in batch"),
+		newRow("", "empty key"),
+		newRow("second", "2"),
+	}
+
+	errs, err := db.AddRows(rows, AddRowsOptions{AllOrNothing: true})
+	assert.Nil(t, err)
+	assert.Equal(t, len(rows), len(errs))
+	assert.ErrorIs(t, errs[0], ErrBatchRejected)
+	assert.ErrorIs(t, errs[0], ErrDuplicateKey)
+	assert.ErrorIs(t, errs[1], ErrDuplicateKey)
+	assert.ErrorIs(t, errs[2], ErrDuplicateKey)
+	assert.ErrorIs(t, errs[3], ErrKeyHeaderEmpty)
+	assert.ErrorIs(t, errs[4], ErrBatchRejected)
+	for _, err := range errs[1:4] {
+		assert.NotErrorIs(t, err, ErrBatchRejected)
+	}
+	i, first := FirstRowError(errs)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, errs[1], first)
+	assert.Equal(t, 1, len(db.GetRows()))
+
+	errs, err = db.AddRows(rows, AddRowsOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, len(rows), len(errs))
+	assert.Equal(t, 3, len(db.GetRows()))
+	assert.NotNil(t, db.GetRowFromKeyHeader("first"))
+	assert.NotNil(t, db.GetRowFromKeyHeader("second"))
+
+	errs, err = db.AddRows([]RowI{newRow("third", "3")}, AddRowsOptions{AllOrNothing: true})
+	assert.Nil(t, errs)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(db.GetRows()))
+}
+
+func TestUpsert(t *testing.T) {
+	db, _ := newDBWithValues()
+
+	row := &Row{
+		RowMap: map[HeaderI]ValueI{
+			&Header{"Title", true, VALUE_STRING}: &Value{"new"},
+		},
+	}
+	err := db.Upsert(row)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(db.GetRows()))
+	assert.Equal(t, 2, len(db.GetRowFromKeyHeader("new").GetRowMap()))
+
+	row = &Row{
+		RowMap: map[HeaderI]ValueI{
+			&Header{"Title", true, VALUE_STRING}:  &Value{"test"},
+			&Header{"Value", false, VALUE_STRING}: &Value{"merged"},
+		},
+	}
+	err = db.Upsert(row)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(db.GetRows()))
+	v, err := db.GetRowFromKeyHeader("test").GetValueFromHeader("Value")
+	assert.Nil(t, err)
+	assert.Equal(t, "merged", v.GetValue())
+
+	row = &Row{
+		RowMap: map[HeaderI]ValueI{
+			&Header{"Title", true, VALUE_STRING}:  &Value{"test"},
+			&Header{"Extra", false, VALUE_STRING}: &Value{"not allowed"},
+		},
+	}
+	err = db.Upsert(row)
+	assert.Error(t, err)
+
+	row = &Row{
+		RowMap: map[HeaderI]ValueI{
+			&Header{"Title", true, VALUE_STRING}: &Value{""},
+		},
+	}
+	err = db.Upsert(row)
+	assert.Error(t, err)
+	assert.Equal(t, 2, len(db.GetRows()))
+}
+
+func TestUpdateWhere(t *testing.T) {
+	db := &DBImpl{
+		Name:      "Test",
+		KeyHeader: "Key",
+		Headers: map[HeaderI]struct{}{
+			&Header{"Key", true, VALUE_STRING}:       struct{}{},
+			&Header{"Platform", false, VALUE_STRING}: struct{}{},
+			&Header{"Hours", false, VALUE_NUMBER}:    struct{}{},
+		},
+		Rows: &Rows{},
+	}
+	for _, r := range [][]string{{"a", "PS4"}, {"b", "PS5"}, {"c", "PS5"}} {
+		err := db.AddRow(&Row{
+			RowMap: map[HeaderI]ValueI{
+				&Header{"Key", true, VALUE_STRING}:       &Value{r[0]},
+				&Header{"Platform", false, VALUE_STRING}: &Value{r[1]},
+			},
+		})
+		assert.Nil(t, err)
+	}
+	isPS5 := func(row RowI) bool {
+		v, _ := row.GetValueFromHeader("Platform")
+		return v.GetValue() == "PS5"
+	}
+
+	count, err := db.UpdateWhere(isPS5, map[string]string{"Hours": "10"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	rows, err := db.GetRowsFromHeaderAndValue("Hours", "10")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows))
+
+	count, err = db.UpdateWhere(isPS5, map[string]string{"Hours": "not a number", "Platform": "PS3"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+	rows, _ = db.GetRowsFromHeaderAndValue("Platform", "PS3")
+	assert.Equal(t, 0, len(rows))
+
+	count, err = db.UpdateWhere(isPS5, map[string]string{"Not Exist": ""})
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = db.UpdateWhere(nil, map[string]string{"Key": "same"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = db.UpdateWhere(nil, map[string]string{"Hours": ""})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestDeleteWhere(t *testing.T) {
+	db, _ := newDBWithValues()
+	for _, key := range []string{"a", "b", "c"} {
+		err := db.AddRow(&Row{
+			RowMap: map[HeaderI]ValueI{
+				&Header{"Title", true, VALUE_STRING}:  &Value{key},
+				&Header{"Value", false, VALUE_STRING}: &Value{"delete"},
+			},
+		})
+		assert.Nil(t, err)
+	}
+	toDelete := func(row RowI) bool {
+		v, _ := row.GetValueFromHeader("Value")
+		return v.GetValue() == "delete"
+	}
+
+	count, err := db.DeleteWhere(toDelete)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 1, len(db.GetRows()))
+
+	count, err = db.DeleteWhere(toDelete)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = db.DeleteWhere(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 0, len(db.GetRows()))
+}
+
+func newNumberDB(t *testing.T) *DBImpl {
+	db, err := New("Test", []HeaderI{
+		&Header{"Title", true, VALUE_STRING},
+		&Header{"Trophies", false, VALUE_NUMBER},
+		&Header{"Points", false, VALUE_NUMBER},
+	}, "Title")
+	assert.Nil(t, err)
+
+	for _, r := range [][]string{{"a", "10", "100"}, {"b", "", "50"}} {
+		err = db.AddRow(&Row{
+			RowMap: map[HeaderI]ValueI{
+				&Header{"Title", true, VALUE_STRING}:     &Value{r[0]},
+				&Header{"Trophies", false, VALUE_NUMBER}: &Value{r[1]},
+				&Header{"Points", false, VALUE_NUMBER}:   &Value{r[2]},
+			},
+		})
+		assert.Nil(t, err)
+	}
+
+	return db
+}
+
+func TestIncrement(t *testing.T) {
+	db := newNumberDB(t)
+
+	f, err := db.Increment("a", "Trophies", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 12.0, f)
+
+	f, err = db.Decrement("a", "Trophies", 0.5)
+	assert.Nil(t, err)
+	assert.Equal(t, 11.5, f)
+	v, _ := db.GetRowFromKeyHeader("a").GetValueFromHeader("Trophies")
+	assert.Equal(t, "11.5", v.GetValue())
+
+	_, err = db.Increment("b", "Trophies", 1)
+	assert.Error(t, err)
+
+	db.SetEmptyAsZero(true)
+	f, err = db.Increment("b", "Trophies", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, f)
+
+	_, err = db.Increment("c", "Trophies", 1)
+	assert.Error(t, err)
+
+	_, err = db.Increment("a", "Title", 1)
+	assert.Error(t, err)
+
+	_, err = db.Increment("a", "Not Exist", 1)
+	assert.Error(t, err)
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	db := newNumberDB(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.Increment("a", "Points", 1)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	v, _ := db.GetRowFromKeyHeader("a").GetValueFromHeader("Points")
+	assert.Equal(t, "150", v.GetValue())
+}
+
+func TestPredicateUsesDB(t *testing.T) {
+	db := newNumberDB(t)
+
+	// The first call changes b, so the rows are selected again once the DB is locked
+	changed := false
+	hasTrophies := func(row RowI) bool {
+		if !changed {
+			changed = true
+			assert.Nil(t, db.AddValueToHeader("5", "Trophies", "b"))
+		}
+		v, err := row.GetValueFromHeader("Trophies")
+		return err == nil && db.GetHeader("Trophies").IsNumber() && v.GetValue() != ""
+	}
+	count, err := db.UpdateWhere(hasTrophies, map[string]string{"Points": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = db.UpdateExpression(func(row RowI) bool {
+		return len(db.GetRows()) == 2 && row.KeyHeaderValueEqual("a")
+	}, `"Points" = "Points" + 1`)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "2", getValue(t, db, "a", "Points"))
+
+	count, err = db.DeleteWhere(func(row RowI) bool {
+		return db.GetRowFromKeyHeader("a") != nil && row.KeyHeaderValueEqual("b")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, db.GetRowFromKeyHeader("b"))
+}
+
+func TestGetRowsConcurrent(t *testing.T) {
+	db := newNumberDB(t)
+
+	// The rows returned are copies, so reading them races with nothing
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			db.AddHeader(&Header{"Extra", false, VALUE_STRING})
+			assert.Nil(t, db.RemoveHeader("Extra"))
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		for _, row := range db.GetRows() {
+			for h, v := range row.GetRowMap() {
+				_ = h.GetName() + v.GetValue()
+			}
+		}
+		rows, err := db.GetRowsFromHeaderAndValue("Title", "a")
+		assert.Nil(t, err)
+		for _, row := range rows {
+			row.UpdateHeaderValue("Points", "0")
+		}
+	}
+
+	assert.Equal(t, "100", getValue(t, db, "a", "Points"))
+}
+
+func TestUpdateExpression(t *testing.T) {
+	db := newNumberDB(t)
+
+	count, err := db.UpdateExpression(nil, `"Points" = "Points" * 2`)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	v, _ := db.GetRowFromKeyHeader("b").GetValueFromHeader("Points")
+	assert.Equal(t, "100", v.GetValue())
+
+	// Row b has an empty Trophies value so nothing is updated
+	count, err = db.UpdateExpression(nil, `"Points" = "Points" + "Trophies"`)
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+	v, _ = db.GetRowFromKeyHeader("a").GetValueFromHeader("Points")
+	assert.Equal(t, "200", v.GetValue())
+
+	db.SetEmptyAsZero(true)
+	onlyA := func(row RowI) bool { return row.KeyHeaderValueEqual("a") }
+	count, err = db.UpdateExpression(onlyA, `"Points" = "Points" + "Trophies"`)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	v, _ = db.GetRowFromKeyHeader("a").GetValueFromHeader("Points")
+	assert.Equal(t, "210", v.GetValue())
+
+	count, err = db.UpdateExpression(nil, `"Points" = "Points" / "Trophies"`)
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = db.UpdateExpression(nil, `"Title" = 1`)
+	assert.Error(t, err)
+
+	_, err = db.UpdateExpression(nil, `"Points" = "Not Exist"`)
+	assert.Error(t, err)
+
+	_, err = db.UpdateExpression(nil, `Points = 1`)
+	assert.Error(t, err)
+}
+
+func TestValidateValue(t *testing.T) {
+	number := &Header{"Number", false, VALUE_NUMBER}
+	assert.Nil(t, ValidateValue(number, "3.5"))
+	assert.Nil(t, ValidateValue(number, ""))
+	assert.Error(t, ValidateValue(number, "three"))
+
+	str := &Header{"String", false, VALUE_STRING}
+	assert.Nil(t, ValidateValue(str, "three"))
+}
+
+func TestFormatNumber(t *testing.T) {
+	for value, want := range map[string]string{"+1": "1", "007": "7", "1e3": "1000", ".5": "0.5", "-0.5": "-0.5", "0x1p4": "16"} {
+		number, ok := FormatNumber(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, want, number)
+	}
+
+	for _, value := range []string{"NaN", "Inf", "-Inf", "three", ""} {
+		_, ok := FormatNumber(value)
+		assert.False(t, ok, value)
+	}
+}
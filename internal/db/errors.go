@@ -0,0 +1,23 @@
+package db
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Returns the sentinel and the cause, so errors.Is and errors.As match either
+func (e *Error) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Err, e.Cause}
+}
+
+// Sets the DB's name on err if it is an *Error made without knowing which DB it came from
+func (db *DBImpl) annotate(err error) error {
+	if e, ok := err.(*Error); ok && e.DB == "" {
+		e.DB = db.Name
+	}
+
+	return err
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	db := newNumberDB(t)
+
+	_, err := db.GetRowsFromHeaderAndValue("Missing", "1")
+	assert.ErrorIs(t, err, ErrHeaderNotExist)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "Test", e.DB)
+	assert.Equal(t, "Missing", e.Header)
+	assert.Equal(t, "header 'Missing' does not exist", err.Error())
+
+	err = db.AddRow(newTestRow(t, db, "a", "1", "1"))
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "Test", e.DB)
+	assert.Equal(t, "Title", e.Header)
+	assert.Equal(t, "a", e.Key)
+
+	errs, _ := db.AddRows([]RowI{newTestRow(t, db, "b", "1", "1")}, AddRowsOptions{})
+	assert.ErrorIs(t, errs[0], ErrDuplicateKey)
+
+	_, err = db.Increment("missing", "Points", 1)
+	assert.ErrorIs(t, err, ErrRowNotExist)
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "missing", e.Key)
+
+	_, err = db.Increment("b", "Trophies", 1)
+	assert.ErrorIs(t, err, ErrNotANumber)
+	_, err = db.Increment("a", "Title", 1)
+	assert.ErrorIs(t, err, ErrHeaderNotNumber)
+
+	_, err = db.UpdateWhere(nil, map[string]string{"Title": "c"})
+	assert.ErrorIs(t, err, ErrUpdateKeyHeader)
+	assert.ErrorIs(t, db.RemoveRow(""), ErrKeyValueEmpty)
+	_, err = db.UpdateExpression(nil, "Points")
+	assert.ErrorIs(t, err, ErrInvalidExpression)
+
+	_, err = New("Test", []HeaderI{}, "")
+	assert.ErrorIs(t, err, ErrKeyHeaderEmpty)
+	_, err = ParseType("date")
+	assert.ErrorIs(t, err, ErrUnknownType)
+}
+
+func TestErrorCause(t *testing.T) {
+	h := &Header{"Points", false, VALUE_NUMBER}
+	_, err := h.Number(&Value{"many"})
+	assert.ErrorIs(t, err, ErrNotANumber)
+
+	var numErr *strconv.NumError
+	assert.True(t, errors.As(err, &numErr))
+	assert.Equal(t, "many", numErr.Num)
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parsed form of an arithmetic update, e.g. "Points Gained" = "Points Gained" * 2
+// target: The header the result is written to
+// left: The first operand
+// op: The operator applied to left and right, 0 if there is only a left operand
+// right: The second operand
+type expression struct {
+	target string
+	left   operand
+	op     byte
+	right  operand
+}
+
+// An operand in an expression, either a header name or a number
+type operand struct {
+	header string
+	number float64
+}
+
+func parseExpression(expr string) (*expression, error) {
+	invalid := func(reason string) error {
+		return &Error{Err: ErrInvalidExpression, Value: expr, Message: fmt.Sprintf(invalidExpressionError, expr, reason)}
+	}
+
+	rest := strings.TrimSpace(expr)
+	target, rest, err := parseHeaderName(rest)
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return nil, invalid(expressionEqualsError)
+	}
+	rest = strings.TrimSpace(rest[1:])
+
+	e := &expression{target: target}
+	e.left, rest, err = parseOperand(rest)
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return e, nil
+	}
+
+	if !strings.ContainsAny(rest[:1], "+-*/") {
+		return nil, invalid(fmt.Sprintf(expressionUnexpectedError, rest))
+	}
+	e.op = rest[0]
+
+	e.right, rest, err = parseOperand(strings.TrimSpace(rest[1:]))
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+
+	if strings.TrimSpace(rest) != "" {
+		return nil, invalid(fmt.Sprintf(expressionUnexpectedError, strings.TrimSpace(rest)))
+	}
+
+	return e, nil
+}
+
+// Parses a double quoted header name from the start of s, returning the name and the rest of s
+func parseHeaderName(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "\"") {
+		return "", s, errors.New(expressionQuoteError)
+	}
+
+	end := strings.Index(s[1:], "\"")
+	if end == -1 {
+		return "", s, errors.New(expressionUnterminatedError)
+	}
+
+	name := s[1 : end+1]
+	if name == "" {
+		return "", s, errors.New(expressionEmptyHeaderError)
+	}
+
+	return name, s[end+2:], nil
+}
+
+// Parses either a header name or a number from the start of s, returning the operand and the rest of s
+func parseOperand(s string) (operand, string, error) {
+	if strings.HasPrefix(s, "\"") {
+		name, rest, err := parseHeaderName(s)
+		return operand{header: name}, rest, err
+	}
+
+	// A sign may start the number or its exponent
+	end := 0
+	for end < len(s) && (strings.ContainsRune("0123456789.eE", rune(s[end])) || isNumberSign(s, end)) {
+		end++
+	}
+
+	number, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return operand{}, s, errors.New(expressionOperandError)
+	}
+
+	return operand{number: number}, s[end:], nil
+}
+
+// Returns whether the character at i of s is a sign at the start of a number or its exponent
+func isNumberSign(s string, i int) bool {
+	if s[i] != '-' && s[i] != '+' {
+		return false
+	}
+
+	return i == 0 && s[i] == '-' || i > 0 && (s[i-1] == 'e' || s[i-1] == 'E')
+}
+
+// Returns the headers the expression reads from
+func (e *expression) headers() []string {
+	headers := []string{}
+	for _, o := range []operand{e.left, e.right} {
+		if o.header != "" {
+			headers = append(headers, o.header)
+		}
+	}
+
+	return headers
+}
+
+// Evaluates the expression, using value to look up the number stored in a header
+func (e *expression) evaluate(value func(header string) (float64, error)) (float64, error) {
+	left, err := e.left.evaluate(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if e.op == 0 {
+		return left, nil
+	}
+
+	right, err := e.right.evaluate(value)
+	if err != nil {
+		return 0, err
+	}
+
+	switch e.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	default:
+		if right == 0 {
+			return 0, errDivideByZero
+		}
+		return left / right, nil
+	}
+}
+
+func (o operand) evaluate(value func(header string) (float64, error)) (float64, error) {
+	if o.header == "" {
+		return o.number, nil
+	}
+
+	return value(o.header)
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpression(t *testing.T) {
+	e, err := parseExpression(`"Points Gained" = "Points Gained" * 2`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Points Gained", e.target)
+	assert.Equal(t, operand{header: "Points Gained"}, e.left)
+	assert.Equal(t, byte('*'), e.op)
+	assert.Equal(t, operand{number: 2}, e.right)
+	assert.Equal(t, []string{"Points Gained"}, e.headers())
+
+	e, err = parseExpression(`"Hours"=-1.5`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hours", e.target)
+	assert.Equal(t, operand{number: -1.5}, e.left)
+	assert.Equal(t, byte(0), e.op)
+	assert.Equal(t, 0, len(e.headers()))
+
+	e, err = parseExpression(`"A" = "B" - "C"`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"B", "C"}, e.headers())
+
+	// Exponents may have a sign, which isn't taken as the operator
+	e, err = parseExpression(`"Points" = "Points" * 1e-3`)
+	assert.Nil(t, err)
+	assert.Equal(t, operand{number: 0.001}, e.right)
+	e, err = parseExpression(`"Points" = 2E+3-1`)
+	assert.Nil(t, err)
+	assert.Equal(t, operand{number: 2000}, e.left)
+	assert.Equal(t, byte('-'), e.op)
+	assert.Equal(t, operand{number: 1}, e.right)
+
+	for _, expr := range []string{
+		``,
+		`Hours = 1`,
+		`"Hours" 1`,
+		`"Hours = 1`,
+		`"" = 1`,
+		`"Hours" =`,
+		`"Hours" = 1 %% 2`,
+		`"Hours" = 1 + `,
+		`"Hours" = 1 + 2 + 3`,
+		`"Hours" = 1e-`,
+		`"Hours" = 1-e3`,
+	} {
+		_, err = parseExpression(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	values := map[string]float64{"A": 6, "B": 3, "Zero": 0}
+	value := func(header string) (float64, error) {
+		v, ok := values[header]
+		if !ok {
+			return 0, errors.New("missing")
+		}
+		return v, nil
+	}
+
+	expected := map[string]float64{
+		`"T" = "A"`:       6,
+		`"T" = "A" + "B"`: 9,
+		`"T" = "A" - 1`:   5,
+		`"T" = "A" * "B"`: 18,
+		`"T" = "A" / "B"`: 2,
+	}
+	for expr, result := range expected {
+		e, err := parseExpression(expr)
+		assert.Nil(t, err)
+		f, err := e.evaluate(value)
+		assert.Nil(t, err)
+		assert.Equal(t, result, f, expr)
+	}
+
+	e, _ := parseExpression(`"T" = "A" / "Zero"`)
+	_, err := e.evaluate(value)
+	assert.Equal(t, errDivideByZero, err)
+
+	e, _ = parseExpression(`"T" = "Missing" + 1`)
+	_, err = e.evaluate(value)
+	assert.Error(t, err)
+}
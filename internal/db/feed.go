@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// The number of recent events kept so a subscriber can resume
+	feedLogSize = 1024
+	// The buffer given to a subscriber that doesn't ask for one
+	defaultEventBuffer = 64
+)
+
+// Fans the changes to a DB out to its subscribers
+// Events are published while the DB's write lock is held, so they are sequenced in the order
+// the changes were made
+type feed struct {
+	mu          sync.Mutex
+	seq         uint64
+	log         []Event
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	filter EventFilter
+	events chan Event
+	// Closed when the subscription is cancelled, releasing a publisher blocked on a full buffer
+	done chan struct{}
+	once sync.Once
+}
+
+// Returns the name of the event type as used over the HTTP API
+func (t EventType) String() string {
+	switch t {
+	case EVENT_ADD_ROW:
+		return "add_row"
+	case EVENT_REMOVE_ROW:
+		return "remove_row"
+	case EVENT_UPDATE_VALUE:
+		return "update_value"
+	case EVENT_ADD_HEADER:
+		return "add_header"
+	case EVENT_REMOVE_HEADER:
+		return "remove_header"
+	case EVENT_RESET:
+		return "reset"
+	}
+
+	return "unknown"
+}
+
+// Returns the event type with the given name, the inverse of EventType.String
+func ParseEventType(name string) (EventType, error) {
+	for t := EVENT_ADD_ROW; t <= EVENT_RESET; t++ {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+
+	return EVENT_ADD_ROW, &Error{Err: ErrUnknownEventType, Value: name, Message: fmt.Sprintf(unknownEventTypeError, name)}
+}
+
+func (db *DBImpl) Subscribe(filter EventFilter) (<-chan Event, func()) {
+	return db.feed.subscribe(filter)
+}
+
+func (db *DBImpl) SubscribeContext(ctx context.Context, filter EventFilter) <-chan Event {
+	events, unsubscribe := db.feed.subscribe(filter)
+	context.AfterFunc(ctx, unsubscribe)
+
+	return events
+}
+
+// Publishes the event to every subscriber whose filter it matches
+// The caller must hold the DB's write lock
+func (db *DBImpl) publish(e Event) {
+	e.DB = db.Name
+	e.Actor = db.actor
+	e.Time = now()
+	e.Seq = db.feed.publish(e)
+	db.history.add(e)
+	db.journal.record(e)
+	if db.auditor != nil {
+		db.auditor.Record(e)
+	}
+}
+
+func (f *feed) subscribe(filter EventFilter) (<-chan Event, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	replay := []Event{}
+	if filter.After > 0 && f.missed(filter.After) {
+		// The subscriber can't catch up from the log, so it's told to start again from now
+		replay = append(replay, Event{Seq: f.seq, Type: EVENT_RESET, Time: now()})
+	} else if filter.After > 0 {
+		for _, e := range f.log {
+			if e.Seq > filter.After && filter.matches(e) {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	buffer := filter.Buffer
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+
+	// The buffer is grown to hold the replayed events so none of them are lost to the policy
+	s := &subscriber{filter: filter, events: make(chan Event, buffer+len(replay)), done: make(chan struct{})}
+	for _, e := range replay {
+		s.events <- e
+	}
+
+	if f.subscribers == nil {
+		f.subscribers = map[*subscriber]struct{}{}
+	}
+	f.subscribers[s] = struct{}{}
+
+	return s.events, func() {
+		s.once.Do(func() {
+			close(s.done)
+		})
+		f.unsubscribe(s)
+	}
+}
+
+func (f *feed) unsubscribe(s *subscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.subscribers[s]; exists {
+		delete(f.subscribers, s)
+		close(s.events)
+	}
+}
+
+// Returns true if some of the events after the sequence number after are no longer in the log, or
+// after is from a feed that has since been replaced, such as before a restart
+// The caller must hold f.mu
+func (f *feed) missed(after uint64) bool {
+	if after > f.seq {
+		return true
+	}
+
+	return len(f.log) > 0 && f.log[0].Seq > after+1
+}
+
+// Returns the sequence number of the last event published, which changes whenever the DB does
+func (f *feed) last() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.seq
+}
+
+// Returns the sequence number given to the event
+func (f *feed) publish(e Event) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	e.Seq = f.seq
+
+	f.log = append(f.log, e)
+	if len(f.log) > feedLogSize {
+		f.log = append([]Event{}, f.log[len(f.log)-feedLogSize:]...)
+	}
+
+	for s := range f.subscribers {
+		if !s.filter.matches(e) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+			continue
+		default:
+		}
+
+		switch s.filter.Policy {
+		case POLICY_BLOCK:
+			select {
+			case s.events <- e:
+			case <-s.done:
+			}
+		case POLICY_DISCONNECT:
+			delete(f.subscribers, s)
+			close(s.events)
+		}
+	}
+
+	return e.Seq
+}
+
+// Returns true if the event passes every part of the filter
+func (filter EventFilter) matches(e Event) bool {
+	return (len(filter.Types) == 0 || contains(filter.Types, e.Type)) &&
+		(len(filter.Keys) == 0 || contains(filter.Keys, e.Key)) &&
+		(len(filter.Headers) == 0 || contains(filter.Headers, e.Header))
+}
+
+func contains[T comparable](items []T, item T) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the row's values by header name
+func rowValues(row RowI) map[string]string {
+	values := map[string]string{}
+	for h, v := range row.GetRowMap() {
+		values[h.GetName()] = v.GetValue()
+	}
+
+	return values
+}
+
+func rowKey(row RowI) string {
+	_, v := row.GetKeyHeaderAndValue()
+	return v.GetValue()
+}
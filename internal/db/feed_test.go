@@ -0,0 +1,228 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the events waiting on the channel without blocking
+func drain(events <-chan Event) []Event {
+	drained := []Event{}
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return drained
+			}
+			drained = append(drained, e)
+		default:
+			return drained
+		}
+	}
+}
+
+func newNumberRow(title string, trophies string) RowI {
+	return &Row{
+		RowMap: map[HeaderI]ValueI{
+			&Header{"Title", true, VALUE_STRING}:     &Value{title},
+			&Header{"Trophies", false, VALUE_NUMBER}: &Value{trophies},
+		},
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	db := newNumberDB(t)
+	events, cancel := db.Subscribe(EventFilter{})
+
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+	assert.Nil(t, db.AddValueToHeader("2", "Trophies", "c"))
+	assert.Nil(t, db.AddValueToHeader("2", "Trophies", "c"))
+	_, err := db.Increment("c", "Trophies", 3)
+	assert.Nil(t, err)
+	db.AddHeader(&Header{"Genre", false, VALUE_STRING})
+	assert.Nil(t, db.RemoveHeader("Genre"))
+	assert.Nil(t, db.RemoveHeader("Genre"))
+	assert.Nil(t, db.RemoveRow("c"))
+	assert.Nil(t, db.RemoveRow("c"))
+
+	received := drain(events)
+	assert.Equal(t, 6, len(received))
+	for i := 1; i < len(received); i++ {
+		assert.Equal(t, received[i-1].Seq+1, received[i].Seq)
+	}
+
+	assert.Equal(t, EVENT_ADD_ROW, received[0].Type)
+	assert.Equal(t, "Test", received[0].DB)
+	assert.Equal(t, "c", received[0].Key)
+	assert.Equal(t, map[string]string{"Title": "c", "Trophies": "1", "Points": ""}, received[0].Row)
+
+	assert.Equal(t, Event{Seq: received[1].Seq, Type: EVENT_UPDATE_VALUE, DB: "Test", Time: received[1].Time, Key: "c", Header: "Trophies", OldValue: "1", NewValue: "2"}, received[1])
+	assert.Equal(t, "5", received[2].NewValue)
+
+	assert.Equal(t, EVENT_ADD_HEADER, received[3].Type)
+	assert.Equal(t, "Genre", received[3].Header)
+	assert.Equal(t, EVENT_REMOVE_HEADER, received[4].Type)
+
+	assert.Equal(t, EVENT_REMOVE_ROW, received[5].Type)
+	assert.Equal(t, "5", received[5].Row["Trophies"])
+
+	cancel()
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestSubscribeBulk(t *testing.T) {
+	db := newNumberDB(t)
+	events, cancel := db.Subscribe(EventFilter{})
+	defer cancel()
+
+	errs, err := db.AddRows([]RowI{newNumberRow("c", "1"), newNumberRow("d", "2")}, AddRowsOptions{AllOrNothing: true})
+	assert.Nil(t, errs)
+	assert.Nil(t, err)
+	_, err = db.UpdateWhere(nil, map[string]string{"Points": "1"})
+	assert.Nil(t, err)
+	_, err = db.UpdateExpression(nil, `"Points" = "Points" * 2`)
+	assert.Nil(t, err)
+	_, err = db.DeleteWhere(func(row RowI) bool { return row.KeyHeaderValueEqual("d") })
+	assert.Nil(t, err)
+
+	counts := map[EventType]int{}
+	for _, e := range drain(events) {
+		counts[e.Type]++
+	}
+	assert.Equal(t, map[EventType]int{EVENT_ADD_ROW: 2, EVENT_UPDATE_VALUE: 8, EVENT_REMOVE_ROW: 1}, counts)
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	db := newNumberDB(t)
+	byType, cancelType := db.Subscribe(EventFilter{Types: []EventType{EVENT_ADD_ROW}})
+	defer cancelType()
+	byKey, cancelKey := db.Subscribe(EventFilter{Keys: []string{"a"}})
+	defer cancelKey()
+	byHeader, cancelHeader := db.Subscribe(EventFilter{Headers: []string{"Points"}})
+	defer cancelHeader()
+
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+	assert.Nil(t, db.AddValueToHeader("1", "Trophies", "a"))
+	assert.Nil(t, db.AddValueToHeader("1", "Points", "b"))
+
+	assert.Equal(t, "c", drain(byType)[0].Key)
+	received := drain(byKey)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, "Trophies", received[0].Header)
+	received = drain(byHeader)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, "b", received[0].Key)
+}
+
+func TestSubscribeResume(t *testing.T) {
+	db := newNumberDB(t)
+	events, cancel := db.Subscribe(EventFilter{})
+	assert.Nil(t, db.AddValueToHeader("1", "Trophies", "a"))
+	last := drain(events)[0].Seq
+	cancel()
+
+	assert.Nil(t, db.AddValueToHeader("2", "Trophies", "a"))
+	assert.Nil(t, db.AddValueToHeader("3", "Trophies", "a"))
+
+	events, cancel = db.Subscribe(EventFilter{After: last, Buffer: 1})
+	defer cancel()
+	assert.Nil(t, db.AddValueToHeader("4", "Trophies", "a"))
+
+	received := drain(events)
+	assert.Equal(t, 3, len(received))
+	assert.Equal(t, last+1, received[0].Seq)
+	assert.Equal(t, "2", received[0].NewValue)
+	assert.Equal(t, "4", received[2].NewValue)
+}
+
+func TestSubscribeResumeMissed(t *testing.T) {
+	db := newNumberDB(t)
+	for i := 0; i < feedLogSize+2; i++ {
+		assert.Nil(t, db.AddValueToHeader(fmt.Sprint(i), "Trophies", "a"))
+	}
+	last := db.feed.last()
+
+	// The event after 1 is no longer retained
+	events, cancel := db.Subscribe(EventFilter{After: 1})
+	assert.Nil(t, db.AddValueToHeader("x", "Trophies", "a"))
+	cancel()
+	received := drain(events)
+	assert.Equal(t, 2, len(received))
+	assert.Equal(t, EVENT_RESET, received[0].Type)
+	assert.Equal(t, last, received[0].Seq)
+	assert.Equal(t, last+1, received[1].Seq)
+
+	// The oldest retained event can still be resumed from
+	last = db.feed.last()
+	events, cancel = db.Subscribe(EventFilter{After: last - feedLogSize})
+	cancel()
+	received = drain(events)
+	assert.Equal(t, feedLogSize, len(received))
+	assert.Equal(t, EVENT_UPDATE_VALUE, received[0].Type)
+
+	// A Seq from before a restart is ahead of the feed
+	events, cancel = db.Subscribe(EventFilter{After: last + 10})
+	cancel()
+	received = drain(events)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, EVENT_RESET, received[0].Type)
+}
+
+func TestSubscribePolicies(t *testing.T) {
+	db := newNumberDB(t)
+	dropped, cancelDrop := db.Subscribe(EventFilter{Buffer: 1, Policy: POLICY_DROP})
+	defer cancelDrop()
+	disconnected, cancelDisconnect := db.Subscribe(EventFilter{Buffer: 1, Policy: POLICY_DISCONNECT})
+	defer cancelDisconnect()
+
+	assert.Nil(t, db.AddValueToHeader("1", "Trophies", "a"))
+	assert.Nil(t, db.AddValueToHeader("2", "Trophies", "a"))
+
+	received := drain(dropped)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, "1", received[0].NewValue)
+
+	received = drain(disconnected)
+	assert.Equal(t, 1, len(received))
+	_, ok := <-disconnected
+	assert.False(t, ok)
+}
+
+func TestSubscribeBlock(t *testing.T) {
+	db := newNumberDB(t)
+	blocked, cancel := db.Subscribe(EventFilter{Buffer: 1, Policy: POLICY_BLOCK})
+
+	assert.Nil(t, db.AddValueToHeader("1", "Trophies", "a"))
+
+	done := make(chan struct{})
+	go func() {
+		db.AddValueToHeader("2", "Trophies", "a")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("change didn't wait for the subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.Equal(t, "1", (<-blocked).NewValue)
+	<-done
+	assert.Equal(t, "2", (<-blocked).NewValue)
+
+	// Cancelling releases a change waiting on a full buffer
+	assert.Nil(t, db.AddValueToHeader("3", "Trophies", "a"))
+	done = make(chan struct{})
+	go func() {
+		db.AddValueToHeader("4", "Trophies", "a")
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+}
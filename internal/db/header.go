@@ -1,9 +1,10 @@
 package db
 
 import (
-	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 func (h *Header) GetName() string {
@@ -28,9 +29,50 @@ func (h *Header) IsKeyHeader() bool {
 
 func (h *Header) Number(value ValueI) (float64, error) {
 	if h.IsString() {
-		err := fmt.Sprintf(notANumberError, value.GetValue())
-		return 0.0, errors.New(err)
+		return 0.0, &Error{Err: ErrNotANumber, Header: h.Name, Value: value.GetValue(), Message: fmt.Sprintf(notANumberError, value.GetValue())}
 	}
 
-	return strconv.ParseFloat(value.GetValue(), 64)
+	f, err := strconv.ParseFloat(value.GetValue(), 64)
+	if err != nil {
+		return 0.0, &Error{Err: ErrNotANumber, Header: h.Name, Value: value.GetValue(), Message: err.Error(), Cause: err}
+	}
+
+	return f, nil
+}
+
+// Returns the name of the type as used in the CLI and snapshots
+func (t Type) String() string {
+	if t == VALUE_NUMBER {
+		return "number"
+	}
+
+	return "string"
+}
+
+// Returns the Type for the given name, either "string" or "number"
+// Returns an error if the name is not a known type
+func ParseType(name string) (Type, error) {
+	switch strings.ToLower(name) {
+	case "string":
+		return VALUE_STRING, nil
+	case "number":
+		return VALUE_NUMBER, nil
+	}
+
+	return VALUE_STRING, &Error{Err: ErrUnknownType, Value: name, Message: fmt.Sprintf(unknownTypeError, name)}
+}
+
+// Returns the DB's headers with the KeyHeader first and the rest ordered by name, the order
+// exports write them in
+func SortedHeaders(d DB) []HeaderI {
+	headers := d.GetHeaders()
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i].IsKeyHeader() != headers[j].IsKeyHeader() {
+			return headers[i].IsKeyHeader()
+		}
+
+		return headers[i].GetName() < headers[j].GetName()
+	})
+
+	return headers
 }
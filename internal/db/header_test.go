@@ -56,3 +56,21 @@ func TestNumber(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 3.14, f)
 }
+
+func TestTypeString(t *testing.T) {
+	assert.Equal(t, "string", VALUE_STRING.String())
+	assert.Equal(t, "number", VALUE_NUMBER.String())
+}
+
+func TestParseType(t *testing.T) {
+	typ, err := ParseType("string")
+	assert.Nil(t, err)
+	assert.Equal(t, VALUE_STRING, typ)
+
+	typ, err = ParseType("Number")
+	assert.Nil(t, err)
+	assert.Equal(t, VALUE_NUMBER, typ)
+
+	_, err = ParseType("bool")
+	assert.Error(t, err)
+}
@@ -0,0 +1,317 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Returns the current time, replaced in tests
+var now = time.Now
+
+// The retention used until SetRetention is called
+var defaultRetention = Retention{MaxChanges: 10000}
+
+// The changes made to a DB, oldest first, used to rebuild earlier versions of it
+// Only changed while the DB's write lock is held
+type history struct {
+	retention *Retention
+	changes   []Event
+	// Set once a change has been dropped, at which point horizon is the time of the newest
+	// change dropped and AsOf can't go back before it
+	expired bool
+	horizon time.Time
+}
+
+func (h *history) add(e Event) {
+	h.changes = append(h.changes, e)
+	h.trim()
+}
+
+// Drops the changes outside of the retention
+func (h *history) trim() {
+	retention := defaultRetention
+	if h.retention != nil {
+		retention = *h.retention
+	}
+
+	var oldest time.Time
+	if retention.MaxAge > 0 {
+		oldest = now().Add(-retention.MaxAge)
+	}
+
+	for len(h.changes) > 0 {
+		tooMany := retention.MaxChanges > 0 && len(h.changes) > retention.MaxChanges
+		tooOld := h.changes[0].Time.Before(oldest)
+		if !tooMany && !tooOld {
+			break
+		}
+
+		h.expired = true
+		h.horizon = h.changes[0].Time
+		h.changes = h.changes[1:]
+	}
+}
+
+func (db *DBImpl) SetRetention(retention Retention) {
+	db.SetRetentionContext(context.Background(), retention)
+}
+
+func (db *DBImpl) SetRetentionContext(ctx context.Context, retention Retention) error {
+	unlock, err := db.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db.history.retention = &retention
+	db.history.trim()
+	return nil
+}
+
+func (db *DBImpl) History(key string) []Version {
+	versions, _ := db.HistoryContext(context.Background(), key)
+	return versions
+}
+
+func (db *DBImpl) HistoryContext(ctx context.Context, key string) ([]Version, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Start from the row as it is now and undo the changes newest first, recording the
+	// values the row had after each change to it
+	var values map[string]string
+	if row := db.Rows.GetRowFromKeyHeader(key); row != nil {
+		values = rowValues(row)
+	}
+
+	versions := []Version{}
+	for i := len(db.history.changes) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		e := db.history.changes[i]
+		switch e.Type {
+		case EVENT_ADD_HEADER:
+			delete(values, e.Header)
+			continue
+		case EVENT_REMOVE_HEADER:
+			if v, exists := e.Values[key]; exists && values != nil {
+				values[e.Header] = v
+			}
+			continue
+		}
+
+		if e.Key != key {
+			continue
+		}
+
+		versions = append(versions, Version{
+			Seq:      e.Seq,
+			Time:     e.Time,
+			Actor:    e.Actor,
+			Type:     e.Type,
+			Header:   e.Header,
+			OldValue: e.OldValue,
+			NewValue: e.NewValue,
+			Values:   copyValues(values),
+		})
+
+		switch e.Type {
+		case EVENT_ADD_ROW:
+			values = nil
+		case EVENT_REMOVE_ROW:
+			values = copyValues(e.Row)
+		case EVENT_UPDATE_VALUE:
+			values[e.Header] = e.OldValue
+		}
+	}
+
+	// Oldest first
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+
+	return versions, nil
+}
+
+func (db *DBImpl) AsOf(t time.Time) (DB, error) {
+	return db.AsOfContext(context.Background(), t)
+}
+
+func (db *DBImpl) AsOfContext(ctx context.Context, t time.Time) (DB, error) {
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if db.history.expired && t.Before(db.history.horizon) {
+		return nil, &Error{Err: ErrHistoryExpired, DB: db.Name, Message: fmt.Sprintf(historyExpiredError, db.history.horizon.Format(time.RFC3339Nano))}
+	}
+
+	past := db.copy()
+	past.readOnly = true
+	for i := len(db.history.changes) - 1; i >= 0 && db.history.changes[i].Time.After(t); i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		past.reverse(db.history.changes[i])
+	}
+
+	return &readOnlyDB{DBImpl: past, source: db, at: t}, nil
+}
+
+// Returns a copy of the DB's headers and rows without its subscribers or history
+func (db *DBImpl) copy() *DBImpl {
+	c := &DBImpl{
+		Name:        db.Name,
+		KeyHeader:   db.KeyHeader,
+		Headers:     map[HeaderI]struct{}{},
+		Rows:        &Rows{},
+		emptyAsZero: db.emptyAsZero,
+	}
+
+	for h := range db.Headers {
+		c.Headers[&Header{Name: h.GetName(), KeyHeader: h.IsKeyHeader(), Type: h.GetType()}] = struct{}{}
+	}
+
+	for _, row := range db.Rows.GetRows() {
+		c.Rows.AddRow(c.newRow(rowValues(row)))
+	}
+
+	return c
+}
+
+// Undoes the change on a copy of the DB without publishing anything
+func (db *DBImpl) reverse(e Event) {
+	switch e.Type {
+	case EVENT_ADD_ROW:
+		db.Rows.DeleteRowWithValue(e.Key)
+	case EVENT_REMOVE_ROW:
+		db.Rows.AddRow(db.newRow(e.Row))
+	case EVENT_UPDATE_VALUE:
+		if row := db.Rows.GetRowFromKeyHeader(e.Key); row != nil {
+			row.UpdateHeaderValue(e.Header, e.OldValue)
+		}
+	case EVENT_ADD_HEADER:
+		for h := range db.Headers {
+			if h.GetName() == e.Header {
+				delete(db.Headers, h)
+			}
+		}
+		db.Rows.RemoveHeader(e.Header)
+	case EVENT_REMOVE_HEADER:
+		db.Headers[&Header{Name: e.Header, KeyHeader: false, Type: e.HeaderType}] = struct{}{}
+		for _, row := range db.Rows.GetRows() {
+			row.AddHeaderWithValue(e.Header, false, e.HeaderType, e.Values[rowKey(row)])
+		}
+	}
+}
+
+// Creates a row with a value for each of the DB's headers
+func (db *DBImpl) newRow(values map[string]string) RowI {
+	row := &Row{RowMap: map[HeaderI]ValueI{}}
+	for h := range db.Headers {
+		row.AddHeaderWithValue(h.GetName(), h.IsKeyHeader(), h.GetType(), values[h.GetName()])
+	}
+
+	return row
+}
+
+func copyValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(values))
+	for header, value := range values {
+		c[header] = value
+	}
+
+	return c
+}
+
+func (r *readOnlyDB) readOnly() error {
+	return &Error{Err: ErrReadOnly, DB: r.Name, Message: fmt.Sprintf(readOnlyError, r.Name)}
+}
+
+// Changes can't be made through the copy so it is returned as it is
+func (r *readOnlyDB) As(actor string) DB {
+	return r
+}
+
+func (r *readOnlyDB) SetAuditor(auditor Auditor) {}
+
+func (r *readOnlyDB) Undo() error {
+	return r.readOnly()
+}
+
+func (r *readOnlyDB) Redo() error {
+	return r.readOnly()
+}
+
+func (r *readOnlyDB) BeginUndoGroup() {}
+
+func (r *readOnlyDB) EndUndoGroup() {}
+
+func (r *readOnlyDB) SetUndoDepth(depth int) {}
+
+func (r *readOnlyDB) ClearUndo() {}
+
+// Nothing changes in a read-only DB, so the channel is closed straight away
+func (r *readOnlyDB) Subscribe(filter EventFilter) (<-chan Event, func()) {
+	events := make(chan Event)
+	close(events)
+
+	return events, func() {}
+}
+
+func (r *readOnlyDB) SubscribeContext(ctx context.Context, filter EventFilter) <-chan Event {
+	events, _ := r.Subscribe(filter)
+	return events
+}
+
+// Returns the versions of the row up to the time the copy was made at
+func (r *readOnlyDB) History(key string) []Version {
+	versions, _ := r.HistoryContext(context.Background(), key)
+	return versions
+}
+
+func (r *readOnlyDB) HistoryContext(ctx context.Context, key string) ([]Version, error) {
+	all, err := r.source.HistoryContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []Version{}
+	for _, v := range all {
+		if !v.Time.After(r.at) {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+// Returns the DB at the given time, or this copy if the time is after it
+func (r *readOnlyDB) AsOf(t time.Time) (DB, error) {
+	return r.AsOfContext(context.Background(), t)
+}
+
+func (r *readOnlyDB) AsOfContext(ctx context.Context, t time.Time) (DB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if t.After(r.at) {
+		return r, nil
+	}
+
+	return r.source.AsOfContext(ctx, t)
+}
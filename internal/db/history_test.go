@@ -0,0 +1,130 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Replaces the clock with one starting at start and moving on a minute each time it's read
+func fakeClock(t *testing.T) time.Time {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := start
+	now = func() time.Time {
+		current = current.Add(time.Minute)
+		return current
+	}
+	t.Cleanup(func() { now = time.Now })
+
+	return start
+}
+
+func TestHistory(t *testing.T) {
+	start := fakeClock(t)
+	db := newNumberDB(t)
+
+	assert.Nil(t, db.AddValueToHeader("12", "Trophies", "a"))
+	assert.Nil(t, db.As("alice").AddValueToHeader("15", "Trophies", "a"))
+	db.AddHeader(&Header{"Genre", false, VALUE_STRING})
+	assert.Nil(t, db.AddValueToHeader("RPG", "Genre", "a"))
+	assert.Nil(t, db.RemoveHeader("Genre"))
+	assert.Nil(t, db.RemoveRow("a"))
+
+	versions := db.History("a")
+	assert.Equal(t, 5, len(versions))
+
+	assert.Equal(t, EVENT_ADD_ROW, versions[0].Type)
+	assert.True(t, versions[0].Time.After(start))
+	assert.True(t, versions[1].Time.After(versions[0].Time))
+	assert.Equal(t, map[string]string{"Title": "a", "Trophies": "10", "Points": "100"}, versions[0].Values)
+
+	assert.Equal(t, EVENT_UPDATE_VALUE, versions[1].Type)
+	assert.Equal(t, "", versions[1].Actor)
+	assert.Equal(t, "10", versions[1].OldValue)
+	assert.Equal(t, "12", versions[1].NewValue)
+	assert.Equal(t, "12", versions[1].Values["Trophies"])
+
+	assert.Equal(t, "alice", versions[2].Actor)
+	assert.Equal(t, "15", versions[2].Values["Trophies"])
+
+	assert.Equal(t, map[string]string{"Title": "a", "Trophies": "15", "Points": "100", "Genre": "RPG"}, versions[3].Values)
+
+	assert.Equal(t, EVENT_REMOVE_ROW, versions[4].Type)
+	assert.Nil(t, versions[4].Values)
+
+	assert.Equal(t, 1, len(db.History("b")))
+	assert.Equal(t, []Version{}, db.History("c"))
+}
+
+func TestAsOf(t *testing.T) {
+	fakeClock(t)
+	db := newNumberDB(t)
+
+	assert.Nil(t, db.AddValueToHeader("12", "Trophies", "a"))
+	updated := db.History("a")[1].Time
+	db.AddHeader(&Header{"Genre", false, VALUE_STRING})
+	assert.Nil(t, db.AddValueToHeader("RPG", "Genre", "a"))
+	assert.Nil(t, db.RemoveHeader("Genre"))
+	assert.Nil(t, db.RemoveHeader("Points"))
+	assert.Nil(t, db.RemoveRow("a"))
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+
+	past, err := db.AsOf(updated)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(past.GetRows()))
+	assert.Nil(t, past.GetRowFromKeyHeader("c"))
+	assert.Equal(t, 3, len(past.GetHeaders()))
+
+	v, _ := past.GetRowFromKeyHeader("a").GetValueFromHeader("Trophies")
+	assert.Equal(t, "12", v.GetValue())
+	v, _ = past.GetRowFromKeyHeader("a").GetValueFromHeader("Points")
+	assert.Equal(t, "100", v.GetValue())
+
+	// Before the Genre header was removed
+	past, err = db.AsOf(db.History("a")[2].Time)
+	assert.Nil(t, err)
+	v, _ = past.GetRowFromKeyHeader("a").GetValueFromHeader("Genre")
+	assert.Equal(t, "RPG", v.GetValue())
+	assert.Equal(t, 3, len(past.History("a")))
+
+	// The copy can't be changed and the DB is untouched
+	assert.Equal(t, "database 'Test' is read-only", past.AddRow(newNumberRow("d", "1")).Error())
+	_, err = past.Increment("a", "Trophies", 1)
+	assert.Error(t, err)
+	assert.Equal(t, 2, len(db.GetRows()))
+	assert.Equal(t, 2, len(db.GetHeaders()))
+
+	// Before the DB was created every row and header is gone
+	past, err = db.AsOf(time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(past.GetRows()))
+	assert.Equal(t, 0, len(past.GetHeaders()))
+}
+
+func TestRetention(t *testing.T) {
+	start := fakeClock(t)
+	db := newNumberDB(t)
+
+	db.SetRetention(Retention{MaxChanges: 3})
+	for _, value := range []string{"1", "2", "3", "4"} {
+		assert.Nil(t, db.AddValueToHeader(value, "Trophies", "a"))
+	}
+
+	versions := db.History("a")
+	assert.Equal(t, 3, len(versions))
+	assert.Equal(t, "1", versions[0].OldValue)
+
+	_, err := db.AsOf(start)
+	assert.Error(t, err)
+	_, err = db.AsOf(versions[0].Time)
+	assert.Nil(t, err)
+
+	// Every change is older than an hour by the time the retention is set
+	db.SetRetention(Retention{MaxAge: time.Hour})
+	for i := 0; i < 60; i++ {
+		now()
+	}
+	assert.Nil(t, db.AddValueToHeader("5", "Trophies", "a"))
+	assert.Equal(t, 1, len(db.History("a")))
+}
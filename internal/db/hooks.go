@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// How deeply changes made by hooks can run further hooks before they are rejected
+const maxHookDepth = 8
+
+// Registers a hook run before a row is added, which can change the row's values or reject it
+func (db *DBImpl) BeforeInsert(hook Hook) {
+	db.addHook(HOOK_BEFORE_INSERT, hook)
+}
+
+// Registers a hook run after a row is added
+func (db *DBImpl) AfterInsert(hook Hook) {
+	db.addHook(HOOK_AFTER_INSERT, hook)
+}
+
+// Registers a hook run before a row's values change, which can change the new values or
+// reject the change
+func (db *DBImpl) BeforeUpdate(hook Hook) {
+	db.addHook(HOOK_BEFORE_UPDATE, hook)
+}
+
+// Registers a hook run after a row's values change
+func (db *DBImpl) AfterUpdate(hook Hook) {
+	db.addHook(HOOK_AFTER_UPDATE, hook)
+}
+
+// Registers a hook run before a row is removed, which can reject the removal
+func (db *DBImpl) BeforeDelete(hook Hook) {
+	db.addHook(HOOK_BEFORE_DELETE, hook)
+}
+
+// Registers a hook run after a row is removed
+func (db *DBImpl) AfterDelete(hook Hook) {
+	db.addHook(HOOK_AFTER_DELETE, hook)
+}
+
+func (db *DBImpl) addHook(t HookType, hook Hook) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.hooks == nil {
+		db.hooks = map[HookType][]Hook{}
+	}
+	db.hooks[t] = append(db.hooks[t], hook)
+}
+
+// Runs the hooks of the given type in the order they were registered, stopping at the first
+// that returns an error
+func (db *DBImpl) runHooks(t HookType, change *Change) error {
+	hooks := db.hooks[t]
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	if db.hookDepth >= maxHookDepth {
+		return &Error{Err: ErrHookRecursion, DB: db.Name, Message: fmt.Sprintf(hookRecursionError, maxHookDepth)}
+	}
+	db.hookDepth++
+	defer func() {
+		db.hookDepth--
+	}()
+
+	for _, hook := range hooks {
+		err := hook(change)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Runs the BeforeInsert hooks for a verified row, returning the row to add, which is rebuilt
+// if the hooks changed its values
+func (db *DBImpl) beforeInsert(row RowI) (RowI, error) {
+	if len(db.hooks[HOOK_BEFORE_INSERT]) == 0 {
+		return row, nil
+	}
+
+	values := rowValues(row)
+	change := &Change{DB: &hookDB{db}, Key: rowKey(row), New: copyValues(values)}
+	err := db.runHooks(HOOK_BEFORE_INSERT, change)
+	if err != nil {
+		return nil, err
+	}
+
+	if !changed(values, change.New) {
+		return row, nil
+	}
+
+	err = db.verifyHookValues(values, change.New, true)
+	if err != nil {
+		return nil, err
+	}
+
+	row = db.newRow(change.New)
+	return row, db.verifyKeyHeader(row)
+}
+
+// Runs the BeforeUpdate hooks for setting the values in the row, returning the change to make
+// or nil if no value would change
+func (db *DBImpl) beforeUpdate(row RowI, values map[string]string) (*Change, error) {
+	if len(db.hooks[HOOK_BEFORE_UPDATE]) == 0 && len(db.hooks[HOOK_AFTER_UPDATE]) == 0 {
+		return &Change{Key: rowKey(row), New: values}, nil
+	}
+
+	old := rowValues(row)
+	new := copyValues(old)
+	for header, value := range values {
+		new[header] = value
+	}
+	if !changed(old, new) {
+		return nil, nil
+	}
+
+	change := &Change{DB: &hookDB{db}, Key: rowKey(row), Old: old, New: copyValues(new)}
+	err := db.runHooks(HOOK_BEFORE_UPDATE, change)
+	if err != nil {
+		return nil, err
+	}
+
+	return change, db.verifyHookValues(new, change.New, false)
+}
+
+// Sets the values of a change returned by beforeUpdate
+func (db *DBImpl) applyUpdate(row RowI, change *Change) {
+	if change == nil {
+		return
+	}
+
+	for header, value := range change.New {
+		db.setValue(row, header, value)
+	}
+}
+
+// Runs the BeforeDelete hooks for removing the row, returning the change to make
+func (db *DBImpl) beforeDelete(row RowI) (*Change, error) {
+	if len(db.hooks[HOOK_BEFORE_DELETE]) == 0 && len(db.hooks[HOOK_AFTER_DELETE]) == 0 {
+		return &Change{Key: rowKey(row)}, nil
+	}
+
+	change := &Change{DB: &hookDB{db}, Key: rowKey(row), Old: rowValues(row)}
+	return change, db.runHooks(HOOK_BEFORE_DELETE, change)
+}
+
+// Runs the After hooks of the given type once a change has been made, row being nil if it
+// was removed
+func (db *DBImpl) afterChange(t HookType, change *Change, row RowI) error {
+	if change == nil || len(db.hooks[t]) == 0 {
+		return nil
+	}
+
+	after := &Change{DB: &hookDB{db}, Key: change.Key, Old: change.Old}
+	if row != nil {
+		after.Key = rowKey(row)
+		after.New = rowValues(row)
+	}
+
+	return db.runHooks(t, after)
+}
+
+// Verifies the values hooks changed from before to after, which have to exist in the DB and
+// match their header's type, the KeyHeader only being changeable for inserts
+func (db *DBImpl) verifyHookValues(before map[string]string, after map[string]string, insert bool) error {
+	for header, value := range after {
+		if before[header] == value {
+			continue
+		}
+
+		if !db.headerExists(header) {
+			return &Error{Err: ErrHeaderNotExist, DB: db.Name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		if header == db.KeyHeader && !insert {
+			return &Error{Err: ErrUpdateKeyHeader, DB: db.Name, Header: header, Message: fmt.Sprintf(updateKeyHeaderError, header)}
+		}
+
+		err := ValidateValue(db.getHeader(header), value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns whether any value in after is different to the one in before
+func changed(before map[string]string, after map[string]string) bool {
+	for header, value := range after {
+		if before[header] != value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *hookDB) GetName() string {
+	return h.db.Name
+}
+
+func (h *hookDB) GetKeyHeader() string {
+	return h.db.KeyHeader
+}
+
+func (h *hookDB) GetHeader(header string) HeaderI {
+	return h.db.getHeader(header)
+}
+
+func (h *hookDB) GetRows() []RowI {
+	return h.db.Rows.GetRows()
+}
+
+func (h *hookDB) GetRowFromKeyHeader(value string) RowI {
+	return h.db.Rows.GetRowFromKeyHeader(value)
+}
+
+func (h *hookDB) AddRow(row RowI) error {
+	return h.db.addRow(row)
+}
+
+func (h *hookDB) Upsert(row RowI) error {
+	return h.db.upsert(row)
+}
+
+func (h *hookDB) RemoveRow(keyValue string) error {
+	return h.db.removeRowWithKey(keyValue)
+}
+
+func (h *hookDB) AddValueToHeader(value string, header string, key string) error {
+	return h.db.addValueToHeader(value, header, key)
+}
+
+func (h *hookDB) UpdateWhere(predicate Predicate, values map[string]string) (int, error) {
+	rows, err := h.db.where(context.Background(), predicate)
+	if err != nil {
+		return 0, err
+	}
+
+	return h.db.updateWhere(context.Background(), rows, values)
+}
+
+func (h *hookDB) DeleteWhere(predicate Predicate) (int, error) {
+	rows, err := h.db.where(context.Background(), predicate)
+	if err != nil {
+		return 0, err
+	}
+
+	return h.db.deleteWhere(context.Background(), rows)
+}
+
+func (h *hookDB) Increment(key string, header string, delta float64) (float64, error) {
+	return h.db.increment(key, header, delta)
+}
@@ -0,0 +1,262 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func trophies(t *testing.T, db *DBImpl, key string) string {
+	v, err := db.GetRowFromKeyHeader(key).GetValueFromHeader("Trophies")
+	assert.Nil(t, err)
+	return v.GetValue()
+}
+
+func TestHooksOrder(t *testing.T) {
+	db := newNumberDB(t)
+	calls := []string{}
+	record := func(name string) Hook {
+		return func(change *Change) error {
+			calls = append(calls, fmt.Sprintf("%s %s", name, change.Key))
+			return nil
+		}
+	}
+	db.AfterInsert(record("after insert"))
+	db.BeforeInsert(record("before insert 1"))
+	db.BeforeInsert(record("before insert 2"))
+	db.BeforeUpdate(record("before update"))
+	db.AfterUpdate(record("after update"))
+	db.BeforeDelete(record("before delete"))
+	db.AfterDelete(record("after delete"))
+
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+	assert.Nil(t, db.AddValueToHeader("2", "Trophies", "c"))
+	// Setting the same value isn't a change so no hooks run
+	assert.Nil(t, db.AddValueToHeader("2", "Trophies", "c"))
+	assert.Nil(t, db.RemoveRow("c"))
+
+	assert.Equal(t, []string{
+		"before insert 1 c", "before insert 2 c", "after insert c",
+		"before update c", "after update c",
+		"before delete c", "after delete c",
+	}, calls)
+}
+
+func TestHooksChange(t *testing.T) {
+	db := newNumberDB(t)
+	var before, after Change
+	db.BeforeUpdate(func(change *Change) error {
+		before = *change
+		return nil
+	})
+	db.AfterUpdate(func(change *Change) error {
+		after = *change
+		return nil
+	})
+
+	_, err := db.Increment("a", "Trophies", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", before.Key)
+	assert.Equal(t, "10", before.Old["Trophies"])
+	assert.Equal(t, "12", before.New["Trophies"])
+	assert.Equal(t, "100", before.New["Points"])
+	assert.Equal(t, "10", after.Old["Trophies"])
+	assert.Equal(t, "12", after.New["Trophies"])
+	assert.Equal(t, "Test", after.DB.GetName())
+}
+
+func TestHooksTrigger(t *testing.T) {
+	db := newNumberDB(t)
+	db.AddHeader(&Header{"Platinumed", false, VALUE_STRING})
+	db.BeforeUpdate(func(change *Change) error {
+		n, _ := strconv.ParseFloat(change.New["Trophies"], 64)
+		if n >= 20 {
+			change.New["Platinumed"] = "1"
+		}
+		return nil
+	})
+
+	_, err := db.Increment("a", "Trophies", 5)
+	assert.Nil(t, err)
+	v, _ := db.GetRowFromKeyHeader("a").GetValueFromHeader("Platinumed")
+	assert.Equal(t, "", v.GetValue())
+
+	result, err := db.Increment("a", "Trophies", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(20), result)
+	v, _ = db.GetRowFromKeyHeader("a").GetValueFromHeader("Platinumed")
+	assert.Equal(t, "1", v.GetValue())
+}
+
+func TestHooksModify(t *testing.T) {
+	db := newNumberDB(t)
+	db.BeforeInsert(func(change *Change) error {
+		if change.New["Trophies"] == "" {
+			change.New["Trophies"] = "0"
+		}
+		return nil
+	})
+	db.BeforeUpdate(func(change *Change) error {
+		if change.New["Trophies"] == "bad" {
+			change.New["Points"] = "lots"
+		}
+		if change.New["Trophies"] == "key" {
+			change.New["Title"] = "z"
+		}
+		return nil
+	})
+
+	assert.Nil(t, db.AddRow(newNumberRow("c", "")))
+	assert.Equal(t, "0", trophies(t, db, "c"))
+
+	err := db.AddValueToHeader("bad", "Trophies", "a")
+	assert.Equal(t, fmt.Sprintf(notANumberError, "lots"), err.Error())
+	assert.Equal(t, "10", trophies(t, db, "a"))
+
+	err = db.AddValueToHeader("key", "Trophies", "a")
+	assert.Equal(t, fmt.Sprintf(updateKeyHeaderError, "Title"), err.Error())
+	assert.Equal(t, "10", trophies(t, db, "a"))
+}
+
+func TestHooksVeto(t *testing.T) {
+	db := newNumberDB(t)
+	rejected := errors.New("rejected")
+	db.BeforeInsert(func(change *Change) error {
+		if change.New["Trophies"] == "0" {
+			return rejected
+		}
+		return nil
+	})
+	db.BeforeUpdate(func(change *Change) error {
+		if change.Key == "b" {
+			return rejected
+		}
+		return nil
+	})
+	db.BeforeDelete(func(change *Change) error {
+		if change.Old["Points"] == "100" {
+			return rejected
+		}
+		return nil
+	})
+
+	assert.Equal(t, rejected, db.AddRow(newNumberRow("c", "0")))
+	assert.Nil(t, db.GetRowFromKeyHeader("c"))
+
+	errs, err := db.AddRows([]RowI{newNumberRow("c", "1"), newNumberRow("d", "0")}, AddRowsOptions{})
+	assert.Equal(t, []error{nil, rejected}, errs)
+	assert.Nil(t, err)
+	assert.NotNil(t, db.GetRowFromKeyHeader("c"))
+
+	// A rejected row leaves every row untouched
+	n, err := db.UpdateWhere(nil, map[string]string{"Trophies": "5"})
+	assert.Equal(t, rejected, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, "10", trophies(t, db, "a"))
+
+	n, err = db.DeleteWhere(nil)
+	assert.Equal(t, rejected, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 3, len(db.GetRows()))
+
+	assert.Equal(t, rejected, db.RemoveRow("a"))
+	assert.Nil(t, db.RemoveRow("b"))
+	assert.Nil(t, db.GetRowFromKeyHeader("b"))
+}
+
+func TestHooksAfterInsertError(t *testing.T) {
+	db := newNumberDB(t)
+	failed := errors.New("failed")
+	db.AfterInsert(func(change *Change) error {
+		if change.Key == "d" {
+			return failed
+		}
+		return nil
+	})
+
+	// The rows are added, the hook's error being returned apart from theirs
+	errs, err := db.AddRows([]RowI{newNumberRow("c", "1"), newNumberRow("d", "2"), newNumberRow("a", "3")}, AddRowsOptions{})
+	assert.Equal(t, failed, err)
+	assert.Nil(t, errs[0])
+	assert.Nil(t, errs[1])
+	assert.ErrorIs(t, errs[2], ErrDuplicateKey)
+	assert.NotNil(t, db.GetRowFromKeyHeader("d"))
+
+	errs, err = db.AddRows([]RowI{newNumberRow("e", "1")}, AddRowsOptions{AllOrNothing: true})
+	assert.Nil(t, errs)
+	assert.Nil(t, err)
+}
+
+func TestHooksAddRowsRejected(t *testing.T) {
+	db := newNumberDB(t)
+	calls := []string{}
+	db.BeforeInsert(func(change *Change) error {
+		calls = append(calls, change.Key)
+		// Renaming d makes it a duplicate of a
+		if change.Key == "d" {
+			change.New["Title"] = "a"
+		}
+		return nil
+	})
+
+	// No hook runs when a row fails before them
+	errs, err := db.AddRows([]RowI{newNumberRow("c", "1"), newNumberRow("a", "2")}, AddRowsOptions{AllOrNothing: true})
+	assert.Nil(t, err)
+	assert.ErrorIs(t, errs[0], ErrBatchRejected)
+	assert.ErrorIs(t, errs[1], ErrDuplicateKey)
+	assert.Empty(t, calls)
+
+	// A key changed by a hook is checked too
+	errs, err = db.AddRows([]RowI{newNumberRow("c", "1"), newNumberRow("d", "2")}, AddRowsOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrDuplicateKey)
+	assert.Equal(t, []string{"c", "d"}, calls)
+	assert.Equal(t, "10", trophies(t, db, "a"))
+	assert.Equal(t, 3, len(db.GetRows()))
+}
+
+func TestHooksNestedChanges(t *testing.T) {
+	db := newNumberDB(t)
+	// Removing a row removes the rows sharing its Points
+	db.AfterDelete(func(change *Change) error {
+		_, err := change.DB.DeleteWhere(func(row RowI) bool {
+			v, _ := row.GetValueFromHeader("Points")
+			return v.GetValue() == change.Old["Points"]
+		})
+		return err
+	})
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+	assert.Nil(t, db.AddValueToHeader("50", "Points", "c"))
+
+	assert.Nil(t, db.RemoveRow("b"))
+	assert.Nil(t, db.GetRowFromKeyHeader("c"))
+	assert.NotNil(t, db.GetRowFromKeyHeader("a"))
+
+	// Changes made by hooks are undone with the change that ran them
+	assert.Nil(t, db.Undo())
+	assert.NotNil(t, db.GetRowFromKeyHeader("b"))
+	assert.NotNil(t, db.GetRowFromKeyHeader("c"))
+}
+
+func TestHooksRecursion(t *testing.T) {
+	db := newNumberDB(t)
+	calls := 0
+	db.AfterUpdate(func(change *Change) error {
+		calls++
+		_, err := change.DB.Increment(change.Key, "Trophies", 1)
+		return err
+	})
+
+	_, err := db.Increment("a", "Trophies", 1)
+	assert.Equal(t, fmt.Sprintf(hookRecursionError, maxHookDepth), err.Error())
+	assert.Equal(t, maxHookDepth, calls)
+
+	// Hooks are free to run again once the change finishes
+	_, err = db.Increment("a", "Trophies", 1)
+	assert.Equal(t, fmt.Sprintf(hookRecursionError, maxHookDepth), err.Error())
+	assert.Equal(t, 2*maxHookDepth, calls)
+}
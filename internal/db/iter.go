@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+)
+
+// Returns a function reporting whether a row matches the query, using headers to check the
+// query's Header exists and find its type
+// Returns an error if the Header doesn't exist, the Op is unknown, or the Value isn't a number
+// for the '<' and '>' operators
+func (q Query) Compile(headers []HeaderI) (func(row RowI) (bool, error), error) {
+	if q.Header == "" {
+		return func(row RowI) (bool, error) {
+			return q.Where == nil || q.Where(row), nil
+		}, nil
+	}
+
+	var header HeaderI
+	for _, h := range headers {
+		if h.GetName() == q.Header {
+			header = h
+		}
+	}
+	if header == nil {
+		return nil, &Error{Err: ErrHeaderNotExist, Header: q.Header, Message: fmt.Sprintf(headerNotExistError, q.Header)}
+	}
+
+	var valueF float64
+	switch q.Op {
+	case "", "=":
+	case "<", ">":
+		var err error
+		valueF, err = strconv.ParseFloat(q.Value, 64)
+		if err != nil {
+			return nil, &Error{Err: ErrNotANumber, Value: q.Value, Message: fmt.Sprintf(notANumberError, q.Value)}
+		}
+	default:
+		return nil, &Error{Err: ErrUnknownOperator, Value: q.Op, Message: fmt.Sprintf(unknownOperatorError, q.Op)}
+	}
+
+	return func(row RowI) (bool, error) {
+		v, err := row.GetValueFromHeader(q.Header)
+		if err != nil {
+			return false, err
+		}
+
+		matches := false
+		switch q.Op {
+		case "", "=":
+			matches = v.GetValue() == q.Value
+		default:
+			vF, err := header.Number(v)
+			if err != nil {
+				return false, err
+			}
+			matches = (q.Op == "<" && vF < valueF) || (q.Op == ">" && vF > valueF)
+		}
+
+		return matches && (q.Where == nil || q.Where(row)), nil
+	}, nil
+}
+
+func (db *DBImpl) Iter(query Query) Iterator {
+	return db.IterContext(context.Background(), query)
+}
+
+// Returns an Iterator over the rows matching the query, stopping with ctx's error once it is done
+// The DB stops recording removed rows for it once ctx is done, so an iterator with a context that
+// ends doesn't leak if it isn't closed
+func (db *DBImpl) IterContext(ctx context.Context, query Query) Iterator {
+	it := &rowIterator{db: db, ctx: ctx, removed: map[string]struct{}{}}
+	unlock, err := db.rlockContext(ctx)
+	if err != nil {
+		it.err = err
+		return it
+	}
+	defer unlock()
+
+	headers := []HeaderI{}
+	for h := range db.Headers {
+		headers = append(headers, h)
+	}
+
+	it.match, it.err = query.Compile(headers)
+	if it.err != nil {
+		return it
+	}
+
+	// Only the references to the rows are copied, each row being read when it is reached
+	it.rows = append([]RowI{}, db.Rows.GetRows()...)
+
+	// Registered while the read lock is held so no row can be removed before the iterator is told
+	db.itersMu.Lock()
+	defer db.itersMu.Unlock()
+	if db.iterators == nil {
+		db.iterators = map[*rowIterator]struct{}{}
+	}
+	db.iterators[it] = struct{}{}
+	// Only unregistered here, as the iterator may be in use on another goroutine, Next stopping on
+	// ctx's error by itself
+	it.stop = context.AfterFunc(ctx, it.unregister)
+
+	return it
+}
+
+func (it *rowIterator) Next() bool {
+	it.row = nil
+	for it.err == nil && !it.closed && it.i < len(it.rows) {
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			break
+		}
+
+		row, err := it.next()
+		if err != nil {
+			it.err = err
+			break
+		}
+
+		if row != nil {
+			it.row = row
+			return true
+		}
+	}
+
+	it.release()
+	return false
+}
+
+// Reads the next row under the DB's read lock, returning a copy of it if it matches the query,
+// or nil if it doesn't or was removed since the iterator was created
+func (it *rowIterator) next() (RowI, error) {
+	it.db.mu.RLock()
+	defer it.db.mu.RUnlock()
+
+	row := it.rows[it.i]
+	it.i++
+	if _, removed := it.removed[rowKey(row)]; removed {
+		return nil, nil
+	}
+
+	matches, err := it.match(row)
+	if err != nil || !matches {
+		return nil, err
+	}
+
+	return copyRow(row), nil
+}
+
+func (it *rowIterator) Row() RowI {
+	return it.row
+}
+
+func (it *rowIterator) Err() error {
+	return it.err
+}
+
+func (it *rowIterator) Close() error {
+	it.closed = true
+	it.row = nil
+	it.release()
+
+	return nil
+}
+
+// Stops the DB telling the iterator about removed rows and drops its references to the rows
+func (it *rowIterator) release() {
+	it.rows = nil
+	if it.stop != nil {
+		it.stop()
+	}
+	it.unregister()
+}
+
+func (it *rowIterator) unregister() {
+	it.db.itersMu.Lock()
+	defer it.db.itersMu.Unlock()
+	delete(it.db.iterators, it)
+}
+
+// Tells the open iterators the row with the given key was removed so they skip it
+// The caller must hold the DB's write lock
+func (db *DBImpl) notifyRemoved(key string) {
+	db.itersMu.Lock()
+	defer db.itersMu.Unlock()
+
+	for it := range db.iterators {
+		it.removed[key] = struct{}{}
+	}
+}
+
+// Returns the rows of the iterator as an iter.Seq, closing it once the loop ends, including
+// when it stops early
+// The iterator's Err should be checked after the loop
+func Seq(it Iterator) iter.Seq[RowI] {
+	return func(yield func(RowI) bool) {
+		defer it.Close()
+
+		for it.Next() {
+			if !yield(it.Row()) {
+				return
+			}
+		}
+	}
+}
+
+// Returns copies of the rows, each with its own map and values but sharing the row's headers,
+// which never change
+func detachRows(rows []RowI) []RowI {
+	copies := make([]RowI, len(rows))
+	for i, row := range rows {
+		copies[i] = detachRow(row)
+	}
+
+	return copies
+}
+
+func detachRow(row RowI) RowI {
+	values := map[HeaderI]ValueI{}
+	for h, v := range row.GetRowMap() {
+		values[h] = &Value{v.GetValue()}
+	}
+
+	return &Row{RowMap: values}
+}
+
+// Returns a copy of the row sharing none of its headers or values
+func copyRow(row RowI) RowI {
+	c := &Row{RowMap: map[HeaderI]ValueI{}}
+	for h, v := range row.GetRowMap() {
+		c.AddHeaderWithValue(h.GetName(), h.IsKeyHeader(), h.GetType(), v.GetValue())
+	}
+
+	return c
+}
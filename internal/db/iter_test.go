@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the keys of the rows the iterator returns
+func iterKeys(t *testing.T, it Iterator) []string {
+	keys := []string{}
+	for it.Next() {
+		keys = append(keys, rowKey(it.Row()))
+	}
+	assert.Nil(t, it.Err())
+
+	return keys
+}
+
+func TestIter(t *testing.T) {
+	db := newNumberDB(t)
+
+	assert.Equal(t, []string{"a", "b"}, iterKeys(t, db.Iter(Query{})))
+	assert.Equal(t, []string{"b"}, iterKeys(t, db.Iter(Query{Header: "Points", Value: "50"})))
+	assert.Equal(t, []string{"a"}, iterKeys(t, db.Iter(Query{Header: "Points", Value: "60", Op: ">"})))
+	assert.Equal(t, []string{"b"}, iterKeys(t, db.Iter(Query{Header: "Points", Value: "60", Op: "<"})))
+	assert.Equal(t, []string{"b"}, iterKeys(t, db.Iter(Query{Where: func(row RowI) bool {
+		return rowKey(row) == "b"
+	}})))
+
+	for _, q := range []Query{
+		{Header: "Missing", Value: "1"},
+		{Header: "Points", Value: "many", Op: ">"},
+		{Header: "Points", Value: "1", Op: "!"},
+		// Row b has no Trophies so can't be compared
+		{Header: "Trophies", Value: "1", Op: ">"},
+	} {
+		it := db.Iter(q)
+		for it.Next() {
+		}
+		assert.Error(t, it.Err(), q)
+		assert.Nil(t, it.Row())
+	}
+
+	assert.Equal(t, 0, len(db.iterators))
+}
+
+func TestIterMutation(t *testing.T) {
+	db := newNumberDB(t)
+	assert.Nil(t, db.AddRow(newTestRow(t, db, "c", "1", "1")))
+
+	it := db.Iter(Query{})
+	assert.True(t, it.Next())
+	row := it.Row()
+	assert.Equal(t, "a", rowKey(row))
+
+	// Changes made while iterating don't deadlock, new rows aren't returned, removed rows are
+	// skipped and updated values are seen
+	assert.Nil(t, db.AddValueToHeader("99", "Points", "a"))
+	assert.Nil(t, db.AddRow(newTestRow(t, db, "d", "1", "1")))
+	assert.Nil(t, db.RemoveRow("b"))
+	assert.Nil(t, db.AddValueToHeader("7", "Points", "c"))
+
+	v, _ := row.GetValueFromHeader("Points")
+	assert.Equal(t, "100", v.GetValue())
+
+	assert.True(t, it.Next())
+	assert.Equal(t, "c", rowKey(it.Row()))
+	v, _ = it.Row().GetValueFromHeader("Points")
+	assert.Equal(t, "7", v.GetValue())
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 0, len(db.iterators))
+}
+
+func TestIterClose(t *testing.T) {
+	db := newNumberDB(t)
+
+	it := db.Iter(Query{})
+	assert.Equal(t, 1, len(db.iterators))
+	assert.True(t, it.Next())
+	assert.Nil(t, it.Close())
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Row())
+	assert.Equal(t, 0, len(db.iterators))
+}
+
+func TestIterContext(t *testing.T) {
+	db := newNumberDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	it := db.IterContext(ctx, Query{})
+	assert.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+
+	it = db.IterContext(ctx, Query{})
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+
+	// An iterator that is never closed is dropped once its context is done
+	ctx, cancel = context.WithCancel(context.Background())
+	it = db.IterContext(ctx, Query{})
+	assert.True(t, it.Next())
+	cancel()
+	assert.Eventually(t, func() bool {
+		db.itersMu.Lock()
+		defer db.itersMu.Unlock()
+		return len(db.iterators) == 0
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, db.RemoveRow("b"))
+	assert.Equal(t, 0, len(it.(*rowIterator).removed))
+}
+
+func TestSeq(t *testing.T) {
+	db := newNumberDB(t)
+
+	keys := []string{}
+	for row := range Seq(db.Iter(Query{})) {
+		keys = append(keys, rowKey(row))
+	}
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	// Breaking out of the loop closes the iterator
+	it := db.Iter(Query{})
+	for range Seq(it) {
+		break
+	}
+	assert.False(t, it.Next())
+	assert.Equal(t, 0, len(db.iterators))
+}
+
+func newTestRow(t *testing.T, db DB, key string, trophies string, points string) RowI {
+	row, err := NewRowFromMap(db, map[string]string{"Title": key, "Trophies": trophies, "Points": points})
+	assert.Nil(t, err)
+
+	return row
+}
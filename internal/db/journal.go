@@ -0,0 +1,195 @@
+package db
+
+import "fmt"
+
+// The number of steps kept for Undo until SetUndoDepth is called
+const defaultUndoDepth = 100
+
+// The changes that can be undone and redone, each step holding the events published by one
+// change to the DB, or by every change made in a group
+// Only changed while the DB's write lock is held
+type journal struct {
+	depth   *int
+	undo    [][]Event
+	redo    [][]Event
+	current []Event
+	// The number of groups open, the current step is only finished once they're all closed
+	groups int
+}
+
+func (j *journal) maxDepth() int {
+	if j.depth == nil {
+		return defaultUndoDepth
+	}
+
+	return *j.depth
+}
+
+func (j *journal) record(e Event) {
+	if j.maxDepth() > 0 {
+		j.current = append(j.current, e)
+	}
+}
+
+// Finishes the current step, making it the next step to undo and forgetting the steps that
+// could have been redone
+func (j *journal) commit() {
+	if j.groups > 0 || len(j.current) == 0 {
+		return
+	}
+
+	j.undo = pushStep(j.undo, j.current, j.maxDepth())
+	j.redo = nil
+	j.current = nil
+}
+
+// Forgets every step, keeping the depth and any open groups
+func (j *journal) clear() {
+	j.undo = nil
+	j.redo = nil
+	j.current = nil
+}
+
+// Adds the step to the stack, dropping the oldest steps past depth
+func pushStep(stack [][]Event, step []Event, depth int) [][]Event {
+	stack = append(stack, step)
+	if len(stack) > depth {
+		stack = append([][]Event{}, stack[len(stack)-depth:]...)
+	}
+
+	return stack
+}
+
+func (db *DBImpl) Undo() error {
+	defer db.lockAs("")()
+
+	return db.undo()
+}
+
+func (db *DBImpl) Redo() error {
+	defer db.lockAs("")()
+
+	return db.redo()
+}
+
+func (db *DBImpl) undo() error {
+	if db.journal.groups > 0 {
+		return &Error{Err: ErrUndoGroupOpen, DB: db.Name, Message: undoGroupOpenError}
+	}
+	if len(db.journal.undo) == 0 {
+		return &Error{Err: ErrNothingToUndo, DB: db.Name, Message: nothingToUndoError}
+	}
+
+	step := db.journal.undo[len(db.journal.undo)-1]
+	db.journal.undo = db.journal.undo[:len(db.journal.undo)-1]
+
+	inverse, err := db.invertStep(step)
+	db.journal.redo = pushStep(db.journal.redo, inverse, db.journal.maxDepth())
+	return err
+}
+
+func (db *DBImpl) redo() error {
+	if db.journal.groups > 0 {
+		return &Error{Err: ErrUndoGroupOpen, DB: db.Name, Message: undoGroupOpenError}
+	}
+	if len(db.journal.redo) == 0 {
+		return &Error{Err: ErrNothingToRedo, DB: db.Name, Message: nothingToRedoError}
+	}
+
+	step := db.journal.redo[len(db.journal.redo)-1]
+	db.journal.redo = db.journal.redo[:len(db.journal.redo)-1]
+
+	inverse, err := db.invertStep(step)
+	db.journal.undo = pushStep(db.journal.undo, inverse, db.journal.maxDepth())
+	return err
+}
+
+func (db *DBImpl) BeginUndoGroup() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.journal.groups++
+}
+
+func (db *DBImpl) EndUndoGroup() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.journal.groups > 0 {
+		db.journal.groups--
+	}
+	db.journal.commit()
+}
+
+func (db *DBImpl) SetUndoDepth(depth int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if depth < 0 {
+		depth = 0
+	}
+	db.journal.depth = &depth
+
+	if len(db.journal.undo) > depth {
+		db.journal.undo = append([][]Event{}, db.journal.undo[len(db.journal.undo)-depth:]...)
+	}
+	if len(db.journal.redo) > depth {
+		db.journal.redo = append([][]Event{}, db.journal.redo[len(db.journal.redo)-depth:]...)
+	}
+}
+
+func (db *DBImpl) ClearUndo() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.journal.clear()
+}
+
+// Reverses the events in the step newest first, returning the events published doing so,
+// which reverse the step again
+func (db *DBImpl) invertStep(step []Event) ([]Event, error) {
+	db.journal.current = nil
+	defer func() {
+		db.journal.current = nil
+	}()
+
+	for i := len(step) - 1; i >= 0; i-- {
+		err := db.invert(step[i])
+		if err != nil {
+			return db.journal.current, err
+		}
+	}
+
+	return db.journal.current, nil
+}
+
+// Makes the change that reverses the event, publishing it like any other change
+func (db *DBImpl) invert(e Event) error {
+	switch e.Type {
+	case EVENT_ADD_ROW:
+		row := db.Rows.GetRowFromKeyHeader(e.Key)
+		if row == nil {
+			return &Error{Err: ErrRowNotExist, DB: db.Name, Key: e.Key, Message: fmt.Sprintf(rowNotExistError, e.Key)}
+		}
+		db.removeRow(row)
+	case EVENT_REMOVE_ROW:
+		return db.insertRow(db.newRow(e.Row))
+	case EVENT_UPDATE_VALUE:
+		row := db.Rows.GetRowFromKeyHeader(e.Key)
+		if row == nil {
+			return &Error{Err: ErrRowNotExist, DB: db.Name, Key: e.Key, Message: fmt.Sprintf(rowNotExistError, e.Key)}
+		}
+		db.setValue(row, e.Header, e.OldValue)
+	case EVENT_ADD_HEADER:
+		return db.removeHeader(e.Header)
+	case EVENT_REMOVE_HEADER:
+		db.addHeader(&Header{Name: e.Header, KeyHeader: false, Type: e.HeaderType})
+		for key, value := range e.Values {
+			if row := db.Rows.GetRowFromKeyHeader(key); row != nil {
+				db.setValue(row, e.Header, value)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the DB from newNumberDB with nothing to undo
+func newJournalDB(t *testing.T) *DBImpl {
+	db := newNumberDB(t)
+	db.journal = journal{}
+
+	return db
+}
+
+// Returns the values of every row by key
+func snapshot(db *DBImpl) map[string]map[string]string {
+	rows := map[string]map[string]string{}
+	for _, row := range db.GetRows() {
+		rows[rowKey(row)] = rowValues(row)
+	}
+
+	return rows
+}
+
+func TestUndoRedo(t *testing.T) {
+	db := newJournalDB(t)
+	initial := snapshot(db)
+
+	assert.Equal(t, nothingToUndoError, db.Undo().Error())
+
+	assert.Nil(t, db.AddValueToHeader("12", "Trophies", "a"))
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+	assert.Nil(t, db.RemoveRow("b"))
+	changed := snapshot(db)
+
+	assert.Nil(t, db.Undo())
+	assert.NotNil(t, db.GetRowFromKeyHeader("b"))
+	assert.Nil(t, db.Undo())
+	assert.Nil(t, db.GetRowFromKeyHeader("c"))
+	assert.Nil(t, db.Undo())
+	assert.Equal(t, initial, snapshot(db))
+	assert.Equal(t, nothingToUndoError, db.Undo().Error())
+
+	assert.Nil(t, db.Redo())
+	assert.Nil(t, db.Redo())
+	assert.Nil(t, db.Redo())
+	assert.Equal(t, changed, snapshot(db))
+	assert.Equal(t, nothingToRedoError, db.Redo().Error())
+
+	assert.Nil(t, db.Undo())
+	assert.Nil(t, db.AddValueToHeader("20", "Points", "a"))
+	assert.Equal(t, nothingToRedoError, db.Redo().Error())
+}
+
+func TestUndoRemoveHeader(t *testing.T) {
+	db := newJournalDB(t)
+	initial := snapshot(db)
+
+	assert.Nil(t, db.RemoveHeader("Points"))
+	assert.Equal(t, 2, len(db.GetHeaders()))
+
+	assert.Nil(t, db.Undo())
+	assert.Equal(t, initial, snapshot(db))
+	header := db.GetHeader("Points")
+	assert.NotNil(t, header)
+	assert.Equal(t, VALUE_NUMBER, header.GetType())
+
+	assert.Nil(t, db.Redo())
+	assert.Equal(t, "", db.GetHeader("Points").GetName())
+}
+
+func TestUndoBulk(t *testing.T) {
+	db := newJournalDB(t)
+	initial := snapshot(db)
+
+	n, err := db.UpdateWhere(func(row RowI) bool { return true }, map[string]string{"Points": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Nil(t, db.Undo())
+	assert.Equal(t, initial, snapshot(db))
+}
+
+func TestUndoGroup(t *testing.T) {
+	db := newJournalDB(t)
+	initial := snapshot(db)
+
+	db.BeginUndoGroup()
+	db.AddHeader(&Header{"Genre", false, VALUE_STRING})
+	db.BeginUndoGroup()
+	assert.Nil(t, db.AddValueToHeader("RPG", "Genre", "a"))
+	db.EndUndoGroup()
+	assert.Nil(t, db.AddRow(newNumberRow("c", "1")))
+	db.EndUndoGroup()
+	changed := snapshot(db)
+
+	assert.Nil(t, db.Undo())
+	assert.Equal(t, initial, snapshot(db))
+	assert.Equal(t, "", db.GetHeader("Genre").GetName())
+	assert.Equal(t, nothingToUndoError, db.Undo().Error())
+
+	assert.Nil(t, db.Redo())
+	assert.Equal(t, changed, snapshot(db))
+
+	// A group left open has to be ended before undoing
+	db.BeginUndoGroup()
+	assert.Nil(t, db.RemoveRow("a"))
+	assert.Nil(t, db.RemoveRow("b"))
+	assert.True(t, errors.Is(db.Undo(), ErrUndoGroupOpen))
+	assert.True(t, errors.Is(db.Redo(), ErrUndoGroupOpen))
+	assert.Nil(t, db.GetRowFromKeyHeader("a"))
+	db.EndUndoGroup()
+	assert.Nil(t, db.Undo())
+	assert.Equal(t, changed, snapshot(db))
+}
+
+func TestUndoAfterLoad(t *testing.T) {
+	db, err := New("Games", []HeaderI{&Header{"Title", true, VALUE_STRING}, &Header{"Hours", false, VALUE_NUMBER}}, "Title")
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(db.Undo(), ErrNothingToUndo))
+
+	db, err = NewFromJSON("Games", "", strings.NewReader(`[{"Title": "Jak 2", "Hours": 23}]`), JSON_ARRAY)
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(db.Undo(), ErrNothingToUndo))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteBinary(db, &buf))
+	db, err = ReadBinary(&buf)
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(db.Undo(), ErrNothingToUndo))
+	assert.NotNil(t, db.GetRowFromKeyHeader("Jak 2"))
+
+	// Changes after loading can still be undone
+	assert.Nil(t, db.RemoveRow("Jak 2"))
+	assert.Nil(t, db.Undo())
+	assert.NotNil(t, db.GetRowFromKeyHeader("Jak 2"))
+}
+
+func TestUndoDepth(t *testing.T) {
+	db := newJournalDB(t)
+	db.SetUndoDepth(2)
+
+	for _, v := range []string{"1", "2", "3"} {
+		assert.Nil(t, db.AddValueToHeader(v, "Trophies", "a"))
+	}
+	assert.Nil(t, db.Undo())
+	assert.Nil(t, db.Undo())
+	assert.Equal(t, nothingToUndoError, db.Undo().Error())
+	v, _ := db.GetRowFromKeyHeader("a").GetValueFromHeader("Trophies")
+	assert.Equal(t, "1", v.GetValue())
+
+	db.SetUndoDepth(0)
+	assert.Nil(t, db.AddValueToHeader("5", "Trophies", "a"))
+	assert.Equal(t, nothingToUndoError, db.Undo().Error())
+}
+
+func TestUndoAs(t *testing.T) {
+	db := newJournalDB(t)
+	events, cancel := db.Subscribe(EventFilter{})
+	defer cancel()
+
+	assert.Nil(t, db.AddValueToHeader("12", "Trophies", "a"))
+	<-events
+	assert.Nil(t, db.As("alice").(Undoable).Undo())
+
+	e := <-events
+	assert.Equal(t, "alice", e.Actor)
+	assert.Equal(t, "12", e.OldValue)
+	assert.Equal(t, "10", e.NewValue)
+}
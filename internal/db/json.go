@@ -0,0 +1,356 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// How many rows NewFromJSON reads to guess the header types from, and how many rows the imports
+// add at a time
+const jsonBatchSize = 1000
+
+// Writes every row of the DB to w as a JSON object of header names to values, either as the
+// elements of a JSON array or one object per line
+// VALUE_NUMBER values are written as JSON numbers, or null when empty, and the KeyHeader is
+// written first
+// Numbers are written in their shortest form, while NaN and Inf are written as strings
+func ExportJSON(d DB, w io.Writer, format JSONFormat) error {
+	headers := SortedHeaders(d)
+	bw := bufio.NewWriter(w)
+
+	it := d.Iter(Query{})
+	defer it.Close()
+
+	first := true
+	if format == JSON_ARRAY {
+		bw.WriteString("[")
+	}
+	for it.Next() {
+		if format == JSON_ARRAY {
+			if !first {
+				bw.WriteString(",")
+			}
+			bw.WriteString("\n")
+		}
+		first = false
+
+		err := writeJSONObject(bw, headers, it.Row())
+		if err != nil {
+			return err
+		}
+
+		if format == JSON_LINES {
+			bw.WriteString("\n")
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if format == JSON_ARRAY {
+		bw.WriteString("\n]\n")
+	}
+
+	return bw.Flush()
+}
+
+// Writes the row as a JSON object with its values in the order of the headers
+func writeJSONObject(w *bufio.Writer, headers []HeaderI, row RowI) error {
+	w.WriteString("{")
+	for i, h := range headers {
+		if i > 0 {
+			w.WriteString(",")
+		}
+
+		name, err := json.Marshal(h.GetName())
+		if err != nil {
+			return err
+		}
+		w.Write(name)
+		w.WriteString(":")
+
+		value := ""
+		if v, err := row.GetValueFromHeader(h.GetName()); err == nil {
+			value = v.GetValue()
+		}
+
+		number, isNumber := "", false
+		if h.IsNumber() && value != "" {
+			number, isNumber = FormatNumber(value)
+		}
+
+		switch {
+		case h.IsNumber() && value == "":
+			w.WriteString("null")
+		case isNumber:
+			w.WriteString(number)
+		default:
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			w.Write(data)
+		}
+	}
+	w.WriteString("}")
+
+	return nil
+}
+
+// Adds the rows read from r, written in the same form as ExportJSON, to the DB, returning how
+// many were added
+// Rows are read and added in batches so the input never needs to fit in memory
+// Returns an error if the input isn't valid, an object has a header the DB doesn't have, a value
+// doesn't match its header's type or a row can't be added, in which case the rows before the
+// batch it was in have been added
+func ImportJSON(d DB, r io.Reader, format JSONFormat) (int, error) {
+	return importJSON(d, newJSONReader(r, format), nil)
+}
+
+// Creates a DB named name from the rows read from r, written in the same form as ExportJSON,
+// guessing the type of each header from the values in the first rows
+// A header is a VALUE_NUMBER header if every value it has in those rows is a JSON number or null,
+// and at least one is a number
+// If keyHeader is empty, the first header of the first object is used
+// Returns an error if there are no rows or any of the errors ImportJSON returns
+func NewFromJSON(name string, keyHeader string, r io.Reader, format JSONFormat) (*DBImpl, error) {
+	reader := newJSONReader(r, format)
+
+	// Read the first rows to guess the types from, keeping them to add once the DB is created
+	sample := []jsonObject{}
+	for len(sample) < jsonBatchSize {
+		object, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sample = append(sample, object)
+	}
+	if len(sample) == 0 {
+		return nil, &Error{Err: ErrInvalidJSON, DB: name, Message: fmt.Sprintf(noJSONRowsError, name)}
+	}
+
+	if keyHeader == "" {
+		keyHeader = sample[0].keys[0]
+	}
+
+	names := []string{}
+	numbers := map[string]bool{}
+	for _, object := range sample {
+		for _, header := range object.keys {
+			number, seen := numbers[header]
+			if !seen {
+				names = append(names, header)
+				number = true
+			}
+
+			switch {
+			case object.numbers[header]:
+			case object.values[header] == "":
+			default:
+				number = false
+			}
+			numbers[header] = number
+		}
+	}
+
+	headers := []HeaderI{}
+	for _, header := range names {
+		t := VALUE_STRING
+		if numbers[header] && hasNumber(sample, header) {
+			t = VALUE_NUMBER
+		}
+		headers = append(headers, &Header{Name: header, KeyHeader: header == keyHeader, Type: t})
+	}
+
+	db, err := New(name, headers, keyHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = importJSON(db, reader, sample)
+	if err != nil {
+		return nil, err
+	}
+	db.journal.clear()
+
+	return db, nil
+}
+
+// Returns true if any of the objects has a JSON number for the header
+func hasNumber(objects []jsonObject, header string) bool {
+	for _, object := range objects {
+		if object.numbers[header] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Adds the objects given followed by those read from the reader to the DB in batches
+func importJSON(d DB, reader *jsonReader, objects []jsonObject) (int, error) {
+	added := 0
+	batch := make([]RowI, 0, jsonBatchSize)
+	indexes := make([]int, 0, jsonBatchSize)
+
+	flush := func() error {
+		errs, err := d.AddRows(batch, AddRowsOptions{AllOrNothing: true})
+		if i, err := FirstRowError(errs); err != nil {
+			return &Error{Err: ErrInvalidJSON, DB: d.GetName(), Message: fmt.Sprintf(jsonRowError, indexes[i], err), Cause: err}
+		}
+		added += len(batch)
+		batch = batch[:0]
+		indexes = indexes[:0]
+
+		// The rows were added even if a hook failed
+		return err
+	}
+
+	for i := 0; ; i++ {
+		var object jsonObject
+		if i < len(objects) {
+			object = objects[i]
+		} else {
+			var err error
+			object, err = reader.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return added, err
+			}
+		}
+
+		row, err := object.row(d)
+		if err != nil {
+			return added, &Error{Err: ErrInvalidJSON, DB: d.GetName(), Message: fmt.Sprintf(jsonRowError, i+1, err), Cause: err}
+		}
+		batch = append(batch, row)
+		indexes = append(indexes, i+1)
+
+		if len(batch) == jsonBatchSize {
+			if err := flush(); err != nil {
+				return added, err
+			}
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return added, err
+		}
+	}
+
+	return added, nil
+}
+
+func newJSONReader(r io.Reader, format JSONFormat) *jsonReader {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	return &jsonReader{dec: dec, format: format}
+}
+
+// Returns the next object, or io.EOF once there are none left
+func (r *jsonReader) next() (jsonObject, error) {
+	if r.done {
+		return jsonObject{}, io.EOF
+	}
+	if r.format == JSON_ARRAY && !r.started {
+		r.started = true
+		if err := r.expect(json.Delim('[')); err != nil {
+			return jsonObject{}, err
+		}
+	}
+
+	if !r.dec.More() {
+		if r.format == JSON_ARRAY {
+			if err := r.expect(json.Delim(']')); err != nil {
+				return jsonObject{}, err
+			}
+		}
+		r.done = true
+		return jsonObject{}, io.EOF
+	}
+
+	r.count++
+	if err := r.expect(json.Delim('{')); err != nil {
+		return jsonObject{}, err
+	}
+
+	object := jsonObject{values: map[string]string{}, numbers: map[string]bool{}}
+	for r.dec.More() {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return jsonObject{}, r.invalid(err.Error())
+		}
+		header := tok.(string)
+
+		tok, err = r.dec.Token()
+		if err != nil {
+			return jsonObject{}, r.invalid(err.Error())
+		}
+
+		switch v := tok.(type) {
+		case string:
+			object.values[header] = v
+		case json.Number:
+			object.values[header] = v.String()
+			object.numbers[header] = true
+		case nil:
+			object.values[header] = ""
+		default:
+			return jsonObject{}, r.invalid(fmt.Sprintf(jsonValueError, header))
+		}
+		object.keys = append(object.keys, header)
+	}
+
+	if err := r.expect(json.Delim('}')); err != nil {
+		return jsonObject{}, err
+	}
+	if len(object.keys) == 0 {
+		return jsonObject{}, r.invalid(emptyJSONObjectError)
+	}
+
+	return object, nil
+}
+
+// Reads the next token, returning an error if it isn't delim
+func (r *jsonReader) expect(delim json.Delim) error {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return r.invalid(err.Error())
+	}
+
+	if tok != delim {
+		return r.invalid(fmt.Sprintf(jsonTokenError, delim, tok))
+	}
+
+	return nil
+}
+
+// Returns an error for the object being read
+func (r *jsonReader) invalid(reason string) error {
+	return &Error{Err: ErrInvalidJSON, Message: fmt.Sprintf(jsonRowError, r.count, reason)}
+}
+
+// Creates a row for the DB from the object, checking each value matches its header's type
+func (o jsonObject) row(d DB) (RowI, error) {
+	for _, header := range o.keys {
+		h := d.GetHeader(header)
+		if h.GetName() == "" {
+			return nil, &Error{Err: ErrHeaderNotExist, DB: d.GetName(), Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		err := ValidateValue(h, o.values[header])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewRowFromMap(d, o.values)
+}
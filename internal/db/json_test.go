@@ -0,0 +1,146 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportJSON(t *testing.T) {
+	db := newNumberDB(t)
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, ExportJSON(db, buf, JSON_ARRAY))
+	assert.Equal(t, "[\n"+
+		`{"Title":"a","Points":100,"Trophies":10},`+"\n"+
+		`{"Title":"b","Points":50,"Trophies":null}`+"\n"+
+		"]\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, ExportJSON(db, buf, JSON_LINES))
+	assert.Equal(t, `{"Title":"a","Points":100,"Trophies":10}`+"\n"+
+		`{"Title":"b","Points":50,"Trophies":null}`+"\n", buf.String())
+
+	empty, err := New("Empty", []HeaderI{&Header{"Title", true, VALUE_STRING}}, "Title")
+	assert.Nil(t, err)
+	buf.Reset()
+	assert.Nil(t, ExportJSON(empty, buf, JSON_ARRAY))
+	assert.Equal(t, "[\n]\n", buf.String())
+}
+
+func TestExportJSONNumbers(t *testing.T) {
+	db, err := New("Numbers", []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"N", false, VALUE_NUMBER}}, "ID")
+	assert.Nil(t, err)
+	values := []string{"+1", "1", "007", ".5", "-0.5", "1e3", "0x1p4", "NaN", "Inf", "-Inf"}
+	for i, value := range values {
+		row, err := NewRowFromMap(db, map[string]string{"ID": fmt.Sprint(i), "N": value})
+		assert.Nil(t, err)
+		assert.Nil(t, db.AddRow(row))
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, ExportJSON(db, buf, JSON_LINES))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, len(values), len(lines))
+	want := []string{"1", "1", "7", "0.5", "-0.5", "1000", "16", `"NaN"`, `"Inf"`, `"-Inf"`}
+	for i, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), line)
+		assert.Equal(t, fmt.Sprintf(`{"ID":"%d","N":%s}`, i, want[i]), line)
+	}
+
+	// Every value reads back as a number
+	imported, err := New("Numbers", []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"N", false, VALUE_NUMBER}}, "ID")
+	assert.Nil(t, err)
+	n, err := ImportJSON(imported, buf, JSON_LINES)
+	assert.Nil(t, err)
+	assert.Equal(t, len(values), n)
+	assert.Equal(t, "NaN", getValue(t, imported, "7", "N"))
+}
+
+func TestImportJSON(t *testing.T) {
+	for _, format := range []JSONFormat{JSON_ARRAY, JSON_LINES} {
+		buf := &bytes.Buffer{}
+		assert.Nil(t, ExportJSON(newNumberDB(t), buf, format))
+
+		db, err := New("Test", []HeaderI{
+			&Header{"Title", true, VALUE_STRING},
+			&Header{"Trophies", false, VALUE_NUMBER},
+			&Header{"Points", false, VALUE_NUMBER},
+		}, "Title")
+		assert.Nil(t, err)
+
+		n, err := ImportJSON(db, buf, format)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, "50", getValue(t, db, "b", "Points"))
+		assert.Equal(t, "", getValue(t, db, "b", "Trophies"))
+
+		// Importing the same rows again fails on the first duplicate key
+		buf.Reset()
+		assert.Nil(t, ExportJSON(newNumberDB(t), buf, format))
+		n, err = ImportJSON(db, buf, format)
+		assert.Equal(t, 0, n)
+		assert.True(t, errors.Is(err, ErrInvalidJSON))
+		assert.True(t, errors.Is(err, ErrDuplicateKey))
+	}
+
+	for input, sentinel := range map[string]error{
+		`{"Title":"c","Missing":1}`:     ErrHeaderNotExist,
+		`{"Title":"c","Points":"many"}`: ErrNotANumber,
+		`{"Title":"c","Points":[1]}`:    ErrInvalidJSON,
+		`{}`:                            ErrInvalidJSON,
+		`{"Title":"c"`:                  ErrInvalidJSON,
+		`["Title"]`:                     ErrInvalidJSON,
+	} {
+		_, err := ImportJSON(newNumberDB(t), strings.NewReader(input), JSON_LINES)
+		assert.True(t, errors.Is(err, sentinel), input)
+	}
+
+	_, err := ImportJSON(newNumberDB(t), strings.NewReader(`{"Title":"c"}`), JSON_ARRAY)
+	assert.True(t, errors.Is(err, ErrInvalidJSON))
+}
+
+func TestNewFromJSON(t *testing.T) {
+	input := `{"Name":"a","Age":30,"Score":null,"City":"Leeds"}
+{"Name":"b","Age":41.5,"Score":null,"City":"12"}
+{"Name":"c","Age":null,"Score":null}
+`
+	db, err := NewFromJSON("People", "", strings.NewReader(input), JSON_LINES)
+	assert.Nil(t, err)
+	assert.Equal(t, "Name", db.GetKeyHeader())
+	assert.True(t, db.GetHeader("Age").IsNumber())
+	// Headers with no numbers, or only nulls, are strings
+	assert.False(t, db.GetHeader("Score").IsNumber())
+	assert.False(t, db.GetHeader("City").IsNumber())
+	assert.Equal(t, 3, len(db.GetRows()))
+	assert.Equal(t, "41.5", getValue(t, db, "b", "Age"))
+
+	db, err = NewFromJSON("People", "City", strings.NewReader(`[{"Name":"a","City":"Leeds"}]`), JSON_ARRAY)
+	assert.Nil(t, err)
+	assert.Equal(t, "City", db.GetKeyHeader())
+
+	// Rows after those the types are guessed from must still match them
+	lines := []string{}
+	for i := 0; i < jsonBatchSize; i++ {
+		lines = append(lines, fmt.Sprintf(`{"Name":"%d","Age":%d}`, i, i))
+	}
+	lines = append(lines, `{"Name":"late","Age":"old"}`)
+	_, err = NewFromJSON("People", "", strings.NewReader(strings.Join(lines, "\n")), JSON_LINES)
+	assert.True(t, errors.Is(err, ErrNotANumber))
+
+	_, err = NewFromJSON("People", "", strings.NewReader("[]"), JSON_ARRAY)
+	assert.True(t, errors.Is(err, ErrInvalidJSON))
+}
+
+// Returns the value the row with the key has for the header
+func getValue(t *testing.T, db DB, key string, header string) string {
+	v, err := db.GetRowFromKeyHeader(key).GetValueFromHeader(header)
+	assert.Nil(t, err)
+
+	return v.GetValue()
+}
@@ -0,0 +1,753 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Data pages hold row records in slots, with the slot array growing from the start of the page
+// after the header and the records growing from the end
+// A slot is the offset and length of its record, both zero once the record is deleted, and slots
+// are reused so a record's slot never changes while it's stored
+// Records longer than maxInlineRecord are kept in a chain of overflow pages, with the slot holding
+// where the chain starts and how long the record is
+const (
+	// The size of the type, slot count and free space end fields at the start of a data page
+	dataHeaderSize = 5
+	slotSize       = 4
+	// The size of the type, next page and length fields at the start of an overflow page
+	overflowHeaderSize = 7
+
+	maxInlineRecord = PAGE_SIZE / 4
+	// Pages with less free space than this aren't tracked as having any
+	minFreeSpace = 32
+)
+
+// How a record is kept in its slot
+const (
+	recordInline byte = iota
+	recordOverflow
+)
+
+// Opens the page file at path as rows, creating it if it doesn't exist, keeping at most poolPages
+// pages in memory
+// Returns an error if the file isn't a page file
+func OpenPagedRows(path string, poolPages int) (*PagedRows, error) {
+	p, err := openPager(path, poolPages)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PagedRows{pager: p, free: map[uint32]int{}}
+	err = r.scanFreeSpace()
+	if err != nil {
+		p.file.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Finds the free space of every data page, which isn't stored in the file
+func (r *PagedRows) scanFreeSpace() error {
+	for id := uint32(1); id < r.pager.pages; id++ {
+		f, err := r.pager.pool.get(id)
+		if err != nil {
+			return err
+		}
+		if f.data[0] == pageData {
+			r.trackFree(id, f.data)
+		}
+		r.pager.pool.release(f, false)
+	}
+
+	return nil
+}
+
+// Writes every change to the file
+func (r *PagedRows) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.fail(r.pager.sync())
+}
+
+// Writes every change to the file and closes it
+func (r *PagedRows) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.fail(r.pager.close())
+}
+
+// Returns the first error reading or writing the file, including those from methods that can't
+// return one
+func (r *PagedRows) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Returns the number of rows
+func (r *PagedRows) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int(r.pager.rows)
+}
+
+// Returns a view of every row in KeyHeader order
+// Each row is read from the file when it's used, and changes made through it are written back
+func (r *PagedRows) GetRows() []RowI {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rows := make([]RowI, 0, r.pager.rows)
+	err := r.pager.btreeScan(func(key string, _ rid) bool {
+		rows = append(rows, r.row(key))
+		return true
+	})
+	r.fail(err)
+
+	return rows
+}
+
+func (r *PagedRows) AddRow(row RowI) error {
+	return r.AddRows([]RowI{row})
+}
+
+func (r *PagedRows) AddRows(rows []RowI) error {
+	return r.addRows(rows, false)
+}
+
+func (r *PagedRows) addCheckedRows(rows []RowI) error {
+	return r.addRows(rows, true)
+}
+
+// Adds the rows, checking first that their KeyHeader values are unique unless checked is true
+func (r *PagedRows) addRows(rows []RowI, checked bool) error {
+	// Read the rows before locking in case they're views of these rows
+	headers := make([]HeaderI, len(rows))
+	keys := make([]string, len(rows))
+	records := make([][]byte, len(rows))
+	for i, row := range rows {
+		h, v := row.GetKeyHeaderAndValue()
+		if h == nil {
+			return &Error{Err: ErrKeyHeaderEmpty, Message: keyHeaderMissingError}
+		}
+		headers[i], keys[i] = h, v.GetValue()
+		records[i] = encodeRow(row.GetRowMap())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	// Check every row first so none are added if any can't be
+	seen := make(map[string]struct{}, len(rows))
+	for i, key := range keys {
+		h := headers[i]
+		if r.pager.keyHeader == "" && len(h.GetName()) > PAGED_MAX_KEY_HEADER {
+			return &Error{Err: ErrKeyTooLong, Header: h.GetName(), Message: fmt.Sprintf(keyHeaderTooLongError, len(h.GetName()), PAGED_MAX_KEY_HEADER)}
+		}
+		if len(key) > PAGED_MAX_KEY {
+			return &Error{Err: ErrKeyTooLong, Header: h.GetName(), Key: key, Message: fmt.Sprintf(keyTooLongError, len(key), PAGED_MAX_KEY)}
+		}
+
+		if checked {
+			continue
+		}
+
+		_, exists, err := r.pager.btreeGet(key)
+		if err != nil {
+			return r.fail(err)
+		}
+		if _, dup := seen[key]; dup || exists {
+			return &Error{Err: ErrDuplicateKey, Header: h.GetName(), Key: key, Message: fmt.Sprintf(keyHeaderValueExistsError, h.GetName(), key)}
+		}
+		seen[key] = struct{}{}
+	}
+
+	for i, key := range keys {
+		if r.pager.keyHeader == "" {
+			r.pager.keyHeader = headers[i].GetName()
+			r.pager.keyType = headers[i].GetType()
+		}
+
+		id, err := r.insertRecord(records[i])
+		if err != nil {
+			return r.fail(err)
+		}
+		err = r.pager.btreeInsert(key, id)
+		if err != nil {
+			return r.fail(err)
+		}
+		r.pager.rows++
+	}
+
+	return nil
+}
+
+func (r *PagedRows) DeleteRow(row RowI) {
+	_, v := row.GetKeyHeaderAndValue()
+	r.DeleteRowWithValue(v.GetValue())
+}
+
+func (r *PagedRows) DeleteRowWithValue(keyValue string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, found, err := r.pager.btreeGet(keyValue)
+	if err != nil || !found {
+		r.fail(err)
+		return
+	}
+
+	if err := r.deleteRecord(id); err != nil {
+		r.fail(err)
+		return
+	}
+	if _, err := r.pager.btreeDelete(keyValue); err != nil {
+		r.fail(err)
+		return
+	}
+	r.pager.rows--
+}
+
+func (r *PagedRows) RemoveHeader(header string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := []string{}
+	err := r.pager.btreeScan(func(key string, _ rid) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		r.fail(err)
+		return
+	}
+
+	for _, key := range keys {
+		err := r.update(key, func(row *Row) error {
+			return row.RemoveHeader(header)
+		})
+		if err != nil {
+			r.fail(err)
+			return
+		}
+	}
+}
+
+func (r *PagedRows) AddValueToRowWithKeyHeader(value string, header string, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.update(key, func(row *Row) error {
+		row.UpdateHeaderValue(header, value)
+		return nil
+	})
+	// Like Rows, a row that doesn't exist is left alone rather than being an error
+	if !errors.Is(err, ErrRowNotExist) {
+		r.fail(err)
+	}
+}
+
+// Returns a view of the row with the KeyHeader value, found through the B+tree without reading
+// any other row
+func (r *PagedRows) GetRowFromKeyHeader(keyHeaderValue string) RowI {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, found, err := r.pager.btreeGet(keyHeaderValue)
+	if err != nil || !found {
+		r.fail(err)
+		return nil
+	}
+
+	return r.row(keyHeaderValue)
+}
+
+func (r *PagedRows) GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rows := make([]RowI, 0)
+	var scanErr error
+	err := r.pager.btreeScan(func(key string, id rid) bool {
+		row, err := r.readRow(id)
+		if err != nil {
+			scanErr = r.fail(err)
+			return false
+		}
+
+		v, err := row.GetValueFromHeader(header)
+		if err != nil {
+			scanErr = err
+			return false
+		}
+		if v.GetValue() == value {
+			rows = append(rows, r.row(key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	return rows, nil
+}
+
+// Returns a view of the row with the key
+func (r *PagedRows) row(key string) *pagedRow {
+	return &pagedRow{rows: r, key: key, header: &Header{r.pager.keyHeader, true, r.pager.keyType}}
+}
+
+// Keeps the first error reading or writing the file for Err, returning err
+func (r *PagedRows) fail(err error) error {
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+
+	return err
+}
+
+// Returns the row with the key read from the file
+func (r *PagedRows) load(key string) (*Row, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, found, err := r.pager.btreeGet(key)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	if !found {
+		return nil, &Error{Err: ErrRowNotExist, Key: key, Message: fmt.Sprintf(rowNotExistError, key)}
+	}
+
+	row, err := r.readRow(id)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+
+	return row, nil
+}
+
+// Reads the row with the key, changes it with fn and writes it back, moving its record if it no
+// longer fits in its page
+func (r *PagedRows) update(key string, fn func(row *Row) error) error {
+	id, found, err := r.pager.btreeGet(key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &Error{Err: ErrRowNotExist, Key: key, Message: fmt.Sprintf(rowNotExistError, key)}
+	}
+
+	row, err := r.readRow(id)
+	if err != nil {
+		return err
+	}
+	err = fn(row)
+	if err != nil {
+		return err
+	}
+
+	// The old record is only deleted once the B+tree points at the new one, so a failed write
+	// leaves the row as it was
+	moved, err := r.insertRecord(encodeRow(row.RowMap))
+	if err != nil {
+		return err
+	}
+	err = r.pager.btreeInsert(key, moved)
+	if err != nil {
+		r.deleteRecord(moved)
+		return err
+	}
+
+	return r.deleteRecord(id)
+}
+
+func (r *PagedRows) readRow(id rid) (*Row, error) {
+	data, err := r.readRecord(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRow(&binaryPayload{data: data})
+}
+
+// Stores the record in a data page with room for it, or a new one, returning where it is
+func (r *PagedRows) insertRecord(record []byte) (rid, error) {
+	stored, err := r.storeRecord(record)
+	if err != nil {
+		return rid{}, err
+	}
+
+	f, err := r.pageWithSpace(len(stored))
+	if err != nil {
+		return rid{}, err
+	}
+	defer r.pager.pool.release(f, true)
+
+	data := f.data
+	count := int(binary.BigEndian.Uint16(data[1:]))
+	slot := count
+	for i := 0; i < count; i++ {
+		if binary.BigEndian.Uint16(data[dataHeaderSize+i*slotSize:]) == 0 {
+			slot = i
+			break
+		}
+	}
+
+	need := len(stored)
+	if slot == count {
+		need += slotSize
+	}
+	end := int(binary.BigEndian.Uint16(data[3:]))
+	if end-need < dataHeaderSize+count*slotSize {
+		end = compactPage(data)
+	}
+	if slot == count {
+		count++
+		binary.BigEndian.PutUint16(data[1:], uint16(count))
+	}
+
+	offset := end - len(stored)
+	copy(data[offset:], stored)
+	binary.BigEndian.PutUint16(data[3:], uint16(offset))
+	binary.BigEndian.PutUint16(data[dataHeaderSize+slot*slotSize:], uint16(offset))
+	binary.BigEndian.PutUint16(data[dataHeaderSize+slot*slotSize+2:], uint16(len(stored)))
+
+	r.trackFree(f.id, data)
+	r.last = f.id
+
+	return rid{page: f.id, slot: uint16(slot)}, nil
+}
+
+// Returns the record as it's kept in its slot, writing it to overflow pages if it's too long
+func (r *PagedRows) storeRecord(record []byte) ([]byte, error) {
+	if len(record) <= maxInlineRecord {
+		return append([]byte{recordInline}, record...), nil
+	}
+
+	// Write the chain from the end so each page can point to the next
+	next := noPage
+	capacity := PAGE_SIZE - overflowHeaderSize
+	for start := (len(record) - 1) / capacity * capacity; start >= 0; start -= capacity {
+		end := min(start+capacity, len(record))
+		f, err := r.pager.allocate(pageOverflow)
+		if err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint32(f.data[1:], next)
+		binary.BigEndian.PutUint16(f.data[5:], uint16(end-start))
+		copy(f.data[overflowHeaderSize:], record[start:end])
+		next = f.id
+		r.pager.pool.release(f, true)
+	}
+
+	stored := []byte{recordOverflow}
+	stored = binary.BigEndian.AppendUint32(stored, next)
+	return binary.BigEndian.AppendUint32(stored, uint32(len(record))), nil
+}
+
+// Returns a pinned data page with room for a record of the given size and a new slot,
+// preferring the page last written to, and allocating a page if none has room
+func (r *PagedRows) pageWithSpace(size int) (*frame, error) {
+	need := size + slotSize
+	id, found := r.last, r.free[r.last] >= need
+	if !found {
+		for page, free := range r.free {
+			if free >= need {
+				id, found = page, true
+				break
+			}
+		}
+	}
+	if found {
+		return r.pager.pool.get(id)
+	}
+
+	f, err := r.pager.allocate(pageData)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(f.data[3:], PAGE_SIZE)
+
+	return f, nil
+}
+
+func (r *PagedRows) readRecord(id rid) ([]byte, error) {
+	f, err := r.pager.pool.get(id.page)
+	if err != nil {
+		return nil, err
+	}
+	defer r.pager.pool.release(f, false)
+
+	stored, err := r.slot(f, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored[0] == recordInline {
+		return append([]byte{}, stored[1:]...), nil
+	}
+
+	next := binary.BigEndian.Uint32(stored[1:])
+	length := int(binary.BigEndian.Uint32(stored[5:]))
+	record := make([]byte, 0, length)
+	for next != noPage && len(record) < length {
+		o, err := r.pager.pool.get(next)
+		if err != nil {
+			return nil, err
+		}
+		if o.data[0] != pageOverflow {
+			r.pager.pool.release(o, false)
+			return nil, r.pager.invalid(fmt.Sprintf(pagedOverflowError, next))
+		}
+		n := int(binary.BigEndian.Uint16(o.data[5:]))
+		record = append(record, o.data[overflowHeaderSize:overflowHeaderSize+n]...)
+		next = binary.BigEndian.Uint32(o.data[1:])
+		r.pager.pool.release(o, false)
+	}
+	if len(record) != length {
+		return nil, r.pager.invalid(fmt.Sprintf(pagedOverflowError, id.page))
+	}
+
+	return record, nil
+}
+
+// Removes the record, freeing its overflow pages
+func (r *PagedRows) deleteRecord(id rid) error {
+	f, err := r.pager.pool.get(id.page)
+	if err != nil {
+		return err
+	}
+	defer r.pager.pool.release(f, true)
+
+	stored, err := r.slot(f, id)
+	if err != nil {
+		return err
+	}
+	if stored[0] == recordOverflow {
+		for next := binary.BigEndian.Uint32(stored[1:]); next != noPage; {
+			o, err := r.pager.pool.get(next)
+			if err != nil {
+				return err
+			}
+			following := binary.BigEndian.Uint32(o.data[1:])
+			r.pager.pool.release(o, false)
+
+			err = r.pager.free(next)
+			if err != nil {
+				return err
+			}
+			next = following
+		}
+	}
+
+	binary.BigEndian.PutUint32(f.data[dataHeaderSize+int(id.slot)*slotSize:], 0)
+	r.trackFree(f.id, f.data)
+	r.last = f.id
+
+	return nil
+}
+
+// Returns the bytes of the record in the slot of the pinned page
+func (r *PagedRows) slot(f *frame, id rid) ([]byte, error) {
+	data := f.data
+	count := int(binary.BigEndian.Uint16(data[1:]))
+	if data[0] != pageData || int(id.slot) >= count {
+		return nil, r.pager.invalid(fmt.Sprintf(pagedSlotError, id.slot, id.page))
+	}
+
+	offset := int(binary.BigEndian.Uint16(data[dataHeaderSize+int(id.slot)*slotSize:]))
+	length := int(binary.BigEndian.Uint16(data[dataHeaderSize+int(id.slot)*slotSize+2:]))
+	if offset == 0 || length == 0 || offset+length > PAGE_SIZE {
+		return nil, r.pager.invalid(fmt.Sprintf(pagedSlotError, id.slot, id.page))
+	}
+
+	stored := data[offset : offset+length]
+	if stored[0] == recordOverflow && length != 9 {
+		return nil, r.pager.invalid(fmt.Sprintf(pagedSlotError, id.slot, id.page))
+	}
+
+	return stored, nil
+}
+
+// Records how much free space the data page has, forgetting pages with too little to use
+func (r *PagedRows) trackFree(id uint32, data []byte) {
+	free := dataPageFree(data)
+	if free < minFreeSpace {
+		delete(r.free, id)
+		return
+	}
+	r.free[id] = free
+}
+
+// Returns the bytes free in a data page once its deleted records are compacted
+func dataPageFree(data []byte) int {
+	count := int(binary.BigEndian.Uint16(data[1:]))
+	used := dataHeaderSize + count*slotSize
+	for i := 0; i < count; i++ {
+		used += int(binary.BigEndian.Uint16(data[dataHeaderSize+i*slotSize+2:]))
+	}
+
+	return PAGE_SIZE - used
+}
+
+// Moves the records of the data page to its end so the space left by deleted records is together,
+// keeping every record in its slot, and returns where the records now start
+func compactPage(data []byte) int {
+	count := int(binary.BigEndian.Uint16(data[1:]))
+	records := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		offset := int(binary.BigEndian.Uint16(data[dataHeaderSize+i*slotSize:]))
+		length := int(binary.BigEndian.Uint16(data[dataHeaderSize+i*slotSize+2:]))
+		if offset != 0 {
+			records[i] = append([]byte{}, data[offset:offset+length]...)
+		}
+	}
+
+	end := PAGE_SIZE
+	for i, record := range records {
+		if record == nil {
+			continue
+		}
+		end -= len(record)
+		copy(data[end:], record)
+		binary.BigEndian.PutUint16(data[dataHeaderSize+i*slotSize:], uint16(end))
+	}
+	binary.BigEndian.PutUint16(data[3:], uint16(end))
+
+	return end
+}
+
+// Encodes the values of a row as a record
+// Each value is stored with its header's name, type and whether it's the KeyHeader
+func encodeRow(values map[HeaderI]ValueI) []byte {
+	headers := make([]HeaderI, 0, len(values))
+	for h := range values {
+		headers = append(headers, h)
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].GetName() < headers[j].GetName()
+	})
+
+	record := binary.AppendUvarint(nil, uint64(len(headers)))
+	for _, h := range headers {
+		flags := byte(h.GetType())
+		if h.IsKeyHeader() {
+			flags |= 0x80
+		}
+		record = appendBinaryString(record, h.GetName())
+		record = append(record, flags)
+		record = appendBinaryString(record, values[h].GetValue())
+	}
+
+	return record
+}
+
+// Decodes the record read by the payload, which must hold nothing else
+func decodeRow(p *binaryPayload) (*Row, error) {
+	n, err := p.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	row := &Row{RowMap: map[HeaderI]ValueI{}}
+	for i := uint64(0); i < n; i++ {
+		name, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		flags, err := p.byte()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		row.RowMap[&Header{name, flags&0x80 != 0, Type(flags &^ 0x80)}] = &Value{value}
+	}
+
+	return row, p.end()
+}
+
+func (row *pagedRow) GetKeyHeaderAndValue() (HeaderI, ValueI) {
+	return row.header, &Value{row.key}
+}
+
+func (row *pagedRow) GetValueFromHeader(header string) (ValueI, error) {
+	r, err := row.rows.load(row.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetValueFromHeader(header)
+}
+
+// Returns the values of the row read from the file
+// Changing the map doesn't change the row, which must be changed through the other methods
+func (row *pagedRow) GetRowMap() map[HeaderI]ValueI {
+	r, err := row.rows.load(row.key)
+	if err != nil {
+		return map[HeaderI]ValueI{}
+	}
+
+	return r.RowMap
+}
+
+func (row *pagedRow) HeaderExists(header string) bool {
+	r, err := row.rows.load(row.key)
+	if err != nil {
+		return false
+	}
+
+	return r.HeaderExists(header)
+}
+
+func (row *pagedRow) KeyHeaderValueEqual(value string) bool {
+	return row.key == value
+}
+
+func (row *pagedRow) AddHeaderWithValue(header string, keyHeader bool, t Type, value string) error {
+	row.rows.mu.Lock()
+	defer row.rows.mu.Unlock()
+
+	return row.rows.update(row.key, func(r *Row) error {
+		return r.AddHeaderWithValue(header, keyHeader, t, value)
+	})
+}
+
+func (row *pagedRow) RemoveHeader(header string) error {
+	row.rows.mu.Lock()
+	defer row.rows.mu.Unlock()
+
+	return row.rows.update(row.key, func(r *Row) error {
+		return r.RemoveHeader(header)
+	})
+}
+
+func (row *pagedRow) UpdateHeaderValue(header string, value string) {
+	row.rows.mu.Lock()
+	defer row.rows.mu.Unlock()
+
+	err := row.rows.update(row.key, func(r *Row) error {
+		r.UpdateHeaderValue(header, value)
+		return nil
+	})
+	if !errors.Is(err, ErrRowNotExist) {
+		row.rows.fail(err)
+	}
+}
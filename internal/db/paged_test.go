@@ -0,0 +1,213 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPagedRow(key string, name string) *Row {
+	return &Row{RowMap: map[HeaderI]ValueI{
+		&Header{"ID", true, VALUE_STRING}:    &Value{key},
+		&Header{"Name", false, VALUE_STRING}: &Value{name},
+	}}
+}
+
+func openPaged(t *testing.T, path string, poolPages int) *PagedRows {
+	rows, err := OpenPagedRows(path, poolPages)
+	assert.Nil(t, err)
+
+	return rows
+}
+
+func TestPagedRows(t *testing.T) {
+	rows := openPaged(t, filepath.Join(t.TempDir(), "rows.pdbp"), 0)
+	defer rows.Close()
+
+	assert.Nil(t, rows.GetRowFromKeyHeader("a"))
+	assert.Nil(t, rows.AddRows([]RowI{newPagedRow("b", "Bee"), newPagedRow("a", "Ay")}))
+	assert.Equal(t, 2, rows.Len())
+
+	// Rows are returned in key order whatever order they were added in
+	keys := []string{}
+	for _, row := range rows.GetRows() {
+		keys = append(keys, rowKey(row))
+	}
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	// Duplicates stop every row being added
+	err := rows.AddRows([]RowI{newPagedRow("c", "Sea"), newPagedRow("a", "Ay")})
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	err = rows.AddRows([]RowI{newPagedRow("c", "Sea"), newPagedRow("c", "Sea")})
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	assert.Nil(t, rows.GetRowFromKeyHeader("c"))
+
+	// Changes through a row are written back
+	row := rows.GetRowFromKeyHeader("a")
+	row.UpdateHeaderValue("Name", "Aye")
+	assert.Nil(t, row.AddHeaderWithValue("Score", false, VALUE_NUMBER, "5"))
+	v, err := rows.GetRowFromKeyHeader("a").GetValueFromHeader("Name")
+	assert.Nil(t, err)
+	assert.Equal(t, "Aye", v.GetValue())
+	assert.True(t, rows.GetRowFromKeyHeader("a").HeaderExists("Score"))
+
+	rows.AddValueToRowWithKeyHeader("Bea", "Name", "b")
+	found, err := rows.GetRowsFromHeaderAndValue("Name", "Bea")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(found))
+	assert.True(t, found[0].KeyHeaderValueEqual("b"))
+
+	// b never had Score added
+	_, err = rows.GetRowsFromHeaderAndValue("Score", "5")
+	assert.True(t, errors.Is(err, ErrHeaderNotExist))
+	rows.RemoveHeader("Score")
+	assert.False(t, rows.GetRowFromKeyHeader("a").HeaderExists("Score"))
+
+	rows.DeleteRowWithValue("a")
+	assert.Nil(t, rows.GetRowFromKeyHeader("a"))
+	assert.Equal(t, 1, rows.Len())
+	assert.Nil(t, rows.Err())
+}
+
+func TestPagedRowsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.pdbp")
+	rows := openPaged(t, path, minPoolPages)
+
+	// Enough rows to split the B+tree several times while the pool holds only a few pages
+	n := 3000
+	for i := 0; i < n; i++ {
+		assert.Nil(t, rows.AddRow(newPagedRow(fmt.Sprintf("%05d", i), strings.Repeat("x", i%50))))
+	}
+	assert.LessOrEqual(t, len(rows.pager.pool.frames), minPoolPages)
+	assert.Greater(t, rows.pager.pool.misses, 0)
+	for i := 0; i < n; i += 2 {
+		rows.DeleteRowWithValue(fmt.Sprintf("%05d", i))
+	}
+	assert.Nil(t, rows.Close())
+
+	rows = openPaged(t, path, minPoolPages)
+	defer rows.Close()
+	assert.Equal(t, n/2, rows.Len())
+	all := rows.GetRows()
+	assert.Equal(t, n/2, len(all))
+	for i, row := range all {
+		assert.Equal(t, fmt.Sprintf("%05d", i*2+1), rowKey(row))
+	}
+	v, err := rows.GetRowFromKeyHeader("01233").GetValueFromHeader("Name")
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Repeat("x", 1233%50), v.GetValue())
+
+	// The space left by deleted rows is reused rather than the file growing
+	pages := rows.pager.pages
+	for i := 0; i < n; i += 2 {
+		assert.Nil(t, rows.AddRow(newPagedRow(fmt.Sprintf("%05d", i), "y")))
+	}
+	assert.Equal(t, n, rows.Len())
+	assert.Less(t, rows.pager.pages, pages+pages/4)
+	assert.Nil(t, rows.Err())
+}
+
+func TestPagedRowsOverflow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.pdbp")
+	rows := openPaged(t, path, 0)
+
+	long := strings.Repeat("0123456789", 1500)
+	assert.Nil(t, rows.AddRow(newPagedRow("long", long)))
+	assert.Nil(t, rows.AddRow(newPagedRow("short", "s")))
+	assert.Nil(t, rows.Close())
+
+	rows = openPaged(t, path, 0)
+	defer rows.Close()
+	v, err := rows.GetRowFromKeyHeader("long").GetValueFromHeader("Name")
+	assert.Nil(t, err)
+	assert.Equal(t, long, v.GetValue())
+
+	// Shrinking the record frees its overflow pages for reuse
+	rows.AddValueToRowWithKeyHeader("s", "Name", "long")
+	assert.NotEqual(t, noPage, rows.pager.freeHead)
+	pages := rows.pager.pages
+	rows.AddValueToRowWithKeyHeader(long, "Name", "short")
+	assert.Equal(t, pages, rows.pager.pages)
+	v, err = rows.GetRowFromKeyHeader("short").GetValueFromHeader("Name")
+	assert.Nil(t, err)
+	assert.Equal(t, long, v.GetValue())
+}
+
+func TestPagedRowsDB(t *testing.T) {
+	rows := openPaged(t, filepath.Join(t.TempDir(), "rows.pdbp"), 0)
+	defer rows.Close()
+
+	db, err := New("Paged", []HeaderI{
+		&Header{"ID", true, VALUE_STRING},
+		&Header{"Name", false, VALUE_STRING},
+	}, "ID")
+	assert.Nil(t, err)
+	db.Rows = rows
+
+	assert.Nil(t, db.AddRow(newPagedRow("a", "Ay")))
+	assert.Nil(t, db.Upsert(newPagedRow("b", "Bee")))
+	assert.Nil(t, db.Upsert(newPagedRow("a", "Aye")))
+	assert.Equal(t, "Aye", getValue(t, db, "a", "Name"))
+
+	db.AddHeader(&Header{"Score", false, VALUE_NUMBER})
+	n, err := db.UpdateWhere(func(row RowI) bool { return row.KeyHeaderValueEqual("b") }, map[string]string{"Score": "7"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "7", getValue(t, db, "b", "Score"))
+	assert.Equal(t, "", getValue(t, db, "a", "Score"))
+
+	assert.Nil(t, db.RemoveHeader("Score"))
+	assert.False(t, db.GetRowFromKeyHeader("b").HeaderExists("Score"))
+	assert.Nil(t, rows.Err())
+}
+
+func TestPagedRowsErrors(t *testing.T) {
+	dir := t.TempDir()
+	rows := openPaged(t, filepath.Join(dir, "rows.pdbp"), 0)
+	err := rows.AddRow(newPagedRow(strings.Repeat("k", PAGED_MAX_KEY+1), ""))
+	assert.True(t, errors.Is(err, ErrKeyTooLong))
+	// The meta page can't hold a longer KeyHeader name
+	err = rows.AddRow(&Row{RowMap: map[HeaderI]ValueI{&Header{strings.Repeat("h", PAGED_MAX_KEY_HEADER+1), true, VALUE_STRING}: &Value{"a"}}})
+	assert.True(t, errors.Is(err, ErrKeyTooLong))
+	assert.Equal(t, 0, rows.Len())
+	assert.Nil(t, rows.AddRow(newPagedRow("a", "")))
+	assert.Nil(t, rows.Close())
+	data, err := os.ReadFile(filepath.Join(dir, "rows.pdbp"))
+	assert.Nil(t, err)
+
+	for name, bad := range map[string][]byte{
+		"size":  data[:len(data)-1],
+		"magic": append([]byte{pageMeta, 'X'}, data[2:]...),
+		"meta":  append(append([]byte{}, data[:7]...), append([]byte{0xff, 0xff, 0xff, 0xff}, data[11:]...)...),
+	} {
+		path := filepath.Join(dir, name+".pdbp")
+		assert.Nil(t, os.WriteFile(path, bad, 0o644))
+		_, err = OpenPagedRows(path, 0)
+		assert.True(t, errors.Is(err, ErrCorrupt), name)
+	}
+
+	version := append([]byte{}, data...)
+	version[6] = 2
+	path := filepath.Join(dir, "version.pdbp")
+	assert.Nil(t, os.WriteFile(path, version, 0o644))
+	_, err = OpenPagedRows(path, 0)
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	// A B+tree root that isn't a node is caught when the rows are read
+	root := append([]byte{}, data...)
+	root[int(binary.BigEndian.Uint32(data[15:]))*PAGE_SIZE] = pageOverflow
+	path = filepath.Join(dir, "root.pdbp")
+	assert.Nil(t, os.WriteFile(path, root, 0o644))
+	rows = openPaged(t, path, 0)
+	defer rows.Close()
+	assert.Nil(t, rows.GetRowFromKeyHeader("a"))
+	assert.True(t, errors.Is(rows.Err(), ErrCorrupt))
+	err = rows.AddRow(newPagedRow("b", ""))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
@@ -0,0 +1,289 @@
+package db
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The size of every page of a page file
+const PAGE_SIZE = 4096
+
+const (
+	pagedMagic          = "PDBP"
+	pagedVersion uint16 = 1
+
+	// The page holding the meta data of the file, which is never allocated so also means no page
+	metaPage uint32 = 0
+	noPage   uint32 = 0
+
+	// The fewest pages a buffer pool holds, enough for the most pages pinned at once
+	minPoolPages = 8
+
+	// The size of the fields before the KeyHeader's name in the meta page
+	metaHeaderSize = 28
+	// The longest KeyHeader name the meta page holds after its length
+	PAGED_MAX_KEY_HEADER = PAGE_SIZE - metaHeaderSize - binary.MaxVarintLen64
+)
+
+// The kinds of page, stored in the first byte of each
+const (
+	pageFree byte = iota
+	pageMeta
+	pageData
+	pageLeaf
+	pageInternal
+	pageOverflow
+)
+
+// Opens the page file at path, creating it if it doesn't exist, with a buffer pool holding at most
+// poolPages pages
+// Returns an error if the file isn't a page file
+func openPager(path string, poolPages int) (*pager, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if poolPages < minPoolPages {
+		poolPages = minPoolPages
+	}
+	p := &pager{file: file, path: path, pool: newBufferPool(file, poolPages), pages: 1}
+
+	if info.Size() == 0 {
+		err = p.writeMeta()
+	} else if info.Size()%PAGE_SIZE != 0 {
+		err = p.invalid(fmt.Sprintf(pagedSizeError, info.Size()))
+	} else {
+		err = p.readMeta(uint32(info.Size() / PAGE_SIZE))
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *pager) readMeta(pages uint32) error {
+	f, err := p.pool.get(metaPage)
+	if err != nil {
+		return err
+	}
+	defer p.pool.release(f, false)
+
+	data := f.data
+	if data[0] != pageMeta || string(data[1:5]) != pagedMagic {
+		return p.invalid(pagedMagicError)
+	}
+	if version := binary.BigEndian.Uint16(data[5:]); version != pagedVersion {
+		return &Error{Err: ErrUnsupportedVersion, Message: fmt.Sprintf(pagedVersionError, p.path, version, pagedVersion)}
+	}
+
+	p.pages = binary.BigEndian.Uint32(data[7:])
+	p.freeHead = binary.BigEndian.Uint32(data[11:])
+	p.root = binary.BigEndian.Uint32(data[15:])
+	p.rows = binary.BigEndian.Uint64(data[19:])
+	p.keyType = Type(data[27])
+	n, read := binary.Uvarint(data[metaHeaderSize:])
+	if read <= 0 || metaHeaderSize+read+int(n) > PAGE_SIZE {
+		return p.invalid(pagedMetaError)
+	}
+	p.keyHeader = string(data[metaHeaderSize+read : metaHeaderSize+read+int(n)])
+
+	if p.pages > pages || p.freeHead >= p.pages || p.root >= p.pages {
+		return p.invalid(pagedMetaError)
+	}
+
+	return nil
+}
+
+func (p *pager) writeMeta() error {
+	if len(p.keyHeader) > PAGED_MAX_KEY_HEADER {
+		return &Error{Err: ErrKeyTooLong, Header: p.keyHeader, Message: fmt.Sprintf(keyHeaderTooLongError, len(p.keyHeader), PAGED_MAX_KEY_HEADER)}
+	}
+
+	f, err := p.pool.get(metaPage)
+	if err != nil {
+		return err
+	}
+
+	data := f.data
+	clear(data)
+	data[0] = pageMeta
+	copy(data[1:], pagedMagic)
+	binary.BigEndian.PutUint16(data[5:], pagedVersion)
+	binary.BigEndian.PutUint32(data[7:], p.pages)
+	binary.BigEndian.PutUint32(data[11:], p.freeHead)
+	binary.BigEndian.PutUint32(data[15:], p.root)
+	binary.BigEndian.PutUint64(data[19:], p.rows)
+	data[27] = byte(p.keyType)
+	copy(data[metaHeaderSize:], appendBinaryString(nil, p.keyHeader))
+	p.pool.release(f, true)
+
+	return nil
+}
+
+// Returns a pinned, zeroed page of the given kind, reusing a freed page if there is one
+// The caller must release it as dirty
+func (p *pager) allocate(kind byte) (*frame, error) {
+	id := p.freeHead
+	if id == noPage {
+		id = p.pages
+		p.pages++
+	}
+
+	f, err := p.pool.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if id == p.freeHead {
+		p.freeHead = binary.BigEndian.Uint32(f.data[1:])
+	}
+
+	clear(f.data)
+	f.data[0] = kind
+
+	return f, nil
+}
+
+// Adds the page to the list of free pages for allocate to reuse
+func (p *pager) free(id uint32) error {
+	f, err := p.pool.get(id)
+	if err != nil {
+		return err
+	}
+
+	clear(f.data)
+	f.data[0] = pageFree
+	binary.BigEndian.PutUint32(f.data[1:], p.freeHead)
+	p.freeHead = id
+	p.pool.release(f, true)
+
+	return nil
+}
+
+// Writes the meta data and every changed page to the file and syncs it
+func (p *pager) sync() error {
+	err := p.writeMeta()
+	if err != nil {
+		return err
+	}
+
+	err = p.pool.flush()
+	if err != nil {
+		return err
+	}
+
+	return p.file.Sync()
+}
+
+func (p *pager) close() error {
+	err := p.sync()
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (p *pager) invalid(reason string) error {
+	return &Error{Err: ErrCorrupt, Message: fmt.Sprintf(pagedFileError, p.path, reason)}
+}
+
+func newBufferPool(file *os.File, capacity int) *bufferPool {
+	return &bufferPool{file: file, capacity: capacity, frames: map[uint32]*frame{}, lru: list.New()}
+}
+
+// Returns the pinned page with the given id, reading it from the file if it isn't in the pool
+// Pages past the end of the file are read as zeroes
+// The least recently used unpinned page is written if needed and dropped to make room
+// Returns an error if every page in the pool is pinned
+func (b *bufferPool) get(id uint32) (*frame, error) {
+	if f, ok := b.frames[id]; ok {
+		b.hits++
+		f.pins++
+		b.lru.MoveToFront(f.elem)
+		return f, nil
+	}
+	b.misses++
+
+	if len(b.frames) >= b.capacity {
+		err := b.evict()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f := &frame{id: id, data: make([]byte, PAGE_SIZE), pins: 1}
+	n, err := b.file.ReadAt(f.data, int64(id)*PAGE_SIZE)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	clear(f.data[n:])
+
+	f.elem = b.lru.PushFront(f)
+	b.frames[id] = f
+
+	return f, nil
+}
+
+// Unpins the page, marking it as changed if dirty is true
+func (b *bufferPool) release(f *frame, dirty bool) {
+	f.pins--
+	f.dirty = f.dirty || dirty
+}
+
+func (b *bufferPool) evict() error {
+	for e := b.lru.Back(); e != nil; e = e.Prev() {
+		f := e.Value.(*frame)
+		if f.pins > 0 {
+			continue
+		}
+
+		err := b.write(f)
+		if err != nil {
+			return err
+		}
+		b.lru.Remove(e)
+		delete(b.frames, f.id)
+		return nil
+	}
+
+	return &Error{Err: ErrBufferPoolFull, Message: fmt.Sprintf(bufferPoolFullError, b.capacity)}
+}
+
+// Writes every changed page to the file
+func (b *bufferPool) flush() error {
+	for _, f := range b.frames {
+		err := b.write(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bufferPool) write(f *frame) error {
+	if !f.dirty {
+		return nil
+	}
+
+	_, err := b.file.WriteAt(f.data, int64(f.id)*PAGE_SIZE)
+	if err != nil {
+		return err
+	}
+	f.dirty = false
+
+	return nil
+}
@@ -1,9 +1,6 @@
 package db
 
-import (
-	"errors"
-	"fmt"
-)
+import "fmt"
 
 func (r *Row) GetKeyHeaderAndValue() (HeaderI, ValueI) {
 	for h, v := range r.RowMap {
@@ -22,7 +19,7 @@ func (r *Row) GetValueFromHeader(header string) (ValueI, error) {
 		}
 	}
 
-	return nil, errors.New(fmt.Sprintf(headerNotExistError, header))
+	return nil, &Error{Err: ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
 }
 
 func (r *Row) GetRowMap() map[HeaderI]ValueI {
@@ -54,7 +51,7 @@ func (r *Row) KeyHeaderValueEqual(value string) bool {
 func (r *Row) AddHeaderWithValue(header string, keyHeader bool, t Type, value string) error {
 	key, _ := r.GetKeyHeaderAndValue()
 	if key != nil && keyHeader {
-		return errors.New(keyHeaderAlreadyExistsError)
+		return &Error{Err: ErrKeyHeaderExists, Message: keyHeaderAlreadyExistsError}
 	}
 
 	h := &Header{header, keyHeader, t}
@@ -67,7 +64,7 @@ func (r *Row) RemoveHeader(header string) error {
 	// Return error if trying to delete key header
 	h, _ := r.GetKeyHeaderAndValue()
 	if h.GetName() == header {
-		return errors.New(fmt.Sprintf(deleteKeyHeaderError, h.GetName()))
+		return &Error{Err: ErrDeleteKeyHeader, Header: h.GetName(), Message: fmt.Sprintf(deleteKeyHeaderError, h.GetName())}
 	}
 
 	newRowMap := map[HeaderI]ValueI{}
@@ -89,3 +86,20 @@ func (r *Row) UpdateHeaderValue(header string, value string) {
 		}
 	}
 }
+
+// Creates a row for the given DB from a map of header names to values, using the DB's
+// headers for the types and KeyHeader
+// Returns an error if a header does not exist in the DB
+func NewRowFromMap(d DB, values map[string]string) (RowI, error) {
+	row := &Row{map[HeaderI]ValueI{}}
+	for header, value := range values {
+		h := d.GetHeader(header)
+		if h.GetName() == "" {
+			return nil, &Error{Err: ErrHeaderNotExist, DB: d.GetName(), Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		row.AddHeaderWithValue(header, header == d.GetKeyHeader(), h.GetType(), value)
+	}
+
+	return row, nil
+}
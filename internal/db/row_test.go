@@ -120,3 +120,20 @@ func TestRowRemoveHeader(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(row.GetRowMap()))
 }
+
+func TestNewRowFromMap(t *testing.T) {
+	db, _ := newDBWithValues()
+
+	row, err := NewRowFromMap(db, map[string]string{"Title": "new", "Value": "v"})
+	assert.Nil(t, err)
+	h, v := row.GetKeyHeaderAndValue()
+	assert.Equal(t, "Title", h.GetName())
+	assert.Equal(t, "new", v.GetValue())
+	v, err = row.GetValueFromHeader("Value")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v.GetValue())
+
+	row, err = NewRowFromMap(db, map[string]string{"Title": "new", "Extra": "v"})
+	assert.Error(t, err)
+	assert.Nil(t, row)
+}
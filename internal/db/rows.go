@@ -1,9 +1,6 @@
 package db
 
-import (
-	"errors"
-	"fmt"
-)
+import "fmt"
 
 func (r *Rows) GetRows() []RowI {
 	return r.Items
@@ -13,7 +10,7 @@ func (r *Rows) AddRow(row RowI) error {
 	h, v := row.GetKeyHeaderAndValue()
 	for _, ro := range r.Items {
 		if ro.KeyHeaderValueEqual(v.GetValue()) {
-			return errors.New(fmt.Sprintf(keyHeaderValueExistsError, h.GetName(), v.GetValue()))
+			return &Error{Err: ErrDuplicateKey, Header: h.GetName(), Key: v.GetValue(), Message: fmt.Sprintf(keyHeaderValueExistsError, h.GetName(), v.GetValue())}
 		}
 	}
 
@@ -22,6 +19,30 @@ func (r *Rows) AddRow(row RowI) error {
 	return nil
 }
 
+func (r *Rows) AddRows(rows []RowI) error {
+	keys := make(map[string]struct{}, len(r.Items)+len(rows))
+	for _, ro := range r.Items {
+		_, v := ro.GetKeyHeaderAndValue()
+		keys[v.GetValue()] = struct{}{}
+	}
+
+	for _, row := range rows {
+		h, v := row.GetKeyHeaderAndValue()
+		if _, exists := keys[v.GetValue()]; exists {
+			return &Error{Err: ErrDuplicateKey, Header: h.GetName(), Key: v.GetValue(), Message: fmt.Sprintf(keyHeaderValueExistsError, h.GetName(), v.GetValue())}
+		}
+		keys[v.GetValue()] = struct{}{}
+	}
+
+	return r.addCheckedRows(rows)
+}
+
+func (r *Rows) addCheckedRows(rows []RowI) error {
+	r.Items = append(r.Items, rows...)
+
+	return nil
+}
+
 func (r *Rows) DeleteRow(row RowI) {
 	_, v := row.GetKeyHeaderAndValue()
 	for i, ro := range r.Items {
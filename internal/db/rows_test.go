@@ -142,3 +142,27 @@ func TestDeleteRowsWithValue(t *testing.T) {
 	rows.DeleteRowWithValue("diff key value")
 	assert.Equal(t, 0, len(rows.GetRows()))
 }
+
+func TestRowsAddRows(t *testing.T) {
+	rows, _, _ := createRows()
+	newRows := []RowI{
+		&Row{
+			RowMap: map[HeaderI]ValueI{
+				&Header{"Key", true, VALUE_STRING}: &Value{"second"},
+			},
+		},
+		&Row{
+			RowMap: map[HeaderI]ValueI{
+				&Header{"Key", true, VALUE_STRING}: &Value{"key value"},
+			},
+		},
+	}
+
+	err := rows.AddRows(newRows)
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(rows.GetRows()))
+
+	err = rows.AddRows(newRows[:1])
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows.GetRows()))
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The files of SnapshotRows and WALRows start with their magic number and a big endian uint16
+// version, followed by blocks written like those of the binary encoding of a DB
+// A snapshot holds a row block for each row, encoded like the records of a page file, and an end
+// block holding the number of rows
+const (
+	snapshotRowsMagic        = "PDBR"
+	rowsFileVersion   uint16 = 1
+)
+
+// Opens the snapshot of rows at path, which is empty if the file doesn't exist
+// Returns an error if the file isn't a snapshot of rows or is corrupt
+func OpenSnapshotRows(path string) (*SnapshotRows, error) {
+	r := &SnapshotRows{path: path}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	br := &binaryReader{r: bufio.NewReader(file)}
+	err = readRowsFileHeader(br, path, snapshotRowsMagic)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []RowI{}
+	for {
+		kind, p, err := br.block()
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case binaryRowBlock:
+			row, err := decodeRow(p)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		case binaryEndBlock:
+			n, err := p.uvarint()
+			if err != nil {
+				return nil, err
+			}
+			if n != uint64(len(rows)) {
+				return nil, p.corrupt(fmt.Sprintf(binaryRowCountError, n, len(rows)))
+			}
+			if err := p.end(); err != nil {
+				return nil, err
+			}
+
+			err = r.AddRows(rows)
+			if err != nil {
+				return nil, &Error{Err: ErrCorrupt, Message: fmt.Sprintf(binaryCorruptError, p.offset, err), Cause: err}
+			}
+			return r, nil
+		default:
+			return nil, p.corrupt(fmt.Sprintf(binaryBlockKindError, binaryRowBlock, kind))
+		}
+	}
+}
+
+// Writes every row to the file, replacing it only once the whole snapshot is written
+func (r *SnapshotRows) Flush() error {
+	tmp := r.path + ".tmp"
+	err := writeRowsFile(tmp, snapshotRowsMagic, func(w *bufio.Writer) {
+		for _, row := range r.Items {
+			writeBinaryBlock(w, binaryRowBlock, encodeRow(row.GetRowMap()))
+		}
+		writeBinaryBlock(w, binaryEndBlock, binary.AppendUvarint(nil, uint64(len(r.Items))))
+	})
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, r.path)
+}
+
+// Writes every row to the file
+// The rows are still held in memory so can be used after, being written again by Flush
+func (r *SnapshotRows) Close() error {
+	return r.Flush()
+}
+
+// Returns nil, as the file is only written by Flush and Close, which return their errors
+func (r *SnapshotRows) Err() error {
+	return nil
+}
+
+// Reads the magic number and version at the start of a file of rows
+func readRowsFileHeader(br *binaryReader, path string, magic string) error {
+	header := make([]byte, len(magic)+2)
+	if err := br.read(header); err != nil {
+		return err
+	}
+	if string(header[:len(magic)]) != magic {
+		return br.corrupt(0, fmt.Sprintf(rowsFileMagicError, path))
+	}
+	if version := binary.BigEndian.Uint16(header[len(magic):]); version != rowsFileVersion {
+		return &Error{Err: ErrUnsupportedVersion, Message: fmt.Sprintf(rowsFileVersionError, path, version, rowsFileVersion)}
+	}
+
+	return nil
+}
+
+func writeRowsHeader(w io.Writer, magic string) error {
+	_, err := w.Write(binary.BigEndian.AppendUint16([]byte(magic), rowsFileVersion))
+	return err
+}
+
+// Creates the file at path holding the magic number, version and whatever write writes,
+// syncing it before it's closed
+func writeRowsFile(path string, magic string, write func(w *bufio.Writer)) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	writeRowsHeader(w, magic)
+	write(w)
+
+	err = w.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
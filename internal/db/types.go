@@ -1,5 +1,16 @@
 package db
 
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
 var (
 	keyHeaderIncorrect          = "key header '%s' incorrect, expected '%s'"
 	keyHeaderEmptyError         = "key header '%s' must not be empty"
@@ -9,8 +20,331 @@ var (
 	keyHeaderValueExistsError   = "row with key header '%s' and value '%s' already exists"
 	keyValueEmptyError          = "key value cannot be empty"
 	notANumberError             = "value %s is not a number"
+	updateKeyHeaderError        = "cannot update key header '%s'"
+	headerNotNumberError        = "header '%s' is not a number header"
+	rowNotExistError            = "row with key value '%s' does not exist"
+	invalidExpressionError      = "invalid expression '%s': %s"
+	divideByZeroError           = "division by zero for row with key value '%s'"
+	unknownTypeError            = "unknown header type '%s', expected 'string' or 'number'"
+	unknownEventTypeError       = "unknown event type '%s'"
+	historyExpiredError         = "changes since %s are no longer retained"
+	readOnlyError               = "database '%s' is read-only"
+	nothingToUndoError          = "nothing to undo"
+	nothingToRedoError          = "nothing to redo"
+	undoGroupOpenError          = "an undo group is still open, end it first"
+	hookRecursionError          = "hooks nested more than %d deep"
+	unknownOperatorError        = "unknown operator '%s', expected '=', '<' or '>'"
+	keyHeaderMissingError       = "key header must exist and not be empty"
+	noJSONRowsError             = "no rows to create database '%s' from"
+	jsonRowError                = "could not import row %d: %v"
+	jsonValueError              = "value for header '%s' must be a string, number or null"
+	jsonTokenError              = "expected %v, got %v"
+	emptyJSONObjectError        = "object has no headers"
+	xlsxError                   = "invalid workbook: %s"
+	xlsxNoSheetError            = "workbook has no sheets"
+	xlsxNoHeaderRowError        = "first sheet has no header row"
+	xlsxMissingPartError        = "missing part '%s'"
+	xlsxPartError               = "could not read '%s': %v"
+	xlsxCellError               = "invalid cell reference '%s'"
+	xlsxSharedStringError       = "invalid shared string '%s' in cell '%s'"
+	xlsxRowError                = "could not import sheet row %d: %v"
+	binaryCorruptError          = "corrupt data at offset %d: %v"
+	binaryVersionError          = "unsupported binary version %d, expected %d or lower"
+	binaryMagicError            = "not a pdb binary file"
+	binaryBlockKindError        = "expected block '%c', got '%c'"
+	binaryBlockSizeError        = "block of %d bytes is too large"
+	binaryChecksumError         = "checksum mismatch"
+	binaryTruncatedError        = "unexpected end of data"
+	binaryColumnError           = "unknown column %d"
+	binaryTypeError             = "unknown header type %d"
+	binaryNumberError           = "unknown number encoding %d"
+	binaryRowCountError         = "end block counts %d rows but %d were read"
+	binaryTrailingError         = "%d unread bytes at end of block"
+	pagedFileError              = "invalid page file '%s': %s"
+	pagedSizeError              = "size %d is not a whole number of pages"
+	pagedMagicError             = "not a pdb page file"
+	pagedVersionError           = "page file '%s' has unsupported version %d, expected %d"
+	pagedMetaError              = "invalid meta page"
+	pagedOverflowError          = "broken overflow chain at page %d"
+	pagedSlotError              = "invalid slot %d of page %d"
+	btreePageError              = "page %d of kind %d is not a B+tree node"
+	btreeNodeError              = "invalid B+tree node at page %d"
+	bufferPoolFullError         = "all %d pages of the buffer pool are in use"
+	keyTooLongError             = "key of %d bytes is longer than the %d allowed"
+	keyHeaderTooLongError       = "key header name of %d bytes is longer than the %d allowed"
+	unknownBackendError         = "unknown backend '%s', expected '%s'"
+	rowsFileMagicError          = "'%s' is not a pdb rows file"
+	rowsFileVersionError        = "rows file '%s' has unsupported version %d, expected %d"
+	walRowError                 = "logged change to row '%s' which does not exist"
+	walKindError                = "unknown change '%c'"
+	batchRejectedError          = "row not added as another row in the batch failed: %s"
+	expressionEqualsError       = "expected '=' after target header"
+	expressionUnexpectedError   = "unexpected '%s'"
+	expressionQuoteError        = "expected a double quoted header name"
+	expressionUnterminatedError = "unterminated header name"
+	expressionEmptyHeaderError  = "empty header name"
+	expressionOperandError      = "expected a header name or a number"
+)
+
+// The kinds of error returned by a DB, wrapped by an *Error so they can be checked for with errors.Is
+var (
+	ErrKeyHeaderIncorrect = errors.New("key header incorrect")
+	ErrKeyHeaderEmpty     = errors.New("key header empty")
+	ErrKeyHeaderExists    = errors.New("key header exists")
+	ErrHeaderNotExist     = errors.New("header does not exist")
+	ErrHeaderExists       = errors.New("header exists")
+	ErrHeaderNotNumber    = errors.New("header is not a number header")
+	ErrDeleteKeyHeader    = errors.New("cannot delete key header")
+	ErrUpdateKeyHeader    = errors.New("cannot update key header")
+	ErrDuplicateKey       = errors.New("duplicate key")
+	ErrKeyValueEmpty      = errors.New("key value empty")
+	ErrRowNotExist        = errors.New("row does not exist")
+	ErrNotANumber         = errors.New("not a number")
+	ErrInvalidExpression  = errors.New("invalid expression")
+	ErrDivideByZero       = errors.New("division by zero")
+	ErrUnknownType        = errors.New("unknown header type")
+	ErrUnknownEventType   = errors.New("unknown event type")
+	ErrUnknownOperator    = errors.New("unknown operator")
+	ErrHistoryExpired     = errors.New("history expired")
+	ErrReadOnly           = errors.New("read-only")
+	ErrNothingToUndo      = errors.New("nothing to undo")
+	ErrNothingToRedo      = errors.New("nothing to redo")
+	ErrUndoGroupOpen      = errors.New("undo group open")
+	ErrHookRecursion      = errors.New("hook recursion")
+	ErrInvalidJSON        = errors.New("invalid JSON")
+	ErrInvalidXLSX        = errors.New("invalid XLSX workbook")
+	ErrCorrupt            = errors.New("corrupt binary data")
+	ErrUnsupportedVersion = errors.New("unsupported binary version")
+	ErrBufferPoolFull     = errors.New("buffer pool full")
+	ErrKeyTooLong         = errors.New("key too long")
+	ErrUnknownBackend     = errors.New("unknown backend")
+	ErrBatchRejected      = errors.New("batch rejected")
 )
 
+// Returned by evaluating an expression so the caller can report which row divided by zero
+var errDivideByZero = errors.New("division by zero")
+
+// The layouts ExportJSON writes and ImportJSON reads
+type JSONFormat int
+
+const (
+	// A JSON array of objects
+	JSON_ARRAY JSONFormat = iota
+	// One JSON object per line, also known as NDJSON
+	JSON_LINES
+)
+
+// The implementation of the reader of the objects written by ExportJSON holding the following fields:
+// dec: The decoder reading the input
+// format: The layout of the input
+// started: Whether the opening bracket of a JSON_ARRAY has been read
+// done: Whether the end of the input has been reached
+// count: The number of objects started, used to say where an error is
+type jsonReader struct {
+	dec     *json.Decoder
+	format  JSONFormat
+	started bool
+	done    bool
+	count   int
+}
+
+// An object read by a jsonReader holding the following fields:
+// keys: The header names in the order they were read
+// values: The values by header name, empty for null
+// numbers: The headers whose values were JSON numbers
+type jsonObject struct {
+	keys    []string
+	values  map[string]string
+	numbers map[string]bool
+}
+
+// The implementation of the reader of the binary encoding holding the following fields:
+// r: The input being read
+// offset: How many bytes of the input have been read, used to say where corruption is
+type binaryReader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+// The payload of a block of the binary encoding holding the following fields:
+// data: The payload
+// pos: How much of the payload has been read
+// offset: The offset of the payload in the input
+type binaryPayload struct {
+	data   []byte
+	pos    int
+	offset int64
+}
+
+// The first sheet of a workbook read by ImportXLSX holding the following fields:
+// headers: The header named by each column of the first row, empty for columns without one
+// rows: The cells of each row after the first, by column
+// numbers: The number of each row in the sheet, used to say where an error is
+type xlsxSheet struct {
+	headers []string
+	rows    [][]xlsxCell
+	numbers []int
+}
+
+// A cell of a sheet holding the following fields:
+// value: The value of the cell, empty if it has none
+// number: Whether the cell is a number cell
+type xlsxCell struct {
+	value  string
+	number bool
+}
+
+// The parts of the workbook XML read by ImportXLSX
+type xlsxWorkbookXML struct {
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStringsXML struct {
+	Items []xlsxTextXML `xml:"si"`
+}
+
+type xlsxWorksheetXML struct {
+	Rows []xlsxRowXML `xml:"sheetData>row"`
+}
+
+type xlsxRowXML struct {
+	Number int           `xml:"r,attr"`
+	Cells  []xlsxCellXML `xml:"c"`
+}
+
+type xlsxCellXML struct {
+	Ref    string      `xml:"r,attr"`
+	Type   string      `xml:"t,attr"`
+	Value  string      `xml:"v"`
+	Inline xlsxTextXML `xml:"is"`
+}
+
+// A string in a workbook, either plain text or runs of rich text
+type xlsxTextXML struct {
+	Text string   `xml:"t"`
+	Runs []string `xml:"r>t"`
+}
+
+// The implementation of the pages of a page file holding the following fields:
+// file: The page file
+// path: The path of the page file, used in errors
+// pool: The pages held in memory
+// pages: The number of pages in the file, including those not yet written
+// freeHead: The first page of the list of freed pages, or noPage
+// root: The root page of the B+tree, or noPage if no row has been added
+// rows: The number of rows
+// keyHeader: The name of the KeyHeader, empty until a row has been added
+// keyType: The type of the KeyHeader
+type pager struct {
+	file      *os.File
+	path      string
+	pool      *bufferPool
+	pages     uint32
+	freeHead  uint32
+	root      uint32
+	rows      uint64
+	keyHeader string
+	keyType   Type
+}
+
+// The implementation of a bounded pool of pages read from a page file holding the following fields:
+// file: The page file
+// capacity: The most pages held at once
+// frames: The pages held, by id
+// lru: The pages held from most to least recently used
+// hits: The number of pages asked for that were held
+// misses: The number of pages asked for that had to be read
+type bufferPool struct {
+	file     *os.File
+	capacity int
+	frames   map[uint32]*frame
+	lru      *list.List
+	hits     int
+	misses   int
+}
+
+// A page held in a bufferPool holding the following fields:
+// id: The id of the page, its offset in the file divided by PAGE_SIZE
+// data: The contents of the page
+// pins: The number of users of the page, which can't be evicted while it has any
+// dirty: Whether the page has changed since it was read or written
+// elem: The page's element in the pool's lru list
+type frame struct {
+	id    uint32
+	data  []byte
+	pins  int
+	dirty bool
+	elem  *list.Element
+}
+
+// A node of the B+tree of a page file holding the following fields:
+// leaf: Whether the node is a leaf
+// keys: The keys of a leaf, or the separators of an internal node, in order
+// rids: The locations of the records of a leaf's keys
+// children: The child pages of an internal node, one more than its keys
+// next: The next leaf, or noPage
+type btreeNode struct {
+	leaf     bool
+	keys     []string
+	rids     []rid
+	children []uint32
+	next     uint32
+}
+
+// The result of splitting a B+tree node holding the following fields:
+// key: The separator of the two halves
+// page: The page of the right half
+type btreeSplit struct {
+	key  string
+	page uint32
+}
+
+// The location of a record in a page file holding the following fields:
+// page: The data page of the record
+// slot: The slot of the record in the page
+type rid struct {
+	page uint32
+	slot uint16
+}
+
+// A view of a row of PagedRows holding the following fields:
+// rows: The rows the row is in
+// key: The KeyHeader value of the row
+// header: The KeyHeader
+type pagedRow struct {
+	rows   *PagedRows
+	key    string
+	header HeaderI
+}
+
+// The implementation of error for the errors returned by a DB holding the following fields:
+// Err: The sentinel for the kind of error, such as ErrHeaderNotExist
+// DB: The name of the DB, or empty if not known where the error was made
+// Header: The header the error is about, or empty
+// Key: The KeyHeader value of the row the error is about, or empty
+// Value: The value the error is about, or empty
+// Message: The message returned by Error
+// Cause: The error that caused this one, or nil
+type Error struct {
+	Err     error
+	DB      string
+	Header  string
+	Key     string
+	Value   string
+	Message string
+	Cause   error
+}
+
 // DB is the interface for any DB implementations
 type DB interface {
 	// Returns the name of the DB
@@ -42,25 +376,377 @@ type DB interface {
 	// Returns an error if the value is an empty string
 	RemoveRow(keyValue string) error
 
-	// Returns all rows in the DB
+	// Returns copies of all rows in the DB, so changing them doesn't change the DB
 	GetRows() []RowI
 
 	// Adds a new value to a given header based on KeyHeader == key
 	// Returns an error if the header does not exist
 	AddValueToHeader(value string, header string, key string) error
 
-	// Returns a row based on KeyHeader == value
+	// Returns a copy of the row based on KeyHeader == value
 	GetRowFromKeyHeader(value string) RowI
 
-	// Returns a list of the rows that have header == value
+	// Returns a list of copies of the rows that have header == value
 	// Returns an error if the header doesn't exist
 	GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error)
 
-	// Returns a list of rows that have header == value and header's value < op > value
+	// Returns a list of copies of the rows that have header == value and header's value < op > value
 	// Op can be either '<' or '>'
 	// Returns error if the value given is not a number
 	// Returns error if the header does not exists
 	GetRowsFromHeaderAndValueNumberOperation(header string, value string, op string) ([]RowI, error)
+
+	// Adds the row if no row has the same KeyHeader value, otherwise merges the row's values
+	// into the existing row
+	// Returns an error if the row has headers that don't exist in the DB
+	Upsert(row RowI) error
+
+	// Adds multiple rows to the DB, checking for duplicate KeyHeader values in a single pass
+	// Returns a list of errors matching the index of each row, or nil if every row was added
+	// If opts.AllOrNothing is true, no rows are added when any of them fail, the rows that didn't
+	// fail themselves having an ErrBatchRejected error caused by the first that did
+	// BeforeInsert hooks only run for rows that pass the other checks, and if opts.AllOrNothing is
+	// true only once every row has
+	// The rows are added even if an AfterInsert hook fails, the first error from one being
+	// returned separately
+	AddRows(rows []RowI, opts AddRowsOptions) ([]error, error)
+
+	// Sets the given header to value pairs on every row matching the predicate, or every row
+	// if the predicate is nil, returning the number of rows updated
+	// Returns an error if a header does not exist, is the KeyHeader, or a value doesn't match
+	// its header's type, in which case no rows are updated
+	UpdateWhere(predicate Predicate, values map[string]string) (int, error)
+
+	// Removes every row matching the predicate, or every row if the predicate is nil,
+	// returning the number of rows removed
+	DeleteWhere(predicate Predicate) (int, error)
+
+	// Adds delta to the VALUE_NUMBER header of the row with KeyHeader == key, returning the new value
+	// Returns an error if the header is not a number header, the row doesn't exist, or the
+	// current value is not a number
+	Increment(key string, header string, delta float64) (float64, error)
+
+	// Subtracts delta from the VALUE_NUMBER header of the row with KeyHeader == key, returning the new value
+	// Returns the same errors as Increment
+	Decrement(key string, header string, delta float64) (float64, error)
+
+	// Applies an arithmetic update such as "Points Gained" = "Points Gained" * 2 to every row
+	// matching the predicate, or every row if the predicate is nil, returning the number of rows updated
+	// Header names are double quoted and the operators +, -, * and / are supported
+	// Returns an error if the expression is invalid or refers to headers that are not number headers,
+	// in which case no rows are updated
+	UpdateExpression(predicate Predicate, expr string) (int, error)
+
+	// Sets whether empty VALUE_NUMBER values are treated as zero by Increment, Decrement and
+	// UpdateExpression, otherwise they return an error
+	SetEmptyAsZero(emptyAsZero bool)
+
+	// Returns a channel receiving an Event for each change to the DB matching the filter, and a
+	// function that cancels the subscription and closes the channel
+	// The channel is also closed if the subscriber is disconnected for falling behind
+	Subscribe(filter EventFilter) (<-chan Event, func())
+
+	// Returns the retained versions of the row with KeyHeader == key, oldest first
+	// Returns an empty list if no changes to the row are retained
+	History(key string) []Version
+
+	// Returns a read-only copy of the DB as it was at the given time
+	// Returns an error if changes made after that time are no longer retained
+	AsOf(t time.Time) (DB, error)
+
+	// Sets how many changes are kept for History and AsOf, dropping any now outside of it
+	SetRetention(retention Retention)
+
+	// Returns an Iterator over the rows matching the query, reading each row only when it is reached
+	// Rows added after the Iterator is created aren't returned and rows removed before they are
+	// reached are skipped, while changes to a row's values are seen if made before it is reached
+	// Errors, such as the query's Header not existing, are returned by the Iterator's Err
+	// The Iterator must be closed if it isn't read until Next returns false
+	Iter(query Query) Iterator
+}
+
+// The points in a change to a row a Hook can be registered at
+type HookType int
+
+const (
+	HOOK_BEFORE_INSERT HookType = iota
+	HOOK_AFTER_INSERT
+	HOOK_BEFORE_UPDATE
+	HOOK_AFTER_UPDATE
+	HOOK_BEFORE_DELETE
+	HOOK_AFTER_DELETE
+)
+
+// A change to a row passed to hooks holding the following fields:
+// DB: The DB being changed, to read it or make further changes from the hook
+// Key: The KeyHeader value of the row
+// Old: The row's values by header name before the change, nil for inserts
+// New: The row's values by header name after the change, nil for deletes
+// Before hooks can change New to change the values written
+type Change struct {
+	DB  HookDB
+	Key string
+	Old map[string]string
+	New map[string]string
+}
+
+// Hook is called with a change to a row while the DB is locked
+// Returning an error from a Before hook rejects the change, an error from an After hook is
+// returned by the method that made the change, which is kept
+// Changes a hook makes through its HookDB are kept even if the change it was run for is rejected
+type Hook func(change *Change) error
+
+// HookDB is the interface hooks use for the DB they are run for, which stays locked while
+// they run so the DB's own methods can't be called
+// Changes made through it run hooks in turn, up to a limited depth
+type HookDB interface {
+	GetName() string
+	GetKeyHeader() string
+	GetHeader(header string) HeaderI
+	GetRows() []RowI
+	GetRowFromKeyHeader(value string) RowI
+	AddRow(row RowI) error
+	Upsert(row RowI) error
+	RemoveRow(keyValue string) error
+	AddValueToHeader(value string, header string, key string) error
+	UpdateWhere(predicate Predicate, values map[string]string) (int, error)
+	DeleteWhere(predicate Predicate) (int, error)
+	Increment(key string, header string, delta float64) (float64, error)
+}
+
+// Auditor is the interface for recording the changes made to DBs
+type Auditor interface {
+	// Records a change, called while the DB's write lock is held so changes are recorded in the
+	// order they were made
+	Record(e Event)
+}
+
+// Attributable is the interface for DBs that can attribute changes to who made them
+type Attributable interface {
+	// Returns a view of the DB attributing the changes made through it to actor
+	As(actor string) DB
+}
+
+// DBContext is the interface for DBs taking a context.Context for each operation
+// Each method matches the DB method without the Context suffix, returning ctx's error if it is done
+// before the operation finishes, in which case nothing is changed
+// Changes are attributed to the actor set on ctx with WithActor
+// GetName and GetKeyHeader don't wait on anything so have no variant
+type DBContext interface {
+	DB
+
+	GetHeaderContext(ctx context.Context, header string) (HeaderI, error)
+	GetHeadersContext(ctx context.Context) ([]HeaderI, error)
+	GetHeadersStringContext(ctx context.Context) ([]string, error)
+	AddHeaderContext(ctx context.Context, header HeaderI) error
+	RemoveHeaderContext(ctx context.Context, header string) error
+	AddRowContext(ctx context.Context, row RowI) error
+
+	// Returns ctx's error for every row if it is done before the rows are added
+	AddRowsContext(ctx context.Context, rows []RowI, opts AddRowsOptions) ([]error, error)
+
+	UpsertContext(ctx context.Context, row RowI) error
+	RemoveRowContext(ctx context.Context, keyValue string) error
+	UpdateWhereContext(ctx context.Context, predicate Predicate, values map[string]string) (int, error)
+	DeleteWhereContext(ctx context.Context, predicate Predicate) (int, error)
+	AddValueToHeaderContext(ctx context.Context, value string, header string, key string) error
+	GetRowsContext(ctx context.Context) ([]RowI, error)
+	GetRowFromKeyHeaderContext(ctx context.Context, value string) (RowI, error)
+	GetRowsFromHeaderAndValueContext(ctx context.Context, header string, value string) ([]RowI, error)
+	GetRowsFromHeaderAndValueNumberOperationContext(ctx context.Context, header string, value string, op string) ([]RowI, error)
+	SetEmptyAsZeroContext(ctx context.Context, emptyAsZero bool) error
+	IncrementContext(ctx context.Context, key string, header string, delta float64) (float64, error)
+	DecrementContext(ctx context.Context, key string, header string, delta float64) (float64, error)
+	UpdateExpressionContext(ctx context.Context, predicate Predicate, expr string) (int, error)
+
+	// Returns a channel of the events matching filter, closed once ctx is done
+	SubscribeContext(ctx context.Context, filter EventFilter) <-chan Event
+
+	HistoryContext(ctx context.Context, key string) ([]Version, error)
+	AsOfContext(ctx context.Context, t time.Time) (DB, error)
+	// Returns an Iterator like Iter that stops once ctx is done, after which the DB stops tracking
+	// it even if it isn't closed
+	IterContext(ctx context.Context, query Query) Iterator
+	SetRetentionContext(ctx context.Context, retention Retention) error
+}
+
+// Undoable is the interface for DBs keeping a journal of changes that can be undone
+type Undoable interface {
+	// Reverses the last change, or group of changes, not yet undone
+	// Returns an error if there is nothing to undo or a group is still open
+	Undo() error
+
+	// Makes the last change undone again
+	// Returns an error if there is nothing to redo, which is the case after any other change, or a
+	// group is still open
+	Redo() error
+
+	// Starts grouping changes into a single step for Undo until the matching EndUndoGroup
+	// Groups can be nested, the step finishing when the outermost group ends
+	BeginUndoGroup()
+
+	// Ends the group started by the last BeginUndoGroup
+	EndUndoGroup()
+
+	// Sets how many steps can be undone, 0 to stop recording them, dropping the oldest past it
+	SetUndoDepth(depth int)
+
+	// Forgets every step that could be undone or redone, such as the changes made loading the DB
+	ClearUndo()
+}
+
+// Backed is the interface for DBs keeping their rows in a backend registered with RegisterBackend
+type Backed interface {
+	// Returns the name of the backend keeping the rows
+	BackendName() string
+
+	// Moves the rows to the backend registered under the name, replacing any rows it already
+	// holds for the DB
+	UseBackend(backend string) error
+}
+
+// Predicate is used to select the rows a bulk operation applies to
+// DB runs it on copies of the rows before locking the DB, so it can call the DB's methods, while
+// HookDB runs it on the rows themselves with the DB locked, so it can only use the HookDB
+type Predicate func(row RowI) bool
+
+// The rows an Iterator returns holding the following fields:
+// Header: The header compared with Value, or empty to match every row
+// Value: The value the Header's value is compared with
+// Op: How the values are compared, '=' or empty for equal values, or '<' or '>' for numbers
+// Where: A further predicate rows must match, or nil
+type Query struct {
+	Header string
+	Value  string
+	Op     string
+	Where  Predicate
+}
+
+// Iterator is the interface for going through the rows matching a Query one at a time
+// An Iterator isn't safe to use from more than one goroutine
+type Iterator interface {
+	// Moves to the next matching row, returning false once there are none left, an error
+	// stopped the iteration or the Iterator was closed
+	Next() bool
+
+	// Returns a copy of the row Next moved to, or nil if Next returned false
+	Row() RowI
+
+	// Returns the error that stopped the iteration, or nil
+	Err() error
+
+	// Releases the rows held by the Iterator, after which Next returns false
+	// Iterators are released once Next returns false, otherwise Close must be called, as the DB
+	// records the rows removed for every open Iterator until it is released
+	Close() error
+}
+
+// The options for AddRows holding the following fields:
+// AllOrNothing: If true, no rows are added when any row fails, otherwise the valid rows are still added
+type AddRowsOptions struct {
+	AllOrNothing bool
+}
+
+// The kind of change an Event describes with the following values:
+// EVENT_ADD_ROW: A row was added
+// EVENT_REMOVE_ROW: A row was removed
+// EVENT_UPDATE_VALUE: A value in a row changed
+// EVENT_ADD_HEADER: A header was added to the DB
+// EVENT_REMOVE_HEADER: A header was removed from the DB
+// EVENT_RESET: Sent first to a subscriber resuming from a Seq whose following events are no longer
+// retained, who must reload the DB and treat the event's Seq as the last change seen
+type EventType int
+
+const (
+	EVENT_ADD_ROW EventType = iota
+	EVENT_REMOVE_ROW
+	EVENT_UPDATE_VALUE
+	EVENT_ADD_HEADER
+	EVENT_REMOVE_HEADER
+	EVENT_RESET
+)
+
+// A change to a DB holding the following fields:
+// Seq: The sequence number of the event, increasing by one for each change to the DB
+// Type: The kind of change
+// DB: The name of the DB that changed
+// Time: When the change was made
+// Actor: Who made the change, empty if the change was made without one
+// Key: The KeyHeader value of the row that changed, empty for header events
+// Header: The header that changed, empty for row events
+// HeaderType: The type of the header for EVENT_ADD_HEADER and EVENT_REMOVE_HEADER
+// OldValue, NewValue: The value before and after an EVENT_UPDATE_VALUE
+// Row: The values of the row added or removed, by header name
+// Values: The values removed by an EVENT_REMOVE_HEADER, by KeyHeader value
+type Event struct {
+	Seq        uint64
+	Type       EventType
+	DB         string
+	Time       time.Time
+	Actor      string
+	Key        string
+	Header     string
+	HeaderType Type
+	OldValue   string
+	NewValue   string
+	Row        map[string]string
+	Values     map[string]string
+}
+
+// What happens to a subscriber whose buffer is full when an event is published, with the following values:
+// POLICY_DROP: The event is dropped for that subscriber, who can notice the gap in Seq
+// POLICY_BLOCK: The change waits until the subscriber has room, holding up every other change to the DB
+// POLICY_DISCONNECT: The subscriber's channel is closed
+type SlowConsumerPolicy int
+
+const (
+	POLICY_DROP SlowConsumerPolicy = iota
+	POLICY_BLOCK
+	POLICY_DISCONNECT
+)
+
+// The options for Subscribe holding the following fields:
+// Types: The kinds of events to receive, or every kind if empty
+// Keys: Only receive events for rows with these KeyHeader values if not empty
+// Headers: Only receive events for these headers if not empty
+// After: Resume from a previous subscription, first receiving the retained events with a Seq after it,
+// or an EVENT_RESET if some of them are no longer retained
+// Buffer: The number of events buffered for the subscriber, 64 if zero
+// Policy: What happens when the buffer is full
+type EventFilter struct {
+	Types   []EventType
+	Keys    []string
+	Headers []string
+	After   uint64
+	Buffer  int
+	Policy  SlowConsumerPolicy
+}
+
+// A version of a row holding the following fields:
+// Seq: The sequence number of the change that made the version
+// Time: When the change was made
+// Actor: Who made the change, empty if the change was made without one
+// Type: The kind of change
+// Header: The header that changed, empty when the row was added or removed
+// OldValue, NewValue: The header's value before and after the change
+// Values: The row's values after the change by header name, nil if the row was removed
+type Version struct {
+	Seq      uint64
+	Time     time.Time
+	Actor    string
+	Type     EventType
+	Header   string
+	OldValue string
+	NewValue string
+	Values   map[string]string
+}
+
+// How much history a DB keeps holding the following fields:
+// MaxChanges: The number of changes kept, 0 for no limit
+// MaxAge: How long a change is kept for, 0 for no limit
+type Retention struct {
+	MaxChanges int
+	MaxAge     time.Duration
 }
 
 // The implementation for DB holding the following fields:
@@ -73,6 +759,84 @@ type DBImpl struct {
 	KeyHeader string
 	Headers   map[HeaderI]struct{}
 	Rows      RowsI
+
+	// Guards Headers and Rows so the DB can be shared between goroutines
+	mu sync.RWMutex
+	// Whether empty VALUE_NUMBER values are treated as zero by the numeric updates
+	emptyAsZero bool
+	// Publishes changes to subscribers
+	feed feed
+	// Who the change being made is attributed to, only set while the write lock is held
+	actor string
+	// The changes kept for History and AsOf
+	history history
+	// The changes that can be undone and redone
+	journal journal
+	// The hooks registered by type, run in the order they were registered
+	hooks map[HookType][]Hook
+	// How many hooks are running, each change made by a hook running its own
+	hookDepth int
+	// Records every change made, or nil
+	auditor Auditor
+	// Set on the copies returned by AsOf, which can't be changed
+	readOnly bool
+	// The name of the backend keeping the rows, empty for BACKEND_MEMORY
+	backend string
+	// The open iterators, told about the rows removed so they skip them
+	iterators map[*rowIterator]struct{}
+	// Guards iterators, which are opened and released while only the read lock is held
+	itersMu sync.Mutex
+}
+
+// A view of a DB attributing the changes made through it holding the following fields:
+// DBImpl: The DB changes are made to
+// actor: Who the changes are attributed to
+type actorDB struct {
+	*DBImpl
+	actor string
+}
+
+// The key the actor making changes is stored under in a context.Context
+type actorKey struct{}
+
+// The view of a locked DB given to hooks holding the following fields:
+// db: The DB hooks are being run for
+type hookDB struct {
+	db *DBImpl
+}
+
+// The implementation of Iterator over a DBImpl holding the following fields:
+// db: The DB being iterated over
+// ctx: The context stopping the iteration once it is done
+// match: Reports whether a row matches the query
+// rows: The rows in the DB when the iterator was created, nil once it is released
+// i: The index of the next row to read
+// removed: The keys of the rows removed since the iterator was created, written under the DB's write lock
+// row: The current row
+// err: The error that stopped the iteration
+// closed: Whether Close has been called
+// stop: Stops ctx releasing the iterator once it is done, nil if it isn't registered
+type rowIterator struct {
+	db      *DBImpl
+	ctx     context.Context
+	match   func(row RowI) (bool, error)
+	rows    []RowI
+	i       int
+	removed map[string]struct{}
+	row     RowI
+	err     error
+	closed  bool
+	stop    func() bool
+}
+
+// A read-only copy of a DB at an earlier time holding the following fields:
+// DBImpl: The copy, which is never changed
+// source: The DB the copy was made from
+// at: The time the copy shows the DB at
+type readOnlyDB struct {
+	*DBImpl
+	source *DBImpl
+	at     time.Time
 }
 
 // RowsI is the interface for the rows in a DB
@@ -84,6 +848,11 @@ type RowsI interface {
 	// Returns an error if the new row has the same KeyHeader value as another row
 	AddRow(row RowI) error
 
+	// Adds multiple rows to the DB
+	// Returns an error if any row has the same KeyHeader value as another row, in which case
+	// none of the rows are added
+	AddRows(rows []RowI) error
+
 	// Deletes a row from the DB based on the rows KeyHeader value
 	DeleteRow(row RowI)
 
@@ -105,12 +874,124 @@ type RowsI interface {
 	GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error)
 }
 
+// checkedRowsI is implemented by the backends that can add rows whose KeyHeader values the DB has
+// already checked are unique, so AddRows only checks them once
+type checkedRowsI interface {
+	// Adds the rows without checking their KeyHeader values
+	addCheckedRows(rows []RowI) error
+}
+
+// StoredRowsI is the interface for rows kept in files by a backend, such as PagedRows
+type StoredRowsI interface {
+	RowsI
+
+	// Writes every change to the files
+	Flush() error
+
+	// Writes every change to the files and closes them
+	Close() error
+
+	// Returns the first error reading or writing the files, including those from the methods of
+	// RowsI and RowI that can't return one
+	Err() error
+}
+
+// A Backend opens the rows of the DB with the given name, registered with RegisterBackend so DBs
+// can be created with it by NewWithBackend
+type Backend func(name string) (RowsI, error)
+
 // The implementation of Rows holding the following fields:
 // Items: The list of Row instances
 type Rows struct {
 	Items []RowI
 }
 
+// The implementation of RowsI keeping rows in memory by column holding the following fields:
+// keyHeader: The KeyHeader, nil until a row has been added
+// keys: The KeyHeader value of each row, in the order they were added
+// index: The position of each row by KeyHeader value
+// columns: The values of every header but the KeyHeader by name
+type ColumnarRows struct {
+	keyHeader *Header
+	keys      []string
+	index     map[string]int
+	columns   map[string]*column
+}
+
+// The values of a header of ColumnarRows, one for each row, holding the following fields:
+// t: The type of the header
+// numbers: The values of a VALUE_NUMBER header, NaN for those that aren't numbers
+// strings: The values of any other header
+// present: Whether each row has the header
+// text: The values of a VALUE_NUMBER header that don't print back the same way from numbers,
+// by row
+type column struct {
+	t       Type
+	numbers []float64
+	strings []string
+	present []bool
+	text    map[int]string
+}
+
+// A view of a row of ColumnarRows holding the following fields:
+// rows: The rows the row is in
+// key: The KeyHeader value of the row
+// pos: Where the row was last seen in the columns
+type columnarRow struct {
+	rows *ColumnarRows
+	key  string
+	pos  int
+}
+
+// The implementation of StoredRowsI keeping rows in memory and writing them all to a file when
+// flushed holding the following fields:
+// Rows: The rows
+// path: The path of the file
+type SnapshotRows struct {
+	Rows
+	path string
+}
+
+// The implementation of StoredRowsI keeping rows in memory and logging every change made to them
+// to a file holding the following fields:
+// rows: The rows
+// path: The path of the log
+// file: The log
+// w: Buffers the changes written to the log
+// err: The first error writing the log
+// mu: Guards file, w and err
+type WALRows struct {
+	rows Rows
+	path string
+	file *os.File
+	w    *bufio.Writer
+	err  error
+	mu   sync.Mutex
+}
+
+// A view of a row of WALRows logging the changes made through it holding the following fields:
+// rows: The rows the row is in
+// row: The row
+type walRow struct {
+	rows *WALRows
+	row  RowI
+}
+
+// The implementation of StoredRowsI kept in a page file, read through a bounded buffer pool and
+// indexed by a B+tree over the KeyHeader, holding the following fields:
+// pager: The pages of the file
+// free: The free space of each data page with room for a record
+// last: The data page last written to, tried first for new records
+// err: The first error reading or writing the file
+// mu: Guards every field, as rows and their views share the pages
+type PagedRows struct {
+	pager *pager
+	free  map[uint32]int
+	last  uint32
+	err   error
+	mu    sync.Mutex
+}
+
 // RowI is the interface for a row in the Rows list
 type RowI interface {
 	// Returns the KeyHeader instance and its Value
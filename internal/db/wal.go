@@ -0,0 +1,403 @@
+package db
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A log of rows holds a block for each change made to its rows, in the order they were made,
+// so the rows are rebuilt by making the changes again
+// Each block's kind is the kind of change and its payload the row's KeyHeader value followed by
+// what changed, except for added rows, whose payload is the row encoded like the records of a
+// page file, and removed headers, whose payload is the header
+// A block cut short at the end of the log, left by a write that never finished, is dropped
+// Each change is made in memory and then written to the log before the method making it returns,
+// so a change survives the process stopping once made, but only survives the machine stopping
+// once Flush or Close has synced the log to disk
+// Flush and Close also compact the log, replacing it with a block adding each row as it is now,
+// so the log only grows with the changes made since the last Flush
+const walRowsMagic = "PDBW"
+
+// The kinds of change logged
+const (
+	walAddRow          byte = 'A'
+	walDeleteRow       byte = 'D'
+	walUpdateValue     byte = 'U'
+	walAddRowHeader    byte = 'H'
+	walRemoveRowHeader byte = 'X'
+	walRemoveHeader    byte = 'R'
+)
+
+// Opens the log of rows at path, creating it if it doesn't exist, and makes every change logged
+// to rebuild the rows
+// Returns an error if the file isn't a log of rows or is corrupt
+func OpenWALRows(path string) (*WALRows, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &WALRows{path: path, file: file}
+	err = r.replay()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.w = bufio.NewWriter(file)
+
+	return r, nil
+}
+
+// Makes every change in the log, leaving the file positioned after the last whole block
+func (r *WALRows) replay() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return writeRowsHeader(r.file, walRowsMagic)
+	}
+
+	br := &binaryReader{r: bufio.NewReader(r.file)}
+	err = readRowsFileHeader(br, r.path, walRowsMagic)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if _, err := br.r.Peek(1); errors.Is(err, io.EOF) {
+			break
+		}
+
+		start := br.offset
+		kind, p, err := br.block()
+		if err != nil {
+			// A bad block at the very end is a write that never finished rather than corruption
+			if _, peekErr := br.r.Peek(1); errors.Is(err, ErrCorrupt) && errors.Is(peekErr, io.EOF) {
+				err = r.file.Truncate(start)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			return err
+		}
+
+		err = r.apply(kind, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = r.file.Seek(0, io.SeekEnd)
+	return err
+}
+
+// Makes the change in the logged block
+func (r *WALRows) apply(kind byte, p *binaryPayload) error {
+	switch kind {
+	case walAddRow:
+		row, err := decodeRow(p)
+		if err != nil {
+			return err
+		}
+		err = r.rows.AddRow(row)
+		if err != nil {
+			return p.corrupt(err.Error())
+		}
+		return nil
+	case walRemoveHeader:
+		header, err := p.string()
+		if err != nil {
+			return err
+		}
+		r.rows.RemoveHeader(header)
+		return p.end()
+	}
+
+	key, err := p.string()
+	if err != nil {
+		return err
+	}
+	row := r.rows.GetRowFromKeyHeader(key)
+	if row == nil {
+		return p.corrupt(fmt.Sprintf(walRowError, key))
+	}
+
+	switch kind {
+	case walDeleteRow:
+		r.rows.DeleteRowWithValue(key)
+	case walUpdateValue:
+		header, err := p.string()
+		if err != nil {
+			return err
+		}
+		value, err := p.string()
+		if err != nil {
+			return err
+		}
+		row.UpdateHeaderValue(header, value)
+	case walAddRowHeader:
+		header, err := p.string()
+		if err != nil {
+			return err
+		}
+		flags, err := p.byte()
+		if err != nil {
+			return err
+		}
+		value, err := p.string()
+		if err != nil {
+			return err
+		}
+		row.AddHeaderWithValue(header, flags&0x80 != 0, Type(flags&^0x80), value)
+	case walRemoveRowHeader:
+		header, err := p.string()
+		if err != nil {
+			return err
+		}
+		row.RemoveHeader(header)
+	default:
+		return p.corrupt(fmt.Sprintf(walKindError, kind))
+	}
+
+	return p.end()
+}
+
+// Appends the change to the log once it's been made in memory, keeping the first error for Err
+func (r *WALRows) log(kind byte, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	writeBinaryBlock(r.w, kind, payload)
+	err := r.w.Flush()
+	if err != nil {
+		r.err = err
+	}
+
+	return err
+}
+
+// Replaces the log with one adding each row as it is now, syncing it to disk
+func (r *WALRows) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.compact()
+}
+
+// Compacts the log and closes it
+func (r *WALRows) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.compact()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Returns the first error writing the log, including those from methods that can't return one
+func (r *WALRows) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Writes the log again as a block adding each row, replacing the old log only once the new one is
+// on disk, then keeps appending changes to the new one
+// The caller must hold mu
+func (r *WALRows) compact() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	tmp := r.path + ".tmp"
+	err := writeRowsFile(tmp, walRowsMagic, func(w *bufio.Writer) {
+		for _, row := range r.rows.Items {
+			writeBinaryBlock(w, walAddRow, encodeRow(row.GetRowMap()))
+		}
+	})
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	r.file.Close()
+	err = os.Rename(tmp, r.path)
+	if err == nil {
+		r.file, err = os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+	if err != nil {
+		r.err = err
+		return err
+	}
+	r.w = bufio.NewWriter(r.file)
+
+	return nil
+}
+
+func (r *WALRows) GetRows() []RowI {
+	rows := make([]RowI, len(r.rows.Items))
+	for i, row := range r.rows.Items {
+		rows[i] = &walRow{rows: r, row: row}
+	}
+
+	return rows
+}
+
+func (r *WALRows) AddRow(row RowI) error {
+	return r.AddRows([]RowI{row})
+}
+
+// Adds copies of the rows so changes made to them after are only made through the log
+func (r *WALRows) AddRows(rows []RowI) error {
+	return r.addRows(rows, r.rows.AddRows)
+}
+
+func (r *WALRows) addCheckedRows(rows []RowI) error {
+	return r.addRows(rows, r.rows.addCheckedRows)
+}
+
+// Adds copies of the rows with add, logging them once they're added
+func (r *WALRows) addRows(rows []RowI, add func(rows []RowI) error) error {
+	copies := make([]RowI, len(rows))
+	for i, row := range rows {
+		copies[i] = copyRow(row)
+	}
+
+	err := add(copies)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range copies {
+		err = r.log(walAddRow, encodeRow(row.GetRowMap()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *WALRows) DeleteRow(row RowI) {
+	_, v := row.GetKeyHeaderAndValue()
+	r.DeleteRowWithValue(v.GetValue())
+}
+
+func (r *WALRows) DeleteRowWithValue(keyValue string) {
+	if r.rows.GetRowFromKeyHeader(keyValue) == nil {
+		return
+	}
+
+	r.rows.DeleteRowWithValue(keyValue)
+	r.log(walDeleteRow, appendBinaryString(nil, keyValue))
+}
+
+func (r *WALRows) RemoveHeader(header string) {
+	r.rows.RemoveHeader(header)
+	r.log(walRemoveHeader, appendBinaryString(nil, header))
+}
+
+func (r *WALRows) AddValueToRowWithKeyHeader(value string, header string, key string) {
+	row := r.GetRowFromKeyHeader(key)
+	if row != nil {
+		row.UpdateHeaderValue(header, value)
+	}
+}
+
+func (r *WALRows) GetRowFromKeyHeader(keyHeaderValue string) RowI {
+	row := r.rows.GetRowFromKeyHeader(keyHeaderValue)
+	if row == nil {
+		return nil
+	}
+
+	return &walRow{rows: r, row: row}
+}
+
+func (r *WALRows) GetRowsFromHeaderAndValue(header string, value string) ([]RowI, error) {
+	rows, err := r.rows.GetRowsFromHeaderAndValue(header, value)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, row := range rows {
+		rows[i] = &walRow{rows: r, row: row}
+	}
+
+	return rows, nil
+}
+
+func (row *walRow) GetKeyHeaderAndValue() (HeaderI, ValueI) {
+	return row.row.GetKeyHeaderAndValue()
+}
+
+func (row *walRow) GetValueFromHeader(header string) (ValueI, error) {
+	return row.row.GetValueFromHeader(header)
+}
+
+// Returns the values of the row
+// Changing the values doesn't log the change, so the row must be changed through the other methods
+func (row *walRow) GetRowMap() map[HeaderI]ValueI {
+	return row.row.GetRowMap()
+}
+
+func (row *walRow) HeaderExists(header string) bool {
+	return row.row.HeaderExists(header)
+}
+
+func (row *walRow) KeyHeaderValueEqual(value string) bool {
+	return row.row.KeyHeaderValueEqual(value)
+}
+
+func (row *walRow) AddHeaderWithValue(header string, keyHeader bool, t Type, value string) error {
+	err := row.row.AddHeaderWithValue(header, keyHeader, t, value)
+	if err != nil {
+		return err
+	}
+
+	flags := byte(t)
+	if keyHeader {
+		flags |= 0x80
+	}
+	payload := appendBinaryString(row.key(), header)
+	payload = append(payload, flags)
+
+	return row.rows.log(walAddRowHeader, appendBinaryString(payload, value))
+}
+
+func (row *walRow) RemoveHeader(header string) error {
+	err := row.row.RemoveHeader(header)
+	if err != nil {
+		return err
+	}
+
+	return row.rows.log(walRemoveRowHeader, appendBinaryString(row.key(), header))
+}
+
+func (row *walRow) UpdateHeaderValue(header string, value string) {
+	if !row.row.HeaderExists(header) {
+		return
+	}
+
+	row.row.UpdateHeaderValue(header, value)
+	payload := appendBinaryString(row.key(), header)
+	row.rows.log(walUpdateValue, appendBinaryString(payload, value))
+}
+
+// Returns the payload of a logged change to the row, holding its KeyHeader value
+func (row *walRow) key() []byte {
+	_, v := row.row.GetKeyHeaderAndValue()
+	return appendBinaryString(nil, v.GetValue())
+}
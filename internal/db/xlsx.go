@@ -0,0 +1,475 @@
+package db
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The longest sheet name Excel accepts
+const xlsxSheetNameMax = 31
+
+// The namespaces of the parts of a workbook
+const (
+	xlsxMainNS    = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelNS     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPackageNS = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+// The style of the header row, an index into the cellXfs of xlsxStyles
+const xlsxBoldStyle = 1
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="` + xlsxMainNS + `">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="` + xlsxPackageNS + `">` +
+	`<Relationship Id="rId1" Type="` + xlsxRelNS + `/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// Writes the DBs to w as an XLSX workbook with one sheet per DB, named after it
+// Each sheet has a bold header row with the KeyHeader in the first column, then one row per row of
+// the DB, with VALUE_NUMBER values as number cells and empty values as empty cells
+// NaN and Inf are written as text cells, as number cells can't hold them
+func ExportXLSX(w io.Writer, dbs ...DB) error {
+	zw := zip.NewWriter(w)
+	names := xlsxSheetNames(dbs)
+
+	var types, workbook, rels strings.Builder
+	types.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	types.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	types.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	types.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	types.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	types.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	workbook.WriteString(`<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxRelNS + `"><sheets>`)
+
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	rels.WriteString(`<Relationships xmlns="` + xlsxPackageNS + `">`)
+
+	for i, d := range dbs {
+		part := fmt.Sprintf("worksheets/sheet%d.xml", i+1)
+
+		f, err := zw.Create("xl/" + part)
+		if err != nil {
+			return err
+		}
+		err = writeXLSXSheet(f, d)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&types, `<Override PartName="/xl/%s" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, part)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(names[i]), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/worksheet" Target="%s"/>`, i+1, xlsxRelNS, part)
+	}
+
+	types.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/styles" Target="styles.xml"/>`, len(dbs)+1, xlsxRelNS)
+	rels.WriteString(`</Relationships>`)
+
+	for _, part := range []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", types.String()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, part.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// Writes the worksheet for the DB
+func writeXLSXSheet(w io.Writer, d DB) error {
+	headers := SortedHeaders(d)
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	bw.WriteString(`<worksheet xmlns="` + xlsxMainNS + `"><sheetData>`)
+
+	bw.WriteString(`<row r="1">`)
+	for i, h := range headers {
+		fmt.Fprintf(bw, `<c r="%s1" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, xlsxColumn(i), xlsxBoldStyle, xmlEscape(h.GetName()))
+	}
+	bw.WriteString(`</row>`)
+
+	it := d.Iter(Query{})
+	defer it.Close()
+
+	for r := 2; it.Next(); r++ {
+		fmt.Fprintf(bw, `<row r="%d">`, r)
+		for i, h := range headers {
+			v, err := it.Row().GetValueFromHeader(h.GetName())
+			if err != nil || v.GetValue() == "" {
+				continue
+			}
+
+			ref := xlsxColumn(i) + strconv.Itoa(r)
+			if number, ok := FormatNumber(v.GetValue()); h.IsNumber() && ok {
+				fmt.Fprintf(bw, `<c r="%s"><v>%s</v></c>`, ref, number)
+			} else {
+				fmt.Fprintf(bw, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(v.GetValue()))
+			}
+		}
+		bw.WriteString(`</row>`)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	bw.WriteString(`</sheetData></worksheet>`)
+
+	return bw.Flush()
+}
+
+// Returns a sheet name for each DB that Excel accepts, replacing the characters it doesn't allow,
+// shortening long names and numbering names that would otherwise be the same
+func xlsxSheetNames(dbs []DB) []string {
+	names := []string{}
+	used := map[string]bool{}
+	for _, d := range dbs {
+		name := strings.Map(func(c rune) rune {
+			if strings.ContainsRune(`[]:*?/\`, c) {
+				return '_'
+			}
+			return c
+		}, d.GetName())
+		if name == "" {
+			name = "Sheet"
+		}
+
+		base := name
+		for n := 1; ; n++ {
+			if n > 1 {
+				suffix := fmt.Sprintf(" (%d)", n)
+				name = truncateRunes(base, xlsxSheetNameMax-len(suffix)) + suffix
+			} else {
+				name = truncateRunes(base, xlsxSheetNameMax)
+			}
+			// Excel compares sheet names ignoring case
+			if !used[strings.ToLower(name)] {
+				break
+			}
+		}
+		used[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+
+	return s
+}
+
+// Returns the letters naming the column with the 0 based index, so 0 is A and 26 is AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
+
+// Returns the 0 based index of the column in a cell reference like B2, or -1 if there isn't one
+func xlsxColumnIndex(ref string) int {
+	i := 0
+	n := 0
+	for ; n < len(ref) && ref[n] >= 'A' && ref[n] <= 'Z'; n++ {
+		i = i*26 + int(ref[n]-'A') + 1
+	}
+
+	return i - 1
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
+
+// Adds the rows of the first sheet of the XLSX workbook read from r, which is size bytes long, to
+// the DB, returning how many were added
+// The first row of the sheet names the header of each column and columns without a name are
+// ignored, as are rows with no values
+// The rows are added all or nothing
+// Returns an error if the workbook isn't valid, a column names a header the DB doesn't have, a
+// value doesn't match its header's type or a row can't be added
+func ImportXLSX(d DB, r io.ReaderAt, size int64) (int, error) {
+	sheet, err := readXLSXSheet(r, size)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, header := range sheet.headers {
+		if header != "" && d.GetHeader(header).GetName() == "" {
+			return 0, &Error{Err: ErrHeaderNotExist, DB: d.GetName(), Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+	}
+
+	return addXLSXRows(d, sheet)
+}
+
+// Creates a DB named name from the first sheet of the XLSX workbook read from r, which is size
+// bytes long, with the headers named by its first row and the first column as the KeyHeader
+// A header is a VALUE_NUMBER header if every cell in its column is a number cell or empty, and at
+// least one is a number
+// Returns an error if the sheet has no header row or any of the errors ImportXLSX returns
+func NewFromXLSX(name string, r io.ReaderAt, size int64) (*DBImpl, error) {
+	sheet, err := readXLSXSheet(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(sheet.headers) == 0 || sheet.headers[0] == "" {
+		return nil, &Error{Err: ErrInvalidXLSX, DB: name, Message: fmt.Sprintf(xlsxError, xlsxNoHeaderRowError)}
+	}
+
+	headers := []HeaderI{}
+	for i, header := range sheet.headers {
+		if header == "" {
+			continue
+		}
+
+		t := VALUE_STRING
+		number := false
+		for _, row := range sheet.rows {
+			if i >= len(row) || row[i].value == "" {
+				continue
+			}
+			number = row[i].number
+			if !number {
+				break
+			}
+		}
+		if number {
+			t = VALUE_NUMBER
+		}
+		headers = append(headers, &Header{Name: header, KeyHeader: i == 0, Type: t})
+	}
+
+	db, err := New(name, headers, sheet.headers[0])
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = addXLSXRows(db, sheet)
+	if err != nil {
+		return nil, err
+	}
+	db.journal.clear()
+
+	return db, nil
+}
+
+// Adds the rows of the sheet to the DB all or nothing
+func addXLSXRows(d DB, sheet *xlsxSheet) (int, error) {
+	rows := []RowI{}
+	numbers := []int{}
+	for n, cells := range sheet.rows {
+		values := map[string]string{}
+		for i, cell := range cells {
+			if i < len(sheet.headers) && sheet.headers[i] != "" && cell.value != "" {
+				values[sheet.headers[i]] = cell.value
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		for header, value := range values {
+			err := ValidateValue(d.GetHeader(header), value)
+			if err != nil {
+				return 0, &Error{Err: ErrInvalidXLSX, DB: d.GetName(), Message: fmt.Sprintf(xlsxRowError, sheet.numbers[n], err), Cause: err}
+			}
+		}
+
+		row, err := NewRowFromMap(d, values)
+		if err != nil {
+			return 0, &Error{Err: ErrInvalidXLSX, DB: d.GetName(), Message: fmt.Sprintf(xlsxRowError, sheet.numbers[n], err), Cause: err}
+		}
+		rows = append(rows, row)
+		numbers = append(numbers, sheet.numbers[n])
+	}
+
+	errs, err := d.AddRows(rows, AddRowsOptions{AllOrNothing: true})
+	if i, err := FirstRowError(errs); err != nil {
+		return 0, &Error{Err: ErrInvalidXLSX, DB: d.GetName(), Message: fmt.Sprintf(xlsxRowError, numbers[i], err), Cause: err}
+	}
+
+	return len(rows), err
+}
+
+// Reads the header row and the values of the rows below it from the first sheet of the workbook
+func readXLSXSheet(r io.ReaderAt, size int64) (*xlsxSheet, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, invalidXLSX(err.Error(), err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbookXML
+	if err := readXLSXPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, invalidXLSX(xlsxNoSheetError, nil)
+	}
+
+	var rels xlsxRelsXML
+	if err := readXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	target := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RelID {
+			target = rel.Target
+		}
+	}
+	// Targets are relative to the workbook unless they start with a slash
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var shared xlsxSharedStringsXML
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXLSXPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var data xlsxWorksheetXML
+	if err := readXLSXPart(files, target, &data); err != nil {
+		return nil, err
+	}
+
+	sheet := &xlsxSheet{}
+	for n, row := range data.Rows {
+		cells := []xlsxCell{}
+		for i, c := range row.Cells {
+			column := i
+			if c.Ref != "" {
+				column = xlsxColumnIndex(c.Ref)
+			}
+			if column < 0 {
+				return nil, invalidXLSX(fmt.Sprintf(xlsxCellError, c.Ref), nil)
+			}
+			for len(cells) <= column {
+				cells = append(cells, xlsxCell{})
+			}
+
+			cell, err := c.cell(shared.Items)
+			if err != nil {
+				return nil, err
+			}
+			cells[column] = cell
+		}
+
+		number := row.Number
+		if number == 0 {
+			number = n + 1
+		}
+
+		if n == 0 {
+			for _, cell := range cells {
+				sheet.headers = append(sheet.headers, strings.TrimSpace(cell.value))
+			}
+			continue
+		}
+		sheet.rows = append(sheet.rows, cells)
+		sheet.numbers = append(sheet.numbers, number)
+	}
+
+	return sheet, nil
+}
+
+// Decodes the XML of the part of the workbook with the given name into v
+func readXLSXPart(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return invalidXLSX(fmt.Sprintf(xlsxMissingPartError, name), nil)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return invalidXLSX(err.Error(), err)
+	}
+	defer rc.Close()
+
+	err = xml.NewDecoder(rc).Decode(v)
+	if err != nil {
+		return invalidXLSX(fmt.Sprintf(xlsxPartError, name, err), err)
+	}
+
+	return nil
+}
+
+func invalidXLSX(reason string, cause error) error {
+	return &Error{Err: ErrInvalidXLSX, Message: fmt.Sprintf(xlsxError, reason), Cause: cause}
+}
+
+// Returns the value of the cell, looking up shared strings in shared
+func (c xlsxCellXML) cell(shared []xlsxTextXML) (xlsxCell, error) {
+	switch c.Type {
+	case "", "n":
+		return xlsxCell{value: strings.TrimSpace(c.Value), number: c.Value != ""}, nil
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || i < 0 || i >= len(shared) {
+			return xlsxCell{}, invalidXLSX(fmt.Sprintf(xlsxSharedStringError, c.Value, c.Ref), err)
+		}
+		return xlsxCell{value: shared[i].text()}, nil
+	case "inlineStr":
+		return xlsxCell{value: c.Inline.text()}, nil
+	case "b":
+		if c.Value == "1" {
+			return xlsxCell{value: "TRUE"}, nil
+		}
+		return xlsxCell{value: "FALSE"}, nil
+	default:
+		// Formula results and errors are kept as text
+		return xlsxCell{value: c.Value}, nil
+	}
+}
+
+// Returns the text of a string, joining the runs of rich text
+func (t xlsxTextXML) text() string {
+	return t.Text + strings.Join(t.Runs, "")
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the contents of the part of the zip file with the given name
+func zipPart(t *testing.T, data []byte, name string) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+
+	f, err := zr.Open(name)
+	assert.Nil(t, err)
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	assert.Nil(t, err)
+
+	return string(content)
+}
+
+// Returns a workbook made of the given parts
+func newZip(t *testing.T, parts map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = io.WriteString(f, content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestExportXLSX(t *testing.T) {
+	db := newNumberDB(t)
+	other, err := New("a/b", []HeaderI{&Header{"ID", true, VALUE_STRING}}, "ID")
+	assert.Nil(t, err)
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, ExportXLSX(buf, db, other))
+
+	workbook := zipPart(t, buf.Bytes(), "xl/workbook.xml")
+	assert.Contains(t, workbook, `<sheet name="Test" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, workbook, `<sheet name="a_b" sheetId="2" r:id="rId2"/>`)
+
+	sheet := zipPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheet, `<row r="1"><c r="A1" t="inlineStr" s="1"><is><t xml:space="preserve">Title</t></is></c>`+
+		`<c r="B1" t="inlineStr" s="1"><is><t xml:space="preserve">Points</t></is></c>`)
+	assert.Contains(t, sheet, `<row r="2"><c r="A2" t="inlineStr"><is><t xml:space="preserve">a</t></is></c><c r="B2"><v>100</v></c><c r="C2"><v>10</v></c></row>`)
+	// Empty values have no cell
+	assert.Contains(t, sheet, `<c r="B3"><v>50</v></c></row>`)
+	assert.Contains(t, zipPart(t, buf.Bytes(), "xl/styles.xml"), "<b/>")
+}
+
+func TestExportXLSXNumbers(t *testing.T) {
+	headers := []HeaderI{&Header{"ID", true, VALUE_STRING}, &Header{"N", false, VALUE_NUMBER}}
+	db, err := New("Numbers", headers, "ID")
+	assert.Nil(t, err)
+	for _, values := range []map[string]string{{"ID": "a", "N": "+1"}, {"ID": "b", "N": "NaN"}, {"ID": "c", "N": "-Inf"}, {"ID": "d", "N": "007"}} {
+		row, err := NewRowFromMap(db, values)
+		assert.Nil(t, err)
+		assert.Nil(t, db.AddRow(row))
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, ExportXLSX(buf, db))
+	data := buf.Bytes()
+	sheet := zipPart(t, data, "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheet, `<c r="B2"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="B3" t="inlineStr"><is><t xml:space="preserve">NaN</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B4" t="inlineStr"><is><t xml:space="preserve">-Inf</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B5"><v>7</v></c>`)
+
+	imported, err := New("Numbers", headers, "ID")
+	assert.Nil(t, err)
+	n, err := ImportXLSX(imported, bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, n)
+	for key, value := range map[string]string{"a": "1", "b": "NaN", "c": "-Inf", "d": "7"} {
+		assert.Equal(t, value, getValue(t, imported, key, "N"), key)
+	}
+}
+
+func TestImportXLSX(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, ExportXLSX(buf, newNumberDB(t)))
+	data := buf.Bytes()
+
+	db, err := New("Test", []HeaderI{
+		&Header{"Title", true, VALUE_STRING},
+		&Header{"Trophies", false, VALUE_NUMBER},
+		&Header{"Points", false, VALUE_NUMBER},
+	}, "Title")
+	assert.Nil(t, err)
+	n, err := ImportXLSX(db, bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "100", getValue(t, db, "a", "Points"))
+	assert.Equal(t, "", getValue(t, db, "b", "Trophies"))
+
+	// The rows are added all or nothing
+	n, err = ImportXLSX(db, bytes.NewReader(data), int64(len(data)))
+	assert.Equal(t, 0, n)
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	assert.True(t, errors.Is(err, ErrInvalidXLSX))
+
+	other, err := New("Other", []HeaderI{&Header{"Title", true, VALUE_STRING}}, "Title")
+	assert.Nil(t, err)
+	_, err = ImportXLSX(other, bytes.NewReader(data), int64(len(data)))
+	assert.True(t, errors.Is(err, ErrHeaderNotExist))
+
+	created, err := NewFromXLSX("Created", bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, "Title", created.GetKeyHeader())
+	assert.True(t, created.GetHeader("Points").IsNumber())
+	assert.True(t, created.GetHeader("Trophies").IsNumber())
+	assert.Equal(t, 2, len(created.GetRows()))
+}
+
+func TestImportXLSXFromExcel(t *testing.T) {
+	// Workbooks saved by Excel use shared strings, may skip cells and name sheets freely
+	data := newZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Games" sheetId="3" r:id="rId7"/><sheet name="Other" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId7" Target="/xl/worksheets/games.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Title</t></si><si><t>Hours</t></si><si><r><t>Jak </t></r><r><t>2</t></r></si><si><t>Done</t></si></sst>`,
+		"xl/worksheets/games.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c><c r="D1" t="s"><v>3</v></c></row>` +
+			`<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3"><v>99</v></c><c r="C3"><v>23.5</v></c><c r="D3" t="b"><v>1</v></c></row>` +
+			`<row r="4"></row>` +
+			`</sheetData></worksheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData></sheetData></worksheet>`,
+	})
+
+	db, err := NewFromXLSX("Games", bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, "Title", db.GetKeyHeader())
+	// The column without a header is ignored
+	assert.Equal(t, 3, len(db.GetHeaders()))
+	assert.True(t, db.GetHeader("Hours").IsNumber())
+	assert.False(t, db.GetHeader("Done").IsNumber())
+	assert.Equal(t, 1, len(db.GetRows()))
+	assert.Equal(t, "23.5", getValue(t, db, "Jak 2", "Hours"))
+	assert.Equal(t, "TRUE", getValue(t, db, "Jak 2", "Done"))
+}
+
+func TestImportXLSXErrors(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"not a zip":   []byte("not a zip"),
+		"no workbook": newZip(t, map[string]string{}),
+		"no sheets": newZip(t, map[string]string{
+			"xl/workbook.xml": `<workbook><sheets></sheets></workbook>`,
+		}),
+		"bad shared string": newZip(t, map[string]string{
+			"xl/workbook.xml":            `<workbook><sheets><sheet r:id="rId1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml":   `<worksheet><sheetData><row><c t="s"><v>5</v></c></row></sheetData></worksheet>`,
+		}),
+		"no header row": newZip(t, map[string]string{
+			"xl/workbook.xml":            `<workbook><sheets><sheet r:id="rId1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml":   `<worksheet><sheetData></sheetData></worksheet>`,
+		}),
+	} {
+		_, err := NewFromXLSX("Bad", bytes.NewReader(data), int64(len(data)))
+		assert.True(t, errors.Is(err, ErrInvalidXLSX), name)
+	}
+}
+
+func TestXLSXSheetNames(t *testing.T) {
+	dbs := []DB{}
+	for _, name := range []string{"Games", "games", strings.Repeat("x", 40), "a:b"} {
+		db, err := New(name, []HeaderI{&Header{"ID", true, VALUE_STRING}}, "ID")
+		assert.Nil(t, err)
+		dbs = append(dbs, db)
+	}
+
+	assert.Equal(t, []string{"Games", "games (2)", strings.Repeat("x", 31), "a_b"}, xlsxSheetNames(dbs))
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, name, xlsxColumn(i))
+		assert.Equal(t, i, xlsxColumnIndex(name+"12"))
+	}
+}
@@ -2,8 +2,10 @@ package demo
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/internal/render"
 	"github.com/brownlow2/pdb/pkg/dbmanager"
 )
 
@@ -85,13 +87,8 @@ func Demo() {
 
 func printRows(d db.DB) {
 	fmt.Println("Rows:")
-	for _, r := range d.GetRows() {
-		rowMap := r.GetRowMap()
-		for h, v := range rowMap {
-			fmt.Printf("%s: %s\n", h.GetName(), v.GetValue())
-		}
-		fmt.Println()
-	}
+	render.Render(os.Stdout, d.GetRows(), render.Options{Style: render.UNICODE})
+	fmt.Println()
 }
 
 func createRowFromMap(m map[string]string, t template) db.RowI {
@@ -0,0 +1,245 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// The characters used to draw a box around a table
+type box struct {
+	horizontal, vertical                  string
+	topLeft, topMiddle, topRight          string
+	middleLeft, middle, middleRight       string
+	bottomLeft, bottomMiddle, bottomRight string
+}
+
+var (
+	asciiBox   = box{"-", "|", "+", "+", "+", "+", "+", "+", "+", "+", "+"}
+	unicodeBox = box{"─", "│", "┌", "┬", "┐", "├", "┼", "┤", "└", "┴", "┘"}
+)
+
+// Renders the rows as a table in the style given by opts
+// The columns are taken from the headers in the rows unless opts.Columns is set
+// Returns an error if a column in opts.Columns isn't in any of the rows
+func Render(w io.Writer, rows []db.RowI, opts Options) error {
+	columns, err := columnsFor(rows, opts.Columns)
+	if err != nil {
+		return err
+	}
+
+	cells := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		line := make([]string, len(columns))
+		for i, c := range columns {
+			v, err := row.GetValueFromHeader(c.Name)
+			if err == nil {
+				line[i] = truncate(v.GetValue(), opts.MaxWidth, opts.Style == ASCII)
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	switch opts.Style {
+	case ASCII:
+		return renderBox(w, columns, cells, asciiBox)
+	case UNICODE:
+		return renderBox(w, columns, cells, unicodeBox)
+	case MARKDOWN:
+		return renderMarkdown(w, columns, cells)
+	case HTML:
+		return renderHTML(w, columns, cells)
+	}
+
+	return errors.New(fmt.Sprintf(unknownStyleError, opts.Style))
+}
+
+// Returns the columns for the rows, either the given names in order or every header found in
+// the rows with the KeyHeader first and the rest sorted by name
+func columnsFor(rows []db.RowI, names []string) ([]*column, error) {
+	headers := map[string]db.HeaderI{}
+	key := ""
+	for _, row := range rows {
+		for h := range row.GetRowMap() {
+			headers[h.GetName()] = h
+			if h.IsKeyHeader() {
+				key = h.GetName()
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		for name := range headers {
+			if name != key {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		if key != "" {
+			names = append([]string{key}, names...)
+		}
+	}
+
+	columns := []*column{}
+	for _, name := range names {
+		h, exists := headers[name]
+		if !exists && len(rows) > 0 {
+			return nil, &db.Error{Err: db.ErrHeaderNotExist, Header: name, Message: fmt.Sprintf(headerNotExistError, name)}
+		}
+
+		columns = append(columns, &column{
+			Name:   name,
+			Number: exists && h.IsNumber(),
+			Width:  utf8.RuneCountInString(name),
+		})
+	}
+
+	return columns, nil
+}
+
+// Shortens the value to at most max characters, marking that it was cut with an ellipsis
+func truncate(value string, max int, ascii bool) string {
+	if max <= 0 || utf8.RuneCountInString(value) <= max {
+		return value
+	}
+
+	ellipsis := "…"
+	if ascii {
+		ellipsis = "..."
+	}
+	if max <= utf8.RuneCountInString(ellipsis) {
+		return string([]rune(value)[:max])
+	}
+
+	return string([]rune(value)[:max-utf8.RuneCountInString(ellipsis)]) + ellipsis
+}
+
+// Pads the value to the column's width, on the left for number columns
+func pad(value string, c *column) string {
+	padding := strings.Repeat(" ", c.Width-utf8.RuneCountInString(value))
+	if c.Number {
+		return padding + value
+	}
+
+	return value + padding
+}
+
+func widen(columns []*column, cells [][]string) {
+	for _, line := range cells {
+		for i, cell := range line {
+			if width := utf8.RuneCountInString(cell); width > columns[i].Width {
+				columns[i].Width = width
+			}
+		}
+	}
+}
+
+func renderBox(w io.Writer, columns []*column, cells [][]string, b box) error {
+	widen(columns, cells)
+
+	rule := func(left string, middle string, right string) string {
+		parts := []string{}
+		for _, c := range columns {
+			parts = append(parts, strings.Repeat(b.horizontal, c.Width+2))
+		}
+		return left + strings.Join(parts, middle) + right + "\n"
+	}
+	line := func(values []string) string {
+		parts := []string{}
+		for i, c := range columns {
+			parts = append(parts, " "+pad(values[i], c)+" ")
+		}
+		return b.vertical + strings.Join(parts, b.vertical) + b.vertical + "\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(rule(b.topLeft, b.topMiddle, b.topRight))
+	// Column names are always left aligned, only the values of number columns are right aligned
+	header := []string{}
+	for _, c := range columns {
+		header = append(header, " "+c.Name+strings.Repeat(" ", c.Width-utf8.RuneCountInString(c.Name))+" ")
+	}
+	sb.WriteString(b.vertical + strings.Join(header, b.vertical) + b.vertical + "\n")
+	sb.WriteString(rule(b.middleLeft, b.middle, b.middleRight))
+	for _, values := range cells {
+		sb.WriteString(line(values))
+	}
+	sb.WriteString(rule(b.bottomLeft, b.bottomMiddle, b.bottomRight))
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func renderMarkdown(w io.Writer, columns []*column, cells [][]string) error {
+	escape := func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " ")
+	}
+	for i, c := range columns {
+		c.Name = escape(c.Name)
+		for _, line := range cells {
+			line[i] = escape(line[i])
+		}
+	}
+	widen(columns, cells)
+	for _, c := range columns {
+		// The separator needs at least three dashes
+		if c.Width < 3 {
+			c.Width = 3
+		}
+	}
+
+	var sb strings.Builder
+	header := []string{}
+	separator := []string{}
+	for _, c := range columns {
+		header = append(header, c.Name+strings.Repeat(" ", c.Width-utf8.RuneCountInString(c.Name)))
+		if c.Number {
+			separator = append(separator, strings.Repeat("-", c.Width-1)+":")
+		} else {
+			separator = append(separator, strings.Repeat("-", c.Width))
+		}
+	}
+	sb.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	sb.WriteString("| " + strings.Join(separator, " | ") + " |\n")
+	for _, values := range cells {
+		line := []string{}
+		for i, c := range columns {
+			line = append(line, pad(values[i], c))
+		}
+		sb.WriteString("| " + strings.Join(line, " | ") + " |\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func renderHTML(w io.Writer, columns []*column, cells [][]string) error {
+	var sb strings.Builder
+	sb.WriteString("<table>\n  <thead>\n    <tr>")
+	for _, c := range columns {
+		sb.WriteString("<th>" + html.EscapeString(c.Name) + "</th>")
+	}
+	sb.WriteString("</tr>\n  </thead>\n  <tbody>\n")
+	for _, values := range cells {
+		sb.WriteString("    <tr>")
+		for i, c := range columns {
+			if c.Number {
+				sb.WriteString(`<td style="text-align: right">`)
+			} else {
+				sb.WriteString("<td>")
+			}
+			sb.WriteString(html.EscapeString(values[i]) + "</td>")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("  </tbody>\n</table>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
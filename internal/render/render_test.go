@@ -0,0 +1,105 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func createRows() []db.RowI {
+	newRow := func(title string, platform string, hours string) db.RowI {
+		return &db.Row{
+			RowMap: map[db.HeaderI]db.ValueI{
+				&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}:     &db.Value{Value: title},
+				&db.Header{Name: "Platform", KeyHeader: false, Type: db.VALUE_STRING}: &db.Value{Value: platform},
+				&db.Header{Name: "Hours", KeyHeader: false, Type: db.VALUE_NUMBER}:    &db.Value{Value: hours},
+			},
+		}
+	}
+
+	return []db.RowI{
+		newRow("Jak 2", "PS4", "23"),
+		newRow("Hogwarts Legacy", "PS5", "155"),
+	}
+}
+
+func TestRenderASCII(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := Render(out, createRows(), Options{Style: ASCII})
+	assert.Nil(t, err)
+	assert.Equal(t, `+-----------------+-------+----------+
+| Title           | Hours | Platform |
++-----------------+-------+----------+
+| Jak 2           |    23 | PS4      |
+| Hogwarts Legacy |   155 | PS5      |
++-----------------+-------+----------+
+`, out.String())
+}
+
+func TestRenderUnicode(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := Render(out, createRows(), Options{Style: UNICODE, Columns: []string{"Hours", "Title"}, MaxWidth: 6})
+	assert.Nil(t, err)
+	assert.Equal(t, `┌───────┬────────┐
+│ Hours │ Title  │
+├───────┼────────┤
+│    23 │ Jak 2  │
+│   155 │ Hogwa… │
+└───────┴────────┘
+`, out.String())
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	rows := createRows()
+	rows[0].UpdateHeaderValue("Platform", "PS4|PS5")
+
+	out := &bytes.Buffer{}
+	err := Render(out, rows, Options{Style: MARKDOWN, Columns: []string{"Title", "Platform", "Hours"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `| Title           | Platform | Hours |
+| --------------- | -------- | ----: |
+| Jak 2           | PS4\|PS5 |    23 |
+| Hogwarts Legacy | PS5      |   155 |
+`, out.String())
+}
+
+func TestRenderHTML(t *testing.T) {
+	rows := createRows()[:1]
+	rows[0].UpdateHeaderValue("Title", "<Jak & Daxter>")
+
+	out := &bytes.Buffer{}
+	err := Render(out, rows, Options{Style: HTML, Columns: []string{"Title", "Hours"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `<table>
+  <thead>
+    <tr><th>Title</th><th>Hours</th></tr>
+  </thead>
+  <tbody>
+    <tr><td>&lt;Jak &amp; Daxter&gt;</td><td style="text-align: right">23</td></tr>
+  </tbody>
+</table>
+`, out.String())
+}
+
+func TestRenderErrors(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := Render(out, createRows(), Options{Columns: []string{"Missing"}})
+	assert.Error(t, err)
+
+	err = Render(out, createRows(), Options{Style: Style(10)})
+	assert.Error(t, err)
+
+	err = Render(out, []db.RowI{}, Options{Columns: []string{"Title"}})
+	assert.Nil(t, err)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 0, false))
+	assert.Equal(t, "short", truncate("short", 5, false))
+	assert.Equal(t, "sho…", truncate("short", 4, false))
+	assert.Equal(t, "s...", truncate("short", 4, true))
+	assert.Equal(t, "sh", truncate("short", 2, true))
+}
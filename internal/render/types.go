@@ -0,0 +1,40 @@
+package render
+
+var (
+	unknownStyleError   = "unknown table style %d"
+	headerNotExistError = "header '%s' does not exist"
+)
+
+// The style a table is rendered in with the following values:
+// ASCII: A box drawn with +, - and | characters
+// UNICODE: A box drawn with Unicode box drawing characters
+// MARKDOWN: A GitHub flavoured Markdown table
+// HTML: An HTML <table> element
+type Style int
+
+const (
+	ASCII Style = iota
+	UNICODE
+	MARKDOWN
+	HTML
+)
+
+// The options used when rendering rows holding the following fields:
+// Style: The style of the table
+// Columns: The headers to render in order, or all of them with the KeyHeader first if empty
+// MaxWidth: The maximum number of characters in a cell before it is truncated, 0 for no limit
+type Options struct {
+	Style    Style
+	Columns  []string
+	MaxWidth int
+}
+
+// The implementation of a column in a rendered table holding the following fields:
+// Name: The header name shown at the top of the column
+// Number: True if the column holds a VALUE_NUMBER header and is right aligned
+// Width: The width of the widest cell in the column
+type column struct {
+	Name   string
+	Number bool
+	Width  int
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// Creates an empty Log writing each entry to w as a JSON line, or keeping them in memory only
+// if w is nil
+func New(w io.Writer) *Log {
+	return &Log{w: w}
+}
+
+// Creates a Log holding the JSON lines entries read from r, as written by a Log or Export,
+// writing new entries to w
+// Returns an error if an entry can't be read
+func Load(r io.Reader, w io.Writer) (*Log, error) {
+	l := New(w)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Entry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, &db.Error{Err: ErrInvalidEntry, Value: strconv.Itoa(line), Message: fmt.Sprintf(invalidEntryError, line, err), Cause: err}
+		}
+		l.entries = append(l.entries, e)
+	}
+
+	return l, scanner.Err()
+}
+
+// Records a change made to a DB, making the Log a db.Auditor
+func (l *Log) Record(e db.Event) {
+	entry := Entry{
+		Time:      e.Time,
+		Actor:     e.Actor,
+		DB:        e.DB,
+		Operation: e.Type.String(),
+		Key:       e.Key,
+		Header:    e.Header,
+		OldValue:  e.OldValue,
+		NewValue:  e.NewValue,
+		Values:    e.Row,
+	}
+	if e.Type == db.EVENT_REMOVE_HEADER {
+		entry.Values = e.Values
+	}
+
+	l.Append(entry)
+}
+
+// Adds the entry to the end of the log, setting its Seq and its Time if it isn't set
+// Returns the entry as it was recorded
+func (l *Log) Append(e Entry) Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = 1
+	if len(l.entries) > 0 {
+		e.Seq = l.entries[len(l.entries)-1].Seq + 1
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	l.entries = append(l.entries, e)
+
+	if l.w != nil && l.err == nil {
+		l.err = writeEntry(l.w, e)
+	}
+
+	return e
+}
+
+// Returns the first error writing an entry, after which entries are only kept in memory
+func (l *Log) Err() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.err
+}
+
+// Returns the entries matching the query, oldest first
+func (l *Log) Query(q Query) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := []Entry{}
+	for _, e := range l.entries {
+		if q.Limit > 0 && len(entries) == q.Limit {
+			break
+		}
+
+		if q.matches(e) {
+			entries = append(entries, copyEntry(e))
+		}
+	}
+
+	return entries
+}
+
+// Writes the entries matching the query to w as JSON lines, oldest first
+func (l *Log) Export(w io.Writer, q Query) error {
+	for _, e := range l.Query(q) {
+		err := writeEntry(w, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q Query) matches(e Entry) bool {
+	switch {
+	case q.Actor != "" && e.Actor != q.Actor:
+		return false
+	case q.DB != "" && e.DB != q.DB:
+		return false
+	case q.Operation != "" && e.Operation != q.Operation:
+		return false
+	case q.Key != "" && e.Key != q.Key:
+		return false
+	case q.Header != "" && e.Header != q.Header:
+		return false
+	case !q.Since.IsZero() && e.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.Time.Before(q.Until):
+		return false
+	case e.Seq <= q.After:
+		return false
+	}
+
+	return true
+}
+
+func writeEntry(w io.Writer, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Returns a copy of the entry so callers can't change the log through its Values
+func copyEntry(e Entry) Entry {
+	if e.Values != nil {
+		values := make(map[string]string, len(e.Values))
+		for k, v := range e.Values {
+			values[k] = v
+		}
+		e.Values = values
+	}
+
+	return e
+}
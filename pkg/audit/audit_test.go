@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func TestRecord(t *testing.T) {
+	log := New(nil)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	log.Record(db.Event{Seq: 7, Type: db.EVENT_UPDATE_VALUE, DB: "Games", Time: at, Actor: "alice", Key: "Jak 2", Header: "Hours", OldValue: "20", NewValue: "23"})
+	log.Record(db.Event{Type: db.EVENT_REMOVE_ROW, DB: "Games", Time: at, Key: "Jak 2", Row: map[string]string{"Title": "Jak 2"}})
+	log.Record(db.Event{Type: db.EVENT_REMOVE_HEADER, DB: "Games", Time: at, Header: "Hours", Values: map[string]string{"Jak 2": "23"}})
+
+	assert.Equal(t, []Entry{
+		{Seq: 1, Time: at, Actor: "alice", DB: "Games", Operation: "update_value", Key: "Jak 2", Header: "Hours", OldValue: "20", NewValue: "23"},
+		{Seq: 2, Time: at, DB: "Games", Operation: "remove_row", Key: "Jak 2", Values: map[string]string{"Title": "Jak 2"}},
+		{Seq: 3, Time: at, DB: "Games", Operation: "remove_header", Header: "Hours", Values: map[string]string{"Jak 2": "23"}},
+	}, log.Query(Query{}))
+}
+
+func TestQuery(t *testing.T) {
+	log := New(nil)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, actor := range []string{"alice", "bob", "alice", "carol"} {
+		log.Append(Entry{Time: start.Add(time.Duration(i) * time.Hour), Actor: actor, DB: "Games", Operation: "add_row", Key: fmt.Sprint(i)})
+	}
+	log.Append(Entry{Actor: "alice", DB: "Games", Operation: OPERATION_REMOVE_DB})
+
+	keys := func(entries []Entry) []string {
+		k := []string{}
+		for _, e := range entries {
+			k = append(k, e.Key)
+		}
+		return k
+	}
+
+	assert.Equal(t, 5, len(log.Query(Query{})))
+	assert.Equal(t, []string{"0", "2", ""}, keys(log.Query(Query{Actor: "alice"})))
+	assert.Equal(t, []string{"0", "2"}, keys(log.Query(Query{Actor: "alice", Operation: "add_row"})))
+	assert.Equal(t, []string{"1", "2"}, keys(log.Query(Query{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)})))
+	assert.Equal(t, []string{"3", ""}, keys(log.Query(Query{After: 3})))
+	assert.Equal(t, []string{"0", "1"}, keys(log.Query(Query{Limit: 2})))
+	assert.Equal(t, []string{"3"}, keys(log.Query(Query{Key: "3"})))
+	assert.Equal(t, 0, len(log.Query(Query{DB: "Other"})))
+
+	// Entries returned can't change the log
+	entries := log.Query(Query{})
+	entries[0].Actor = "mallory"
+	assert.Equal(t, "alice", log.Query(Query{})[0].Actor)
+	assert.False(t, log.Query(Query{})[4].Time.IsZero())
+}
+
+func TestExportAndLoad(t *testing.T) {
+	file := &bytes.Buffer{}
+	log := New(file)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log.Append(Entry{Time: at, Actor: "alice", DB: "Games", Operation: "add_row", Key: "Jak 2", Values: map[string]string{"Title": "Jak 2"}})
+	log.Append(Entry{Time: at, DB: "Games", Operation: "update_value", Key: "Jak 2", Header: "Hours", NewValue: "23"})
+	assert.Nil(t, log.Err())
+
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, `{"seq":1,"time":"2024-01-01T00:00:00Z","actor":"alice","db":"Games","operation":"add_row","key":"Jak 2","values":{"Title":"Jak 2"}}`, lines[0])
+
+	exported := &bytes.Buffer{}
+	assert.Nil(t, log.Export(exported, Query{Header: "Hours"}))
+	assert.Equal(t, lines[1]+"\n", exported.String())
+
+	loaded, err := Load(strings.NewReader(file.String()), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, log.Query(Query{}), loaded.Query(Query{}))
+	assert.Equal(t, uint64(3), loaded.Append(Entry{DB: "Games", Operation: OPERATION_CREATE_DB}).Seq)
+
+	_, err = Load(strings.NewReader(lines[0]+"\nnot json\n"), nil)
+	assert.Contains(t, err.Error(), "could not read audit entry on line 2")
+}
+
+// Fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+func TestWriteError(t *testing.T) {
+	log := New(failingWriter{})
+	log.Append(Entry{DB: "Games", Operation: OPERATION_CREATE_DB})
+	log.Append(Entry{DB: "Games", Operation: OPERATION_REMOVE_DB})
+
+	assert.Equal(t, "disk full", log.Err().Error())
+	assert.Equal(t, 2, len(log.Query(Query{})))
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	invalidEntryError = "could not read audit entry on line %d: %s"
+)
+
+// Returned, wrapped by a *db.Error, when a log being loaded has a line that isn't an entry
+var ErrInvalidEntry = errors.New("invalid audit entry")
+
+// The operations on a DBManager recorded alongside the changes made to DBs, whose operations
+// are named by db.EventType.String
+const (
+	OPERATION_CREATE_DB = "create_db"
+	OPERATION_REMOVE_DB = "remove_db"
+)
+
+// A recorded operation holding the following fields:
+// Seq: The position of the entry in the log, starting at 1
+// Time: When the operation was made
+// Actor: Who made the operation, if known
+// DB: The DB the operation was made on
+// Operation: The kind of operation, such as add_row or create_db
+// Key: The KeyHeader value of the row changed
+// Header: The header changed
+// OldValue, NewValue: The value before and after an update
+// Values: The values of an added or removed row, or the values of a removed header by key
+type Entry struct {
+	Seq       uint64            `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor,omitempty"`
+	DB        string            `json:"db"`
+	Operation string            `json:"operation"`
+	Key       string            `json:"key,omitempty"`
+	Header    string            `json:"header,omitempty"`
+	OldValue  string            `json:"oldValue,omitempty"`
+	NewValue  string            `json:"newValue,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+}
+
+// Selects entries from a Log holding the following fields, with zero values matching any entry:
+// Actor, DB, Operation, Key, Header: The value the entry's field must equal
+// Since: The earliest time an entry can have been made at
+// Until: The time an entry must have been made before
+// After: The Seq entries must come after
+// Limit: The most entries returned, oldest first
+type Query struct {
+	Actor     string
+	DB        string
+	Operation string
+	Key       string
+	Header    string
+	Since     time.Time
+	Until     time.Time
+	After     uint64
+	Limit     int
+}
+
+// The implementation of an append-only audit log holding the following fields:
+// mu: Guards the fields below so changes to different DBs can be recorded concurrently
+// entries: The entries recorded, oldest first
+// w: Where each entry is written as a JSON line as it is recorded, or nil
+// err: The first error writing to w
+type Log struct {
+	mu      sync.RWMutex
+	entries []Entry
+	w       io.Writer
+	err     error
+}
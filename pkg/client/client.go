@@ -0,0 +1,226 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/server"
+)
+
+// Creates a client for the pdb server at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: http.DefaultClient,
+		Retries:    3,
+		Backoff:    100 * time.Millisecond,
+		ctx:        context.Background(),
+	}
+}
+
+// Returns a copy of the client, and DBs retrieved from it, making requests with ctx
+func (c *Client) WithContext(ctx context.Context) *Client {
+	withCtx := *c
+	withCtx.ctx = ctx
+	return &withCtx
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Returns the sentinel for the kind of error the server responded with, so errors.Is can check
+// for the same errors as an in-process DB returns, or nil if the server didn't say
+func (e *Error) Unwrap() error {
+	return server.CodeError(e.Code)
+}
+
+func (c *Client) GetDBs() map[string]db.DB {
+	dbs, err := c.getDBs()
+	if err != nil {
+		return map[string]db.DB{}
+	}
+
+	return dbs
+}
+
+func (c *Client) GetDBsContext(ctx context.Context) (map[string]db.DB, error) {
+	return c.WithContext(ctx).getDBs()
+}
+
+func (c *Client) getDBs() (map[string]db.DB, error) {
+	var dbsJSON []server.DBJSON
+	err := c.do(http.MethodGet, "/dbs", nil, &dbsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	dbs := map[string]db.DB{}
+	for _, d := range dbsJSON {
+		dbs[d.Name] = c.newDB(d.Name, d.KeyHeader)
+	}
+
+	return dbs, nil
+}
+
+func (c *Client) CreateDB(name string, headers []db.HeaderI, keyHeader string) error {
+	body := server.DBJSON{Name: name, KeyHeader: keyHeader, Headers: []server.HeaderJSON{}}
+	for _, h := range headers {
+		body.Headers = append(body.Headers, server.HeaderJSON{Name: h.GetName(), Type: h.GetType().String()})
+	}
+
+	return c.do(http.MethodPost, "/dbs", body, nil)
+}
+
+func (c *Client) CreateDBContext(ctx context.Context, name string, headers []db.HeaderI, keyHeader string) error {
+	return c.WithContext(ctx).CreateDB(name, headers, keyHeader)
+}
+
+func (c *Client) DBExists(name string) bool {
+	_, err := c.RetrieveDB(name)
+	return err == nil
+}
+
+// Returns false if the server doesn't have the DB, or an error if it couldn't be asked
+func (c *Client) DBExistsContext(ctx context.Context, name string) (bool, error) {
+	_, err := c.RetrieveDBContext(ctx, name)
+	if errors.Is(err, dbmanager.ErrDBNotExist) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (c *Client) RetrieveDB(name string) (db.DB, error) {
+	var d server.DBJSON
+	err := c.do(http.MethodGet, dbPath(name), nil, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.newDB(d.Name, d.KeyHeader), nil
+}
+
+// Returns the DB with the given name, making its requests with ctx
+func (c *Client) RetrieveDBContext(ctx context.Context, name string) (db.DB, error) {
+	return c.WithContext(ctx).RetrieveDB(name)
+}
+
+func (c *Client) RemoveDB(name string) error {
+	return c.do(http.MethodDelete, dbPath(name), nil, nil)
+}
+
+func (c *Client) RemoveDBContext(ctx context.Context, name string) error {
+	return c.WithContext(ctx).RemoveDB(name)
+}
+
+func (c *Client) newDB(name string, keyHeader string) *DB {
+	return &DB{client: c, name: name, keyHeader: keyHeader}
+}
+
+// Sends a request with body encoded as JSON, decoding the response into out if it isn't nil
+// GET, PUT and DELETE requests are retried after network errors and 5xx or 429 responses
+// Returns an *Error if the server responds with an error
+func (c *Client) do(method string, path string, body interface{}, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	retries := 0
+	if method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete {
+		retries = c.Retries
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(method, path, data)
+		retry := err != nil && c.ctx.Err() == nil
+		if err == nil && (resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests) {
+			retry = true
+		}
+
+		if !retry || attempt >= retries {
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decodeResponse(method, path, resp, out)
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) send(method string, path string, data []byte) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if actor := c.actor(); actor != "" {
+		req.Header.Set(server.ActorHeader, actor)
+	}
+
+	return c.HTTPClient.Do(req)
+}
+
+func (c *Client) actor() string {
+	if c.Actor != "" {
+		return c.Actor
+	}
+
+	return db.ActorFromContext(c.ctx)
+}
+
+func decodeResponse(method string, path string, resp *http.Response, out interface{}) error {
+	if resp.StatusCode >= 300 {
+		var errJSON server.ErrorJSON
+		err := json.NewDecoder(resp.Body).Decode(&errJSON)
+		if err != nil || errJSON.Error == "" {
+			errJSON.Error = fmt.Sprintf(unexpectedStatus, resp.StatusCode, method, path)
+		}
+		return &Error{StatusCode: resp.StatusCode, Code: errJSON.Code, Message: errJSON.Error, Details: errJSON.Details, Codes: errJSON.Codes}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+func dbPath(name string) string {
+	return "/dbs/" + url.PathEscape(name)
+}
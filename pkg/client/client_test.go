@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ dbmanager.DBManagerContext = (*Client)(nil)
+
+func newTestClient(t *testing.T) *Client {
+	ts := httptest.NewServer(server.New(dbmanager.New()))
+	t.Cleanup(ts.Close)
+
+	c := New(ts.URL)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestCreateDB(t *testing.T) {
+	c := newTestClient(t)
+
+	headers := []db.HeaderI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "Year", Type: db.VALUE_NUMBER},
+	}
+	err := c.CreateDB("Games", headers, "Title")
+	assert.Nil(t, err)
+	assert.True(t, c.DBExists("Games"))
+	assert.False(t, c.DBExists("Films"))
+
+	err = c.CreateDB("Games", headers, "Title")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*Error).StatusCode)
+	assert.ErrorIs(t, err, dbmanager.ErrDBExists)
+
+	dbs := c.GetDBs()
+	assert.Equal(t, 1, len(dbs))
+	assert.Equal(t, "Title", dbs["Games"].GetKeyHeader())
+
+	d, err := c.RetrieveDB("Games")
+	assert.Nil(t, err)
+	assert.Equal(t, "Games", d.GetName())
+
+	_, err = c.RetrieveDB("Films")
+	assert.Equal(t, "database 'Films' does not exist", err.Error())
+	assert.ErrorIs(t, err, dbmanager.ErrDBNotExist)
+	exists, err := c.DBExistsContext(context.Background(), "Films")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	assert.Nil(t, c.RemoveDB("Games"))
+	assert.False(t, c.DBExists("Games"))
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"name": "Games", "keyHeader": "Title", "headers": []}]`))
+	}))
+	defer ts.Close()
+
+	c := New(ts.URL)
+	c.Backoff = time.Millisecond
+	dbs := c.GetDBs()
+	assert.Equal(t, int32(3), requests)
+	assert.Equal(t, 1, len(dbs))
+
+	// Requests that aren't idempotent aren't retried
+	atomic.StoreInt32(&requests, 0)
+	err := c.CreateDB("Films", []db.HeaderI{}, "Title")
+	assert.Equal(t, int32(1), requests)
+	assert.Equal(t, http.StatusServiceUnavailable, err.(*Error).StatusCode)
+
+	// Retries stop after c.Retries
+	atomic.StoreInt32(&requests, -10)
+	_, err = c.RetrieveDB("Games")
+	assert.Equal(t, int32(-6), requests)
+	assert.NotNil(t, err)
+}
+
+func TestWithContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := New(ts.URL)
+	c.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.WithContext(ctx).RetrieveDB("Games")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestActor(t *testing.T) {
+	actors := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actors <- r.Header.Get(server.ActorHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := New(ts.URL)
+	assert.Nil(t, c.WithContext(db.WithActor(context.Background(), "alice")).RemoveDB("Games"))
+	assert.Equal(t, "alice", <-actors)
+
+	c.Actor = "bob"
+	assert.Nil(t, c.WithContext(db.WithActor(context.Background(), "alice")).RemoveDB("Games"))
+	assert.Equal(t, "bob", <-actors)
+}
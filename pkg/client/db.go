@@ -0,0 +1,548 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/server"
+)
+
+// Returns a copy of the DB making requests with the client's context
+// Rows returned by a remote DB are copies, changing them doesn't change the DB on the server
+func (d *DB) WithClient(c *Client) *DB {
+	return c.newDB(d.name, d.keyHeader)
+}
+
+// Returns the last error from a method without an error return, such as GetRows or AddHeader,
+// and clears it
+func (d *DB) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.err
+	d.err = nil
+	return err
+}
+
+func (d *DB) setErr(err error) {
+	if err == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
+
+func (d *DB) GetName() string {
+	return d.name
+}
+
+func (d *DB) GetKeyHeader() string {
+	return d.keyHeader
+}
+
+func (d *DB) GetHeader(header string) db.HeaderI {
+	for _, h := range d.GetHeaders() {
+		if h.GetName() == header {
+			return h
+		}
+	}
+
+	return &db.Header{}
+}
+
+func (d *DB) GetHeaders() []db.HeaderI {
+	headers, err := d.headers()
+	d.setErr(err)
+	if err != nil {
+		return []db.HeaderI{}
+	}
+
+	return headers
+}
+
+func (d *DB) GetHeadersString() []string {
+	headersString := []string{}
+	for _, h := range d.GetHeaders() {
+		if h.IsKeyHeader() {
+			headersString = append(headersString, fmt.Sprintf("%s (K)", h.GetName()))
+		} else {
+			headersString = append(headersString, h.GetName())
+		}
+	}
+
+	return headersString
+}
+
+func (d *DB) AddHeader(header db.HeaderI) {
+	body := server.HeaderJSON{Name: header.GetName(), Type: header.GetType().String()}
+	err := d.client.do(http.MethodPost, d.path("/headers"), body, nil)
+	// Adding a header that already exists does nothing, as with an in-process DB
+	if errors.Is(err, db.ErrHeaderExists) {
+		return
+	}
+	d.setErr(err)
+}
+
+func (d *DB) RemoveHeader(header string) error {
+	err := d.client.do(http.MethodDelete, d.path("/headers/"+url.PathEscape(header)), nil, nil)
+	if errors.Is(err, db.ErrHeaderNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (d *DB) AddRow(row db.RowI) error {
+	return d.client.do(http.MethodPost, d.path("/rows"), rowToValues(row), nil)
+}
+
+func (d *DB) RemoveRow(keyValue string) error {
+	if keyValue == "" {
+		return &db.Error{Err: db.ErrKeyValueEmpty, DB: d.name, Message: keyValueEmptyError}
+	}
+
+	err := d.client.do(http.MethodDelete, d.rowPath(keyValue, ""), nil, nil)
+	if errors.Is(err, db.ErrRowNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (d *DB) GetRows() []db.RowI {
+	rows, err := d.rows(url.Values{})
+	d.setErr(err)
+	if err != nil {
+		return []db.RowI{}
+	}
+
+	return rows
+}
+
+func (d *DB) AddValueToHeader(value string, header string, key string) error {
+	err := d.client.do(http.MethodPatch, d.rowPath(key, ""), map[string]string{header: value}, nil)
+	// A missing row is ignored, as with an in-process DB
+	if errors.Is(err, db.ErrRowNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (d *DB) GetRowFromKeyHeader(value string) db.RowI {
+	headers, err := d.headers()
+	if err != nil {
+		d.setErr(err)
+		return nil
+	}
+
+	var object map[string]interface{}
+	err = d.client.do(http.MethodGet, d.rowPath(value, ""), nil, &object)
+	if err != nil {
+		if !errors.Is(err, db.ErrRowNotExist) {
+			d.setErr(err)
+		}
+		return nil
+	}
+
+	return newRow(headers, object)
+}
+
+func (d *DB) GetRowsFromHeaderAndValue(header string, value string) ([]db.RowI, error) {
+	return d.rows(url.Values{header: []string{value}})
+}
+
+func (d *DB) GetRowsFromHeaderAndValueNumberOperation(header string, value string, op string) ([]db.RowI, error) {
+	// Evaluated here rather than with a server filter so rows that aren't numbers are reported
+	// the same way as an in-process DB
+	valueF, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, &db.Error{Err: db.ErrNotANumber, DB: d.name, Value: value, Message: fmt.Sprintf(notANumberError, value)}
+	}
+
+	h := d.GetHeader(header)
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	if h.GetName() == "" {
+		return nil, &db.Error{Err: db.ErrHeaderNotExist, DB: d.name, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	all, err := d.rows(url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]db.RowI, 0)
+	for _, row := range all {
+		v, _ := row.GetValueFromHeader(header)
+		vF, err := h.Number(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if (op == "<" && vF < valueF) || (op == ">" && vF > valueF) {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+func (d *DB) Upsert(row db.RowI) error {
+	h, v := row.GetKeyHeaderAndValue()
+	if h == nil || v.GetValue() == "" {
+		return &db.Error{Err: db.ErrKeyHeaderEmpty, DB: d.name, Header: d.keyHeader, Message: fmt.Sprintf(keyHeaderEmptyError, d.keyHeader)}
+	}
+
+	if h.GetName() != d.keyHeader {
+		return &db.Error{Err: db.ErrKeyHeaderIncorrect, DB: d.name, Header: h.GetName(), Message: fmt.Sprintf(keyHeaderIncorrect, h.GetName(), d.keyHeader)}
+	}
+
+	// PATCH merges the values into an existing row, whereas PUT would clear the headers the row lacks
+	values := rowToValues(row)
+	err := d.client.do(http.MethodPatch, d.rowPath(v.GetValue(), ""), values, nil)
+	if errors.Is(err, db.ErrRowNotExist) {
+		return d.client.do(http.MethodPut, d.rowPath(v.GetValue(), ""), values, nil)
+	}
+
+	return err
+}
+
+func (d *DB) AddRows(rows []db.RowI, opts db.AddRowsOptions) ([]error, error) {
+	path := d.path("/rows")
+	if !opts.AllOrNothing {
+		path = d.path("/rows?mode=best-effort")
+	}
+
+	objects := []map[string]string{}
+	for _, row := range rows {
+		objects = append(objects, rowToValues(row))
+	}
+
+	err := d.client.do(http.MethodPost, path, objects, nil)
+	if err == nil {
+		return nil, nil
+	}
+
+	// Spread the per row errors back over the rows they belong to
+	errs := make([]error, len(rows))
+	var e *Error
+	if !errors.As(err, &e) || len(e.Details) != len(rows) {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs, nil
+	}
+
+	failed := false
+	for i, detail := range e.Details {
+		if detail != "" {
+			rowErr := &Error{StatusCode: e.StatusCode, Message: detail}
+			if len(e.Codes) == len(e.Details) {
+				rowErr.Code = e.Codes[i]
+			}
+			errs[i] = rowErr
+			failed = true
+		}
+	}
+
+	// Every row was added but a hook failed after adding them
+	if !failed {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// Selects the rows on the client, then updates them on the server by key
+// Rows changed by another client between the two requests are updated based on the
+// values they had when selected
+func (d *DB) UpdateWhere(predicate db.Predicate, values map[string]string) (int, error) {
+	keys, err := d.keys(predicate)
+	if err != nil {
+		return 0, err
+	}
+
+	var count server.CountJSON
+	err = d.client.do(http.MethodPost, d.path("/update"), server.BulkJSON{Keys: keys, Values: values}, &count)
+	return count.Count, err
+}
+
+// Selects the rows on the client, then deletes them on the server by key
+func (d *DB) DeleteWhere(predicate db.Predicate) (int, error) {
+	keys, err := d.keys(predicate)
+	if err != nil {
+		return 0, err
+	}
+
+	var count server.CountJSON
+	err = d.client.do(http.MethodPost, d.path("/delete"), server.BulkJSON{Keys: keys}, &count)
+	return count.Count, err
+}
+
+func (d *DB) Increment(key string, header string, delta float64) (float64, error) {
+	var value server.NumberJSON
+	body := server.IncrementJSON{Header: header, Delta: delta}
+	err := d.client.do(http.MethodPost, d.rowPath(key, "/increment"), body, &value)
+	return value.Value, err
+}
+
+func (d *DB) Decrement(key string, header string, delta float64) (float64, error) {
+	return d.Increment(key, header, -delta)
+}
+
+// Selects the rows on the client, then applies the expression to them on the server by key
+func (d *DB) UpdateExpression(predicate db.Predicate, expr string) (int, error) {
+	keys, err := d.keys(predicate)
+	if err != nil {
+		return 0, err
+	}
+
+	var count server.CountJSON
+	err = d.client.do(http.MethodPost, d.path("/expression"), server.BulkJSON{Keys: keys, Expression: expr}, &count)
+	return count.Count, err
+}
+
+func (d *DB) SetEmptyAsZero(emptyAsZero bool) {
+	d.setErr(d.client.do(http.MethodPut, d.path("/settings"), server.SettingsJSON{EmptyAsZero: emptyAsZero}, nil))
+}
+
+func (d *DB) History(key string) []db.Version {
+	var versionsJSON []server.VersionJSON
+	err := d.client.do(http.MethodGet, d.rowPath(key, "/history"), nil, &versionsJSON)
+	d.setErr(err)
+
+	versions := []db.Version{}
+	for _, v := range versionsJSON {
+		t, err := db.ParseEventType(v.Type)
+		if err != nil {
+			d.setErr(err)
+			return []db.Version{}
+		}
+
+		version := db.Version{Seq: v.Seq, Time: v.Time, Actor: v.Actor, Type: t, Header: v.Header, Values: v.Values}
+		if v.OldValue != nil {
+			version.OldValue = *v.OldValue
+		}
+		if v.NewValue != nil {
+			version.NewValue = *v.NewValue
+		}
+		versions = append(versions, version)
+	}
+
+	return versions
+}
+
+// Returns a copy of the DB making its reads at the given time, changes made through it fail
+func (d *DB) AsOf(t time.Time) (db.DB, error) {
+	past := d.client.newDB(d.name, d.keyHeader)
+	past.asOf = &t
+
+	// Check the time is still retained
+	err := d.client.do(http.MethodGet, past.path(""), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return past, nil
+}
+
+func (d *DB) SetRetention(retention db.Retention) {
+	body := server.RetentionJSON{MaxChanges: retention.MaxChanges}
+	if retention.MaxAge > 0 {
+		body.MaxAge = retention.MaxAge.String()
+	}
+
+	d.setErr(d.client.do(http.MethodPut, d.path("/retention"), body, nil))
+}
+
+// Returns the path of the DB followed by suffix, adding the asOf query parameter to it for
+// a DB returned by AsOf
+func (d *DB) path(suffix string) string {
+	path := dbPath(d.name) + suffix
+	if d.asOf == nil {
+		return path
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return path + separator + "asOf=" + url.QueryEscape(d.asOf.Format(time.RFC3339Nano))
+}
+
+func (d *DB) rowPath(key string, suffix string) string {
+	return d.path("/rows/" + url.PathEscape(key) + suffix)
+}
+
+func (d *DB) headers() ([]db.HeaderI, error) {
+	var dbJSON server.DBJSON
+	err := d.client.do(http.MethodGet, d.path(""), nil, &dbJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []db.HeaderI{}
+	for _, h := range dbJSON.Headers {
+		t, err := db.ParseType(h.Type)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, &db.Header{Name: h.Name, KeyHeader: h.Key, Type: t})
+	}
+
+	return headers, nil
+}
+
+// Returns an Iterator over the rows matching the query, fetched in one request when Next is
+// first called so changes made while iterating aren't seen
+func (d *DB) Iter(query db.Query) db.Iterator {
+	return &rowIterator{d: d, query: query}
+}
+
+func (it *rowIterator) Next() bool {
+	it.row = nil
+	if it.err != nil || it.closed {
+		return false
+	}
+
+	if !it.fetched {
+		it.fetched = true
+		it.err = it.fetch()
+		if it.err != nil {
+			return false
+		}
+	}
+
+	for it.i < len(it.rows) {
+		row := it.rows[it.i]
+		it.i++
+
+		matches, err := it.match(row)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if matches {
+			it.row = row
+			return true
+		}
+	}
+
+	it.rows = nil
+	return false
+}
+
+// Fetches the rows, leaving the server to select rows with equal values
+func (it *rowIterator) fetch() error {
+	headers, err := it.d.headers()
+	if err != nil {
+		return err
+	}
+
+	it.match, err = it.query.Compile(headers)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if it.query.Header != "" && (it.query.Op == "" || it.query.Op == "=") {
+		query.Set(it.query.Header, it.query.Value)
+	}
+
+	it.rows, err = it.d.rows(query)
+	return err
+}
+
+func (it *rowIterator) Row() db.RowI {
+	return it.row
+}
+
+func (it *rowIterator) Err() error {
+	return it.err
+}
+
+func (it *rowIterator) Close() error {
+	it.closed = true
+	it.row = nil
+	it.rows = nil
+
+	return nil
+}
+
+// Returns the rows matching the query's filters
+func (d *DB) rows(query url.Values) ([]db.RowI, error) {
+	headers, err := d.headers()
+	if err != nil {
+		return nil, err
+	}
+
+	var page server.RowsJSON
+	err = d.client.do(http.MethodGet, d.path("/rows?"+query.Encode()), nil, &page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []db.RowI{}
+	for _, object := range page.Rows {
+		rows = append(rows, newRow(headers, object))
+	}
+
+	return rows, nil
+}
+
+// Returns the keys of the rows matching the predicate, or nil for every row if it is nil
+func (d *DB) keys(predicate db.Predicate) ([]string, error) {
+	if predicate == nil {
+		return nil, nil
+	}
+
+	rows, err := d.rows(url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, row := range rows {
+		if predicate(row) {
+			_, v := row.GetKeyHeaderAndValue()
+			keys = append(keys, v.GetValue())
+		}
+	}
+
+	return keys, nil
+}
+
+// Creates a row from a JSON object using the DB's headers for the types and KeyHeader
+func newRow(headers []db.HeaderI, object map[string]interface{}) db.RowI {
+	row := &db.Row{RowMap: map[db.HeaderI]db.ValueI{}}
+	for _, h := range headers {
+		value := ""
+		if v, exists := object[h.GetName()]; exists && v != nil {
+			value = fmt.Sprint(v)
+		}
+		row.AddHeaderWithValue(h.GetName(), h.IsKeyHeader(), h.GetType(), value)
+	}
+
+	return row
+}
+
+func rowToValues(row db.RowI) map[string]string {
+	values := map[string]string{}
+	for h, v := range row.GetRowMap() {
+		values[h.GetName()] = v.GetValue()
+	}
+
+	return values
+}
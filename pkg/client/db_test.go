@@ -0,0 +1,205 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ db.DB = (*DB)(nil)
+
+func newTestDB(t *testing.T) *DB {
+	c := newTestClient(t)
+	headers := []db.HeaderI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "Year", Type: db.VALUE_NUMBER},
+	}
+	assert.Nil(t, c.CreateDB("Games", headers, "Title"))
+
+	d, err := c.RetrieveDB("Games")
+	assert.Nil(t, err)
+	return d.(*DB)
+}
+
+func newTestRow(d db.DB, title string, year string) db.RowI {
+	row, _ := db.NewRowFromMap(d, map[string]string{"Title": title, "Year": year})
+	return row
+}
+
+func TestHeaders(t *testing.T) {
+	d := newTestDB(t)
+
+	assert.Equal(t, []string{"Title (K)", "Year"}, d.GetHeadersString())
+	assert.True(t, d.GetHeader("Year").IsNumber())
+	assert.Equal(t, "", d.GetHeader("Genre").GetName())
+
+	d.AddHeader(&db.Header{Name: "Genre", Type: db.VALUE_STRING})
+	d.AddHeader(&db.Header{Name: "Genre", Type: db.VALUE_STRING})
+	assert.Nil(t, d.Err())
+	assert.Equal(t, 3, len(d.GetHeaders()))
+
+	assert.Nil(t, d.RemoveHeader("Genre"))
+	assert.Nil(t, d.RemoveHeader("Genre"))
+	assert.Equal(t, 2, len(d.GetHeaders()))
+}
+
+func TestRows(t *testing.T) {
+	d := newTestDB(t)
+
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 2", "2003")))
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 3", "2004")))
+	assert.Equal(t, "row with key header 'Title' and value 'Jak 2' already exists", d.AddRow(newTestRow(d, "Jak 2", "2003")).Error())
+
+	rows := d.GetRows()
+	assert.Nil(t, d.Err())
+	assert.Equal(t, 2, len(rows))
+
+	row := d.GetRowFromKeyHeader("Jak 3")
+	v, _ := row.GetValueFromHeader("Year")
+	assert.Equal(t, "2004", v.GetValue())
+	assert.Nil(t, d.GetRowFromKeyHeader("Jak 4"))
+	assert.Nil(t, d.Err())
+
+	rows, err := d.GetRowsFromHeaderAndValue("Year", "2003")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rows))
+
+	rows, err = d.GetRowsFromHeaderAndValueNumberOperation("Year", "2003", ">")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rows))
+
+	_, err = d.GetRowsFromHeaderAndValueNumberOperation("Genre", "2003", ">")
+	assert.Equal(t, "header 'Genre' does not exist", err.Error())
+
+	assert.Nil(t, d.AddValueToHeader("2005", "Year", "Jak 3"))
+	assert.Nil(t, d.AddValueToHeader("2005", "Year", "Jak 4"))
+	v, _ = d.GetRowFromKeyHeader("Jak 3").GetValueFromHeader("Year")
+	assert.Equal(t, "2005", v.GetValue())
+
+	assert.Nil(t, d.RemoveRow("Jak 3"))
+	assert.Nil(t, d.RemoveRow("Jak 3"))
+	assert.Equal(t, "key value cannot be empty", d.RemoveRow("").Error())
+	assert.Equal(t, 1, len(d.GetRows()))
+}
+
+func TestUpsertAndAddRows(t *testing.T) {
+	d := newTestDB(t)
+
+	assert.Nil(t, d.Upsert(newTestRow(d, "Jak 2", "2003")))
+	assert.Nil(t, d.Upsert(newTestRow(d, "Jak 2", "2004")))
+	v, _ := d.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Year")
+	assert.Equal(t, "2004", v.GetValue())
+
+	// Headers the row lacks keep their values
+	d.AddHeader(&db.Header{Name: "Genre", Type: db.VALUE_STRING})
+	assert.Nil(t, d.AddValueToHeader("Platformer", "Genre", "Jak 2"))
+	assert.Nil(t, d.Upsert(newTestRow(d, "Jak 2", "2005")))
+	v, _ = d.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Genre")
+	assert.Equal(t, "Platformer", v.GetValue())
+	assert.Nil(t, d.RemoveHeader("Genre"))
+
+	rows := []db.RowI{newTestRow(d, "Jak 3", "2004"), newTestRow(d, "Jak 2", "2003")}
+	errs, err := d.AddRows(rows, db.AddRowsOptions{AllOrNothing: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(errs))
+	assert.ErrorIs(t, errs[0], db.ErrBatchRejected)
+	assert.ErrorIs(t, errs[1], db.ErrDuplicateKey)
+	assert.Equal(t, "row with key header 'Title' and value 'Jak 2' already exists", errs[1].Error())
+	assert.Equal(t, 1, len(d.GetRows()))
+
+	errs, err = d.AddRows(rows, db.AddRowsOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, errs[0])
+	assert.NotNil(t, errs[1])
+	assert.Equal(t, 2, len(d.GetRows()))
+
+	errs, err = d.AddRows([]db.RowI{newTestRow(d, "Jak X", "2005")}, db.AddRowsOptions{})
+	assert.Nil(t, errs)
+	assert.Nil(t, err)
+}
+
+func TestBulk(t *testing.T) {
+	d := newTestDB(t)
+	for _, row := range []db.RowI{newTestRow(d, "Jak 2", "2003"), newTestRow(d, "Jak 3", "2004"), newTestRow(d, "Jak X", "2005")} {
+		assert.Nil(t, d.AddRow(row))
+	}
+
+	after2003 := func(row db.RowI) bool {
+		v, _ := row.GetValueFromHeader("Year")
+		return v.GetValue() > "2003"
+	}
+
+	n, err := d.UpdateExpression(after2003, `"Year" = "Year" + 10`)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	v, _ := d.GetRowFromKeyHeader("Jak X").GetValueFromHeader("Year")
+	assert.Equal(t, "2015", v.GetValue())
+
+	n, err = d.UpdateWhere(nil, map[string]string{"Year": "2000"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	value, err := d.Increment("Jak 2", "Year", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(2003), value)
+	value, err = d.Decrement("Jak 2", "Year", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(2002), value)
+
+	n, err = d.DeleteWhere(func(row db.RowI) bool {
+		v, _ := row.GetValueFromHeader("Year")
+		return v.GetValue() == "2000"
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 1, len(d.GetRows()))
+
+	d.SetEmptyAsZero(true)
+	assert.Nil(t, d.Err())
+}
+
+func TestIter(t *testing.T) {
+	d := newTestDB(t)
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 2", "2003")))
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 3", "2004")))
+	assert.Nil(t, d.AddRow(newTestRow(d, "Ratchet", "2002")))
+
+	titles := func(it db.Iterator) []string {
+		titles := []string{}
+		for row := range db.Seq(it) {
+			_, v := row.GetKeyHeaderAndValue()
+			titles = append(titles, v.GetValue())
+		}
+		assert.Nil(t, it.Err())
+		return titles
+	}
+
+	assert.Equal(t, []string{"Jak 2", "Jak 3", "Ratchet"}, titles(d.Iter(db.Query{})))
+	assert.Equal(t, []string{"Jak 3"}, titles(d.Iter(db.Query{Header: "Year", Value: "2004"})))
+	assert.Equal(t, []string{"Jak 2", "Jak 3"}, titles(d.Iter(db.Query{Header: "Year", Value: "2002", Op: ">"})))
+
+	it := d.Iter(db.Query{Header: "Genre", Value: "Platformer"})
+	assert.False(t, it.Next())
+	assert.Equal(t, "header 'Genre' does not exist", it.Err().Error())
+
+	it = d.Iter(db.Query{})
+	assert.True(t, it.Next())
+	assert.Nil(t, it.Close())
+	assert.False(t, it.Next())
+}
+
+func TestErrors(t *testing.T) {
+	d := newTestDB(t)
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 2", "2003")))
+
+	// Errors from the server match the same sentinels as an in-process DB
+	assert.ErrorIs(t, d.AddRow(newTestRow(d, "Jak 2", "2003")), db.ErrDuplicateKey)
+	_, err := d.GetRowsFromHeaderAndValue("Genre", "Platformer")
+	assert.ErrorIs(t, err, db.ErrHeaderNotExist)
+	_, err = d.Increment("Jak 3", "Year", 1)
+	assert.ErrorIs(t, err, db.ErrRowNotExist)
+	_, err = d.UpdateExpression(nil, `"Year" = "Title"`)
+	assert.ErrorIs(t, err, db.ErrHeaderNotNumber)
+	assert.ErrorIs(t, d.RemoveRow(""), db.ErrKeyValueEmpty)
+}
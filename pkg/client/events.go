@@ -0,0 +1,176 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/server"
+)
+
+// Streams the DB's changes from the server's events endpoint
+// The channel is closed when the stream ends, and if the stream couldn't be opened or was broken
+// off the error is available from Err
+// Resume after the channel closes by subscribing again with After set to the last Seq received
+// An EVENT_RESET means the changes since After are lost, so the DB must be read again
+func (d *DB) Subscribe(filter db.EventFilter) (<-chan db.Event, func()) {
+	query := url.Values{}
+	for _, t := range filter.Types {
+		query.Add("type", t.String())
+	}
+	for _, key := range filter.Keys {
+		query.Add("key", key)
+	}
+	for _, header := range filter.Headers {
+		query.Add("header", header)
+	}
+
+	ctx, cancel := context.WithCancel(d.client.ctx)
+	resp, err := d.openEvents(ctx, d.path("/events?"+query.Encode()), filter.After)
+	if err != nil {
+		cancel()
+		d.setErr(err)
+
+		events := make(chan db.Event)
+		close(events)
+		return events, func() {}
+	}
+
+	buffer := filter.Buffer
+	if buffer <= 0 {
+		buffer = 64
+	}
+
+	events := make(chan db.Event, buffer)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		d.readEvents(ctx, resp, func(e db.Event) bool {
+			select {
+			case events <- e:
+				return true
+			default:
+			}
+
+			switch filter.Policy {
+			case db.POLICY_BLOCK:
+				select {
+				case events <- e:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			case db.POLICY_DISCONNECT:
+				return false
+			}
+			return true
+		})
+	}()
+
+	return events, cancel
+}
+
+func (d *DB) openEvents(ctx context.Context, path string, after uint64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.client.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if after > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(after, 10))
+	}
+
+	resp, err := d.client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, decodeResponse(http.MethodGet, path, resp, nil)
+	}
+
+	return resp, nil
+}
+
+// Parses the Server-Sent Events in the response, passing each to deliver until it returns
+// false or the stream ends
+// Rows can be larger than bufio.Scanner's default line length, so lines of up to 16MB are read
+func (d *DB) readEvents(ctx context.Context, resp *http.Response, deliver func(e db.Event) bool) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	data := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data: ") {
+			data += strings.TrimPrefix(line, "data: ")
+			continue
+		}
+
+		// Ids and event names are repeated in the data, and lines starting with : are heartbeats
+		if line != "" || data == "" {
+			continue
+		}
+
+		var eventJSON server.EventJSON
+		err := json.Unmarshal([]byte(data), &eventJSON)
+		data = ""
+		if err != nil {
+			d.setErr(err)
+			return
+		}
+
+		e, err := d.newEvent(eventJSON)
+		if err != nil {
+			d.setErr(err)
+			return
+		}
+
+		if !deliver(e) || ctx.Err() != nil {
+			return
+		}
+	}
+
+	// Reading fails once the subscription is cancelled, which isn't an error
+	if ctx.Err() == nil {
+		d.setErr(scanner.Err())
+	}
+}
+
+func (d *DB) newEvent(eventJSON server.EventJSON) (db.Event, error) {
+	t, err := db.ParseEventType(eventJSON.Type)
+	if err != nil {
+		return db.Event{}, err
+	}
+
+	e := db.Event{
+		Seq:    eventJSON.Seq,
+		Type:   t,
+		DB:     d.name,
+		Time:   eventJSON.Time,
+		Actor:  eventJSON.Actor,
+		Key:    eventJSON.Key,
+		Header: eventJSON.Header,
+		Row:    eventJSON.Row,
+	}
+
+	if eventJSON.HeaderType != "" {
+		e.HeaderType, err = db.ParseType(eventJSON.HeaderType)
+		if err != nil {
+			return db.Event{}, err
+		}
+	}
+	if eventJSON.OldValue != nil {
+		e.OldValue = *eventJSON.OldValue
+	}
+	if eventJSON.NewValue != nil {
+		e.NewValue = *eventJSON.NewValue
+	}
+
+	return e, nil
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, events <-chan db.Event) db.Event {
+	select {
+	case e, ok := <-events:
+		assert.True(t, ok)
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+
+	return db.Event{}
+}
+
+func TestSubscribe(t *testing.T) {
+	d := newTestDB(t)
+
+	events, cancel := d.Subscribe(db.EventFilter{})
+	assert.Nil(t, d.Err())
+
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 2", "2003")))
+	assert.Nil(t, d.AddValueToHeader("2004", "Year", "Jak 2"))
+
+	e := receive(t, events)
+	assert.Equal(t, db.EVENT_ADD_ROW, e.Type)
+	assert.Equal(t, "Games", e.DB)
+	assert.Equal(t, "2003", e.Row["Year"])
+	added := e.Seq
+
+	e = receive(t, events)
+	assert.Equal(t, db.EVENT_UPDATE_VALUE, e.Type)
+	assert.Equal(t, "2003", e.OldValue)
+	assert.Equal(t, "2004", e.NewValue)
+
+	cancel()
+	for range events {
+	}
+
+	// Resume after the row was added, only wanting header changes and updates to Year
+	filter := db.EventFilter{After: added, Types: []db.EventType{db.EVENT_UPDATE_VALUE, db.EVENT_ADD_HEADER}}
+	events, cancel = d.Subscribe(filter)
+	defer cancel()
+
+	d.AddHeader(&db.Header{Name: "Genre", Type: db.VALUE_STRING})
+	assert.Equal(t, "2004", receive(t, events).NewValue)
+	e = receive(t, events)
+	assert.Equal(t, db.EVENT_ADD_HEADER, e.Type)
+	assert.Equal(t, "Genre", e.Header)
+}
+
+func TestSubscribeLargeEvent(t *testing.T) {
+	d := newTestDB(t)
+	events, cancel := d.Subscribe(db.EventFilter{})
+	defer cancel()
+
+	// The event's data line is longer than bufio.Scanner's default limit
+	title := strings.Repeat("Jak ", 50000)
+	assert.Nil(t, d.AddRow(newTestRow(d, title, "2003")))
+	assert.Equal(t, title, receive(t, events).Key)
+	assert.Nil(t, d.Err())
+}
+
+func TestSubscribeMissingDB(t *testing.T) {
+	d := newTestDB(t)
+	c := d.client
+	assert.Nil(t, c.RemoveDB("Games"))
+
+	events, cancel := d.Subscribe(db.EventFilter{})
+	defer cancel()
+
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Equal(t, "database 'Games' does not exist", d.Err().Error())
+}
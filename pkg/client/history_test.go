@@ -0,0 +1,40 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	d := newTestDB(t)
+
+	assert.Nil(t, d.AddRow(newTestRow(d, "Jak 2", "2003")))
+	assert.Nil(t, d.AddValueToHeader("2004", "Year", "Jak 2"))
+
+	versions := d.History("Jak 2")
+	assert.Nil(t, d.Err())
+	assert.Equal(t, 2, len(versions))
+	assert.Equal(t, db.EVENT_ADD_ROW, versions[0].Type)
+	assert.Equal(t, "2003", versions[1].OldValue)
+	assert.Equal(t, "2004", versions[1].Values["Year"])
+
+	past, err := d.AsOf(versions[0].Time)
+	assert.Nil(t, err)
+	v, _ := past.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Year")
+	assert.Equal(t, "2003", v.GetValue())
+	assert.Equal(t, 1, len(past.GetRows()))
+	assert.Equal(t, 1, len(past.History("Jak 2")))
+
+	err = past.AddRow(newTestRow(d, "Jak 3", "2004"))
+	assert.Equal(t, "database 'Games' is read-only", err.Error())
+
+	d.SetRetention(db.Retention{MaxChanges: 1})
+	assert.Nil(t, d.Err())
+	assert.Equal(t, 1, len(d.History("Jak 2")))
+
+	_, err = d.AsOf(time.Time{})
+	assert.Equal(t, 410, err.(*Error).StatusCode)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+var (
+	// These match the messages returned by an in-process DB for checks made before any request
+	keyValueEmptyError  = "key value cannot be empty"
+	keyHeaderEmptyError = "key header '%s' must not be empty"
+	keyHeaderIncorrect  = "key header '%s' incorrect, expected '%s'"
+	headerNotExistError = "header '%s' does not exist"
+	notANumberError     = "value %s is not a number"
+	unexpectedStatus    = "unexpected status %d from %s %s"
+)
+
+// The implementation of dbmanager.DBManager over the pdb HTTP API holding the following fields:
+// BaseURL: The URL the pdb server is listening on, e.g. http://localhost:8080
+// HTTPClient: The client requests are sent with
+// Retries: The number of times idempotent requests are retried after a network error or 5xx response
+// Backoff: The delay before the first retry, doubling for each retry after it
+// Actor: Who the changes made are attributed to, defaulting to the actor set on ctx with db.WithActor
+// ctx: The context requests are made with
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Retries    int
+	Backoff    time.Duration
+	Actor      string
+
+	ctx context.Context
+}
+
+// The implementation of db.DB for a DB on a pdb server holding the following fields:
+// client: The client requests are sent with
+// name: The name of the DB
+// keyHeader: The KeyHeader of the DB, which can't change once created
+// asOf: The time reads are made at for a DB returned by AsOf, nil for the current DB
+// err: The last error from a method that has no error return
+type DB struct {
+	client    *Client
+	name      string
+	keyHeader string
+	asOf      *time.Time
+
+	mu  sync.Mutex
+	err error
+}
+
+// The implementation of db.Iterator for a DB on a pdb server holding the following fields:
+// d: The DB being iterated over
+// query: The query rows must match
+// match: Reports whether a row matches the query, set once the rows are fetched
+// rows: The rows fetched from the server
+// i: The index of the next row to check
+// row: The current row
+// err: The error that stopped the iteration
+// fetched: Whether the rows have been fetched
+// closed: Whether Close has been called
+type rowIterator struct {
+	d       *DB
+	query   db.Query
+	match   func(row db.RowI) (bool, error)
+	rows    []db.RowI
+	i       int
+	row     db.RowI
+	err     error
+	fetched bool
+	closed  bool
+}
+
+// The implementation of an error response from the pdb server holding the following fields:
+// StatusCode: The HTTP status of the response
+// Code: The kind of error, as returned by server.ErrorCode, or empty if the server didn't say
+// Message: The error message, the same as the in-process DB would return
+// Details: Per row errors for requests adding several rows
+// Codes: The kind of each of the per row errors in Details
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []string
+	Codes      []string
+}
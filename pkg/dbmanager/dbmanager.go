@@ -1,10 +1,11 @@
 package dbmanager
 
 import (
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/audit"
 )
 
 func New() *DBManagerImpl {
@@ -14,33 +15,276 @@ func New() *DBManagerImpl {
 }
 
 func (dbm *DBManagerImpl) GetDBs() map[string]db.DB {
-	return dbm.DBs
+	dbs, _ := dbm.GetDBsContext(context.Background())
+	return dbs
+}
+
+func (dbm *DBManagerImpl) GetDBsContext(ctx context.Context) (map[string]db.DB, error) {
+	dbm.mu.RLock()
+	defer dbm.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Return a copy so callers can range over it while DBs are created or removed
+	actor := db.ActorFromContext(ctx)
+	dbs := make(map[string]db.DB, len(dbm.DBs))
+	for name, d := range dbm.DBs {
+		dbs[name] = attribute(d, actor)
+	}
+
+	return dbs, nil
 }
 
 func (dbm *DBManagerImpl) CreateDB(name string, headers []db.HeaderI, keyHeader string) error {
-	if dbm.DBExists(name) {
-		return errors.New(fmt.Sprintf(dbExistsError, name))
+	return dbm.CreateDBContext(context.Background(), name, headers, keyHeader)
+}
+
+func (dbm *DBManagerImpl) CreateDBContext(ctx context.Context, name string, headers []db.HeaderI, keyHeader string) error {
+	return dbm.CreateDBWithOptionsContext(ctx, name, headers, keyHeader, CreateDBOptions{})
+}
+
+// Creates a DB like CreateDB, keeping its rows in the backend chosen by opts
+// Returns an error if the backend isn't registered or can't open the DB's rows
+func (dbm *DBManagerImpl) CreateDBWithOptions(name string, headers []db.HeaderI, keyHeader string, opts CreateDBOptions) error {
+	return dbm.CreateDBWithOptionsContext(context.Background(), name, headers, keyHeader, opts)
+}
+
+func (dbm *DBManagerImpl) CreateDBWithOptionsContext(ctx context.Context, name string, headers []db.HeaderI, keyHeader string, opts CreateDBOptions) error {
+	dbm.mu.Lock()
+	defer dbm.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if dbm.dbExists(name) {
+		return &db.Error{Err: ErrDBExists, DB: name, Message: fmt.Sprintf(dbExistsError, name)}
 	}
 
-	db, err := db.New(name, headers, keyHeader)
+	backend := opts.Backend
+	if backend == "" {
+		backend = db.BACKEND_MEMORY
+	}
+	d, err := db.NewWithBackend(name, headers, keyHeader, backend)
 	if err != nil {
 		return err
 	}
 
-	dbm.DBs[name] = db
+	dbm.DBs[name] = d
+	if dbm.audit != nil {
+		d.SetAuditor(dbm.audit)
+		dbm.audit.Append(audit.Entry{Actor: db.ActorFromContext(ctx), DB: name, Operation: audit.OPERATION_CREATE_DB})
+	}
+
+	return nil
+}
+
+// Adds a DB created elsewhere, such as one read from a binary snapshot
+// Returns an error if a DB with its name already exists
+func (dbm *DBManagerImpl) addDB(d *db.DBImpl) error {
+	dbm.mu.Lock()
+	defer dbm.mu.Unlock()
+
+	name := d.GetName()
+	if dbm.dbExists(name) {
+		return &db.Error{Err: ErrDBExists, DB: name, Message: fmt.Sprintf(dbExistsError, name)}
+	}
+
+	dbm.DBs[name] = d
+	if dbm.audit != nil {
+		d.SetAuditor(dbm.audit)
+	}
 
 	return nil
 }
 
 func (dbm *DBManagerImpl) RetrieveDB(name string) (db.DB, error) {
-	if !dbm.DBExists(name) {
-		return nil, errors.New(fmt.Sprintf(dbNotExistError, name))
+	return dbm.RetrieveDBContext(context.Background(), name)
+}
+
+func (dbm *DBManagerImpl) RetrieveDBContext(ctx context.Context, name string) (db.DB, error) {
+	dbm.mu.RLock()
+	defer dbm.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !dbm.dbExists(name) {
+		return nil, &db.Error{Err: ErrDBNotExist, DB: name, Message: fmt.Sprintf(dbNotExistError, name)}
+	}
+
+	return attribute(dbm.DBs[name], db.ActorFromContext(ctx)), nil
+}
+
+func (dbm *DBManagerImpl) RemoveDB(name string) error {
+	return dbm.RemoveDBContext(context.Background(), name)
+}
+
+func (dbm *DBManagerImpl) RemoveDBContext(ctx context.Context, name string) error {
+	dbm.mu.Lock()
+	defer dbm.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !dbm.dbExists(name) {
+		return &db.Error{Err: ErrDBNotExist, DB: name, Message: fmt.Sprintf(dbNotExistError, name)}
+	}
+
+	d := dbm.DBs[name]
+	delete(dbm.DBs, name)
+	if dbm.removed == nil {
+		dbm.removed = map[string]struct{}{}
+	}
+	dbm.removed[name] = struct{}{}
+	if dbm.audit != nil {
+		dbm.audit.Append(audit.Entry{Actor: db.ActorFromContext(ctx), DB: name, Operation: audit.OPERATION_REMOVE_DB})
+	}
+
+	// The files of a DB kept by a file backend are left for it to be opened again
+	if d, ok := d.(*db.DBImpl); ok {
+		return d.Close()
+	}
+
+	return nil
+}
+
+// Closes the files of every DB kept by a file backend, returning the first error
+func (dbm *DBManagerImpl) Close() error {
+	dbm.mu.Lock()
+	defer dbm.mu.Unlock()
+
+	var err error
+	for _, d := range dbm.DBs {
+		if d, ok := d.(*db.DBImpl); ok {
+			if closeErr := d.Close(); err == nil {
+				err = closeErr
+			}
+		}
 	}
 
-	return dbm.DBs[name], nil
+	return err
 }
 
 func (dbm *DBManagerImpl) DBExists(name string) bool {
+	exists, _ := dbm.DBExistsContext(context.Background(), name)
+	return exists
+}
+
+func (dbm *DBManagerImpl) DBExistsContext(ctx context.Context, name string) (bool, error) {
+	dbm.mu.RLock()
+	defer dbm.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return dbm.dbExists(name), nil
+}
+
+func (dbm *DBManagerImpl) dbExists(name string) bool {
 	_, exists := dbm.DBs[name]
 	return exists
 }
+
+// Records every change made to the manager and its DBs in log from now on, nil to stop
+// recording them
+func (dbm *DBManagerImpl) SetAuditLog(log *audit.Log) {
+	dbm.mu.Lock()
+	defer dbm.mu.Unlock()
+
+	dbm.audit = log
+	for _, d := range dbm.DBs {
+		if impl, ok := d.(*db.DBImpl); ok {
+			if log == nil {
+				impl.SetAuditor(nil)
+			} else {
+				impl.SetAuditor(log)
+			}
+		}
+	}
+}
+
+// Returns the log set with SetAuditLog, or nil
+func (dbm *DBManagerImpl) AuditLog() *audit.Log {
+	dbm.mu.RLock()
+	defer dbm.mu.RUnlock()
+
+	return dbm.audit
+}
+
+// Returns a view of the manager attributing the changes made through it, including to the
+// DBs it returns, to actor
+func (dbm *DBManagerImpl) As(actor string) DBManager {
+	return &actorManager{DBManagerImpl: dbm, actor: actor}
+}
+
+// Returns a view of the manager attributing changes to the actor set on ctx with db.WithActor,
+// or the manager itself if there is none
+func (dbm *DBManagerImpl) WithContext(ctx context.Context) DBManager {
+	actor := db.ActorFromContext(ctx)
+	if actor == "" {
+		return dbm
+	}
+
+	return dbm.As(actor)
+}
+
+func (a *actorManager) GetDBs() map[string]db.DB {
+	dbs, _ := a.GetDBsContext(context.Background())
+	return dbs
+}
+
+func (a *actorManager) GetDBsContext(ctx context.Context) (map[string]db.DB, error) {
+	return a.DBManagerImpl.GetDBsContext(db.WithActor(ctx, a.actor))
+}
+
+func (a *actorManager) CreateDB(name string, headers []db.HeaderI, keyHeader string) error {
+	return a.CreateDBContext(context.Background(), name, headers, keyHeader)
+}
+
+func (a *actorManager) CreateDBContext(ctx context.Context, name string, headers []db.HeaderI, keyHeader string) error {
+	return a.DBManagerImpl.CreateDBContext(db.WithActor(ctx, a.actor), name, headers, keyHeader)
+}
+
+func (a *actorManager) CreateDBWithOptions(name string, headers []db.HeaderI, keyHeader string, opts CreateDBOptions) error {
+	return a.CreateDBWithOptionsContext(context.Background(), name, headers, keyHeader, opts)
+}
+
+func (a *actorManager) CreateDBWithOptionsContext(ctx context.Context, name string, headers []db.HeaderI, keyHeader string, opts CreateDBOptions) error {
+	return a.DBManagerImpl.CreateDBWithOptionsContext(db.WithActor(ctx, a.actor), name, headers, keyHeader, opts)
+}
+
+func (a *actorManager) RetrieveDB(name string) (db.DB, error) {
+	return a.RetrieveDBContext(context.Background(), name)
+}
+
+func (a *actorManager) RetrieveDBContext(ctx context.Context, name string) (db.DB, error) {
+	return a.DBManagerImpl.RetrieveDBContext(db.WithActor(ctx, a.actor), name)
+}
+
+func (a *actorManager) RemoveDB(name string) error {
+	return a.RemoveDBContext(context.Background(), name)
+}
+
+func (a *actorManager) RemoveDBContext(ctx context.Context, name string) error {
+	return a.DBManagerImpl.RemoveDBContext(db.WithActor(ctx, a.actor), name)
+}
+
+// Returns a view of the DB attributing changes to actor, or the DB itself if there is no actor
+// or it doesn't support it
+func attribute(d db.DB, actor string) db.DB {
+	if actor == "" {
+		return d
+	}
+
+	if a, ok := d.(db.Attributable); ok {
+		return a.As(actor)
+	}
+
+	return d
+}
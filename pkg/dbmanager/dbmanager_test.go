@@ -1,12 +1,15 @@
 package dbmanager
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/audit"
 )
 
 func TestNew(t *testing.T) {
@@ -45,6 +48,29 @@ func TestCreateDB(t *testing.T) {
 	testCreateDB(t, "new db", []db.HeaderI{&db.Header{"NotKey", true, db.VALUE_STRING}}, "Title", true)
 }
 
+func TestCreateDBWithOptions(t *testing.T) {
+	db.RegisterBackend(db.BACKEND_WAL, db.WALBackend(t.TempDir()))
+	headers := []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}
+
+	dbm := New()
+	err := dbm.CreateDBWithOptions("Games", headers, "Title", CreateDBOptions{Backend: "tape"})
+	assert.True(t, errors.Is(err, db.ErrUnknownBackend))
+	assert.False(t, dbm.DBExists("Games"))
+
+	assert.Nil(t, dbm.CreateDBWithOptions("Games", headers, "Title", CreateDBOptions{Backend: db.BACKEND_WAL}))
+	d, err := dbm.RetrieveDB("Games")
+	assert.Nil(t, err)
+	assert.Nil(t, d.AddRow(&db.Row{RowMap: map[db.HeaderI]db.ValueI{headers[0]: &db.Value{Value: "Jak 2"}}}))
+
+	// Removing the DB closes its log, which a new DB with the same name reads its rows from
+	assert.Nil(t, dbm.RemoveDB("Games"))
+	assert.Nil(t, dbm.As("someone").(*actorManager).CreateDBWithOptions("Games", headers, "Title", CreateDBOptions{Backend: db.BACKEND_WAL}))
+	d, err = dbm.RetrieveDB("Games")
+	assert.Nil(t, err)
+	assert.NotNil(t, d.GetRowFromKeyHeader("Jak 2"))
+	assert.Nil(t, dbm.Close())
+}
+
 func TestDBExists(t *testing.T) {
 	dbi, err := db.New("test", []db.HeaderI{&db.Header{"Title", true, db.VALUE_STRING}}, "Title")
 	assert.Nil(t, err)
@@ -57,3 +83,109 @@ func TestDBExists(t *testing.T) {
 	assert.True(t, dbm.DBExists("test"))
 	assert.False(t, dbm.DBExists("Not exists"))
 }
+
+func TestRetrieveDB(t *testing.T) {
+	dbi, err := db.New("test", []db.HeaderI{&db.Header{"Title", true, db.VALUE_STRING}}, "Title")
+	assert.Nil(t, err)
+	dbm := &DBManagerImpl{
+		DBs: map[string]db.DB{
+			"test": dbi,
+		},
+	}
+
+	d, err := dbm.RetrieveDB("test")
+	assert.Nil(t, err)
+	assert.Equal(t, dbi, d)
+
+	d, err = dbm.RetrieveDB("Not exists")
+	assert.Error(t, err)
+	assert.Nil(t, d)
+}
+
+func TestRemoveDB(t *testing.T) {
+	dbm := &DBManagerImpl{
+		DBs: map[string]db.DB{
+			"existing db": &db.DBImpl{},
+		},
+	}
+
+	err := dbm.RemoveDB("Not exists")
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(dbm.GetDBs()))
+
+	err = dbm.RemoveDB("existing db")
+	assert.Nil(t, err)
+	assert.False(t, dbm.DBExists("existing db"))
+}
+
+func TestAuditLog(t *testing.T) {
+	dbm := New()
+	assert.Nil(t, dbm.CreateDB("Before", []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}, "Title"))
+
+	log := audit.New(nil)
+	dbm.SetAuditLog(log)
+	assert.Equal(t, log, dbm.AuditLog())
+
+	alice := dbm.WithContext(db.WithActor(context.Background(), "alice"))
+	assert.Nil(t, alice.CreateDB("Games", []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}, "Title"))
+	d, err := alice.RetrieveDB("Games")
+	assert.Nil(t, err)
+	assert.Nil(t, d.AddRow(&db.Row{RowMap: map[db.HeaderI]db.ValueI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}: &db.Value{Value: "Jak 2"},
+	}}))
+
+	before, _ := dbm.RetrieveDB("Before")
+	before.AddHeader(&db.Header{Name: "Platform", Type: db.VALUE_STRING})
+	assert.Nil(t, dbm.As("bob").RemoveDB("Games"))
+
+	entries := log.Query(audit.Query{})
+	assert.Equal(t, 4, len(entries))
+	assert.Equal(t, audit.Entry{Seq: 1, Time: entries[0].Time, Actor: "alice", DB: "Games", Operation: audit.OPERATION_CREATE_DB}, entries[0])
+	assert.Equal(t, "add_row", entries[1].Operation)
+	assert.Equal(t, "alice", entries[1].Actor)
+	assert.Equal(t, "Jak 2", entries[1].Key)
+	assert.Equal(t, "add_header", entries[2].Operation)
+	assert.Equal(t, "Before", entries[2].DB)
+	assert.Equal(t, "", entries[2].Actor)
+	assert.Equal(t, audit.OPERATION_REMOVE_DB, entries[3].Operation)
+	assert.Equal(t, "bob", entries[3].Actor)
+
+	assert.Equal(t, dbm, dbm.WithContext(context.Background()))
+
+	dbm.SetAuditLog(nil)
+	before.AddHeader(&db.Header{Name: "Hours", Type: db.VALUE_NUMBER})
+	assert.Equal(t, 4, len(log.Query(audit.Query{})))
+}
+
+func TestContext(t *testing.T) {
+	var dbm DBManagerContext = New()
+	headers := []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Nil(t, dbm.CreateDBContext(ctx, "Games", headers, "Title"))
+	exists, err := dbm.DBExistsContext(ctx, "Games")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	cancel()
+	assert.ErrorIs(t, dbm.CreateDBContext(ctx, "Other", headers, "Title"), context.Canceled)
+	assert.ErrorIs(t, dbm.RemoveDBContext(ctx, "Games"), context.Canceled)
+	_, err = dbm.RetrieveDBContext(ctx, "Games")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = dbm.GetDBsContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = dbm.DBExistsContext(ctx, "Games")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, dbm.DBExists("Games"))
+	assert.False(t, dbm.DBExists("Other"))
+
+	// DBs retrieved with an actor set on the context attribute their changes to it
+	events, unsubscribe := dbm.GetDBs()["Games"].Subscribe(db.EventFilter{})
+	defer unsubscribe()
+	d, err := dbm.RetrieveDBContext(db.WithActor(context.Background(), "alice"), "Games")
+	assert.Nil(t, err)
+	assert.Nil(t, d.AddRow(&db.Row{RowMap: map[db.HeaderI]db.ValueI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}: &db.Value{Value: "Jak 2"},
+	}}))
+	assert.Equal(t, "alice", (<-events).Actor)
+}
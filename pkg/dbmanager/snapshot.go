@@ -0,0 +1,283 @@
+package dbmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// The extensions of JSON snapshots and of snapshots in the binary encoding of db.WriteBinary
+const (
+	snapshotExt       = ".json"
+	binarySnapshotExt = ".pdb"
+)
+
+// The JSON snapshot of a DB written to the data directory holding the following fields:
+// Name: The name of the DB
+// KeyHeader: The KeyHeader of the DB
+// Headers: The headers of the DB, KeyHeader first
+// Rows: Each row as a map of header names to values
+// Backend: The backend keeping the DB's rows, empty for db.BACKEND_MEMORY
+type snapshot struct {
+	Name      string              `json:"name"`
+	KeyHeader string              `json:"keyHeader"`
+	Headers   []snapshotHeader    `json:"headers"`
+	Rows      []map[string]string `json:"rows"`
+	Backend   string              `json:"backend,omitempty"`
+}
+
+// The JSON snapshot of a header holding the following fields:
+// Name: The name of the header
+// Type: The type of the header, either "string" or "number"
+type snapshotHeader struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Loads every DB snapshot in dir, JSON or binary, into a new DBManagerImpl
+// Each DB keeps its rows in the backend it was saved from, the rows in the snapshot replacing any
+// the backend already holds, so the backend must be registered first
+// A missing directory is treated as empty
+func Load(dir string) (*DBManagerImpl, error) {
+	dbm := New()
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return dbm, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		switch filepath.Ext(entry.Name()) {
+		case snapshotExt:
+			err = dbm.loadSnapshot(filepath.Join(dir, entry.Name()))
+		case binarySnapshotExt:
+			err = dbm.loadBinarySnapshot(filepath.Join(dir, entry.Name()))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dbm, nil
+}
+
+// Writes a JSON snapshot of every DB to dir, creating it if needed
+func (dbm *DBManagerImpl) Save(dir string) error {
+	return dbm.save(dir, snapshotExt, func(w io.Writer, d db.DB) error {
+		data, err := json.MarshalIndent(newSnapshot(d), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
+
+// Writes a snapshot of every DB to dir in the binary encoding of db.WriteBinary, which is smaller
+// and faster to load than JSON, creating dir if needed
+func (dbm *DBManagerImpl) SaveBinary(dir string) error {
+	return dbm.save(dir, binarySnapshotExt, func(w io.Writer, d db.DB) error {
+		return db.WriteBinary(d, w)
+	})
+}
+
+// Writes each DB to a file in dir with the given extension, removing any snapshot of it with the
+// other extension so Load doesn't find two
+// The snapshots of DBs removed since the last save are deleted so Load doesn't bring them back
+func (dbm *DBManagerImpl) save(dir string, ext string, write func(w io.Writer, d db.DB) error) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = dbm.removeSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	for name, d := range dbm.GetDBs() {
+		// Write to a temporary file first so a failed save doesn't corrupt the last snapshot
+		path := filepath.Join(dir, snapshotFileName(name, ext))
+		tmp := path + ".tmp"
+		f, err := os.Create(tmp)
+		if err != nil {
+			return err
+		}
+		err = write(f, d)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+
+		err = os.Rename(tmp, path)
+		if err != nil {
+			return err
+		}
+
+		for _, other := range []string{snapshotExt, binarySnapshotExt} {
+			if other != ext {
+				os.Remove(filepath.Join(dir, snapshotFileName(name, other)))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Deletes the snapshots in dir of the DBs removed since the last save that haven't been created
+// again
+func (dbm *DBManagerImpl) removeSnapshots(dir string) error {
+	dbm.mu.Lock()
+	defer dbm.mu.Unlock()
+
+	for name := range dbm.removed {
+		if dbm.dbExists(name) {
+			delete(dbm.removed, name)
+			continue
+		}
+
+		for _, ext := range []string{snapshotExt, binarySnapshotExt} {
+			err := os.Remove(filepath.Join(dir, snapshotFileName(name, ext)))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		delete(dbm.removed, name)
+	}
+
+	return nil
+}
+
+func (dbm *DBManagerImpl) loadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var s snapshot
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+	}
+
+	headers := []db.HeaderI{}
+	for _, h := range s.Headers {
+		t, err := db.ParseType(h.Type)
+		if err != nil {
+			return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+		}
+		headers = append(headers, &db.Header{Name: h.Name, KeyHeader: h.Name == s.KeyHeader, Type: t})
+	}
+
+	err = dbm.CreateDB(s.Name, headers, s.KeyHeader)
+	if err != nil {
+		return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+	}
+
+	d, _ := dbm.RetrieveDB(s.Name)
+	rows := make([]db.RowI, 0, len(s.Rows))
+	for _, values := range s.Rows {
+		row, err := db.NewRowFromMap(d, values)
+		if err != nil {
+			return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+		}
+		rows = append(rows, row)
+	}
+
+	// The DB was just created, so it has no hooks to fail
+	errs, _ := d.AddRows(rows, db.AddRowsOptions{AllOrNothing: true})
+	if _, err := db.FirstRowError(errs); err != nil {
+		return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+	}
+
+	if b, ok := d.(db.Backed); ok && s.Backend != "" {
+		err = b.UseBackend(s.Backend)
+		if err != nil {
+			return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+		}
+	}
+
+	// Loading the rows isn't a change to undo
+	if u, ok := d.(db.Undoable); ok {
+		u.ClearUndo()
+	}
+
+	return nil
+}
+
+func (dbm *DBManagerImpl) loadBinarySnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d, err := db.ReadBinaryWithBackend(f)
+	if err != nil {
+		return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+	}
+
+	err = dbm.addDB(d)
+	if err != nil {
+		return &db.Error{Err: ErrSnapshot, Value: path, Message: fmt.Sprintf(snapshotError, path, err), Cause: err}
+	}
+
+	return nil
+}
+
+func newSnapshot(d db.DB) *snapshot {
+	s := &snapshot{
+		Name:      d.GetName(),
+		KeyHeader: d.GetKeyHeader(),
+		Headers:   []snapshotHeader{},
+		Rows:      []map[string]string{},
+	}
+	if b, ok := d.(db.Backed); ok && b.BackendName() != db.BACKEND_MEMORY {
+		s.Backend = b.BackendName()
+	}
+
+	headers := d.GetHeaders()
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i].IsKeyHeader() != headers[j].IsKeyHeader() {
+			return headers[i].IsKeyHeader()
+		}
+		return headers[i].GetName() < headers[j].GetName()
+	})
+	for _, h := range headers {
+		s.Headers = append(s.Headers, snapshotHeader{h.GetName(), h.GetType().String()})
+	}
+
+	for _, row := range d.GetRows() {
+		values := map[string]string{}
+		for h, v := range row.GetRowMap() {
+			values[h.GetName()] = v.GetValue()
+		}
+		s.Rows = append(s.Rows, values)
+	}
+
+	return s
+}
+
+// Returns the file name used for a DB's snapshot with the extension, escaping characters that
+// aren't safe in paths
+func snapshotFileName(name string, ext string) string {
+	return strings.ReplaceAll(url.PathEscape(name), "%20", " ") + ext
+}
@@ -0,0 +1,155 @@
+package dbmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	dbm := New()
+	err := dbm.CreateDB("Platinum Tracker", []db.HeaderI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "Hours to Platinum", KeyHeader: false, Type: db.VALUE_NUMBER},
+	}, "Title")
+	assert.Nil(t, err)
+	d, _ := dbm.RetrieveDB("Platinum Tracker")
+	row, err := db.NewRowFromMap(d, map[string]string{"Title": "Jak 2", "Hours to Platinum": "23"})
+	assert.Nil(t, err)
+	assert.Nil(t, d.AddRow(row))
+
+	err = dbm.Save(dir)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "Platinum Tracker.json"))
+	assert.Nil(t, err)
+
+	loaded, err := Load(dir)
+	assert.Nil(t, err)
+	assert.True(t, loaded.DBExists("Platinum Tracker"))
+	ld, _ := loaded.RetrieveDB("Platinum Tracker")
+	assert.Equal(t, "Title", ld.GetKeyHeader())
+	assert.True(t, ld.GetHeader("Hours to Platinum").IsNumber())
+	assert.True(t, ld.GetHeader("Title").IsKeyHeader())
+	assert.Equal(t, 1, len(ld.GetRows()))
+	v, err := ld.GetRowFromKeyHeader("Jak 2").GetValueFromHeader("Hours to Platinum")
+	assert.Nil(t, err)
+	assert.Equal(t, "23", v.GetValue())
+
+	// Loading isn't a change that can be undone
+	assert.True(t, errors.Is(ld.(db.Undoable).Undo(), db.ErrNothingToUndo))
+}
+
+func TestSaveRemovedDB(t *testing.T) {
+	dir := t.TempDir()
+	dbm := newSQLManager(t)
+	assert.Nil(t, dbm.Save(dir))
+	assert.Nil(t, dbm.RemoveDB("Platinum Tracker"))
+	assert.Nil(t, dbm.SaveBinary(dir))
+
+	loaded, err := Load(dir)
+	assert.Nil(t, err)
+	assert.False(t, loaded.DBExists("Platinum Tracker"))
+	assert.Equal(t, 1, len(loaded.GetDBs()))
+	_, err = os.Stat(filepath.Join(dir, "Platinum Tracker.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A DB created again after being removed is saved as normal
+	d, _ := dbm.RetrieveDB("Empty")
+	assert.Nil(t, dbm.RemoveDB("Empty"))
+	assert.Nil(t, dbm.addDB(d.(*db.DBImpl)))
+	assert.Nil(t, dbm.Save(dir))
+	loaded, err = Load(dir)
+	assert.Nil(t, err)
+	assert.True(t, loaded.DBExists("Empty"))
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	dbm, err := Load(filepath.Join(t.TempDir(), "missing"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(dbm.GetDBs()))
+}
+
+func TestLoadInvalidSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{not json"), 0o644)
+	assert.Nil(t, err)
+	_, err = Load(dir)
+	assert.ErrorIs(t, err, ErrSnapshot)
+
+	snapshot := `{"name": "bad", "keyHeader": "Title", "headers": [{"name": "Title", "type": "bool"}]}`
+	err = os.WriteFile(filepath.Join(dir, "bad.json"), []byte(snapshot), 0o644)
+	assert.Nil(t, err)
+	_, err = Load(dir)
+	assert.ErrorIs(t, err, ErrSnapshot)
+	assert.ErrorIs(t, err, db.ErrUnknownType)
+}
+
+func TestSaveBinary(t *testing.T) {
+	dir := t.TempDir()
+	dbm := newSQLManager(t)
+	assert.Nil(t, dbm.Save(dir))
+	assert.Nil(t, dbm.SaveBinary(dir))
+
+	// The JSON snapshots are replaced so each DB is only loaded once
+	_, err := os.Stat(filepath.Join(dir, "Platinum Tracker.json"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "Platinum Tracker.pdb"))
+	assert.Nil(t, err)
+
+	loaded, err := Load(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(loaded.GetDBs()))
+	d, _ := loaded.RetrieveDB("Platinum Tracker")
+	assert.True(t, d.GetHeader("Hours to Platinum").IsNumber())
+	v, err := d.GetRowFromKeyHeader("Jak 2").GetValueFromHeader(`Say "Hi"`)
+	assert.Nil(t, err)
+	assert.Equal(t, "it's", v.GetValue())
+
+	err = os.WriteFile(filepath.Join(dir, "Empty.pdb"), []byte("PDB"), 0o644)
+	assert.Nil(t, err)
+	_, err = Load(dir)
+	assert.ErrorIs(t, err, ErrSnapshot)
+	assert.ErrorIs(t, err, db.ErrCorrupt)
+}
+
+func TestSaveBackend(t *testing.T) {
+	headers := []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}
+
+	for _, save := range []func(dbm *DBManagerImpl, dir string) error{(*DBManagerImpl).Save, (*DBManagerImpl).SaveBinary} {
+		db.RegisterBackend(db.BACKEND_WAL, db.WALBackend(t.TempDir()))
+		dir := t.TempDir()
+		dbm := New()
+		for _, backend := range []string{db.BACKEND_MEMORY, db.BACKEND_COLUMNAR, db.BACKEND_WAL} {
+			assert.Nil(t, dbm.CreateDBWithOptions(backend, headers, "Title", CreateDBOptions{Backend: backend}))
+			d, _ := dbm.RetrieveDB(backend)
+			assert.Nil(t, d.AddRow(&db.Row{RowMap: map[db.HeaderI]db.ValueI{headers[0]: &db.Value{Value: "Jak 2"}}}))
+		}
+		assert.Nil(t, save(dbm, dir))
+		assert.Nil(t, dbm.Close())
+
+		// Each DB is reopened on the backend it was created with
+		loaded, err := Load(dir)
+		assert.Nil(t, err)
+		for _, backend := range []string{db.BACKEND_MEMORY, db.BACKEND_COLUMNAR, db.BACKEND_WAL} {
+			d, err := loaded.RetrieveDB(backend)
+			assert.Nil(t, err)
+			assert.Equal(t, backend, d.(db.Backed).BackendName())
+			assert.Equal(t, 1, len(d.GetRows()))
+		}
+		assert.Nil(t, loaded.Close())
+	}
+
+	// A snapshot naming a backend that isn't registered can't be loaded
+	dir := t.TempDir()
+	snapshot := `{"name": "Tape", "keyHeader": "Title", "headers": [{"name": "Title", "type": "string"}], "rows": [], "backend": "tape"}`
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "Tape.json"), []byte(snapshot), 0o644))
+	_, err := Load(dir)
+	assert.ErrorIs(t, err, ErrSnapshot)
+	assert.ErrorIs(t, err, db.ErrUnknownBackend)
+}
@@ -0,0 +1,586 @@
+package dbmanager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// How many rows each INSERT statement written by ExportSQL holds
+const sqlBatchSize = 500
+
+// The SQL column types used for each header type
+const (
+	SQL_TEXT = "TEXT"
+	SQL_REAL = "REAL"
+)
+
+// The kinds of token read from a SQL dump
+type sqlTokenKind int
+
+const (
+	sqlEOF sqlTokenKind = iota
+	// A keyword or unquoted identifier
+	sqlWord
+	// A double quoted identifier
+	sqlIdent
+	// A single quoted string
+	sqlString
+	sqlNumber
+	// One of ( ) , ;
+	sqlPunct
+)
+
+// A token read from a SQL dump holding the following fields:
+// kind: The kind of token
+// text: The token with any quotes removed
+// line: The line the token started on
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	line int
+}
+
+// The implementation of the reader of the SQL written by ExportSQL holding the following fields:
+// r: The input being read
+// line: The line currently being read
+// peeked: The token returned by peek that next hasn't returned yet, or nil
+type sqlParser struct {
+	r      *bufio.Reader
+	line   int
+	peeked *sqlToken
+}
+
+// Writes every DB as a CREATE TABLE statement followed by INSERT statements for its rows, in a
+// form SQLite and Postgres can run
+// VALUE_STRING headers are TEXT columns and VALUE_NUMBER headers REAL columns, with the KeyHeader
+// first as the PRIMARY KEY
+// Empty values are written as NULL
+// Numbers are written in their shortest form, and NaN and Inf as strings
+func (dbm *DBManagerImpl) ExportSQL(w io.Writer) error {
+	dbs := dbm.GetDBs()
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("BEGIN;\n")
+	for _, name := range names {
+		err := writeSQLTable(bw, dbs[name])
+		if err != nil {
+			return err
+		}
+	}
+	bw.WriteString("COMMIT;\n")
+
+	return bw.Flush()
+}
+
+// Writes the CREATE TABLE and INSERT statements for the DB
+func writeSQLTable(w *bufio.Writer, d db.DB) error {
+	headers := db.SortedHeaders(d)
+	table := quoteIdent(d.GetName())
+
+	fmt.Fprintf(w, "\nCREATE TABLE %s (\n", table)
+	columns := make([]string, 0, len(headers))
+	for i, h := range headers {
+		column := quoteIdent(h.GetName())
+		columns = append(columns, column)
+
+		t := SQL_TEXT
+		if h.IsNumber() {
+			t = SQL_REAL
+		}
+		w.WriteString("  " + column + " " + t)
+		if h.IsKeyHeader() {
+			w.WriteString(" PRIMARY KEY")
+		}
+		if i < len(headers)-1 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString(");\n")
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", table, strings.Join(columns, ", "))
+
+	it := d.Iter(db.Query{})
+	defer it.Close()
+
+	count := 0
+	for it.Next() {
+		if count%sqlBatchSize == 0 {
+			if count > 0 {
+				w.WriteString(";\n")
+			}
+			w.WriteString(insert)
+		} else {
+			w.WriteString(",\n")
+		}
+		count++
+
+		values := make([]string, 0, len(headers))
+		for _, h := range headers {
+			values = append(values, sqlLiteral(h, it.Row()))
+		}
+		w.WriteString("  (" + strings.Join(values, ", ") + ")")
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if count > 0 {
+		w.WriteString(";\n")
+	}
+
+	return nil
+}
+
+// Returns the row's value for the header as a SQL literal
+// Numbers are normalized, as SQL doesn't have all the forms a number can be written in, such as
+// +1, while NaN and Inf, which have no literal, are written as strings
+func sqlLiteral(h db.HeaderI, row db.RowI) string {
+	value := ""
+	if v, err := row.GetValueFromHeader(h.GetName()); err == nil {
+		value = v.GetValue()
+	}
+	if value == "" {
+		return "NULL"
+	}
+
+	if number, ok := db.FormatNumber(value); h.IsNumber() && ok {
+		return number
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Returns the name as a double quoted SQL identifier so names with spaces or quotes can be used
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Creates the DBs and adds the rows described by SQL in the form ExportSQL writes
+// Only CREATE TABLE statements with TEXT and REAL columns and one PRIMARY KEY, INSERT statements
+// naming their columns, BEGIN and COMMIT are understood
+// The rows of each INSERT statement are added all or nothing
+// Returns an error if the SQL isn't understood, a table already exists, an INSERT is into a table
+// that doesn't exist or its rows can't be added, in which case the statements before it have been
+// run
+func (dbm *DBManagerImpl) ImportSQL(r io.Reader) error {
+	p := &sqlParser{r: bufio.NewReader(r), line: 1}
+
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case tok.kind == sqlEOF:
+			return nil
+		case tok.is(sqlPunct, ";"):
+			continue
+		case tok.is(sqlWord, "BEGIN"), tok.is(sqlWord, "COMMIT"):
+			// SQLite writes BEGIN TRANSACTION
+			if next, err := p.peek(); err == nil && next.is(sqlWord, "TRANSACTION") {
+				p.next()
+			}
+			err = p.expect(sqlPunct, ";")
+		case tok.is(sqlWord, "CREATE"):
+			err = dbm.parseCreate(p)
+		case tok.is(sqlWord, "INSERT"):
+			err = dbm.parseInsert(p)
+		default:
+			err = p.invalid(tok, fmt.Sprintf(sqlStatementError, tok.text))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Parses the rest of a CREATE TABLE statement and creates the DB
+func (dbm *DBManagerImpl) parseCreate(p *sqlParser) error {
+	if err := p.expect(sqlWord, "TABLE"); err != nil {
+		return err
+	}
+	start, err := p.next()
+	if err != nil {
+		return err
+	}
+	name, err := p.ident(start)
+	if err != nil {
+		return err
+	}
+	if err := p.expect(sqlPunct, "("); err != nil {
+		return err
+	}
+
+	headers := []db.HeaderI{}
+	keyHeader := ""
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		column, err := p.ident(tok)
+		if err != nil {
+			return err
+		}
+
+		tok, err = p.next()
+		if err != nil {
+			return err
+		}
+		var t db.Type
+		switch {
+		case tok.is(sqlWord, SQL_TEXT):
+			t = db.VALUE_STRING
+		case tok.is(sqlWord, SQL_REAL):
+			t = db.VALUE_NUMBER
+		default:
+			return p.invalid(tok, fmt.Sprintf(sqlColumnTypeError, tok.text, column))
+		}
+
+		tok, err = p.next()
+		if err != nil {
+			return err
+		}
+		if tok.is(sqlWord, "PRIMARY") {
+			if err := p.expect(sqlWord, "KEY"); err != nil {
+				return err
+			}
+			if keyHeader != "" {
+				return p.invalid(tok, fmt.Sprintf(sqlPrimaryKeysError, name))
+			}
+			keyHeader = column
+			if tok, err = p.next(); err != nil {
+				return err
+			}
+		}
+		headers = append(headers, &db.Header{Name: column, KeyHeader: column == keyHeader, Type: t})
+
+		if tok.is(sqlPunct, ")") {
+			break
+		}
+		if !tok.is(sqlPunct, ",") {
+			return p.invalid(tok, fmt.Sprintf(sqlTokenError, ", or )", tok.text))
+		}
+	}
+	if keyHeader == "" {
+		return p.invalid(start, fmt.Sprintf(sqlPrimaryKeysError, name))
+	}
+	if err := p.expect(sqlPunct, ";"); err != nil {
+		return err
+	}
+
+	return dbm.CreateDB(name, headers, keyHeader)
+}
+
+// Parses the rest of an INSERT statement and adds its rows to the DB
+func (dbm *DBManagerImpl) parseInsert(p *sqlParser) error {
+	if err := p.expect(sqlWord, "INTO"); err != nil {
+		return err
+	}
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	name, err := p.ident(tok)
+	if err != nil {
+		return err
+	}
+	d, err := dbm.RetrieveDB(name)
+	if err != nil {
+		return err
+	}
+
+	columns, err := p.list(func(tok sqlToken) (string, error) {
+		return p.ident(tok)
+	})
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if d.GetHeader(column).GetName() == "" {
+			return &db.Error{Err: db.ErrHeaderNotExist, DB: name, Header: column, Message: fmt.Sprintf(sqlColumnError, column, name)}
+		}
+	}
+
+	if err := p.expect(sqlWord, "VALUES"); err != nil {
+		return err
+	}
+
+	rows := []db.RowI{}
+	for {
+		start, err := p.peek()
+		if err != nil {
+			return err
+		}
+		values, err := p.list(p.literal)
+		if err != nil {
+			return err
+		}
+		if len(values) != len(columns) {
+			return p.invalid(start, fmt.Sprintf(sqlValueCountError, len(columns), len(values)))
+		}
+
+		m := map[string]string{}
+		for i, column := range columns {
+			err = db.ValidateValue(d.GetHeader(column), values[i])
+			if err != nil {
+				return err
+			}
+			m[column] = values[i]
+		}
+		row, err := db.NewRowFromMap(d, m)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.is(sqlPunct, ";") {
+			break
+		}
+		if !tok.is(sqlPunct, ",") {
+			return p.invalid(tok, fmt.Sprintf(sqlTokenError, ", or ;", tok.text))
+		}
+	}
+
+	errs, err := d.AddRows(rows, db.AddRowsOptions{AllOrNothing: true})
+	if _, err := db.FirstRowError(errs); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// Parses a parenthesised, comma separated list of items
+func (p *sqlParser) list(item func(tok sqlToken) (string, error)) ([]string, error) {
+	if err := p.expect(sqlPunct, "("); err != nil {
+		return nil, err
+	}
+
+	items := []string{}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		s, err := item(tok)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+
+		tok, err = p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.is(sqlPunct, ")") {
+			return items, nil
+		}
+		if !tok.is(sqlPunct, ",") {
+			return nil, p.invalid(tok, fmt.Sprintf(sqlTokenError, ", or )", tok.text))
+		}
+	}
+}
+
+// Returns the name the token is, quoted or not
+func (p *sqlParser) ident(tok sqlToken) (string, error) {
+	if tok.kind != sqlIdent && tok.kind != sqlWord {
+		return "", p.invalid(tok, fmt.Sprintf(sqlTokenError, "a name", tok.text))
+	}
+
+	return tok.text, nil
+}
+
+// Returns the value the token is, with NULL as an empty value
+func (p *sqlParser) literal(tok sqlToken) (string, error) {
+	switch {
+	case tok.kind == sqlString, tok.kind == sqlNumber:
+		return tok.text, nil
+	case tok.is(sqlWord, "NULL"):
+		return "", nil
+	default:
+		return "", p.invalid(tok, fmt.Sprintf(sqlTokenError, "a value", tok.text))
+	}
+}
+
+// Reads the next token, returning an error if it isn't the given word or punctuation
+func (p *sqlParser) expect(kind sqlTokenKind, text string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if !tok.is(kind, text) {
+		return p.invalid(tok, fmt.Sprintf(sqlTokenError, text, tok.text))
+	}
+
+	return nil
+}
+
+// Returns the next token without reading past it
+func (p *sqlParser) peek() (sqlToken, error) {
+	if p.peeked == nil {
+		tok, err := p.read()
+		if err != nil {
+			return sqlToken{}, err
+		}
+		p.peeked = &tok
+	}
+
+	return *p.peeked, nil
+}
+
+// Returns the next token, with a sqlEOF token once there are none left
+func (p *sqlParser) next() (sqlToken, error) {
+	tok, err := p.peek()
+	p.peeked = nil
+
+	return tok, err
+}
+
+// Reads a token from the input, skipping whitespace and -- comments
+func (p *sqlParser) read() (sqlToken, error) {
+	c, err := p.skip()
+	if err == io.EOF {
+		return sqlToken{kind: sqlEOF, text: "end of input", line: p.line}, nil
+	}
+	if err != nil {
+		return sqlToken{}, err
+	}
+
+	tok := sqlToken{line: p.line}
+	switch {
+	case strings.ContainsRune("(),;", c):
+		tok.kind = sqlPunct
+		tok.text = string(c)
+	case c == '\'' || c == '"':
+		tok.kind = sqlString
+		if c == '"' {
+			tok.kind = sqlIdent
+		}
+		tok.text, err = p.quoted(c)
+		if err != nil {
+			return sqlToken{}, p.invalid(tok, err.Error())
+		}
+	case c == '-' || c == '+' || c == '.' || unicode.IsDigit(c):
+		tok.kind = sqlNumber
+		tok.text = p.word(c, func(c rune) bool {
+			return unicode.IsDigit(c) || strings.ContainsRune(".eE+-", c)
+		})
+		if _, err := strconv.ParseFloat(tok.text, 64); err != nil {
+			return sqlToken{}, p.invalid(tok, fmt.Sprintf(sqlNumberError, tok.text))
+		}
+	case c == '_' || unicode.IsLetter(c):
+		tok.kind = sqlWord
+		tok.text = p.word(c, func(c rune) bool {
+			return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+		})
+	default:
+		return sqlToken{}, p.invalid(tok, fmt.Sprintf(sqlCharacterError, c))
+	}
+
+	return tok, nil
+}
+
+// Returns the first rune after any whitespace and comments
+func (p *sqlParser) skip() (rune, error) {
+	for {
+		c, _, err := p.r.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case c == '\n':
+			p.line++
+		case unicode.IsSpace(c):
+		case c == '-':
+			next, _, err := p.r.ReadRune()
+			if err == nil && next == '-' {
+				// Skip the comment up to the newline, which is counted on the next loop
+				_, err = p.r.ReadString('\n')
+				if err == nil {
+					p.r.UnreadByte()
+				}
+				continue
+			}
+			if err == nil {
+				p.r.UnreadRune()
+			}
+			return c, nil
+		default:
+			return c, nil
+		}
+	}
+}
+
+// Reads the rest of a quoted string or identifier, where two quotes stand for one
+func (p *sqlParser) quoted(quote rune) (string, error) {
+	var b strings.Builder
+	for {
+		c, _, err := p.r.ReadRune()
+		if err == io.EOF {
+			return "", errors.New(sqlUnterminatedError)
+		}
+		if err != nil {
+			return "", err
+		}
+		if c == '\n' {
+			p.line++
+		}
+
+		if c == quote {
+			next, _, err := p.r.ReadRune()
+			if err != nil || next != quote {
+				if err == nil {
+					p.r.UnreadRune()
+				}
+				return b.String(), nil
+			}
+		}
+		b.WriteRune(c)
+	}
+}
+
+// Reads the rest of a word starting with first made of the runes in accepts
+func (p *sqlParser) word(first rune, accepts func(c rune) bool) string {
+	var b strings.Builder
+	b.WriteRune(first)
+	for {
+		c, _, err := p.r.ReadRune()
+		if err != nil {
+			return b.String()
+		}
+		if !accepts(c) {
+			p.r.UnreadRune()
+			return b.String()
+		}
+		b.WriteRune(c)
+	}
+}
+
+// Returns an error for the SQL at the token
+func (p *sqlParser) invalid(tok sqlToken, reason string) error {
+	return &db.Error{Err: ErrInvalidSQL, Message: fmt.Sprintf(sqlError, tok.line, reason)}
+}
+
+// Returns true if the token is the given word, ignoring case, or punctuation
+func (t sqlToken) is(kind sqlTokenKind, text string) bool {
+	return t.kind == kind && strings.EqualFold(t.text, text)
+}
@@ -0,0 +1,160 @@
+package dbmanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// Returns a manager with a DB whose names need quoting and one with no rows
+func newSQLManager(t *testing.T) *DBManagerImpl {
+	dbm := New()
+	err := dbm.CreateDB("Platinum Tracker", []db.HeaderI{
+		&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "Hours to Platinum", KeyHeader: false, Type: db.VALUE_NUMBER},
+		&db.Header{Name: `Say "Hi"`, KeyHeader: false, Type: db.VALUE_STRING},
+	}, "Title")
+	assert.Nil(t, err)
+	assert.Nil(t, dbm.CreateDB("Empty", []db.HeaderI{
+		&db.Header{Name: "ID", KeyHeader: true, Type: db.VALUE_NUMBER},
+	}, "ID"))
+
+	d, _ := dbm.RetrieveDB("Platinum Tracker")
+	for _, values := range []map[string]string{
+		{"Title": "Jak 2", "Hours to Platinum": "23.5", `Say "Hi"`: "it's"},
+		{"Title": "Ratchet", "Hours to Platinum": ""},
+	} {
+		row, err := db.NewRowFromMap(d, values)
+		assert.Nil(t, err)
+		assert.Nil(t, d.AddRow(row))
+	}
+
+	return dbm
+}
+
+func TestExportSQL(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, newSQLManager(t).ExportSQL(buf))
+	assert.Equal(t, `BEGIN;
+
+CREATE TABLE "Empty" (
+  "ID" REAL PRIMARY KEY
+);
+
+CREATE TABLE "Platinum Tracker" (
+  "Title" TEXT PRIMARY KEY,
+  "Hours to Platinum" REAL,
+  "Say ""Hi""" TEXT
+);
+INSERT INTO "Platinum Tracker" ("Title", "Hours to Platinum", "Say ""Hi""") VALUES
+  ('Jak 2', 23.5, 'it''s'),
+  ('Ratchet', NULL, NULL);
+COMMIT;
+`, buf.String())
+}
+
+func TestImportSQL(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, newSQLManager(t).ExportSQL(buf))
+
+	dbm := New()
+	assert.Nil(t, dbm.ImportSQL(buf))
+	assert.True(t, dbm.DBExists("Empty"))
+	d, err := dbm.RetrieveDB("Platinum Tracker")
+	assert.Nil(t, err)
+	assert.Equal(t, "Title", d.GetKeyHeader())
+	assert.True(t, d.GetHeader("Hours to Platinum").IsNumber())
+	assert.Equal(t, 2, len(d.GetRows()))
+	v, err := d.GetRowFromKeyHeader("Jak 2").GetValueFromHeader(`Say "Hi"`)
+	assert.Nil(t, err)
+	assert.Equal(t, "it's", v.GetValue())
+
+	// Keywords in any case, unquoted names, comments and SQLite's BEGIN TRANSACTION are understood
+	dbm = New()
+	assert.Nil(t, dbm.ImportSQL(strings.NewReader(`-- made by hand
+begin transaction;
+create table games (title text, hours real primary key);
+insert into games (hours, title) values (-1.5e1, 'a'), (2, NULL); -- two rows
+commit;`)))
+	d, _ = dbm.RetrieveDB("games")
+	assert.Equal(t, "hours", d.GetKeyHeader())
+	assert.Equal(t, 2, len(d.GetRows()))
+
+	// Rows are split into as many INSERT statements as needed
+	big := New()
+	assert.Nil(t, big.CreateDB("Big", []db.HeaderI{&db.Header{Name: "ID", KeyHeader: true, Type: db.VALUE_NUMBER}}, "ID"))
+	d, _ = big.RetrieveDB("Big")
+	for i := 0; i < sqlBatchSize+1; i++ {
+		row, _ := db.NewRowFromMap(d, map[string]string{"ID": fmt.Sprint(i)})
+		assert.Nil(t, d.AddRow(row))
+	}
+	buf.Reset()
+	assert.Nil(t, big.ExportSQL(buf))
+	assert.Equal(t, 2, strings.Count(buf.String(), "INSERT INTO"))
+	dbm = New()
+	assert.Nil(t, dbm.ImportSQL(buf))
+	d, _ = dbm.RetrieveDB("Big")
+	assert.Equal(t, sqlBatchSize+1, len(d.GetRows()))
+}
+
+func TestSQLNumbers(t *testing.T) {
+	dbm := New()
+	assert.Nil(t, dbm.CreateDB("Numbers", []db.HeaderI{
+		&db.Header{Name: "ID", KeyHeader: true, Type: db.VALUE_STRING},
+		&db.Header{Name: "N", KeyHeader: false, Type: db.VALUE_NUMBER},
+	}, "ID"))
+	d, _ := dbm.RetrieveDB("Numbers")
+	values := map[string]string{"a": "NaN", "b": "Inf", "c": "+1", "d": "1", "e": "007", "f": "-Inf"}
+	for key, value := range values {
+		row, err := db.NewRowFromMap(d, map[string]string{"ID": key, "N": value})
+		assert.Nil(t, err)
+		assert.Nil(t, d.AddRow(row))
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, dbm.ExportSQL(buf))
+	assert.Contains(t, buf.String(), "('a', 'NaN')")
+	assert.Contains(t, buf.String(), "('c', 1)")
+
+	imported := New()
+	assert.Nil(t, imported.ImportSQL(buf))
+	d, err := imported.RetrieveDB("Numbers")
+	assert.Nil(t, err)
+	want := map[string]string{"a": "NaN", "b": "Inf", "c": "1", "d": "1", "e": "7", "f": "-Inf"}
+	for key, value := range want {
+		v, err := d.GetRowFromKeyHeader(key).GetValueFromHeader("N")
+		assert.Nil(t, err)
+		assert.Equal(t, value, v.GetValue(), key)
+	}
+}
+
+func TestImportSQLErrors(t *testing.T) {
+	create := `CREATE TABLE "T" ("K" TEXT PRIMARY KEY, "N" REAL);` + "\n"
+	for input, sentinel := range map[string]error{
+		"DROP TABLE t;":                                                  ErrInvalidSQL,
+		`CREATE TABLE "T" ("K" TEXT);`:                                   ErrInvalidSQL,
+		`CREATE TABLE "T" ("K" BLOB PRIMARY KEY);`:                       ErrInvalidSQL,
+		`CREATE TABLE "T" ("K" TEXT PRIMARY KEY`:                         ErrInvalidSQL,
+		create + create:                                                  ErrDBExists,
+		`INSERT INTO "T" ("K") VALUES ('a');`:                            ErrDBNotExist,
+		create + `INSERT INTO "T" ("M") VALUES ('a');`:                   db.ErrHeaderNotExist,
+		create + `INSERT INTO "T" ("K", "N") VALUES ('a');`:              ErrInvalidSQL,
+		create + `INSERT INTO "T" ("K", "N") VALUES ('a', 'x');`:         db.ErrNotANumber,
+		create + `INSERT INTO "T" ("K", "N") VALUES ('a', 1), ('a', 2);`: db.ErrDuplicateKey,
+		create + `INSERT INTO "T" ("K") VALUES ('a);`:                    ErrInvalidSQL,
+		create + `INSERT INTO "T" ("K") VALUES (1-);`:                    ErrInvalidSQL,
+		create + `INSERT INTO "T" ("K") VALUES (?);`:                     ErrInvalidSQL,
+	} {
+		err := New().ImportSQL(strings.NewReader(input))
+		assert.True(t, errors.Is(err, sentinel), input)
+	}
+
+	err := New().ImportSQL(strings.NewReader("\n\nDROP TABLE t;"))
+	assert.Contains(t, err.Error(), "line 3")
+}
@@ -1,12 +1,38 @@
 package dbmanager
 
 import (
+	"context"
+	"errors"
+	"sync"
+
 	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/audit"
 )
 
 var (
 	dbExistsError   = "database '%s' already exists"
 	dbNotExistError = "database '%s' does not exist"
+	snapshotError   = "could not load snapshot '%s': %s"
+
+	sqlError             = "invalid SQL on line %d: %s"
+	sqlStatementError    = "unsupported statement '%s'"
+	sqlColumnTypeError   = "unsupported type '%s' for column '%s', expected TEXT or REAL"
+	sqlPrimaryKeysError  = "table '%s' must have exactly one PRIMARY KEY column"
+	sqlColumnError       = "column '%s' does not exist in table '%s'"
+	sqlValueCountError   = "expected %d values, got %d"
+	sqlTokenError        = "expected %s, got '%s'"
+	sqlCharacterError    = "unexpected character '%c'"
+	sqlNumberError       = "invalid number '%s'"
+	sqlUnterminatedError = "unterminated quoted string"
+)
+
+// The kinds of error returned by a DBManager, wrapped by a *db.Error so they can be checked for
+// with errors.Is
+var (
+	ErrDBExists   = errors.New("database exists")
+	ErrDBNotExist = errors.New("database does not exist")
+	ErrSnapshot   = errors.New("invalid snapshot")
+	ErrInvalidSQL = errors.New("invalid SQL")
 )
 
 // DBManager is the interface for any DB manager instances
@@ -22,10 +48,56 @@ type DBManager interface {
 
 	// Returns true if the DB exists in the DBManager's map of DBs
 	DBExists(name string) bool
+
+	// Returns the DB instance with the given name
+	// Returns an error if the DB does not exist
+	RetrieveDB(name string) (db.DB, error)
+
+	// Removes the DB instance with the given name from the DB map
+	// Returns an error if the DB does not exist
+	RemoveDB(name string) error
+}
+
+// DBManagerContext is the interface for DB managers taking a context.Context for each operation
+// Each method matches the DBManager method without the Context suffix, returning ctx's error if it
+// is done first
+// Changes are attributed to the actor set on ctx with db.WithActor, as are those made to the DBs
+// returned
+type DBManagerContext interface {
+	DBManager
+
+	GetDBsContext(ctx context.Context) (map[string]db.DB, error)
+	CreateDBContext(ctx context.Context, name string, headers []db.HeaderI, keyHeader string) error
+	DBExistsContext(ctx context.Context, name string) (bool, error)
+	RetrieveDBContext(ctx context.Context, name string) (db.DB, error)
+	RemoveDBContext(ctx context.Context, name string) error
+}
+
+// The options for CreateDBWithOptions holding the following fields:
+// Backend: The name of the backend registered with db.RegisterBackend keeping the DB's rows,
+// db.BACKEND_MEMORY if empty
+type CreateDBOptions struct {
+	Backend string
 }
 
 // The implementation for DBManager holding the following fields:
 // DBs: the map containing the name of the DB mapped to the DB instance
 type DBManagerImpl struct {
 	DBs map[string]db.DB
+
+	// Guards DBs so the manager can be shared between goroutines
+	mu sync.RWMutex
+	// Records the changes made to the manager and its DBs, or nil
+	audit *audit.Log
+	// The names of the DBs removed since the manager was last saved, whose snapshots are deleted
+	// by the next save
+	removed map[string]struct{}
+}
+
+// A view of a DB manager attributing the changes made through it holding the following fields:
+// DBManagerImpl: The DB manager changes are made to
+// actor: Who the changes are attributed to
+type actorManager struct {
+	*DBManagerImpl
+	actor string
 }
@@ -0,0 +1,26 @@
+package dbmanager
+
+import (
+	"io"
+	"sort"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+// Writes every DB to w as an XLSX workbook with one sheet per DB, ordered by name
+// See db.ExportXLSX for the layout of each sheet
+func (dbm *DBManagerImpl) ExportXLSX(w io.Writer) error {
+	dbs := dbm.GetDBs()
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sheets := make([]db.DB, 0, len(names))
+	for _, name := range names {
+		sheets = append(sheets, dbs[name])
+	}
+
+	return db.ExportXLSX(w, sheets...)
+}
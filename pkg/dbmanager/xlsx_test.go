@@ -0,0 +1,22 @@
+package dbmanager
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func TestExportXLSX(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, newSQLManager(t).ExportXLSX(buf))
+
+	// The first sheet is the DB whose name sorts first
+	data := buf.Bytes()
+	d, err := db.NewFromXLSX("Empty", bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, "ID", d.GetKeyHeader())
+	assert.Equal(t, 0, len(d.GetRows()))
+}
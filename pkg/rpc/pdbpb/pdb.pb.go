@@ -0,0 +1,1729 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: pdb.proto
+
+package pdbpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Type int32
+
+const (
+	Type_TYPE_STRING Type = 0
+	Type_TYPE_NUMBER Type = 1
+)
+
+// Enum value maps for Type.
+var (
+	Type_name = map[int32]string{
+		0: "TYPE_STRING",
+		1: "TYPE_NUMBER",
+	}
+	Type_value = map[string]int32{
+		"TYPE_STRING": 0,
+		"TYPE_NUMBER": 1,
+	}
+)
+
+func (x Type) Enum() *Type {
+	p := new(Type)
+	*p = x
+	return p
+}
+
+func (x Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_pdb_proto_enumTypes[0].Descriptor()
+}
+
+func (Type) Type() protoreflect.EnumType {
+	return &file_pdb_proto_enumTypes[0]
+}
+
+func (x Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Type.Descriptor instead.
+func (Type) EnumDescriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{0}
+}
+
+type Filter_Op int32
+
+const (
+	Filter_OP_EQ Filter_Op = 0
+	Filter_OP_NE Filter_Op = 1
+	Filter_OP_LT Filter_Op = 2
+	Filter_OP_GT Filter_Op = 3
+)
+
+// Enum value maps for Filter_Op.
+var (
+	Filter_Op_name = map[int32]string{
+		0: "OP_EQ",
+		1: "OP_NE",
+		2: "OP_LT",
+		3: "OP_GT",
+	}
+	Filter_Op_value = map[string]int32{
+		"OP_EQ": 0,
+		"OP_NE": 1,
+		"OP_LT": 2,
+		"OP_GT": 3,
+	}
+)
+
+func (x Filter_Op) Enum() *Filter_Op {
+	p := new(Filter_Op)
+	*p = x
+	return p
+}
+
+func (x Filter_Op) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Filter_Op) Descriptor() protoreflect.EnumDescriptor {
+	return file_pdb_proto_enumTypes[1].Descriptor()
+}
+
+func (Filter_Op) Type() protoreflect.EnumType {
+	return &file_pdb_proto_enumTypes[1]
+}
+
+func (x Filter_Op) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Filter_Op.Descriptor instead.
+func (Filter_Op) EnumDescriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type Header struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          Type                   `protobuf:"varint,2,opt,name=type,proto3,enum=pdb.v1.Type" json:"type,omitempty"`
+	Key           bool                   `protobuf:"varint,3,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Header) Reset() {
+	*x = Header{}
+	mi := &file_pdb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Header) ProtoMessage() {}
+
+func (x *Header) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Header.ProtoReflect.Descriptor instead.
+func (*Header) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Header) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Header) GetType() Type {
+	if x != nil {
+		return x.Type
+	}
+	return Type_TYPE_STRING
+}
+
+func (x *Header) GetKey() bool {
+	if x != nil {
+		return x.Key
+	}
+	return false
+}
+
+type DB struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	KeyHeader     string                 `protobuf:"bytes,2,opt,name=key_header,json=keyHeader,proto3" json:"key_header,omitempty"`
+	Headers       []*Header              `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty"`
+	Rows          int64                  `protobuf:"varint,4,opt,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DB) Reset() {
+	*x = DB{}
+	mi := &file_pdb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DB) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DB) ProtoMessage() {}
+
+func (x *DB) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DB.ProtoReflect.Descriptor instead.
+func (*DB) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DB) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DB) GetKeyHeader() string {
+	if x != nil {
+		return x.KeyHeader
+	}
+	return ""
+}
+
+func (x *DB) GetHeaders() []*Header {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *DB) GetRows() int64 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+// A row as its header names to values, numbers are formatted as strings
+type Row struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        map[string]string      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	mi := &file_pdb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Row) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// Keeps rows where the value of the header compares to the value with op
+// LT and GT compare numbers and skip rows whose value isn't a number
+type Filter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Header        string                 `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Op            Filter_Op              `protobuf:"varint,2,opt,name=op,proto3,enum=pdb.v1.Filter_Op" json:"op,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_pdb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Filter) GetHeader() string {
+	if x != nil {
+		return x.Header
+	}
+	return ""
+}
+
+func (x *Filter) GetOp() Filter_Op {
+	if x != nil {
+		return x.Op
+	}
+	return Filter_OP_EQ
+}
+
+func (x *Filter) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type ListDBsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDBsRequest) Reset() {
+	*x = ListDBsRequest{}
+	mi := &file_pdb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDBsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDBsRequest) ProtoMessage() {}
+
+func (x *ListDBsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDBsRequest.ProtoReflect.Descriptor instead.
+func (*ListDBsRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{4}
+}
+
+type ListDBsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dbs           []*DB                  `protobuf:"bytes,1,rep,name=dbs,proto3" json:"dbs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDBsResponse) Reset() {
+	*x = ListDBsResponse{}
+	mi := &file_pdb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDBsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDBsResponse) ProtoMessage() {}
+
+func (x *ListDBsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDBsResponse.ProtoReflect.Descriptor instead.
+func (*ListDBsResponse) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListDBsResponse) GetDbs() []*DB {
+	if x != nil {
+		return x.Dbs
+	}
+	return nil
+}
+
+type CreateDBRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	KeyHeader     string                 `protobuf:"bytes,2,opt,name=key_header,json=keyHeader,proto3" json:"key_header,omitempty"`
+	Headers       []*Header              `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDBRequest) Reset() {
+	*x = CreateDBRequest{}
+	mi := &file_pdb_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDBRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDBRequest) ProtoMessage() {}
+
+func (x *CreateDBRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDBRequest.ProtoReflect.Descriptor instead.
+func (*CreateDBRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateDBRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateDBRequest) GetKeyHeader() string {
+	if x != nil {
+		return x.KeyHeader
+	}
+	return ""
+}
+
+func (x *CreateDBRequest) GetHeaders() []*Header {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type GetDBRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDBRequest) Reset() {
+	*x = GetDBRequest{}
+	mi := &file_pdb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDBRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDBRequest) ProtoMessage() {}
+
+func (x *GetDBRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDBRequest.ProtoReflect.Descriptor instead.
+func (*GetDBRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetDBRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type RemoveDBRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveDBRequest) Reset() {
+	*x = RemoveDBRequest{}
+	mi := &file_pdb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveDBRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveDBRequest) ProtoMessage() {}
+
+func (x *RemoveDBRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveDBRequest.ProtoReflect.Descriptor instead.
+func (*RemoveDBRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RemoveDBRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type RemoveDBResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveDBResponse) Reset() {
+	*x = RemoveDBResponse{}
+	mi := &file_pdb_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveDBResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveDBResponse) ProtoMessage() {}
+
+func (x *RemoveDBResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveDBResponse.ProtoReflect.Descriptor instead.
+func (*RemoveDBResponse) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{9}
+}
+
+type AddHeaderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Header        *Header                `protobuf:"bytes,2,opt,name=header,proto3" json:"header,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddHeaderRequest) Reset() {
+	*x = AddHeaderRequest{}
+	mi := &file_pdb_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddHeaderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddHeaderRequest) ProtoMessage() {}
+
+func (x *AddHeaderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddHeaderRequest.ProtoReflect.Descriptor instead.
+func (*AddHeaderRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AddHeaderRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *AddHeaderRequest) GetHeader() *Header {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+type RemoveHeaderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Header        string                 `protobuf:"bytes,2,opt,name=header,proto3" json:"header,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveHeaderRequest) Reset() {
+	*x = RemoveHeaderRequest{}
+	mi := &file_pdb_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveHeaderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveHeaderRequest) ProtoMessage() {}
+
+func (x *RemoveHeaderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveHeaderRequest.ProtoReflect.Descriptor instead.
+func (*RemoveHeaderRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RemoveHeaderRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *RemoveHeaderRequest) GetHeader() string {
+	if x != nil {
+		return x.Header
+	}
+	return ""
+}
+
+type AddRowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Row           *Row                   `protobuf:"bytes,2,opt,name=row,proto3" json:"row,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRowRequest) Reset() {
+	*x = AddRowRequest{}
+	mi := &file_pdb_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRowRequest) ProtoMessage() {}
+
+func (x *AddRowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRowRequest.ProtoReflect.Descriptor instead.
+func (*AddRowRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AddRowRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *AddRowRequest) GetRow() *Row {
+	if x != nil {
+		return x.Row
+	}
+	return nil
+}
+
+type AddRowsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Rows          []*Row                 `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"`
+	BestEffort    bool                   `protobuf:"varint,3,opt,name=best_effort,json=bestEffort,proto3" json:"best_effort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRowsRequest) Reset() {
+	*x = AddRowsRequest{}
+	mi := &file_pdb_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRowsRequest) ProtoMessage() {}
+
+func (x *AddRowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRowsRequest.ProtoReflect.Descriptor instead.
+func (*AddRowsRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AddRowsRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *AddRowsRequest) GetRows() []*Row {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *AddRowsRequest) GetBestEffort() bool {
+	if x != nil {
+		return x.BestEffort
+	}
+	return false
+}
+
+// errors holds an error message for each row that wasn't added, empty for rows that were
+type AddRowsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Added         int64                  `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	Errors        []string               `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRowsResponse) Reset() {
+	*x = AddRowsResponse{}
+	mi := &file_pdb_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRowsResponse) ProtoMessage() {}
+
+func (x *AddRowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRowsResponse.ProtoReflect.Descriptor instead.
+func (*AddRowsResponse) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AddRowsResponse) GetAdded() int64 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *AddRowsResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+type UpsertRowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Row           *Row                   `protobuf:"bytes,2,opt,name=row,proto3" json:"row,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpsertRowRequest) Reset() {
+	*x = UpsertRowRequest{}
+	mi := &file_pdb_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpsertRowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpsertRowRequest) ProtoMessage() {}
+
+func (x *UpsertRowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpsertRowRequest.ProtoReflect.Descriptor instead.
+func (*UpsertRowRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UpsertRowRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *UpsertRowRequest) GetRow() *Row {
+	if x != nil {
+		return x.Row
+	}
+	return nil
+}
+
+type GetRowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRowRequest) Reset() {
+	*x = GetRowRequest{}
+	mi := &file_pdb_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRowRequest) ProtoMessage() {}
+
+func (x *GetRowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRowRequest.ProtoReflect.Descriptor instead.
+func (*GetRowRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetRowRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *GetRowRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type RemoveRowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveRowRequest) Reset() {
+	*x = RemoveRowRequest{}
+	mi := &file_pdb_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveRowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveRowRequest) ProtoMessage() {}
+
+func (x *RemoveRowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveRowRequest.ProtoReflect.Descriptor instead.
+func (*RemoveRowRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RemoveRowRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *RemoveRowRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type RemoveRowResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveRowResponse) Reset() {
+	*x = RemoveRowResponse{}
+	mi := &file_pdb_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveRowResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveRowResponse) ProtoMessage() {}
+
+func (x *RemoveRowResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveRowResponse.ProtoReflect.Descriptor instead.
+func (*RemoveRowResponse) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{18}
+}
+
+type UpdateRowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Values        map[string]string      `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRowRequest) Reset() {
+	*x = UpdateRowRequest{}
+	mi := &file_pdb_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRowRequest) ProtoMessage() {}
+
+func (x *UpdateRowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRowRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRowRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UpdateRowRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *UpdateRowRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *UpdateRowRequest) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type IncrementRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Header        string                 `protobuf:"bytes,3,opt,name=header,proto3" json:"header,omitempty"`
+	Delta         float64                `protobuf:"fixed64,4,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrementRequest) Reset() {
+	*x = IncrementRequest{}
+	mi := &file_pdb_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrementRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementRequest) ProtoMessage() {}
+
+func (x *IncrementRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementRequest.ProtoReflect.Descriptor instead.
+func (*IncrementRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *IncrementRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *IncrementRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrementRequest) GetHeader() string {
+	if x != nil {
+		return x.Header
+	}
+	return ""
+}
+
+func (x *IncrementRequest) GetDelta() float64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+type IncrementResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrementResponse) Reset() {
+	*x = IncrementResponse{}
+	mi := &file_pdb_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrementResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementResponse) ProtoMessage() {}
+
+func (x *IncrementResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementResponse.ProtoReflect.Descriptor instead.
+func (*IncrementResponse) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *IncrementResponse) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type UpdateWhereRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Filters       []*Filter              `protobuf:"bytes,2,rep,name=filters,proto3" json:"filters,omitempty"`
+	Values        map[string]string      `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateWhereRequest) Reset() {
+	*x = UpdateWhereRequest{}
+	mi := &file_pdb_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateWhereRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateWhereRequest) ProtoMessage() {}
+
+func (x *UpdateWhereRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateWhereRequest.ProtoReflect.Descriptor instead.
+func (*UpdateWhereRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *UpdateWhereRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *UpdateWhereRequest) GetFilters() []*Filter {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+func (x *UpdateWhereRequest) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type DeleteWhereRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Filters       []*Filter              `protobuf:"bytes,2,rep,name=filters,proto3" json:"filters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteWhereRequest) Reset() {
+	*x = DeleteWhereRequest{}
+	mi := &file_pdb_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteWhereRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteWhereRequest) ProtoMessage() {}
+
+func (x *DeleteWhereRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteWhereRequest.ProtoReflect.Descriptor instead.
+func (*DeleteWhereRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DeleteWhereRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *DeleteWhereRequest) GetFilters() []*Filter {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_pdb_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CountResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type ScanRowsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Db            string                 `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Filters       []*Filter              `protobuf:"bytes,2,rep,name=filters,proto3" json:"filters,omitempty"`
+	Limit         int64                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRowsRequest) Reset() {
+	*x = ScanRowsRequest{}
+	mi := &file_pdb_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRowsRequest) ProtoMessage() {}
+
+func (x *ScanRowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pdb_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRowsRequest.ProtoReflect.Descriptor instead.
+func (*ScanRowsRequest) Descriptor() ([]byte, []int) {
+	return file_pdb_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ScanRowsRequest) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *ScanRowsRequest) GetFilters() []*Filter {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+func (x *ScanRowsRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ScanRowsRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+var File_pdb_proto protoreflect.FileDescriptor
+
+const file_pdb_proto_rawDesc = "" +
+	"\n" +
+	"\tpdb.proto\x12\x06pdb.v1\"P\n" +
+	"\x06Header\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\x04type\x18\x02 \x01(\x0e2\f.pdb.v1.TypeR\x04type\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\bR\x03key\"u\n" +
+	"\x02DB\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"key_header\x18\x02 \x01(\tR\tkeyHeader\x12(\n" +
+	"\aheaders\x18\x03 \x03(\v2\x0e.pdb.v1.HeaderR\aheaders\x12\x12\n" +
+	"\x04rows\x18\x04 \x01(\x03R\x04rows\"q\n" +
+	"\x03Row\x12/\n" +
+	"\x06values\x18\x01 \x03(\v2\x17.pdb.v1.Row.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8b\x01\n" +
+	"\x06Filter\x12\x16\n" +
+	"\x06header\x18\x01 \x01(\tR\x06header\x12!\n" +
+	"\x02op\x18\x02 \x01(\x0e2\x11.pdb.v1.Filter.OpR\x02op\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"0\n" +
+	"\x02Op\x12\t\n" +
+	"\x05OP_EQ\x10\x00\x12\t\n" +
+	"\x05OP_NE\x10\x01\x12\t\n" +
+	"\x05OP_LT\x10\x02\x12\t\n" +
+	"\x05OP_GT\x10\x03\"\x10\n" +
+	"\x0eListDBsRequest\"/\n" +
+	"\x0fListDBsResponse\x12\x1c\n" +
+	"\x03dbs\x18\x01 \x03(\v2\n" +
+	".pdb.v1.DBR\x03dbs\"n\n" +
+	"\x0fCreateDBRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"key_header\x18\x02 \x01(\tR\tkeyHeader\x12(\n" +
+	"\aheaders\x18\x03 \x03(\v2\x0e.pdb.v1.HeaderR\aheaders\"\"\n" +
+	"\fGetDBRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"%\n" +
+	"\x0fRemoveDBRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\x12\n" +
+	"\x10RemoveDBResponse\"J\n" +
+	"\x10AddHeaderRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12&\n" +
+	"\x06header\x18\x02 \x01(\v2\x0e.pdb.v1.HeaderR\x06header\"=\n" +
+	"\x13RemoveHeaderRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x16\n" +
+	"\x06header\x18\x02 \x01(\tR\x06header\">\n" +
+	"\rAddRowRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x1d\n" +
+	"\x03row\x18\x02 \x01(\v2\v.pdb.v1.RowR\x03row\"b\n" +
+	"\x0eAddRowsRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x1f\n" +
+	"\x04rows\x18\x02 \x03(\v2\v.pdb.v1.RowR\x04rows\x12\x1f\n" +
+	"\vbest_effort\x18\x03 \x01(\bR\n" +
+	"bestEffort\"?\n" +
+	"\x0fAddRowsResponse\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\x03R\x05added\x12\x16\n" +
+	"\x06errors\x18\x02 \x03(\tR\x06errors\"A\n" +
+	"\x10UpsertRowRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x1d\n" +
+	"\x03row\x18\x02 \x01(\v2\v.pdb.v1.RowR\x03row\"1\n" +
+	"\rGetRowRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"4\n" +
+	"\x10RemoveRowRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"\x13\n" +
+	"\x11RemoveRowResponse\"\xad\x01\n" +
+	"\x10UpdateRowRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12<\n" +
+	"\x06values\x18\x03 \x03(\v2$.pdb.v1.UpdateRowRequest.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"b\n" +
+	"\x10IncrementRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x16\n" +
+	"\x06header\x18\x03 \x01(\tR\x06header\x12\x14\n" +
+	"\x05delta\x18\x04 \x01(\x01R\x05delta\")\n" +
+	"\x11IncrementResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x01R\x05value\"\xc9\x01\n" +
+	"\x12UpdateWhereRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12(\n" +
+	"\afilters\x18\x02 \x03(\v2\x0e.pdb.v1.FilterR\afilters\x12>\n" +
+	"\x06values\x18\x03 \x03(\v2&.pdb.v1.UpdateWhereRequest.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"N\n" +
+	"\x12DeleteWhereRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12(\n" +
+	"\afilters\x18\x02 \x03(\v2\x0e.pdb.v1.FilterR\afilters\"%\n" +
+	"\rCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\"y\n" +
+	"\x0fScanRowsRequest\x12\x0e\n" +
+	"\x02db\x18\x01 \x01(\tR\x02db\x12(\n" +
+	"\afilters\x18\x02 \x03(\v2\x0e.pdb.v1.FilterR\afilters\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x03R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset*(\n" +
+	"\x04Type\x12\x0f\n" +
+	"\vTYPE_STRING\x10\x00\x12\x0f\n" +
+	"\vTYPE_NUMBER\x10\x012\x84\a\n" +
+	"\x03PDB\x12:\n" +
+	"\aListDBs\x12\x16.pdb.v1.ListDBsRequest\x1a\x17.pdb.v1.ListDBsResponse\x12/\n" +
+	"\bCreateDB\x12\x17.pdb.v1.CreateDBRequest\x1a\n" +
+	".pdb.v1.DB\x12)\n" +
+	"\x05GetDB\x12\x14.pdb.v1.GetDBRequest\x1a\n" +
+	".pdb.v1.DB\x12=\n" +
+	"\bRemoveDB\x12\x17.pdb.v1.RemoveDBRequest\x1a\x18.pdb.v1.RemoveDBResponse\x121\n" +
+	"\tAddHeader\x12\x18.pdb.v1.AddHeaderRequest\x1a\n" +
+	".pdb.v1.DB\x127\n" +
+	"\fRemoveHeader\x12\x1b.pdb.v1.RemoveHeaderRequest\x1a\n" +
+	".pdb.v1.DB\x12,\n" +
+	"\x06AddRow\x12\x15.pdb.v1.AddRowRequest\x1a\v.pdb.v1.Row\x12:\n" +
+	"\aAddRows\x12\x16.pdb.v1.AddRowsRequest\x1a\x17.pdb.v1.AddRowsResponse\x122\n" +
+	"\tUpsertRow\x12\x18.pdb.v1.UpsertRowRequest\x1a\v.pdb.v1.Row\x12,\n" +
+	"\x06GetRow\x12\x15.pdb.v1.GetRowRequest\x1a\v.pdb.v1.Row\x12@\n" +
+	"\tRemoveRow\x12\x18.pdb.v1.RemoveRowRequest\x1a\x19.pdb.v1.RemoveRowResponse\x122\n" +
+	"\tUpdateRow\x12\x18.pdb.v1.UpdateRowRequest\x1a\v.pdb.v1.Row\x12@\n" +
+	"\tIncrement\x12\x18.pdb.v1.IncrementRequest\x1a\x19.pdb.v1.IncrementResponse\x12@\n" +
+	"\vUpdateWhere\x12\x1a.pdb.v1.UpdateWhereRequest\x1a\x15.pdb.v1.CountResponse\x12@\n" +
+	"\vDeleteWhere\x12\x1a.pdb.v1.DeleteWhereRequest\x1a\x15.pdb.v1.CountResponse\x122\n" +
+	"\bScanRows\x12\x17.pdb.v1.ScanRowsRequest\x1a\v.pdb.v1.Row0\x01B(Z&github.com/brownlow2/pdb/pkg/rpc/pdbpbb\x06proto3"
+
+var (
+	file_pdb_proto_rawDescOnce sync.Once
+	file_pdb_proto_rawDescData []byte
+)
+
+func file_pdb_proto_rawDescGZIP() []byte {
+	file_pdb_proto_rawDescOnce.Do(func() {
+		file_pdb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pdb_proto_rawDesc), len(file_pdb_proto_rawDesc)))
+	})
+	return file_pdb_proto_rawDescData
+}
+
+var file_pdb_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pdb_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
+var file_pdb_proto_goTypes = []any{
+	(Type)(0),                   // 0: pdb.v1.Type
+	(Filter_Op)(0),              // 1: pdb.v1.Filter.Op
+	(*Header)(nil),              // 2: pdb.v1.Header
+	(*DB)(nil),                  // 3: pdb.v1.DB
+	(*Row)(nil),                 // 4: pdb.v1.Row
+	(*Filter)(nil),              // 5: pdb.v1.Filter
+	(*ListDBsRequest)(nil),      // 6: pdb.v1.ListDBsRequest
+	(*ListDBsResponse)(nil),     // 7: pdb.v1.ListDBsResponse
+	(*CreateDBRequest)(nil),     // 8: pdb.v1.CreateDBRequest
+	(*GetDBRequest)(nil),        // 9: pdb.v1.GetDBRequest
+	(*RemoveDBRequest)(nil),     // 10: pdb.v1.RemoveDBRequest
+	(*RemoveDBResponse)(nil),    // 11: pdb.v1.RemoveDBResponse
+	(*AddHeaderRequest)(nil),    // 12: pdb.v1.AddHeaderRequest
+	(*RemoveHeaderRequest)(nil), // 13: pdb.v1.RemoveHeaderRequest
+	(*AddRowRequest)(nil),       // 14: pdb.v1.AddRowRequest
+	(*AddRowsRequest)(nil),      // 15: pdb.v1.AddRowsRequest
+	(*AddRowsResponse)(nil),     // 16: pdb.v1.AddRowsResponse
+	(*UpsertRowRequest)(nil),    // 17: pdb.v1.UpsertRowRequest
+	(*GetRowRequest)(nil),       // 18: pdb.v1.GetRowRequest
+	(*RemoveRowRequest)(nil),    // 19: pdb.v1.RemoveRowRequest
+	(*RemoveRowResponse)(nil),   // 20: pdb.v1.RemoveRowResponse
+	(*UpdateRowRequest)(nil),    // 21: pdb.v1.UpdateRowRequest
+	(*IncrementRequest)(nil),    // 22: pdb.v1.IncrementRequest
+	(*IncrementResponse)(nil),   // 23: pdb.v1.IncrementResponse
+	(*UpdateWhereRequest)(nil),  // 24: pdb.v1.UpdateWhereRequest
+	(*DeleteWhereRequest)(nil),  // 25: pdb.v1.DeleteWhereRequest
+	(*CountResponse)(nil),       // 26: pdb.v1.CountResponse
+	(*ScanRowsRequest)(nil),     // 27: pdb.v1.ScanRowsRequest
+	nil,                         // 28: pdb.v1.Row.ValuesEntry
+	nil,                         // 29: pdb.v1.UpdateRowRequest.ValuesEntry
+	nil,                         // 30: pdb.v1.UpdateWhereRequest.ValuesEntry
+}
+var file_pdb_proto_depIdxs = []int32{
+	0,  // 0: pdb.v1.Header.type:type_name -> pdb.v1.Type
+	2,  // 1: pdb.v1.DB.headers:type_name -> pdb.v1.Header
+	28, // 2: pdb.v1.Row.values:type_name -> pdb.v1.Row.ValuesEntry
+	1,  // 3: pdb.v1.Filter.op:type_name -> pdb.v1.Filter.Op
+	3,  // 4: pdb.v1.ListDBsResponse.dbs:type_name -> pdb.v1.DB
+	2,  // 5: pdb.v1.CreateDBRequest.headers:type_name -> pdb.v1.Header
+	2,  // 6: pdb.v1.AddHeaderRequest.header:type_name -> pdb.v1.Header
+	4,  // 7: pdb.v1.AddRowRequest.row:type_name -> pdb.v1.Row
+	4,  // 8: pdb.v1.AddRowsRequest.rows:type_name -> pdb.v1.Row
+	4,  // 9: pdb.v1.UpsertRowRequest.row:type_name -> pdb.v1.Row
+	29, // 10: pdb.v1.UpdateRowRequest.values:type_name -> pdb.v1.UpdateRowRequest.ValuesEntry
+	5,  // 11: pdb.v1.UpdateWhereRequest.filters:type_name -> pdb.v1.Filter
+	30, // 12: pdb.v1.UpdateWhereRequest.values:type_name -> pdb.v1.UpdateWhereRequest.ValuesEntry
+	5,  // 13: pdb.v1.DeleteWhereRequest.filters:type_name -> pdb.v1.Filter
+	5,  // 14: pdb.v1.ScanRowsRequest.filters:type_name -> pdb.v1.Filter
+	6,  // 15: pdb.v1.PDB.ListDBs:input_type -> pdb.v1.ListDBsRequest
+	8,  // 16: pdb.v1.PDB.CreateDB:input_type -> pdb.v1.CreateDBRequest
+	9,  // 17: pdb.v1.PDB.GetDB:input_type -> pdb.v1.GetDBRequest
+	10, // 18: pdb.v1.PDB.RemoveDB:input_type -> pdb.v1.RemoveDBRequest
+	12, // 19: pdb.v1.PDB.AddHeader:input_type -> pdb.v1.AddHeaderRequest
+	13, // 20: pdb.v1.PDB.RemoveHeader:input_type -> pdb.v1.RemoveHeaderRequest
+	14, // 21: pdb.v1.PDB.AddRow:input_type -> pdb.v1.AddRowRequest
+	15, // 22: pdb.v1.PDB.AddRows:input_type -> pdb.v1.AddRowsRequest
+	17, // 23: pdb.v1.PDB.UpsertRow:input_type -> pdb.v1.UpsertRowRequest
+	18, // 24: pdb.v1.PDB.GetRow:input_type -> pdb.v1.GetRowRequest
+	19, // 25: pdb.v1.PDB.RemoveRow:input_type -> pdb.v1.RemoveRowRequest
+	21, // 26: pdb.v1.PDB.UpdateRow:input_type -> pdb.v1.UpdateRowRequest
+	22, // 27: pdb.v1.PDB.Increment:input_type -> pdb.v1.IncrementRequest
+	24, // 28: pdb.v1.PDB.UpdateWhere:input_type -> pdb.v1.UpdateWhereRequest
+	25, // 29: pdb.v1.PDB.DeleteWhere:input_type -> pdb.v1.DeleteWhereRequest
+	27, // 30: pdb.v1.PDB.ScanRows:input_type -> pdb.v1.ScanRowsRequest
+	7,  // 31: pdb.v1.PDB.ListDBs:output_type -> pdb.v1.ListDBsResponse
+	3,  // 32: pdb.v1.PDB.CreateDB:output_type -> pdb.v1.DB
+	3,  // 33: pdb.v1.PDB.GetDB:output_type -> pdb.v1.DB
+	11, // 34: pdb.v1.PDB.RemoveDB:output_type -> pdb.v1.RemoveDBResponse
+	3,  // 35: pdb.v1.PDB.AddHeader:output_type -> pdb.v1.DB
+	3,  // 36: pdb.v1.PDB.RemoveHeader:output_type -> pdb.v1.DB
+	4,  // 37: pdb.v1.PDB.AddRow:output_type -> pdb.v1.Row
+	16, // 38: pdb.v1.PDB.AddRows:output_type -> pdb.v1.AddRowsResponse
+	4,  // 39: pdb.v1.PDB.UpsertRow:output_type -> pdb.v1.Row
+	4,  // 40: pdb.v1.PDB.GetRow:output_type -> pdb.v1.Row
+	20, // 41: pdb.v1.PDB.RemoveRow:output_type -> pdb.v1.RemoveRowResponse
+	4,  // 42: pdb.v1.PDB.UpdateRow:output_type -> pdb.v1.Row
+	23, // 43: pdb.v1.PDB.Increment:output_type -> pdb.v1.IncrementResponse
+	26, // 44: pdb.v1.PDB.UpdateWhere:output_type -> pdb.v1.CountResponse
+	26, // 45: pdb.v1.PDB.DeleteWhere:output_type -> pdb.v1.CountResponse
+	4,  // 46: pdb.v1.PDB.ScanRows:output_type -> pdb.v1.Row
+	31, // [31:47] is the sub-list for method output_type
+	15, // [15:31] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_pdb_proto_init() }
+func file_pdb_proto_init() {
+	if File_pdb_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pdb_proto_rawDesc), len(file_pdb_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   29,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pdb_proto_goTypes,
+		DependencyIndexes: file_pdb_proto_depIdxs,
+		EnumInfos:         file_pdb_proto_enumTypes,
+		MessageInfos:      file_pdb_proto_msgTypes,
+	}.Build()
+	File_pdb_proto = out.File
+	file_pdb_proto_goTypes = nil
+	file_pdb_proto_depIdxs = nil
+}
@@ -0,0 +1,715 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pdb.proto
+
+package pdbpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PDB_ListDBs_FullMethodName      = "/pdb.v1.PDB/ListDBs"
+	PDB_CreateDB_FullMethodName     = "/pdb.v1.PDB/CreateDB"
+	PDB_GetDB_FullMethodName        = "/pdb.v1.PDB/GetDB"
+	PDB_RemoveDB_FullMethodName     = "/pdb.v1.PDB/RemoveDB"
+	PDB_AddHeader_FullMethodName    = "/pdb.v1.PDB/AddHeader"
+	PDB_RemoveHeader_FullMethodName = "/pdb.v1.PDB/RemoveHeader"
+	PDB_AddRow_FullMethodName       = "/pdb.v1.PDB/AddRow"
+	PDB_AddRows_FullMethodName      = "/pdb.v1.PDB/AddRows"
+	PDB_UpsertRow_FullMethodName    = "/pdb.v1.PDB/UpsertRow"
+	PDB_GetRow_FullMethodName       = "/pdb.v1.PDB/GetRow"
+	PDB_RemoveRow_FullMethodName    = "/pdb.v1.PDB/RemoveRow"
+	PDB_UpdateRow_FullMethodName    = "/pdb.v1.PDB/UpdateRow"
+	PDB_Increment_FullMethodName    = "/pdb.v1.PDB/Increment"
+	PDB_UpdateWhere_FullMethodName  = "/pdb.v1.PDB/UpdateWhere"
+	PDB_DeleteWhere_FullMethodName  = "/pdb.v1.PDB/DeleteWhere"
+	PDB_ScanRows_FullMethodName     = "/pdb.v1.PDB/ScanRows"
+)
+
+// PDBClient is the client API for PDB service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PDB exposes a DB manager and its DBs
+// Errors returned by the DB manager or a DB are mapped to status codes, NOT_FOUND for a
+// missing DB, header or row, ALREADY_EXISTS for a duplicate DB, header or row and
+// INVALID_ARGUMENT for everything else the caller can fix
+type PDBClient interface {
+	ListDBs(ctx context.Context, in *ListDBsRequest, opts ...grpc.CallOption) (*ListDBsResponse, error)
+	CreateDB(ctx context.Context, in *CreateDBRequest, opts ...grpc.CallOption) (*DB, error)
+	GetDB(ctx context.Context, in *GetDBRequest, opts ...grpc.CallOption) (*DB, error)
+	RemoveDB(ctx context.Context, in *RemoveDBRequest, opts ...grpc.CallOption) (*RemoveDBResponse, error)
+	AddHeader(ctx context.Context, in *AddHeaderRequest, opts ...grpc.CallOption) (*DB, error)
+	RemoveHeader(ctx context.Context, in *RemoveHeaderRequest, opts ...grpc.CallOption) (*DB, error)
+	AddRow(ctx context.Context, in *AddRowRequest, opts ...grpc.CallOption) (*Row, error)
+	// Adds several rows, either all of them or none unless best_effort is set
+	AddRows(ctx context.Context, in *AddRowsRequest, opts ...grpc.CallOption) (*AddRowsResponse, error)
+	UpsertRow(ctx context.Context, in *UpsertRowRequest, opts ...grpc.CallOption) (*Row, error)
+	GetRow(ctx context.Context, in *GetRowRequest, opts ...grpc.CallOption) (*Row, error)
+	RemoveRow(ctx context.Context, in *RemoveRowRequest, opts ...grpc.CallOption) (*RemoveRowResponse, error)
+	// Sets values of an existing row
+	UpdateRow(ctx context.Context, in *UpdateRowRequest, opts ...grpc.CallOption) (*Row, error)
+	Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	// Sets values of every row matching the filters
+	UpdateWhere(ctx context.Context, in *UpdateWhereRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	// Deletes every row matching the filters
+	DeleteWhere(ctx context.Context, in *DeleteWhereRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	// Streams the rows matching the filters ordered by KeyHeader value
+	ScanRows(ctx context.Context, in *ScanRowsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Row], error)
+}
+
+type pDBClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPDBClient(cc grpc.ClientConnInterface) PDBClient {
+	return &pDBClient{cc}
+}
+
+func (c *pDBClient) ListDBs(ctx context.Context, in *ListDBsRequest, opts ...grpc.CallOption) (*ListDBsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDBsResponse)
+	err := c.cc.Invoke(ctx, PDB_ListDBs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) CreateDB(ctx context.Context, in *CreateDBRequest, opts ...grpc.CallOption) (*DB, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DB)
+	err := c.cc.Invoke(ctx, PDB_CreateDB_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) GetDB(ctx context.Context, in *GetDBRequest, opts ...grpc.CallOption) (*DB, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DB)
+	err := c.cc.Invoke(ctx, PDB_GetDB_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) RemoveDB(ctx context.Context, in *RemoveDBRequest, opts ...grpc.CallOption) (*RemoveDBResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveDBResponse)
+	err := c.cc.Invoke(ctx, PDB_RemoveDB_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) AddHeader(ctx context.Context, in *AddHeaderRequest, opts ...grpc.CallOption) (*DB, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DB)
+	err := c.cc.Invoke(ctx, PDB_AddHeader_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) RemoveHeader(ctx context.Context, in *RemoveHeaderRequest, opts ...grpc.CallOption) (*DB, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DB)
+	err := c.cc.Invoke(ctx, PDB_RemoveHeader_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) AddRow(ctx context.Context, in *AddRowRequest, opts ...grpc.CallOption) (*Row, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Row)
+	err := c.cc.Invoke(ctx, PDB_AddRow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) AddRows(ctx context.Context, in *AddRowsRequest, opts ...grpc.CallOption) (*AddRowsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddRowsResponse)
+	err := c.cc.Invoke(ctx, PDB_AddRows_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) UpsertRow(ctx context.Context, in *UpsertRowRequest, opts ...grpc.CallOption) (*Row, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Row)
+	err := c.cc.Invoke(ctx, PDB_UpsertRow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) GetRow(ctx context.Context, in *GetRowRequest, opts ...grpc.CallOption) (*Row, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Row)
+	err := c.cc.Invoke(ctx, PDB_GetRow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) RemoveRow(ctx context.Context, in *RemoveRowRequest, opts ...grpc.CallOption) (*RemoveRowResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveRowResponse)
+	err := c.cc.Invoke(ctx, PDB_RemoveRow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) UpdateRow(ctx context.Context, in *UpdateRowRequest, opts ...grpc.CallOption) (*Row, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Row)
+	err := c.cc.Invoke(ctx, PDB_UpdateRow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, PDB_Increment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) UpdateWhere(ctx context.Context, in *UpdateWhereRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, PDB_UpdateWhere_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) DeleteWhere(ctx context.Context, in *DeleteWhereRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, PDB_DeleteWhere_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pDBClient) ScanRows(ctx context.Context, in *ScanRowsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Row], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PDB_ServiceDesc.Streams[0], PDB_ScanRows_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ScanRowsRequest, Row]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PDB_ScanRowsClient = grpc.ServerStreamingClient[Row]
+
+// PDBServer is the server API for PDB service.
+// All implementations must embed UnimplementedPDBServer
+// for forward compatibility.
+//
+// PDB exposes a DB manager and its DBs
+// Errors returned by the DB manager or a DB are mapped to status codes, NOT_FOUND for a
+// missing DB, header or row, ALREADY_EXISTS for a duplicate DB, header or row and
+// INVALID_ARGUMENT for everything else the caller can fix
+type PDBServer interface {
+	ListDBs(context.Context, *ListDBsRequest) (*ListDBsResponse, error)
+	CreateDB(context.Context, *CreateDBRequest) (*DB, error)
+	GetDB(context.Context, *GetDBRequest) (*DB, error)
+	RemoveDB(context.Context, *RemoveDBRequest) (*RemoveDBResponse, error)
+	AddHeader(context.Context, *AddHeaderRequest) (*DB, error)
+	RemoveHeader(context.Context, *RemoveHeaderRequest) (*DB, error)
+	AddRow(context.Context, *AddRowRequest) (*Row, error)
+	// Adds several rows, either all of them or none unless best_effort is set
+	AddRows(context.Context, *AddRowsRequest) (*AddRowsResponse, error)
+	UpsertRow(context.Context, *UpsertRowRequest) (*Row, error)
+	GetRow(context.Context, *GetRowRequest) (*Row, error)
+	RemoveRow(context.Context, *RemoveRowRequest) (*RemoveRowResponse, error)
+	// Sets values of an existing row
+	UpdateRow(context.Context, *UpdateRowRequest) (*Row, error)
+	Increment(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	// Sets values of every row matching the filters
+	UpdateWhere(context.Context, *UpdateWhereRequest) (*CountResponse, error)
+	// Deletes every row matching the filters
+	DeleteWhere(context.Context, *DeleteWhereRequest) (*CountResponse, error)
+	// Streams the rows matching the filters ordered by KeyHeader value
+	ScanRows(*ScanRowsRequest, grpc.ServerStreamingServer[Row]) error
+	mustEmbedUnimplementedPDBServer()
+}
+
+// UnimplementedPDBServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPDBServer struct{}
+
+func (UnimplementedPDBServer) ListDBs(context.Context, *ListDBsRequest) (*ListDBsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDBs not implemented")
+}
+func (UnimplementedPDBServer) CreateDB(context.Context, *CreateDBRequest) (*DB, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDB not implemented")
+}
+func (UnimplementedPDBServer) GetDB(context.Context, *GetDBRequest) (*DB, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDB not implemented")
+}
+func (UnimplementedPDBServer) RemoveDB(context.Context, *RemoveDBRequest) (*RemoveDBResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveDB not implemented")
+}
+func (UnimplementedPDBServer) AddHeader(context.Context, *AddHeaderRequest) (*DB, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddHeader not implemented")
+}
+func (UnimplementedPDBServer) RemoveHeader(context.Context, *RemoveHeaderRequest) (*DB, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveHeader not implemented")
+}
+func (UnimplementedPDBServer) AddRow(context.Context, *AddRowRequest) (*Row, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRow not implemented")
+}
+func (UnimplementedPDBServer) AddRows(context.Context, *AddRowsRequest) (*AddRowsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRows not implemented")
+}
+func (UnimplementedPDBServer) UpsertRow(context.Context, *UpsertRowRequest) (*Row, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpsertRow not implemented")
+}
+func (UnimplementedPDBServer) GetRow(context.Context, *GetRowRequest) (*Row, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRow not implemented")
+}
+func (UnimplementedPDBServer) RemoveRow(context.Context, *RemoveRowRequest) (*RemoveRowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveRow not implemented")
+}
+func (UnimplementedPDBServer) UpdateRow(context.Context, *UpdateRowRequest) (*Row, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRow not implemented")
+}
+func (UnimplementedPDBServer) Increment(context.Context, *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Increment not implemented")
+}
+func (UnimplementedPDBServer) UpdateWhere(context.Context, *UpdateWhereRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateWhere not implemented")
+}
+func (UnimplementedPDBServer) DeleteWhere(context.Context, *DeleteWhereRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWhere not implemented")
+}
+func (UnimplementedPDBServer) ScanRows(*ScanRowsRequest, grpc.ServerStreamingServer[Row]) error {
+	return status.Errorf(codes.Unimplemented, "method ScanRows not implemented")
+}
+func (UnimplementedPDBServer) mustEmbedUnimplementedPDBServer() {}
+func (UnimplementedPDBServer) testEmbeddedByValue()             {}
+
+// UnsafePDBServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PDBServer will
+// result in compilation errors.
+type UnsafePDBServer interface {
+	mustEmbedUnimplementedPDBServer()
+}
+
+func RegisterPDBServer(s grpc.ServiceRegistrar, srv PDBServer) {
+	// If the following call pancis, it indicates UnimplementedPDBServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PDB_ServiceDesc, srv)
+}
+
+func _PDB_ListDBs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDBsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).ListDBs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_ListDBs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).ListDBs(ctx, req.(*ListDBsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_CreateDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDBRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).CreateDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_CreateDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).CreateDB(ctx, req.(*CreateDBRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_GetDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDBRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).GetDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_GetDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).GetDB(ctx, req.(*GetDBRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_RemoveDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveDBRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).RemoveDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_RemoveDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).RemoveDB(ctx, req.(*RemoveDBRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_AddHeader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddHeaderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).AddHeader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_AddHeader_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).AddHeader(ctx, req.(*AddHeaderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_RemoveHeader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveHeaderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).RemoveHeader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_RemoveHeader_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).RemoveHeader(ctx, req.(*RemoveHeaderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_AddRow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).AddRow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_AddRow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).AddRow(ctx, req.(*AddRowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_AddRows_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRowsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).AddRows(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_AddRows_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).AddRows(ctx, req.(*AddRowsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_UpsertRow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertRowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).UpsertRow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_UpsertRow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).UpsertRow(ctx, req.(*UpsertRowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_GetRow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).GetRow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_GetRow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).GetRow(ctx, req.(*GetRowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_RemoveRow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveRowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).RemoveRow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_RemoveRow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).RemoveRow(ctx, req.(*RemoveRowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_UpdateRow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).UpdateRow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_UpdateRow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).UpdateRow(ctx, req.(*UpdateRowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_Increment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).Increment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_Increment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).Increment(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_UpdateWhere_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateWhereRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).UpdateWhere(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_UpdateWhere_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).UpdateWhere(ctx, req.(*UpdateWhereRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_DeleteWhere_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWhereRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PDBServer).DeleteWhere(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PDB_DeleteWhere_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PDBServer).DeleteWhere(ctx, req.(*DeleteWhereRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PDB_ScanRows_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRowsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PDBServer).ScanRows(m, &grpc.GenericServerStream[ScanRowsRequest, Row]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PDB_ScanRowsServer = grpc.ServerStreamingServer[Row]
+
+// PDB_ServiceDesc is the grpc.ServiceDesc for PDB service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PDB_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pdb.v1.PDB",
+	HandlerType: (*PDBServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDBs",
+			Handler:    _PDB_ListDBs_Handler,
+		},
+		{
+			MethodName: "CreateDB",
+			Handler:    _PDB_CreateDB_Handler,
+		},
+		{
+			MethodName: "GetDB",
+			Handler:    _PDB_GetDB_Handler,
+		},
+		{
+			MethodName: "RemoveDB",
+			Handler:    _PDB_RemoveDB_Handler,
+		},
+		{
+			MethodName: "AddHeader",
+			Handler:    _PDB_AddHeader_Handler,
+		},
+		{
+			MethodName: "RemoveHeader",
+			Handler:    _PDB_RemoveHeader_Handler,
+		},
+		{
+			MethodName: "AddRow",
+			Handler:    _PDB_AddRow_Handler,
+		},
+		{
+			MethodName: "AddRows",
+			Handler:    _PDB_AddRows_Handler,
+		},
+		{
+			MethodName: "UpsertRow",
+			Handler:    _PDB_UpsertRow_Handler,
+		},
+		{
+			MethodName: "GetRow",
+			Handler:    _PDB_GetRow_Handler,
+		},
+		{
+			MethodName: "RemoveRow",
+			Handler:    _PDB_RemoveRow_Handler,
+		},
+		{
+			MethodName: "UpdateRow",
+			Handler:    _PDB_UpdateRow_Handler,
+		},
+		{
+			MethodName: "Increment",
+			Handler:    _PDB_Increment_Handler,
+		},
+		{
+			MethodName: "UpdateWhere",
+			Handler:    _PDB_UpdateWhere_Handler,
+		},
+		{
+			MethodName: "DeleteWhere",
+			Handler:    _PDB_DeleteWhere_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ScanRows",
+			Handler:       _PDB_ScanRows_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pdb.proto",
+}
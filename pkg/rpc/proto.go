@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/rpc/pdbpb"
+)
+
+// Returns the DB's schema with the KeyHeader first and the other headers sorted by name
+func newDB(d db.DB) *pdbpb.DB {
+	headers := d.GetHeaders()
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i].IsKeyHeader() != headers[j].IsKeyHeader() {
+			return headers[i].IsKeyHeader()
+		}
+		return headers[i].GetName() < headers[j].GetName()
+	})
+
+	dbProto := &pdbpb.DB{
+		Name:      d.GetName(),
+		KeyHeader: d.GetKeyHeader(),
+		Rows:      int64(len(d.GetRows())),
+	}
+	for _, h := range headers {
+		dbProto.Headers = append(dbProto.Headers, &pdbpb.Header{
+			Name: h.GetName(),
+			Type: typeToProto(h.GetType()),
+			Key:  h.IsKeyHeader(),
+		})
+	}
+
+	return dbProto
+}
+
+func sortDBs(dbs []*pdbpb.DB) {
+	sort.Slice(dbs, func(i, j int) bool {
+		return dbs[i].GetName() < dbs[j].GetName()
+	})
+}
+
+func typeToProto(t db.Type) pdbpb.Type {
+	if t == db.VALUE_NUMBER {
+		return pdbpb.Type_TYPE_NUMBER
+	}
+
+	return pdbpb.Type_TYPE_STRING
+}
+
+func typeFromProto(t pdbpb.Type) (db.Type, error) {
+	switch t {
+	case pdbpb.Type_TYPE_STRING:
+		return db.VALUE_STRING, nil
+	case pdbpb.Type_TYPE_NUMBER:
+		return db.VALUE_NUMBER, nil
+	}
+
+	return db.VALUE_STRING, &db.Error{Err: db.ErrUnknownType, Value: t.String(), Message: fmt.Sprintf(unknownTypeError, t)}
+}
+
+func rowToProto(row db.RowI) *pdbpb.Row {
+	values := map[string]string{}
+	for h, v := range row.GetRowMap() {
+		values[h.GetName()] = v.GetValue()
+	}
+
+	return &pdbpb.Row{Values: values}
+}
+
+// Creates a row for the DB, checking each value matches its header's type
+func newRow(d db.DB, values map[string]string) (db.RowI, error) {
+	for header, value := range values {
+		h := d.GetHeader(header)
+		if h.GetName() == "" {
+			return nil, &db.Error{Err: db.ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		err := db.ValidateValue(h, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db.NewRowFromMap(d, values)
+}
+
+// Returns a copy of the rows ordered by their KeyHeader value
+func sortRows(rows []db.RowI) []db.RowI {
+	sorted := append([]db.RowI{}, rows...)
+	sort.Slice(sorted, func(i, j int) bool {
+		_, vi := sorted[i].GetKeyHeaderAndValue()
+		_, vj := sorted[j].GetKeyHeaderAndValue()
+		return vi.GetValue() < vj.GetValue()
+	})
+
+	return sorted
+}
@@ -0,0 +1,508 @@
+package rpc
+
+//go:generate protoc -I pdbpb --go_out=pdbpb --go_opt=paths=source_relative --go-grpc_out=pdbpb --go-grpc_opt=paths=source_relative pdb.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/rpc/pdbpb"
+	"github.com/brownlow2/pdb/pkg/server"
+)
+
+// Creates the gRPC service for the DB manager
+func New(dbm *dbmanager.DBManagerImpl) *Server {
+	return &Server{DBM: dbm}
+}
+
+// Creates a gRPC server with the pdb service registered for the DB manager
+func NewGRPCServer(dbm *dbmanager.DBManagerImpl, opts ...grpc.ServerOption) *grpc.Server {
+	gs := grpc.NewServer(opts...)
+	pdbpb.RegisterPDBServer(gs, New(dbm))
+	return gs
+}
+
+func (s *Server) ListDBs(ctx context.Context, req *pdbpb.ListDBsRequest) (*pdbpb.ListDBsResponse, error) {
+	resp := &pdbpb.ListDBsResponse{}
+	for _, d := range s.DBM.GetDBs() {
+		resp.Dbs = append(resp.Dbs, newDB(d))
+	}
+	sortDBs(resp.Dbs)
+
+	return resp, nil
+}
+
+func (s *Server) CreateDB(ctx context.Context, req *pdbpb.CreateDBRequest) (*pdbpb.DB, error) {
+	headers := []db.HeaderI{}
+	for _, h := range req.GetHeaders() {
+		t, err := typeFromProto(h.GetType())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		headers = append(headers, &db.Header{Name: h.GetName(), KeyHeader: h.GetName() == req.GetKeyHeader(), Type: t})
+	}
+
+	err := s.manager(ctx).CreateDB(req.GetName(), headers, req.GetKeyHeader())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return s.GetDB(ctx, &pdbpb.GetDBRequest{Name: req.GetName()})
+}
+
+func (s *Server) GetDB(ctx context.Context, req *pdbpb.GetDBRequest) (*pdbpb.DB, error) {
+	d, err := s.retrieveDB(ctx, req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	return newDB(d), nil
+}
+
+func (s *Server) RemoveDB(ctx context.Context, req *pdbpb.RemoveDBRequest) (*pdbpb.RemoveDBResponse, error) {
+	err := s.manager(ctx).RemoveDB(req.GetName())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return &pdbpb.RemoveDBResponse{}, nil
+}
+
+func (s *Server) AddHeader(ctx context.Context, req *pdbpb.AddHeaderRequest) (*pdbpb.DB, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := typeFromProto(req.GetHeader().GetType())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	name := req.GetHeader().GetName()
+	if d.GetHeader(name).GetName() != "" {
+		return nil, status.Error(codes.AlreadyExists, fmt.Sprintf(headerExistsError, name))
+	}
+
+	d.AddHeader(&db.Header{Name: name, KeyHeader: false, Type: t})
+	return newDB(d), nil
+}
+
+func (s *Server) RemoveHeader(ctx context.Context, req *pdbpb.RemoveHeaderRequest) (*pdbpb.DB, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	if d.GetHeader(req.GetHeader()).GetName() == "" {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf(headerNotExistError, req.GetHeader()))
+	}
+
+	// The only header left that can't be removed is the KeyHeader
+	err = d.RemoveHeader(req.GetHeader())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return newDB(d), nil
+}
+
+func (s *Server) AddRow(ctx context.Context, req *pdbpb.AddRowRequest) (*pdbpb.Row, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := newRow(d, req.GetRow().GetValues())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	err = d.AddRow(row)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return rowToProto(row), nil
+}
+
+// Adds the rows, reporting the rows that couldn't be added in the response rather than as an
+// error so each row's error can be returned
+func (s *Server) AddRows(ctx context.Context, req *pdbpb.AddRowsRequest) (*pdbpb.AddRowsResponse, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(req.GetRows()))
+	failed := false
+	rows := []db.RowI{}
+	indexes := []int{}
+	for i, r := range req.GetRows() {
+		row, err := newRow(d, r.GetValues())
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+		rows = append(rows, row)
+		indexes = append(indexes, i)
+	}
+
+	// Rows that couldn't be created fail the whole request unless adding on a best effort basis
+	added := int64(0)
+	if !failed || req.GetBestEffort() {
+		rowErrs, err := d.AddRows(rows, db.AddRowsOptions{AllOrNothing: !req.GetBestEffort()})
+		// The rows were added, but like AddRow the hook's error is what's returned
+		if err != nil {
+			return nil, statusFor(err)
+		}
+		for i := range rows {
+			if rowErrs != nil && rowErrs[i] != nil {
+				errs[indexes[i]] = rowErrs[i]
+				failed = true
+			} else if rowErrs == nil || req.GetBestEffort() {
+				added++
+			}
+		}
+	}
+
+	resp := &pdbpb.AddRowsResponse{Added: added}
+	if failed {
+		resp.Errors = make([]string, len(errs))
+		for i, err := range errs {
+			if err != nil {
+				resp.Errors[i] = err.Error()
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// Creates the row or replaces its values, headers missing from the request keep their
+// current values
+func (s *Server) UpsertRow(ctx context.Context, req *pdbpb.UpsertRowRequest) (*pdbpb.Row, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := newRow(d, req.GetRow().GetValues())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	err = d.Upsert(row)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	_, v := row.GetKeyHeaderAndValue()
+	return rowToProto(d.GetRowFromKeyHeader(v.GetValue())), nil
+}
+
+func (s *Server) GetRow(ctx context.Context, req *pdbpb.GetRowRequest) (*pdbpb.Row, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := retrieveRow(d, req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return rowToProto(row), nil
+}
+
+func (s *Server) RemoveRow(ctx context.Context, req *pdbpb.RemoveRowRequest) (*pdbpb.RemoveRowResponse, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = retrieveRow(d, req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.RemoveRow(req.GetKey())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return &pdbpb.RemoveRowResponse{}, nil
+}
+
+func (s *Server) UpdateRow(ctx context.Context, req *pdbpb.UpdateRowRequest) (*pdbpb.Row, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = retrieveRow(d, req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for header, value := range req.GetValues() {
+		if header != d.GetKeyHeader() {
+			values[header] = value
+		} else if value != req.GetKey() {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf(keyMismatchError, value, req.GetKey()))
+		}
+	}
+
+	_, err = d.UpdateWhere(func(row db.RowI) bool {
+		return row.KeyHeaderValueEqual(req.GetKey())
+	}, values)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return rowToProto(d.GetRowFromKeyHeader(req.GetKey())), nil
+}
+
+func (s *Server) Increment(ctx context.Context, req *pdbpb.IncrementRequest) (*pdbpb.IncrementResponse, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := d.Increment(req.GetKey(), req.GetHeader(), req.GetDelta())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return &pdbpb.IncrementResponse{Value: value}, nil
+}
+
+func (s *Server) UpdateWhere(ctx context.Context, req *pdbpb.UpdateWhereRequest) (*pdbpb.CountResponse, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	predicate, err := newPredicate(d, req.GetFilters())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	count, err := d.UpdateWhere(predicate, req.GetValues())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return &pdbpb.CountResponse{Count: int64(count)}, nil
+}
+
+func (s *Server) DeleteWhere(ctx context.Context, req *pdbpb.DeleteWhereRequest) (*pdbpb.CountResponse, error) {
+	d, err := s.retrieveDB(ctx, req.GetDb())
+	if err != nil {
+		return nil, err
+	}
+
+	predicate, err := newPredicate(d, req.GetFilters())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	count, err := d.DeleteWhere(predicate)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return &pdbpb.CountResponse{Count: int64(count)}, nil
+}
+
+// Sends the matching rows one message at a time, stopping early if the client goes away
+func (s *Server) ScanRows(req *pdbpb.ScanRowsRequest, stream pdbpb.PDB_ScanRowsServer) error {
+	d, err := s.retrieveDB(stream.Context(), req.GetDb())
+	if err != nil {
+		return err
+	}
+
+	if req.GetLimit() < 0 {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf(negativeError, "limit"))
+	}
+	if req.GetOffset() < 0 {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf(negativeError, "offset"))
+	}
+
+	predicate, err := newPredicate(d, req.GetFilters())
+	if err != nil {
+		return statusFor(err)
+	}
+
+	ctx := stream.Context()
+	all, err := getRows(ctx, d)
+	if err != nil {
+		return statusFor(err)
+	}
+
+	rows := []db.RowI{}
+	for _, row := range all {
+		if err := ctx.Err(); err != nil {
+			return statusFor(err)
+		}
+
+		if predicate == nil || predicate(row) {
+			rows = append(rows, row)
+		}
+	}
+	rows = sortRows(rows)
+
+	if req.GetOffset() >= int64(len(rows)) {
+		return nil
+	}
+	rows = rows[req.GetOffset():]
+	if req.GetLimit() > 0 && req.GetLimit() < int64(len(rows)) {
+		rows = rows[:req.GetLimit()]
+	}
+
+	for _, row := range rows {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		err := stream.Send(rowToProto(row))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the DB manager attributing changes to the actor in the call's ActorMetadata
+func (s *Server) manager(ctx context.Context) dbmanager.DBManager {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if actors := md.Get(ActorMetadata); len(actors) > 0 {
+			ctx = db.WithActor(ctx, actors[0])
+		}
+	}
+
+	return s.DBM.WithContext(ctx)
+}
+
+// Returns the DB with the given name, or a NotFound error if it doesn't exist
+func (s *Server) retrieveDB(ctx context.Context, name string) (db.DB, error) {
+	d, err := s.manager(ctx).RetrieveDB(name)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+
+	return d, nil
+}
+
+// Returns the row with the given key, or a NotFound error if it doesn't exist
+func retrieveRow(d db.DB, key string) (db.RowI, error) {
+	row := d.GetRowFromKeyHeader(key)
+	if row == nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf(rowNotExistError, key))
+	}
+
+	return row, nil
+}
+
+// Returns the status for an error returned by the DB manager or a DB, keeping its message
+// The code is the one for the HTTP status the HTTP API responds with, so both APIs agree
+func statusFor(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	code := codes.InvalidArgument
+	switch server.HTTPStatus(err) {
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// Returns the DB's rows, giving up once ctx is done if the DB supports it
+func getRows(ctx context.Context, d db.DB) ([]db.RowI, error) {
+	if dc, ok := d.(db.DBContext); ok {
+		return dc.GetRowsContext(ctx)
+	}
+
+	return d.GetRows(), nil
+}
+
+// Returns a predicate matching rows that pass every filter, or nil if there are none
+func newPredicate(d db.DB, filters []*pdbpb.Filter) (db.Predicate, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	predicates := []db.Predicate{}
+	for _, f := range filters {
+		predicate, err := newFilter(d, f)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	return func(row db.RowI) bool {
+		for _, predicate := range predicates {
+			if !predicate(row) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func newFilter(d db.DB, f *pdbpb.Filter) (db.Predicate, error) {
+	header, value := f.GetHeader(), f.GetValue()
+	if d.GetHeader(header).GetName() == "" {
+		return nil, &db.Error{Err: db.ErrHeaderNotExist, Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	switch f.GetOp() {
+	case pdbpb.Filter_OP_EQ, pdbpb.Filter_OP_NE:
+		equal := f.GetOp() == pdbpb.Filter_OP_EQ
+		return func(row db.RowI) bool {
+			v, err := row.GetValueFromHeader(header)
+			return err == nil && (v.GetValue() == value) == equal
+		}, nil
+	case pdbpb.Filter_OP_LT, pdbpb.Filter_OP_GT:
+		target, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, &db.Error{Err: db.ErrNotANumber, Value: value, Message: fmt.Sprintf(notANumberError, value)}
+		}
+		less := f.GetOp() == pdbpb.Filter_OP_LT
+		return func(row db.RowI) bool {
+			v, err := row.GetValueFromHeader(header)
+			if err != nil {
+				return false
+			}
+			n, err := strconv.ParseFloat(v.GetValue(), 64)
+			if err != nil {
+				return false
+			}
+			if less {
+				return n < target
+			}
+			return n > target
+		}, nil
+	}
+
+	return nil, &db.Error{Err: db.ErrUnknownOperator, Value: f.GetOp().String(), Message: fmt.Sprintf(unknownOperatorError, f.GetOp())}
+}
@@ -0,0 +1,327 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/audit"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/rpc/pdbpb"
+)
+
+// Returns a client connected to a server over an in-process listener
+func newTestClient(t *testing.T) pdbpb.PDBClient {
+	return newTestClientFor(t, dbmanager.New())
+}
+
+// Returns a client connected to a server for dbm over an in-process listener
+func newTestClientFor(t *testing.T, dbm *dbmanager.DBManagerImpl) pdbpb.PDBClient {
+	lis := bufconn.Listen(1024 * 1024)
+	gs := NewGRPCServer(dbm)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pdbpb.NewPDBClient(conn)
+}
+
+// Returns a client with a Games DB holding three rows
+func newTestGames(t *testing.T) pdbpb.PDBClient {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.CreateDB(ctx, &pdbpb.CreateDBRequest{
+		Name:      "Games",
+		KeyHeader: "Title",
+		Headers: []*pdbpb.Header{
+			{Name: "Title", Type: pdbpb.Type_TYPE_STRING},
+			{Name: "Year", Type: pdbpb.Type_TYPE_NUMBER},
+		},
+	})
+	assert.Nil(t, err)
+
+	resp, err := c.AddRows(ctx, &pdbpb.AddRowsRequest{Db: "Games", Rows: []*pdbpb.Row{
+		{Values: map[string]string{"Title": "Jak 2", "Year": "2003"}},
+		{Values: map[string]string{"Title": "Jak 3", "Year": "2004"}},
+		{Values: map[string]string{"Title": "Jak X", "Year": "2005"}},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), resp.GetAdded())
+
+	return c
+}
+
+func assertCode(t *testing.T, code codes.Code, err error) {
+	assert.Equal(t, code, status.Code(err), err)
+}
+
+func TestDBs(t *testing.T) {
+	c := newTestGames(t)
+	ctx := context.Background()
+
+	d, err := c.GetDB(ctx, &pdbpb.GetDBRequest{Name: "Games"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Title", d.GetKeyHeader())
+	assert.Equal(t, int64(3), d.GetRows())
+	assert.True(t, d.GetHeaders()[0].GetKey())
+	assert.Equal(t, pdbpb.Type_TYPE_NUMBER, d.GetHeaders()[1].GetType())
+
+	_, err = c.GetDB(ctx, &pdbpb.GetDBRequest{Name: "Films"})
+	assertCode(t, codes.NotFound, err)
+	assert.Equal(t, "database 'Films' does not exist", status.Convert(err).Message())
+
+	_, err = c.CreateDB(ctx, &pdbpb.CreateDBRequest{Name: "Games", KeyHeader: "Title"})
+	assertCode(t, codes.AlreadyExists, err)
+
+	_, err = c.CreateDB(ctx, &pdbpb.CreateDBRequest{Name: "Films", KeyHeader: "Title", Headers: []*pdbpb.Header{{Name: "Title", Type: 7}}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	list, err := c.ListDBs(ctx, &pdbpb.ListDBsRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list.GetDbs()))
+
+	_, err = c.RemoveDB(ctx, &pdbpb.RemoveDBRequest{Name: "Games"})
+	assert.Nil(t, err)
+	_, err = c.RemoveDB(ctx, &pdbpb.RemoveDBRequest{Name: "Games"})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestHeaders(t *testing.T) {
+	c := newTestGames(t)
+	ctx := context.Background()
+
+	d, err := c.AddHeader(ctx, &pdbpb.AddHeaderRequest{Db: "Games", Header: &pdbpb.Header{Name: "Genre"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(d.GetHeaders()))
+
+	_, err = c.AddHeader(ctx, &pdbpb.AddHeaderRequest{Db: "Games", Header: &pdbpb.Header{Name: "Genre"}})
+	assertCode(t, codes.AlreadyExists, err)
+
+	_, err = c.RemoveHeader(ctx, &pdbpb.RemoveHeaderRequest{Db: "Games", Header: "Title"})
+	assertCode(t, codes.InvalidArgument, err)
+
+	d, err = c.RemoveHeader(ctx, &pdbpb.RemoveHeaderRequest{Db: "Games", Header: "Genre"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(d.GetHeaders()))
+
+	_, err = c.RemoveHeader(ctx, &pdbpb.RemoveHeaderRequest{Db: "Games", Header: "Genre"})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestRows(t *testing.T) {
+	c := newTestGames(t)
+	ctx := context.Background()
+
+	_, err := c.AddRow(ctx, &pdbpb.AddRowRequest{Db: "Games", Row: &pdbpb.Row{Values: map[string]string{"Title": "Jak 2"}}})
+	assertCode(t, codes.AlreadyExists, err)
+
+	_, err = c.AddRow(ctx, &pdbpb.AddRowRequest{Db: "Games", Row: &pdbpb.Row{Values: map[string]string{"Title": "Daxter", "Year": "soon"}}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = c.AddRow(ctx, &pdbpb.AddRowRequest{Db: "Games", Row: &pdbpb.Row{Values: map[string]string{"Title": "Daxter", "Genre": "Platformer"}}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	row, err := c.AddRow(ctx, &pdbpb.AddRowRequest{Db: "Games", Row: &pdbpb.Row{Values: map[string]string{"Title": "Daxter"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Title": "Daxter", "Year": ""}, row.GetValues())
+
+	row, err = c.UpsertRow(ctx, &pdbpb.UpsertRowRequest{Db: "Games", Row: &pdbpb.Row{Values: map[string]string{"Title": "Daxter", "Year": "2006"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "2006", row.GetValues()["Year"])
+
+	row, err = c.UpdateRow(ctx, &pdbpb.UpdateRowRequest{Db: "Games", Key: "Daxter", Values: map[string]string{"Year": "2005"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "2005", row.GetValues()["Year"])
+
+	_, err = c.UpdateRow(ctx, &pdbpb.UpdateRowRequest{Db: "Games", Key: "Daxter", Values: map[string]string{"Title": "Jak"}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = c.UpdateRow(ctx, &pdbpb.UpdateRowRequest{Db: "Games", Key: "Jak", Values: map[string]string{"Year": "2001"}})
+	assertCode(t, codes.NotFound, err)
+
+	incremented, err := c.Increment(ctx, &pdbpb.IncrementRequest{Db: "Games", Key: "Daxter", Header: "Year", Delta: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(2007), incremented.GetValue())
+
+	_, err = c.Increment(ctx, &pdbpb.IncrementRequest{Db: "Games", Key: "Daxter", Header: "Title", Delta: 2})
+	assertCode(t, codes.InvalidArgument, err)
+
+	row, err = c.GetRow(ctx, &pdbpb.GetRowRequest{Db: "Games", Key: "Daxter"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2007", row.GetValues()["Year"])
+
+	_, err = c.RemoveRow(ctx, &pdbpb.RemoveRowRequest{Db: "Games", Key: "Daxter"})
+	assert.Nil(t, err)
+
+	_, err = c.GetRow(ctx, &pdbpb.GetRowRequest{Db: "Games", Key: "Daxter"})
+	assertCode(t, codes.NotFound, err)
+	assert.Equal(t, "row with key value 'Daxter' does not exist", status.Convert(err).Message())
+
+	_, err = c.RemoveRow(ctx, &pdbpb.RemoveRowRequest{Db: "Games", Key: "Daxter"})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestAddRows(t *testing.T) {
+	c := newTestGames(t)
+	ctx := context.Background()
+
+	rows := []*pdbpb.Row{
+		{Values: map[string]string{"Title": "Daxter"}},
+		{Values: map[string]string{"Title": "Jak 2"}},
+		{Values: map[string]string{"Title": "Jak", "Year": "soon"}},
+	}
+
+	resp, err := c.AddRows(ctx, &pdbpb.AddRowsRequest{Db: "Games", Rows: rows})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), resp.GetAdded())
+	assert.Equal(t, []string{"", "", "value soon is not a number"}, resp.GetErrors())
+
+	resp, err = c.AddRows(ctx, &pdbpb.AddRowsRequest{Db: "Games", Rows: rows, BestEffort: true})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), resp.GetAdded())
+	assert.Equal(t, "", resp.GetErrors()[0])
+	assert.Equal(t, "row with key header 'Title' and value 'Jak 2' already exists", resp.GetErrors()[1])
+	assert.Equal(t, "value soon is not a number", resp.GetErrors()[2])
+}
+
+func TestWhere(t *testing.T) {
+	c := newTestGames(t)
+	ctx := context.Background()
+
+	after2003 := []*pdbpb.Filter{{Header: "Year", Op: pdbpb.Filter_OP_GT, Value: "2003"}}
+
+	count, err := c.UpdateWhere(ctx, &pdbpb.UpdateWhereRequest{Db: "Games", Filters: after2003, Values: map[string]string{"Year": "2010"}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count.GetCount())
+
+	_, err = c.UpdateWhere(ctx, &pdbpb.UpdateWhereRequest{Db: "Games", Values: map[string]string{"Title": "Jak"}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = c.DeleteWhere(ctx, &pdbpb.DeleteWhereRequest{Db: "Games", Filters: []*pdbpb.Filter{{Header: "Genre"}}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = c.DeleteWhere(ctx, &pdbpb.DeleteWhereRequest{Db: "Games", Filters: []*pdbpb.Filter{{Header: "Year", Op: pdbpb.Filter_OP_LT, Value: "soon"}}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	count, err = c.DeleteWhere(ctx, &pdbpb.DeleteWhereRequest{Db: "Games", Filters: []*pdbpb.Filter{{Header: "Year", Value: "2010"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count.GetCount())
+}
+
+func scan(t *testing.T, c pdbpb.PDBClient, req *pdbpb.ScanRowsRequest) ([]string, error) {
+	stream, err := c.ScanRows(context.Background(), req)
+	assert.Nil(t, err)
+
+	titles := []string{}
+	for {
+		row, err := stream.Recv()
+		if err == io.EOF {
+			return titles, nil
+		}
+		if err != nil {
+			return titles, err
+		}
+		titles = append(titles, row.GetValues()["Title"])
+	}
+}
+
+func TestScanRows(t *testing.T) {
+	c := newTestGames(t)
+
+	titles, err := scan(t, c, &pdbpb.ScanRowsRequest{Db: "Games"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Jak 2", "Jak 3", "Jak X"}, titles)
+
+	titles, err = scan(t, c, &pdbpb.ScanRowsRequest{Db: "Games", Offset: 1, Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Jak 3"}, titles)
+
+	titles, err = scan(t, c, &pdbpb.ScanRowsRequest{Db: "Games", Filters: []*pdbpb.Filter{{Header: "Title", Op: pdbpb.Filter_OP_NE, Value: "Jak 3"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Jak 2", "Jak X"}, titles)
+
+	titles, err = scan(t, c, &pdbpb.ScanRowsRequest{Db: "Games", Offset: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, titles)
+
+	_, err = scan(t, c, &pdbpb.ScanRowsRequest{Db: "Games", Limit: -1})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = scan(t, c, &pdbpb.ScanRowsRequest{Db: "Films"})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestActor(t *testing.T) {
+	dbm := dbmanager.New()
+	log := audit.New(nil)
+	dbm.SetAuditLog(log)
+	c := newTestClientFor(t, dbm)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ActorMetadata, "alice")
+
+	_, err := c.CreateDB(ctx, &pdbpb.CreateDBRequest{
+		Name:      "Games",
+		KeyHeader: "Title",
+		Headers:   []*pdbpb.Header{{Name: "Title", Type: pdbpb.Type_TYPE_STRING}},
+	})
+	assert.Nil(t, err)
+	_, err = c.AddRow(ctx, &pdbpb.AddRowRequest{Db: "Games", Row: &pdbpb.Row{Values: map[string]string{"Title": "Jak 2"}}})
+	assert.Nil(t, err)
+	_, err = c.RemoveRow(context.Background(), &pdbpb.RemoveRowRequest{Db: "Games", Key: "Jak 2"})
+	assert.Nil(t, err)
+
+	entries := log.Query(audit.Query{})
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, audit.OPERATION_CREATE_DB, entries[0].Operation)
+	assert.Equal(t, "alice", entries[1].Actor)
+	assert.Equal(t, "add_row", entries[1].Operation)
+	assert.Equal(t, "", entries[2].Actor)
+	assert.Equal(t, "remove_row", entries[2].Operation)
+}
+
+// The codes match the statuses of the HTTP API
+func TestStatusFor(t *testing.T) {
+	tests := map[error]codes.Code{
+		dbmanager.ErrDBNotExist: codes.NotFound,
+		db.ErrRowNotExist:       codes.NotFound,
+		db.ErrHeaderNotExist:    codes.InvalidArgument,
+		dbmanager.ErrDBExists:   codes.AlreadyExists,
+		db.ErrKeyHeaderExists:   codes.InvalidArgument,
+		db.ErrHeaderExists:      codes.AlreadyExists,
+		db.ErrDuplicateKey:      codes.AlreadyExists,
+		db.ErrNotANumber:        codes.InvalidArgument,
+		db.ErrDeleteKeyHeader:   codes.InvalidArgument,
+		db.ErrDivideByZero:      codes.InvalidArgument,
+		errors.New("other"):     codes.InvalidArgument,
+	}
+
+	for sentinel, code := range tests {
+		err := &db.Error{Err: sentinel, Message: "failed"}
+		s := status.Convert(statusFor(err))
+		assert.Equal(t, code, s.Code(), sentinel.Error())
+		assert.Equal(t, "failed", s.Message())
+	}
+
+	assert.Equal(t, codes.Canceled, status.Code(statusFor(context.Canceled)))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(statusFor(context.DeadlineExceeded)))
+}
@@ -0,0 +1,28 @@
+package rpc
+
+import (
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+	"github.com/brownlow2/pdb/pkg/rpc/pdbpb"
+)
+
+var (
+	rowNotExistError     = "row with key value '%s' does not exist"
+	headerNotExistError  = "header '%s' does not exist"
+	headerExistsError    = "header '%s' already exists"
+	keyMismatchError     = "key header value '%s' does not match key '%s'"
+	notANumberError      = "value %s is not a number"
+	unknownTypeError     = "unknown header type %d"
+	unknownOperatorError = "unknown operator %d"
+	negativeError        = "%s must not be negative"
+)
+
+// The metadata key naming who the changes made by a call are attributed to
+const ActorMetadata = "x-actor"
+
+// The implementation of the pdb gRPC service over a DBManager holding the following fields:
+// DBM: The DB manager requests operate on
+type Server struct {
+	pdbpb.UnimplementedPDBServer
+
+	DBM *dbmanager.DBManagerImpl
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brownlow2/pdb/pkg/audit"
+)
+
+// Writes the audit log entries matching the query parameters as JSON lines, oldest first
+// The actor, db, operation, key and header parameters select entries with that value, since
+// and until (RFC 3339) the time they were made in, after the Seq they follow and limit how
+// many are returned
+func (s *Server) audit(w http.ResponseWriter, r *http.Request) {
+	log := s.DBM.AuditLog()
+	if log == nil {
+		writeError(w, http.StatusNotFound, errors.New(auditDisabledError))
+		return
+	}
+
+	q, err := newAuditQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	log.Export(w, q)
+}
+
+func newAuditQuery(r *http.Request) (audit.Query, error) {
+	params := r.URL.Query()
+	q := audit.Query{
+		Actor:     params.Get("actor"),
+		DB:        params.Get("db"),
+		Operation: params.Get("operation"),
+		Key:       params.Get("key"),
+		Header:    params.Get("header"),
+	}
+
+	var err error
+	for _, t := range []struct {
+		param string
+		value *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if params.Get(t.param) == "" {
+			continue
+		}
+
+		*t.value, err = time.Parse(time.RFC3339Nano, params.Get(t.param))
+		if err != nil {
+			return q, errors.New(fmt.Sprintf(invalidQueryError, t.param, err))
+		}
+	}
+
+	if params.Get("after") != "" {
+		q.After, err = strconv.ParseUint(params.Get("after"), 10, 64)
+		if err != nil {
+			return q, errors.New(fmt.Sprintf(invalidQueryError, "after", err))
+		}
+	}
+
+	if params.Get("limit") != "" {
+		q.Limit, err = strconv.Atoi(params.Get("limit"))
+		if err == nil && q.Limit < 0 {
+			err = errors.New(negativeError)
+		}
+		if err != nil {
+			return q, errors.New(fmt.Sprintf(invalidQueryError, "limit", err))
+		}
+	}
+
+	return q, nil
+}
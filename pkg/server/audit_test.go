@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/pkg/audit"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+// Sends the request as actor, returning the status and body
+func doAs(t *testing.T, ts *httptest.Server, actor string, method string, path string, body string) (int, string) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	assert.Nil(t, err)
+	req.Header.Set(ActorHeader, actor)
+	resp, err := ts.Client().Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+
+	return resp.StatusCode, string(data)
+}
+
+func readEntries(t *testing.T, body string) []audit.Entry {
+	entries := []audit.Entry{}
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var e audit.Entry
+		assert.Nil(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func TestAudit(t *testing.T) {
+	dbm := dbmanager.New()
+	ts := httptest.NewServer(New(dbm))
+	t.Cleanup(ts.Close)
+
+	status, _ := do(t, ts, "GET", "/audit", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	dbm.SetAuditLog(audit.New(nil))
+	status, body := doAs(t, ts, "alice", "POST", "/dbs", `{"name": "Plat", "keyHeader": "Title", "headers": [{"name": "Title", "type": "string"}, {"name": "Hours", "type": "number"}]}`)
+	assert.Equal(t, http.StatusCreated, status, body)
+	status, body = doAs(t, ts, "bob", "POST", "/dbs/Plat/rows", `{"Title": "Jak 2", "Hours": 23}`)
+	assert.Equal(t, http.StatusCreated, status, body)
+	status, body = do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 25}`)
+	assert.Equal(t, http.StatusOK, status, body)
+
+	resp, err := ts.Client().Get(ts.URL + "/audit")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	data, _ := io.ReadAll(resp.Body)
+	entries := readEntries(t, string(data))
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, audit.OPERATION_CREATE_DB, entries[0].Operation)
+	assert.Equal(t, "bob", entries[1].Actor)
+	assert.Equal(t, "add_row", entries[1].Operation)
+	assert.Equal(t, "", entries[2].Actor)
+	assert.Equal(t, "update_value", entries[2].Operation)
+	assert.Equal(t, "23", entries[2].OldValue)
+	assert.Equal(t, "25", entries[2].NewValue)
+
+	status, body = do(t, ts, "GET", "/audit?actor=bob", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Jak 2", readEntries(t, body)[0].Key)
+
+	status, body = do(t, ts, "GET", "/audit?after=1&limit=1", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, uint64(2), readEntries(t, body)[0].Seq)
+	assert.Equal(t, 1, len(readEntries(t, body)))
+
+	status, body = doAs(t, ts, "carol", "DELETE", "/dbs/Plat", "")
+	assert.Equal(t, http.StatusNoContent, status, body)
+	status, body = do(t, ts, "GET", "/audit?operation=remove_db&actor=carol", "")
+	assert.Equal(t, 1, len(readEntries(t, body)))
+
+	for _, query := range []string{"since=yesterday", "until=1", "after=-1", "limit=-1"} {
+		status, _ = do(t, ts, "GET", "/audit?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+// The code and HTTP status sent in error responses for each kind of error, checked in order so
+// the most specific kind is found first
+// The gRPC API maps the same statuses to its codes so both APIs agree
+var errorCodes = []struct {
+	code   string
+	err    error
+	status int
+}{
+	{"batch_rejected", db.ErrBatchRejected, http.StatusConflict},
+	{"db_exists", dbmanager.ErrDBExists, http.StatusConflict},
+	{"db_not_exist", dbmanager.ErrDBNotExist, http.StatusNotFound},
+	{"snapshot", dbmanager.ErrSnapshot, http.StatusBadRequest},
+	{"invalid_sql", dbmanager.ErrInvalidSQL, http.StatusBadRequest},
+	{"key_header_incorrect", db.ErrKeyHeaderIncorrect, http.StatusBadRequest},
+	{"key_header_empty", db.ErrKeyHeaderEmpty, http.StatusBadRequest},
+	{"key_header_exists", db.ErrKeyHeaderExists, http.StatusBadRequest},
+	{"header_not_exist", db.ErrHeaderNotExist, http.StatusBadRequest},
+	{"header_exists", db.ErrHeaderExists, http.StatusConflict},
+	{"header_not_number", db.ErrHeaderNotNumber, http.StatusBadRequest},
+	{"delete_key_header", db.ErrDeleteKeyHeader, http.StatusBadRequest},
+	{"update_key_header", db.ErrUpdateKeyHeader, http.StatusBadRequest},
+	{"duplicate_key", db.ErrDuplicateKey, http.StatusConflict},
+	{"key_value_empty", db.ErrKeyValueEmpty, http.StatusBadRequest},
+	{"row_not_exist", db.ErrRowNotExist, http.StatusNotFound},
+	{"not_a_number", db.ErrNotANumber, http.StatusBadRequest},
+	{"invalid_expression", db.ErrInvalidExpression, http.StatusBadRequest},
+	{"divide_by_zero", db.ErrDivideByZero, http.StatusBadRequest},
+	{"unknown_type", db.ErrUnknownType, http.StatusBadRequest},
+	{"unknown_event_type", db.ErrUnknownEventType, http.StatusBadRequest},
+	{"unknown_operator", db.ErrUnknownOperator, http.StatusBadRequest},
+	{"history_expired", db.ErrHistoryExpired, http.StatusBadRequest},
+	{"read_only", db.ErrReadOnly, http.StatusBadRequest},
+	{"nothing_to_undo", db.ErrNothingToUndo, http.StatusBadRequest},
+	{"nothing_to_redo", db.ErrNothingToRedo, http.StatusBadRequest},
+	{"undo_group_open", db.ErrUndoGroupOpen, http.StatusConflict},
+	{"hook_recursion", db.ErrHookRecursion, http.StatusBadRequest},
+	{"invalid_json", db.ErrInvalidJSON, http.StatusBadRequest},
+	{"invalid_xlsx", db.ErrInvalidXLSX, http.StatusBadRequest},
+	{"corrupt", db.ErrCorrupt, http.StatusBadRequest},
+	{"unsupported_version", db.ErrUnsupportedVersion, http.StatusBadRequest},
+	{"buffer_pool_full", db.ErrBufferPoolFull, http.StatusBadRequest},
+	{"key_too_long", db.ErrKeyTooLong, http.StatusBadRequest},
+	{"unknown_backend", db.ErrUnknownBackend, http.StatusBadRequest},
+	{"canceled", context.Canceled, http.StatusServiceUnavailable},
+	{"deadline_exceeded", context.DeadlineExceeded, http.StatusGatewayTimeout},
+}
+
+// Returns the code sent in error responses for the kind of error err is, or an empty string if
+// it isn't one of the known kinds
+func ErrorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+
+	return ""
+}
+
+// Returns the HTTP status for an error returned by the DB manager or a DB
+// Errors that aren't one of the known kinds are caused by invalid input
+func HTTPStatus(err error) int {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.status
+		}
+	}
+
+	return http.StatusBadRequest
+}
+
+// Returns the sentinel error for a code sent in an error response, or nil if the code is unknown
+func CodeError(code string) error {
+	for _, c := range errorCodes {
+		if c.code == code {
+			return c.err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+var (
+	// How often a comment is sent on an idle stream so proxies don't close it
+	heartbeatInterval = 15 * time.Second
+	// The number of events buffered for a stream before it is closed for falling behind
+	eventBuffer = 256
+)
+
+// Streams the DB's changes as Server-Sent Events until the client disconnects
+// The type, key and header query parameters, each repeatable, filter the changes
+// A client resumes from the change after the id in the Last-Event-ID header, or the
+// lastEventId query parameter for clients that can't set headers
+// A client resuming from an id whose following changes are no longer retained, or from before a
+// restart, is sent a reset event first and must reload the DB, resuming from the reset's id
+// A client too slow to keep up has its stream closed and can resume from the last id it saw
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New(streamingError))
+		return
+	}
+
+	filter, err := newEventFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	events, cancel := d.Subscribe(filter)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(newEventJSON(e))
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+func newEventFilter(r *http.Request) (db.EventFilter, error) {
+	query := r.URL.Query()
+	filter := db.EventFilter{
+		Keys:    query["key"],
+		Headers: query["header"],
+		Buffer:  eventBuffer,
+		Policy:  db.POLICY_DISCONNECT,
+	}
+
+	for _, name := range query["type"] {
+		t, err := db.ParseEventType(name)
+		if err != nil {
+			return filter, errors.New(fmt.Sprintf(invalidQueryError, "type", err))
+		}
+		filter.Types = append(filter.Types, t)
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = query.Get("lastEventId")
+	}
+	if lastID != "" {
+		after, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			return filter, errors.New(fmt.Sprintf(invalidQueryError, "lastEventId", err))
+		}
+		filter.After = after
+	}
+
+	return filter, nil
+}
+
+func newEventJSON(e db.Event) EventJSON {
+	eventJSON := EventJSON{
+		Seq:    e.Seq,
+		Type:   e.Type.String(),
+		Time:   e.Time,
+		Actor:  e.Actor,
+		Key:    e.Key,
+		Header: e.Header,
+		Row:    e.Row,
+	}
+	switch e.Type {
+	case db.EVENT_ADD_HEADER:
+		eventJSON.HeaderType = e.HeaderType.String()
+	case db.EVENT_UPDATE_VALUE:
+		eventJSON.OldValue, eventJSON.NewValue = &e.OldValue, &e.NewValue
+	}
+
+	return eventJSON
+}
+
+func newVersionJSON(v db.Version) VersionJSON {
+	versionJSON := VersionJSON{
+		Seq:    v.Seq,
+		Time:   v.Time,
+		Actor:  v.Actor,
+		Type:   v.Type.String(),
+		Header: v.Header,
+		Values: v.Values,
+	}
+	if v.Type == db.EVENT_UPDATE_VALUE {
+		versionJSON.OldValue, versionJSON.NewValue = &v.OldValue, &v.NewValue
+	}
+
+	return versionJSON
+}
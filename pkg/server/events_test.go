@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  EventJSON
+}
+
+// Opens the event stream at path, returning a function reading the next event
+func openEvents(t *testing.T, ts *httptest.Server, path string, lastID string) (int, func() sseEvent) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+path, nil)
+	assert.Nil(t, err)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := ts.Client().Do(req)
+	assert.Nil(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	reader := bufio.NewReader(resp.Body)
+	return resp.StatusCode, func() sseEvent {
+		var e sseEvent
+		for {
+			line, err := reader.ReadString('\n')
+			assert.Nil(t, err)
+			line = strings.TrimSuffix(line, "\n")
+
+			switch {
+			case line == "" && e.id != "":
+				return e
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.data))
+			}
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, next := openEvents(t, ts, "/dbs/Plat/events", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 30}`)
+	e := next()
+	assert.Equal(t, "update_value", e.event)
+	assert.Equal(t, strconv.FormatUint(e.data.Seq, 10), e.id)
+	assert.Equal(t, "Jak 2", e.data.Key)
+	assert.Equal(t, "Hours", e.data.Header)
+	assert.Equal(t, "23", *e.data.OldValue)
+	assert.Equal(t, "30", *e.data.NewValue)
+	lastID := e.id
+
+	do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "Jak 3"}`)
+	e = next()
+	assert.Equal(t, "add_row", e.event)
+	assert.Equal(t, "Jak 3", e.data.Row["Title"])
+
+	do(t, ts, "POST", "/dbs/Plat/headers", `{"name": "Trophies", "type": "number"}`)
+	e = next()
+	assert.Equal(t, "add_header", e.event)
+	assert.Equal(t, "number", e.data.HeaderType)
+
+	do(t, ts, "DELETE", "/dbs/Plat/rows/Jak%203", "")
+	assert.Equal(t, "remove_row", next().event)
+
+	// Resuming replays the changes after the last id seen
+	_, resumed := openEvents(t, ts, "/dbs/Plat/events", lastID)
+	assert.Equal(t, "add_row", resumed().event)
+	assert.Equal(t, "add_header", resumed().event)
+
+	// Resuming from an id the server doesn't have, such as one from before a restart, resets
+	_, reset := openEvents(t, ts, "/dbs/Plat/events", "1000")
+	e = reset()
+	assert.Equal(t, "reset", e.event)
+	do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 31}`)
+	assert.Equal(t, e.data.Seq+1, reset().data.Seq)
+}
+
+func TestEventsFilter(t *testing.T) {
+	ts := newTestServer(t)
+
+	_, next := openEvents(t, ts, "/dbs/Plat/events?type=update_value&header=Platform", "")
+
+	do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 30}`)
+	do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "Jak 3"}`)
+	do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Platform": "PS5"}`)
+
+	e := next()
+	assert.Equal(t, "Platform", e.data.Header)
+	assert.Equal(t, "PS5", *e.data.NewValue)
+
+	status, _ := do(t, ts, "GET", "/dbs/Plat/events?type=rename", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat/events?lastEventId=x", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "GET", "/dbs/Missing/events", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
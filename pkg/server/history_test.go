@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	ts := newTestServer(t)
+
+	do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 30}`)
+	do(t, ts, "DELETE", "/dbs/Plat/rows/Jak%202", "")
+
+	status, body := do(t, ts, "GET", "/dbs/Plat/rows/Jak%202/history", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	var versions []VersionJSON
+	assert.Nil(t, json.Unmarshal([]byte(body), &versions))
+	assert.Equal(t, 3, len(versions))
+	assert.Equal(t, "add_row", versions[0].Type)
+	assert.Equal(t, "23", versions[0].Values["Hours"])
+	assert.Equal(t, "23", *versions[1].OldValue)
+	assert.Equal(t, "30", *versions[1].NewValue)
+	assert.Equal(t, "remove_row", versions[2].Type)
+	assert.Nil(t, versions[2].Values)
+
+	asOf := "?asOf=" + url.QueryEscape(versions[1].Time.Format(time.RFC3339Nano))
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows/Jak%202"+asOf, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Hours":30`)
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows"+asOf+"&Platform=PS4", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"total":1`)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/rows"+asOf, `{"Title": "Jak 3"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "read-only")
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat?asOf=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestRetention(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, _ := do(t, ts, "PUT", "/dbs/Plat/retention", `{"maxChanges": 1}`)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": 30}`)
+	_, body := do(t, ts, "GET", "/dbs/Plat/rows/Jak%202/history", "")
+	var versions []VersionJSON
+	assert.Nil(t, json.Unmarshal([]byte(body), &versions))
+	assert.Equal(t, 1, len(versions))
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat?asOf=2000-01-01T00:00:00Z", "")
+	assert.Equal(t, http.StatusGone, status)
+
+	status, _ = do(t, ts, "PUT", "/dbs/Plat/retention", `{"maxAge": "soon"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "PUT", "/dbs/Plat/retention", `{"maxChanges": -1}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/brownlow2/pdb/internal/db"
+)
+
+func newDBJSON(d db.DB) DBJSON {
+	headers := d.GetHeaders()
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i].IsKeyHeader() != headers[j].IsKeyHeader() {
+			return headers[i].IsKeyHeader()
+		}
+		return headers[i].GetName() < headers[j].GetName()
+	})
+
+	dbJSON := DBJSON{
+		Name:      d.GetName(),
+		KeyHeader: d.GetKeyHeader(),
+		Headers:   []HeaderJSON{},
+		Rows:      len(d.GetRows()),
+	}
+	for _, h := range headers {
+		dbJSON.Headers = append(dbJSON.Headers, newHeaderJSON(h))
+	}
+
+	return dbJSON
+}
+
+func newHeaderJSON(h db.HeaderI) HeaderJSON {
+	return HeaderJSON{Name: h.GetName(), Type: h.GetType().String(), Key: h.IsKeyHeader()}
+}
+
+// Returns the row as a map of header names to values, using JSON numbers for the values of
+// VALUE_NUMBER headers and null for empty numbers
+// NaN and Inf are kept as strings, as JSON numbers can't hold them
+func rowToJSON(row db.RowI) map[string]interface{} {
+	object := map[string]interface{}{}
+	for h, v := range row.GetRowMap() {
+		switch {
+		case !h.IsNumber():
+			object[h.GetName()] = v.GetValue()
+		case v.GetValue() == "":
+			object[h.GetName()] = nil
+		default:
+			if number, ok := db.FormatNumber(v.GetValue()); ok {
+				object[h.GetName()] = json.Number(number)
+			} else {
+				object[h.GetName()] = v.GetValue()
+			}
+		}
+	}
+
+	return object
+}
+
+// Decodes a JSON object of header names to string, number or null values
+func jsonToValues(data []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	object := map[string]interface{}{}
+	err := decoder.Decode(&object)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(invalidBodyError, err))
+	}
+
+	values := map[string]string{}
+	for header, value := range object {
+		switch v := value.(type) {
+		case string:
+			values[header] = v
+		case json.Number:
+			values[header] = v.String()
+		case nil:
+			values[header] = ""
+		default:
+			return nil, errors.New(fmt.Sprintf(invalidJSONValueError, header))
+		}
+	}
+
+	return values, nil
+}
+
+// Decodes a JSON object into a row for the DB
+func decodeRow(d db.DB, data []byte) (db.RowI, error) {
+	values, err := jsonToValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRow(d, values)
+}
+
+// Creates a row for the DB, checking each value matches its header's type
+func newRow(d db.DB, values map[string]string) (db.RowI, error) {
+	for header, value := range values {
+		h := d.GetHeader(header)
+		if h.GetName() == "" {
+			return nil, &db.Error{Err: db.ErrHeaderNotExist, DB: d.GetName(), Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+		}
+
+		err := db.ValidateValue(h, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db.NewRowFromMap(d, values)
+}
+
+// Returns a copy of the rows ordered by their KeyHeader value
+func sortRows(rows []db.RowI) []db.RowI {
+	sorted := append([]db.RowI{}, rows...)
+	sort.Slice(sorted, func(i, j int) bool {
+		_, vi := sorted[i].GetKeyHeaderAndValue()
+		_, vj := sorted[j].GetKeyHeaderAndValue()
+		return vi.GetValue() < vj.GetValue()
+	})
+
+	return sorted
+}
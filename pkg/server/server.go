@@ -0,0 +1,782 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+// Creates a Server serving the DBs in dbm
+func New(dbm *dbmanager.DBManagerImpl) *Server {
+	s := &Server{DBM: dbm, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /dbs", s.listDBs)
+	s.mux.HandleFunc("POST /dbs", s.createDB)
+	s.mux.HandleFunc("GET /dbs/{name}", s.getDB)
+	s.mux.HandleFunc("DELETE /dbs/{name}", s.deleteDB)
+	s.mux.HandleFunc("GET /dbs/{name}/headers", s.listHeaders)
+	s.mux.HandleFunc("POST /dbs/{name}/headers", s.addHeader)
+	s.mux.HandleFunc("DELETE /dbs/{name}/headers/{header}", s.removeHeader)
+	s.mux.HandleFunc("GET /dbs/{name}/rows", s.listRows)
+	s.mux.HandleFunc("POST /dbs/{name}/rows", s.addRows)
+	s.mux.HandleFunc("GET /dbs/{name}/rows/{key}", s.getRow)
+	s.mux.HandleFunc("PUT /dbs/{name}/rows/{key}", s.putRow)
+	s.mux.HandleFunc("PATCH /dbs/{name}/rows/{key}", s.patchRow)
+	s.mux.HandleFunc("DELETE /dbs/{name}/rows/{key}", s.deleteRow)
+	s.mux.HandleFunc("POST /dbs/{name}/rows/{key}/increment", s.increment)
+	s.mux.HandleFunc("POST /dbs/{name}/update", s.updateWhere)
+	s.mux.HandleFunc("POST /dbs/{name}/delete", s.deleteWhere)
+	s.mux.HandleFunc("POST /dbs/{name}/expression", s.updateExpression)
+	s.mux.HandleFunc("PUT /dbs/{name}/settings", s.putSettings)
+	s.mux.HandleFunc("GET /dbs/{name}/events", s.events)
+	s.mux.HandleFunc("GET /dbs/{name}/rows/{key}/history", s.history)
+	s.mux.HandleFunc("PUT /dbs/{name}/retention", s.putRetention)
+	s.mux.HandleFunc("GET /audit", s.audit)
+
+	return s
+}
+
+// Serves the request, attributing the changes it makes to the actor in its ActorHeader
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actor := r.Header.Get(ActorHeader)
+	if actor != "" {
+		r = r.WithContext(db.WithActor(r.Context(), actor))
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Returns the DB manager attributing changes to the actor making the request
+func (s *Server) manager(r *http.Request) dbmanager.DBManager {
+	return s.DBM.WithContext(r.Context())
+}
+
+func (s *Server) listDBs(w http.ResponseWriter, r *http.Request) {
+	dbs := []DBJSON{}
+	for _, d := range s.DBM.GetDBs() {
+		dbs = append(dbs, newDBJSON(d))
+	}
+	sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name < dbs[j].Name })
+
+	writeJSON(w, http.StatusOK, dbs)
+}
+
+func (s *Server) createDB(w http.ResponseWriter, r *http.Request) {
+	var body DBJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	headers := []db.HeaderI{}
+	for _, h := range body.Headers {
+		t, err := db.ParseType(h.Type)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		headers = append(headers, &db.Header{Name: h.Name, KeyHeader: h.Name == body.KeyHeader, Type: t})
+	}
+
+	err := s.manager(r).CreateDB(body.Name, headers, body.KeyHeader)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	d, _ := s.DBM.RetrieveDB(body.Name)
+	writeJSON(w, http.StatusCreated, newDBJSON(d))
+}
+
+func (s *Server) getDB(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newDBJSON(d))
+}
+
+func (s *Server) deleteDB(w http.ResponseWriter, r *http.Request) {
+	err := s.manager(r).RemoveDB(r.PathValue("name"))
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listHeaders(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newDBJSON(d).Headers)
+}
+
+func (s *Server) addHeader(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body HeaderJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	t, err := db.ParseType(body.Type)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if d.GetHeader(body.Name).GetName() != "" {
+		writeError(w, http.StatusConflict, &db.Error{Err: db.ErrHeaderExists, DB: d.GetName(), Header: body.Name, Message: fmt.Sprintf(headerExistsError, body.Name)})
+		return
+	}
+
+	h := &db.Header{Name: body.Name, KeyHeader: false, Type: t}
+	d.AddHeader(h)
+	writeJSON(w, http.StatusCreated, newHeaderJSON(h))
+}
+
+func (s *Server) removeHeader(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	header := r.PathValue("header")
+	if d.GetHeader(header).GetName() == "" {
+		writeError(w, http.StatusNotFound, &db.Error{Err: db.ErrHeaderNotExist, DB: d.GetName(), Header: header, Message: fmt.Sprintf(headerNotExistError, header)})
+		return
+	}
+
+	err := d.RemoveHeader(header)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Lists the rows ordered by KeyHeader value
+// Query parameters filter the rows, <header>=<value> keeps rows with that value and
+// <header>[ne], <header>[lt] and <header>[gt] compare against the value instead
+// The limit and offset parameters select a page of the matching rows
+func (s *Server) listRows(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, offset := 0, 0
+	predicates := []db.Predicate{}
+	for param, values := range query {
+		var err error
+		switch param {
+		case "limit":
+			limit, err = strconv.Atoi(values[0])
+			if err == nil && limit < 0 {
+				err = errors.New(negativeError)
+			}
+		case "offset":
+			offset, err = strconv.Atoi(values[0])
+			if err == nil && offset < 0 {
+				err = errors.New(negativeError)
+			}
+		case "asOf":
+		default:
+			var predicate db.Predicate
+			for _, value := range values {
+				predicate, err = newFilter(d, param, value)
+				if err != nil {
+					break
+				}
+				predicates = append(predicates, predicate)
+			}
+			// Report missing headers the same way the DB does
+			if err != nil && d.GetHeader(filterHeader(param)).GetName() == "" {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(invalidQueryError, param, err)))
+			return
+		}
+	}
+
+	// Stop scanning once the client goes away
+	ctx := r.Context()
+	all, err := getRows(ctx, d)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	rows := []db.RowI{}
+	for _, row := range all {
+		if err := ctx.Err(); err != nil {
+			writeError(w, HTTPStatus(err), err)
+			return
+		}
+
+		matches := true
+		for _, predicate := range predicates {
+			matches = matches && predicate(row)
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+	rows = sortRows(rows)
+
+	page := RowsJSON{Rows: []map[string]interface{}{}, Total: len(rows), Offset: offset, Limit: limit}
+	if offset < len(rows) {
+		rows = rows[offset:]
+		if limit > 0 && limit < len(rows) {
+			rows = rows[:limit]
+		}
+		for _, row := range rows {
+			page.Rows = append(page.Rows, rowToJSON(row))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Adds a single row from a JSON object, or several from a JSON array where either all
+// of them are added or none are, unless the mode=best-effort query parameter is given
+func (s *Server) addRows(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body json.RawMessage
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		row, err := decodeRow(d, body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		err = d.AddRow(row)
+		if err != nil {
+			writeError(w, HTTPStatus(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, rowToJSON(row))
+		return
+	}
+
+	var objects []json.RawMessage
+	err := json.Unmarshal(body, &objects)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(invalidBodyError, err)))
+		return
+	}
+
+	bestEffort := r.URL.Query().Get("mode") == "best-effort"
+	errs := make([]error, len(objects))
+	failed := false
+	rows := []db.RowI{}
+	indexes := []int{}
+	for i, object := range objects {
+		row, err := decodeRow(d, object)
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+		rows = append(rows, row)
+		indexes = append(indexes, i)
+	}
+
+	// Rows that couldn't be decoded fail the whole request unless adding on a best effort basis
+	var afterErr error
+	if !failed || bestEffort {
+		var rowErrs []error
+		rowErrs, afterErr = d.AddRows(rows, db.AddRowsOptions{AllOrNothing: !bestEffort})
+		for i, err := range rowErrs {
+			if err != nil {
+				errs[indexes[i]] = err
+				failed = true
+			}
+		}
+	}
+
+	if failed || afterErr != nil {
+		resp := ErrorJSON{Details: make([]string, len(errs)), Codes: make([]string, len(errs))}
+		status := http.StatusBadRequest
+		for i, err := range errs {
+			if err != nil {
+				resp.Details[i] = err.Error()
+				resp.Codes[i] = ErrorCode(err)
+			}
+		}
+		if _, err := db.FirstRowError(errs); err != nil {
+			resp.Error = err.Error()
+			resp.Code = ErrorCode(err)
+			status = HTTPStatus(err)
+		}
+		// Rows that were added have no error of their own when a hook fails after adding them
+		if resp.Error == "" {
+			resp.Error = afterErr.Error()
+			resp.Code = ErrorCode(afterErr)
+			status = HTTPStatus(afterErr)
+		}
+		writeJSON(w, status, resp)
+		return
+	}
+
+	objectsJSON := []map[string]interface{}{}
+	for _, row := range rows {
+		objectsJSON = append(objectsJSON, rowToJSON(row))
+	}
+	writeJSON(w, http.StatusCreated, objectsJSON)
+}
+
+func (s *Server) getRow(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	row, ok := retrieveRow(w, r, d)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rowToJSON(row))
+}
+
+// Creates or replaces the row with the key in the path
+// Headers missing from the body are cleared, PATCH being used to change only some of them
+func (s *Server) putRow(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	key := r.PathValue("key")
+	values, ok := decodeValues(w, r, d, key)
+	if !ok {
+		return
+	}
+	for _, h := range d.GetHeaders() {
+		if _, ok := values[h.GetName()]; !ok {
+			values[h.GetName()] = ""
+		}
+	}
+	values[d.GetKeyHeader()] = key
+
+	row, err := newRow(d, values)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	status := http.StatusOK
+	if d.GetRowFromKeyHeader(key) == nil {
+		status = http.StatusCreated
+	}
+
+	err = d.Upsert(row)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	writeJSON(w, status, rowToJSON(d.GetRowFromKeyHeader(key)))
+}
+
+// Updates the given values of an existing row
+func (s *Server) patchRow(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	_, ok = retrieveRow(w, r, d)
+	if !ok {
+		return
+	}
+
+	key := r.PathValue("key")
+	values, ok := decodeValues(w, r, d, key)
+	if !ok {
+		return
+	}
+
+	_, err := d.UpdateWhere(func(row db.RowI) bool {
+		return row.KeyHeaderValueEqual(key)
+	}, values)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rowToJSON(d.GetRowFromKeyHeader(key)))
+}
+
+func (s *Server) deleteRow(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	_, ok = retrieveRow(w, r, d)
+	if !ok {
+		return
+	}
+
+	err := d.RemoveRow(r.PathValue("key"))
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) increment(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body IncrementJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	value, err := d.Increment(r.PathValue("key"), body.Header, body.Delta)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NumberJSON{Value: value})
+}
+
+func (s *Server) updateWhere(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body BulkJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	count, err := d.UpdateWhere(body.predicate(), body.Values)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CountJSON{Count: count})
+}
+
+func (s *Server) deleteWhere(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body BulkJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	count, err := d.DeleteWhere(body.predicate())
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CountJSON{Count: count})
+}
+
+func (s *Server) updateExpression(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body BulkJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	count, err := d.UpdateExpression(body.predicate(), body.Expression)
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CountJSON{Count: count})
+}
+
+// Lists the retained versions of the row, oldest first, even if it has since been removed
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	versions := []VersionJSON{}
+	for _, v := range d.History(r.PathValue("key")) {
+		versions = append(versions, newVersionJSON(v))
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+func (s *Server) putRetention(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body RetentionJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	retention := db.Retention{MaxChanges: body.MaxChanges}
+	if body.MaxAge != "" {
+		var err error
+		retention.MaxAge, err = time.ParseDuration(body.MaxAge)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(invalidBodyError, err)))
+			return
+		}
+	}
+
+	if retention.MaxChanges < 0 || retention.MaxAge < 0 {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(invalidBodyError, negativeError)))
+		return
+	}
+
+	d.SetRetention(retention)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) putSettings(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.retrieveDB(w, r)
+	if !ok {
+		return
+	}
+
+	var body SettingsJSON
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	d.SetEmptyAsZero(body.EmptyAsZero)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns a predicate matching the rows with the given keys, or nil to match every row
+func (b BulkJSON) predicate() db.Predicate {
+	if b.Keys == nil {
+		return nil
+	}
+
+	keys := map[string]struct{}{}
+	for _, key := range b.Keys {
+		keys[key] = struct{}{}
+	}
+
+	return func(row db.RowI) bool {
+		_, v := row.GetKeyHeaderAndValue()
+		_, exists := keys[v.GetValue()]
+		return exists
+	}
+}
+
+// Returns the DB named in the path, writing a 404 response if it doesn't exist
+// GET requests with the asOf query parameter get the DB as it was at that time instead
+func (s *Server) retrieveDB(w http.ResponseWriter, r *http.Request) (db.DB, bool) {
+	d, err := s.manager(r).RetrieveDB(r.PathValue("name"))
+	if err != nil {
+		writeError(w, HTTPStatus(err), err)
+		return nil, false
+	}
+
+	asOf := r.URL.Query().Get("asOf")
+	if asOf == "" {
+		return d, true
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusBadRequest, &db.Error{Err: db.ErrReadOnly, DB: d.GetName(), Message: fmt.Sprintf(readOnlyError, d.GetName())})
+		return nil, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(invalidQueryError, "asOf", err)))
+		return nil, false
+	}
+
+	d, err = d.AsOf(t)
+	if err != nil {
+		writeError(w, http.StatusGone, err)
+		return nil, false
+	}
+
+	return d, true
+}
+
+// Returns the row with the key in the path, writing a 404 response if it doesn't exist
+func retrieveRow(w http.ResponseWriter, r *http.Request, d db.DB) (db.RowI, bool) {
+	key := r.PathValue("key")
+	row := d.GetRowFromKeyHeader(key)
+	if row == nil {
+		writeError(w, http.StatusNotFound, &db.Error{Err: db.ErrRowNotExist, DB: d.GetName(), Key: key, Message: fmt.Sprintf(rowNotExistError, key)})
+		return nil, false
+	}
+
+	return row, true
+}
+
+// Decodes a JSON object of values for the row with the given key, removing the KeyHeader
+// Writes a 400 response if the body is invalid or sets the KeyHeader to a different value
+func decodeValues(w http.ResponseWriter, r *http.Request, d db.DB, key string) (map[string]string, bool) {
+	var body json.RawMessage
+	if !decodeBody(w, r, &body) {
+		return nil, false
+	}
+
+	values, err := jsonToValues(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if v, exists := values[d.GetKeyHeader()]; exists {
+		if v != key {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(keyMismatchError, v, key)))
+			return nil, false
+		}
+		delete(values, d.GetKeyHeader())
+	}
+
+	return values, true
+}
+
+// Decodes the request body into v, writing a 400 response if it isn't valid JSON
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf(invalidBodyError, err)))
+		return false
+	}
+
+	return true
+}
+
+// Returns a predicate for a query parameter, either <header> for equality or
+// <header>[ne], <header>[lt] or <header>[gt]
+func newFilter(d db.DB, param string, value string) (db.Predicate, error) {
+	header, op := filterHeader(param), "eq"
+	if header != param {
+		op = param[len(header)+1 : len(param)-1]
+	}
+
+	if d.GetHeader(header).GetName() == "" {
+		return nil, &db.Error{Err: db.ErrHeaderNotExist, DB: d.GetName(), Header: header, Message: fmt.Sprintf(headerNotExistError, header)}
+	}
+
+	switch op {
+	case "eq", "ne":
+		equal := op == "eq"
+		return func(row db.RowI) bool {
+			v, err := row.GetValueFromHeader(header)
+			return err == nil && (v.GetValue() == value) == equal
+		}, nil
+	case "lt", "gt":
+		target, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, &db.Error{Err: db.ErrNotANumber, Value: value, Message: fmt.Sprintf(notANumberError, value)}
+		}
+		return func(row db.RowI) bool {
+			v, err := row.GetValueFromHeader(header)
+			if err != nil {
+				return false
+			}
+			f, err := strconv.ParseFloat(v.GetValue(), 64)
+			if err != nil {
+				return false
+			}
+			if op == "lt" {
+				return f < target
+			}
+			return f > target
+		}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf(unknownOperatorError, op))
+}
+
+// Returns the header a query parameter filters on, without any [op] suffix
+func filterHeader(param string) string {
+	if i := strings.LastIndex(param, "["); i > 0 && strings.HasSuffix(param, "]") {
+		return param[:i]
+	}
+
+	return param
+}
+
+// Returns the DB's rows, giving up once ctx is done if the DB supports it
+func getRows(ctx context.Context, d db.DB) ([]db.RowI, error) {
+	if dc, ok := d.(db.DBContext); ok {
+		return dc.GetRowsContext(ctx)
+	}
+
+	return d.GetRows(), nil
+}
+
+// Writes v as the JSON body of the response with the given status
+// v is encoded before anything is written, so a value that can't be encoded is answered with a
+// 500 rather than the status and an empty body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		buf.Reset()
+		json.NewEncoder(buf).Encode(ErrorJSON{Error: fmt.Sprintf(encodeResponseError, err)})
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorJSON{Error: err.Error(), Code: ErrorCode(err)})
+}
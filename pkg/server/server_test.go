@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brownlow2/pdb/internal/db"
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+// Sends a request to the server, returning the status code and body
+func do(t *testing.T, ts *httptest.Server, method string, path string, body string) (int, string) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	assert.Nil(t, err)
+	resp, err := ts.Client().Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+
+	return resp.StatusCode, string(data)
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(New(dbmanager.New()))
+	t.Cleanup(ts.Close)
+
+	status, body := do(t, ts, "POST", "/dbs", `{
+		"name": "Plat",
+		"keyHeader": "Title",
+		"headers": [
+			{"name": "Title", "type": "string"},
+			{"name": "Platform", "type": "string"},
+			{"name": "Hours", "type": "number"}
+		]
+	}`)
+	assert.Equal(t, http.StatusCreated, status, body)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/rows", `[
+		{"Title": "Jak 2", "Platform": "PS4", "Hours": 23},
+		{"Title": "Hogwarts Legacy", "Platform": "PS5", "Hours": 55},
+		{"Title": "Destroy All Humans", "Platform": "PS5", "Hours": null}
+	]`)
+	assert.Equal(t, http.StatusCreated, status, body)
+
+	return ts
+}
+
+func TestDBs(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "GET", "/dbs", "")
+	assert.Equal(t, http.StatusOK, status)
+	var dbs []DBJSON
+	assert.Nil(t, json.Unmarshal([]byte(body), &dbs))
+	assert.Equal(t, 1, len(dbs))
+	assert.Equal(t, "Plat", dbs[0].Name)
+	assert.Equal(t, 3, dbs[0].Rows)
+	assert.Equal(t, HeaderJSON{"Title", "string", true}, dbs[0].Headers[0])
+
+	status, _ = do(t, ts, "POST", "/dbs", `{"name": "Plat", "keyHeader": "Title", "headers": [{"name": "Title", "type": "string"}]}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, _ = do(t, ts, "POST", "/dbs", `{"name": "Other", "keyHeader": "Title", "headers": [{"name": "Title", "type": "bool"}]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "POST", "/dbs", `{"name": `)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "GET", "/dbs/Missing", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat", "")
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestHeaders(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "POST", "/dbs/Plat/headers", `{"name": "Trophies", "type": "number"}`)
+	assert.Equal(t, http.StatusCreated, status, body)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/headers", `{"name": "Trophies", "type": "number"}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/headers", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `{"name":"Trophies","type":"number","key":false}`)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat/headers/Title", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat/headers/Trophies", "")
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat/headers/Trophies", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestListRows(t *testing.T) {
+	ts := newTestServer(t)
+
+	var page RowsJSON
+	status, body := do(t, ts, "GET", "/dbs/Plat/rows?Platform=PS5", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, json.Unmarshal([]byte(body), &page))
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, "Destroy All Humans", page.Rows[0]["Title"])
+	assert.Nil(t, page.Rows[0]["Hours"])
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows?Hours[gt]=30", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, json.Unmarshal([]byte(body), &page))
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, 55.0, page.Rows[0]["Hours"])
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows?limit=1&offset=1", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, json.Unmarshal([]byte(body), &page))
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, 1, len(page.Rows))
+	assert.Equal(t, "Hogwarts Legacy", page.Rows[0]["Title"])
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows?offset=5&Platform[ne]=PS4", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, json.Unmarshal([]byte(body), &page))
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, 0, len(page.Rows))
+
+	for _, query := range []string{"Missing=1", "Hours[lt]=many", "Hours[le]=1", "limit=-1", "offset=x"} {
+		status, _ = do(t, ts, "GET", "/dbs/Plat/rows?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+
+	status, _ = do(t, ts, "GET", "/dbs/Missing/rows", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestListRowsCancelled(t *testing.T) {
+	s := New(dbmanager.New())
+	assert.Nil(t, s.DBM.CreateDB("Games", []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}, "Title"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest("GET", "/dbs/Games/rows", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestAddRows(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "New", "Hours": "4.5"}`)
+	assert.Equal(t, http.StatusCreated, status, body)
+	assert.Contains(t, body, `"Hours":4.5`)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "New"}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "Other", "Hours": "lots"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "Other", "Missing": "1"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "Other", "Hours": [1]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/rows", `[{"Title": "Batch"}, {"Title": "Jak 2"}]`)
+	assert.Equal(t, http.StatusConflict, status)
+	var errJSON ErrorJSON
+	assert.Nil(t, json.Unmarshal([]byte(body), &errJSON))
+	assert.Equal(t, 2, len(errJSON.Details))
+	assert.Contains(t, errJSON.Details[0], "another row in the batch failed")
+	assert.Equal(t, errJSON.Error, errJSON.Details[1])
+	assert.Equal(t, []string{"batch_rejected", "duplicate_key"}, errJSON.Codes)
+	assert.Equal(t, "duplicate_key", errJSON.Code)
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat/rows/Batch", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestAddRowsAfterHookError(t *testing.T) {
+	dbm := dbmanager.New()
+	assert.Nil(t, dbm.CreateDB("Plat", []db.HeaderI{&db.Header{Name: "Title", KeyHeader: true, Type: db.VALUE_STRING}}, "Title"))
+	d, _ := dbm.RetrieveDB("Plat")
+	d.(*db.DBImpl).AfterInsert(func(change *db.Change) error {
+		return db.ErrReadOnly
+	})
+	ts := httptest.NewServer(New(dbm))
+	t.Cleanup(ts.Close)
+
+	// Every row is added, so none has an error of its own
+	status, body := do(t, ts, "POST", "/dbs/Plat/rows", `[{"Title": "a"}, {"Title": "b"}]`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	var errJSON ErrorJSON
+	assert.Nil(t, json.Unmarshal([]byte(body), &errJSON))
+	assert.Equal(t, "read_only", errJSON.Code)
+	assert.Equal(t, []string{"", ""}, errJSON.Details)
+	assert.Equal(t, 2, len(d.GetRows()))
+}
+
+func TestRow(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "GET", "/dbs/Plat/rows/Jak%202", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Platform":"PS4"`)
+
+	status, body = do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Platform": "PS5"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Platform":"PS5"`)
+	assert.Contains(t, body, `"Hours":23`)
+
+	status, _ = do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Hours": "lots"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "PATCH", "/dbs/Plat/rows/Jak%202", `{"Title": "Renamed"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "PATCH", "/dbs/Plat/rows/Missing", `{"Platform": "PS5"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// PUT replaces the row, clearing the headers the body leaves out
+	status, body = do(t, ts, "PUT", "/dbs/Plat/rows/Jak%202", `{"Title": "Jak 2", "Hours": 30}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Hours":30`)
+	assert.Contains(t, body, `"Platform":""`)
+
+	status, body = do(t, ts, "PUT", "/dbs/Plat/rows/Jak%203", `{"Platform": "PS4"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Contains(t, body, `"Title":"Jak 3"`)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat/rows/Jak%203", "")
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = do(t, ts, "DELETE", "/dbs/Plat/rows/Jak%203", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat/rows/Jak%203", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestRowNumbers(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "POST", "/dbs/Plat/rows", `[{"Title": "Nan", "Hours": "NaN"}, {"Title": "Plus", "Hours": "+5"}]`)
+	assert.Equal(t, http.StatusCreated, status, body)
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows/Nan", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Hours":"NaN"`)
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows/Plus", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"Hours":5`)
+
+	status, body = do(t, ts, "GET", "/dbs/Plat/rows", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, json.Valid([]byte(body)), body)
+}
+
+func TestWriteJSONEncodeError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"Hours": math.NaN()})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var e ErrorJSON
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &e))
+	assert.Contains(t, e.Error, "could not be encoded")
+}
+
+func TestAddRowsBestEffort(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "POST", "/dbs/Plat/rows?mode=best-effort", `[{"Title": "New"}, {"Title": "Jak 2"}, {"Title": "Bad", "Hours": "lots"}]`)
+	assert.Equal(t, http.StatusConflict, status)
+	var errJSON ErrorJSON
+	assert.Nil(t, json.Unmarshal([]byte(body), &errJSON))
+	assert.Equal(t, "", errJSON.Details[0])
+	assert.NotEqual(t, "", errJSON.Details[1])
+	assert.NotEqual(t, "", errJSON.Details[2])
+
+	status, _ = do(t, ts, "GET", "/dbs/Plat/rows/New", "")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestBulk(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := do(t, ts, "POST", "/dbs/Plat/update", `{"keys": ["Jak 2", "Hogwarts Legacy"], "values": {"Platform": "PS3"}}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{\"count\":2}\n", body)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/update", `{"keys": null, "values": {"Hours": "lots"}}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/expression", `{"keys": ["Jak 2"], "expression": "\"Hours\" = \"Hours\" * 2"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{\"count\":1}\n", body)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/rows/Jak%202/increment", `{"header": "Hours", "delta": 4}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{\"value\":50}\n", body)
+
+	status, _ = do(t, ts, "POST", "/dbs/Plat/rows/Destroy%20All%20Humans/increment", `{"header": "Hours", "delta": 1}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = do(t, ts, "PUT", "/dbs/Plat/settings", `{"emptyAsZero": true}`)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/rows/Destroy%20All%20Humans/increment", `{"header": "Hours", "delta": 1}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{\"value\":1}\n", body)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/delete", `{"keys": ["Jak 2", "Missing"]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{\"count\":1}\n", body)
+
+	status, body = do(t, ts, "POST", "/dbs/Plat/delete", `{"keys": null}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{\"count\":2}\n", body)
+}
+
+func TestErrorCode(t *testing.T) {
+	ts := newTestServer(t)
+
+	var errJSON ErrorJSON
+	status, body := do(t, ts, "POST", "/dbs/Plat/rows", `{"Title": "Jak 2", "Platform": "PS4"}`)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Nil(t, json.Unmarshal([]byte(body), &errJSON))
+	assert.Equal(t, "duplicate_key", errJSON.Code)
+
+	status, body = do(t, ts, "GET", "/dbs/Missing", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Nil(t, json.Unmarshal([]byte(body), &errJSON))
+	assert.Equal(t, "db_not_exist", errJSON.Code)
+
+	for _, c := range errorCodes {
+		assert.Equal(t, c.code, ErrorCode(&db.Error{Err: c.err}))
+		assert.Equal(t, c.err, CodeError(c.code))
+	}
+	assert.Equal(t, "", ErrorCode(errors.New("other")))
+	assert.Nil(t, CodeError("other"))
+}
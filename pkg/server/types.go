@@ -0,0 +1,169 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/brownlow2/pdb/pkg/dbmanager"
+)
+
+var (
+	rowNotExistError      = "row with key value '%s' does not exist"
+	headerNotExistError   = "header '%s' does not exist"
+	invalidBodyError      = "invalid request body: %s"
+	invalidQueryError     = "invalid query parameter '%s': %s"
+	keyMismatchError      = "key header value '%s' does not match '%s' in the path"
+	invalidJSONValueError = "value for header '%s' must be a string or number"
+	headerExistsError     = "header '%s' already exists"
+	notANumberError       = "value %s is not a number"
+	unknownOperatorError  = "unknown operator '%s', expected eq, ne, lt or gt"
+	negativeError         = "must not be negative"
+	streamingError        = "streaming is not supported by the connection"
+	readOnlyError         = "database '%s' is read-only"
+	auditDisabledError    = "the audit log is not enabled"
+	encodeResponseError   = "the response could not be encoded: %s"
+)
+
+// The request header naming who the changes made by a request are attributed to
+const ActorHeader = "X-Actor"
+
+// The implementation of the HTTP API over a DBManager holding the following fields:
+// DBM: The DB manager requests operate on
+// mux: Routes requests to their handlers
+type Server struct {
+	DBM *dbmanager.DBManagerImpl
+	mux *http.ServeMux
+}
+
+// The JSON representation of a DB's schema holding the following fields:
+// Name: The name of the DB
+// KeyHeader: The KeyHeader of the DB
+// Headers: The headers of the DB, KeyHeader first
+// Rows: The number of rows in the DB
+type DBJSON struct {
+	Name      string       `json:"name"`
+	KeyHeader string       `json:"keyHeader"`
+	Headers   []HeaderJSON `json:"headers"`
+	Rows      int          `json:"rows"`
+}
+
+// The JSON representation of a header holding the following fields:
+// Name: The name of the header
+// Type: The type of the header, either "string" or "number"
+// Key: True if the header is the KeyHeader
+type HeaderJSON struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Key  bool   `json:"key"`
+}
+
+// The JSON response for a page of rows holding the following fields:
+// Rows: The rows in the page, each a map of header names to values
+// Total: The number of rows matching the filters before pagination
+// Offset: The index of the first row in the page
+// Limit: The maximum number of rows in a page, 0 if there is no limit
+type RowsJSON struct {
+	Rows   []map[string]interface{} `json:"rows"`
+	Total  int                      `json:"total"`
+	Offset int                      `json:"offset"`
+	Limit  int                      `json:"limit"`
+}
+
+// The JSON body of an error response holding the following fields:
+// Error: The error message
+// Code: The kind of error, as returned by ErrorCode, or empty if it isn't a known kind
+// Details: Per row errors when adding several rows, empty for rows that were added, which is every
+// row when a hook failed after adding them
+// Codes: The kind of each of the per row errors in Details
+type ErrorJSON struct {
+	Error   string   `json:"error"`
+	Code    string   `json:"code,omitempty"`
+	Details []string `json:"details,omitempty"`
+	Codes   []string `json:"codes,omitempty"`
+}
+
+// The JSON body of the bulk update, delete and expression requests holding the following fields:
+// Keys: The KeyHeader values of the rows to apply to, or null for every row
+// Values: The header names to values set by an update
+// Expression: The arithmetic expression applied by an expression update
+type BulkJSON struct {
+	Keys       []string          `json:"keys"`
+	Values     map[string]string `json:"values,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+}
+
+// The JSON response for a bulk request holding the following fields:
+// Count: The number of rows the request applied to
+type CountJSON struct {
+	Count int `json:"count"`
+}
+
+// The JSON body of an increment request holding the following fields:
+// Header: The VALUE_NUMBER header to increment
+// Delta: The amount added to the header's value
+type IncrementJSON struct {
+	Header string  `json:"header"`
+	Delta  float64 `json:"delta"`
+}
+
+// The JSON response holding a single number in the following field:
+// Value: The number
+type NumberJSON struct {
+	Value float64 `json:"value"`
+}
+
+// The JSON body for a DB's settings holding the following fields:
+// EmptyAsZero: Whether empty VALUE_NUMBER values are treated as zero by numeric updates
+type SettingsJSON struct {
+	EmptyAsZero bool `json:"emptyAsZero"`
+}
+
+// The JSON data of a change streamed from /dbs/{name}/events holding the following fields:
+// Seq: The sequence number of the change, also sent as the SSE event id
+// Type: The kind of change, also sent as the SSE event name
+// Time: When the change was made
+// Actor: Who made the change, if known
+// Key: The KeyHeader value of the row that changed
+// Header: The header that changed
+// HeaderType: The type of an added header
+// OldValue, NewValue: The value before and after an update
+// Row: The values of an added or removed row
+type EventJSON struct {
+	Seq        uint64            `json:"seq"`
+	Type       string            `json:"type"`
+	Time       time.Time         `json:"time"`
+	Actor      string            `json:"actor,omitempty"`
+	Key        string            `json:"key,omitempty"`
+	Header     string            `json:"header,omitempty"`
+	HeaderType string            `json:"headerType,omitempty"`
+	OldValue   *string           `json:"oldValue,omitempty"`
+	NewValue   *string           `json:"newValue,omitempty"`
+	Row        map[string]string `json:"row,omitempty"`
+}
+
+// The JSON representation of a version of a row holding the following fields:
+// Seq: The sequence number of the change that made the version
+// Time: When the change was made
+// Actor: Who made the change, if known
+// Type: The kind of change
+// Header: The header that changed
+// OldValue, NewValue: The header's value before and after an update
+// Values: The row's values after the change, null if the row was removed
+type VersionJSON struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor,omitempty"`
+	Type     string            `json:"type"`
+	Header   string            `json:"header,omitempty"`
+	OldValue *string           `json:"oldValue,omitempty"`
+	NewValue *string           `json:"newValue,omitempty"`
+	Values   map[string]string `json:"values"`
+}
+
+// The JSON body for a DB's history retention holding the following fields:
+// MaxChanges: The number of changes kept, 0 for no limit
+// MaxAge: How long changes are kept for as a Go duration such as "72h", empty for no limit
+type RetentionJSON struct {
+	MaxChanges int    `json:"maxChanges"`
+	MaxAge     string `json:"maxAge,omitempty"`
+}